@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -34,6 +35,9 @@ import (
 	yaml "gopkg.in/yaml.v2"
 )
 
+// taggerStreamHeartbeat is how often an empty batch is sent to tagger stream clients
+const taggerStreamHeartbeat = 30 * time.Second
+
 // SetupHandlers adds the specific handlers for /agent endpoints
 func SetupHandlers(r *mux.Router) {
 	r.HandleFunc("/version", common.GetVersion).Methods("GET")
@@ -51,6 +55,7 @@ func SetupHandlers(r *mux.Router) {
 	r.HandleFunc("/config-check", getConfigCheck).Methods("GET")
 	r.HandleFunc("/config", getRuntimeConfig).Methods("GET")
 	r.HandleFunc("/tagger-list", getTaggerList).Methods("GET")
+	r.HandleFunc("/tagger-stream", streamTagger).Methods("GET")
 	r.HandleFunc("/secrets", secretInfo).Methods("GET")
 }
 
@@ -293,6 +298,76 @@ func getTaggerList(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonTags)
 }
 
+// streamTagger streams the changes of the entity tags as JSON lines, each holding a
+// batch of events, until the client disconnects. The first batch holds every entity
+// known by the tagger. Empty batches are sent regularly to detect dead clients.
+func streamTagger(w http.ResponseWriter, r *http.Request) {
+	cardinality := collectors.LowCardinality
+	if c := r.URL.Query().Get("cardinality"); c != "" {
+		var err error
+		if cardinality, err = tagger.StringToTagCardinality(c); err != nil {
+			body, _ := json.Marshal(map[string]string{"error": err.Error()})
+			http.Error(w, string(body), 400)
+			return
+		}
+	}
+
+	ch, err := tagger.Subscribe(cardinality)
+	if err != nil {
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+	defer tagger.Unsubscribe(ch)
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		body, _ := json.Marshal(map[string]string{"error": "streaming is not supported"})
+		http.Error(w, string(body), 500)
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		log.Errorf("Unable to start the tagger stream: %s", err)
+		return
+	}
+	defer conn.Close()
+	// the stream outlives the write timeout of the API server
+	conn.SetWriteDeadline(time.Time{})
+
+	buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/x-ndjson\r\nConnection: close\r\n\r\n")
+	encoder := json.NewEncoder(buf)
+	heartbeat := time.NewTicker(taggerStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		if err := buf.Flush(); err != nil {
+			log.Debugf("Tagger stream client disconnected: %s", err)
+			return
+		}
+
+		res := response.TaggerStreamResponse{Events: []response.TaggerStreamEvent{}}
+		select {
+		case events, ok := <-ch:
+			if !ok {
+				// the client lagged behind, it has to reconnect
+				return
+			}
+			for _, e := range events {
+				res.Events = append(res.Events, response.TaggerStreamEvent{
+					Type:   e.EventType.String(),
+					Entity: e.Entity,
+					Tags:   e.Tags,
+				})
+			}
+		case <-heartbeat.C:
+		}
+		if err := encoder.Encode(res); err != nil {
+			log.Debugf("Tagger stream client disconnected: %s", err)
+			return
+		}
+	}
+}
+
 func secretInfo(w http.ResponseWriter, r *http.Request) {
 	info, err := secrets.GetDebugInfo()
 	if err != nil {
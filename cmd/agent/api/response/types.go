@@ -27,3 +27,15 @@ type TaggerListEntity struct {
 	Sources []string `json:"sources"`
 	Tags    []string `json:"tags"`
 }
+
+// TaggerStreamResponse holds a batch of entity events of the tagger stream
+type TaggerStreamResponse struct {
+	Events []TaggerStreamEvent `json:"events"`
+}
+
+// TaggerStreamEvent holds a change of the tags of an entity
+type TaggerStreamEvent struct {
+	Type   string   `json:"type"` // added, modified or deleted
+	Entity string   `json:"entity"`
+	Tags   []string `json:"tags,omitempty"`
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/clusteragent"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/DataDog/datadog-agent/pkg/version"
 	"github.com/spf13/cobra"
@@ -51,6 +52,9 @@ import (
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/system"
 	_ "github.com/DataDog/datadog-agent/pkg/collector/corechecks/systemd"
 
+	// register the external check loader
+	_ "github.com/DataDog/datadog-agent/pkg/collector/external"
+
 	// register metadata providers
 	_ "github.com/DataDog/datadog-agent/pkg/collector/metadata"
 	_ "github.com/DataDog/datadog-agent/pkg/metadata"
@@ -256,6 +260,11 @@ func StartAgent() error {
 	agg := aggregator.InitAggregator(s, metricSamplePool, hostname, "agent")
 	agg.AddAgentStartupTelemetry(version.AgentVersion)
 
+	// send the series requested by the node_agent external metrics backend of the cluster agent
+	if config.Datadog.GetBool("cluster_agent.enabled") && config.Datadog.GetBool("cluster_agent.external_metrics_series.enabled") {
+		agg.SetSeriesForwarder(clusteragent.NewExternalSeriesForwarder(hostname))
+	}
+
 	// start dogstatsd
 	if config.Datadog.GetBool("use_dogstatsd") {
 		var err error
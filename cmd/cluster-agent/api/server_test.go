@@ -41,6 +41,16 @@ func TestValidateTokenMiddleware(t *testing.T) {
 			"imposter",
 			http.StatusForbidden,
 		},
+		{
+			"/api/v1/tags/stream/node?token=k8xqv0r2-42",
+			"abc123",
+			http.StatusOK,
+		},
+		{
+			"/api/v1/tags/stream/node",
+			"imposter",
+			http.StatusForbidden,
+		},
 		{
 			"/version",
 			"abc123",
@@ -0,0 +1,102 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent"
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// installExternalSeriesEndpoints registers the endpoint receiving the series of the node agents
+func installExternalSeriesEndpoints(r *mux.Router, sc clusteragent.ServerContext) {
+	r.HandleFunc("/autoscalers/series/{nodeName}", postExternalSeries(sc)).Methods("POST")
+}
+
+// postExternalSeries stores the series sent by a node agent for the node_agent external metrics backend
+func postExternalSeries(sc clusteragent.ServerContext) func(w http.ResponseWriter, r *http.Request) {
+	/*
+		Input
+			localhost:5001/api/v1/autoscalers/series/localhost
+			Body: apiv1.SeriesPayload
+			Example: {"series":[{"name":"nginx.net.request_per_s","tags":["kube_service:web"],"timestamp":1582000000,"value":12.5}]}
+		Outputs
+			Status: 200
+			Returns: apiv1.SeriesResponse
+			Example: {"metrics":["nginx.net.request_per_s"]}
+
+			Status: 307
+			Redirects to the leader
+
+			Status: 412
+			Returns: string
+			Example: "The node agent backend of the external metrics is not enabled"
+	*/
+	if sc.ExternalSeries == nil {
+		return externalSeriesDisabledHandler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only the leader queries the series, the followers redirect the node agents to it
+		le, err := leaderelection.GetLeaderEngine()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			incrementRequestMetric("postExternalSeries", http.StatusServiceUnavailable)
+			return
+		}
+		if !le.IsLeader() {
+			leaderIP, err := le.GetLeaderIP()
+			if err != nil || leaderIP == "" {
+				http.Error(w, fmt.Sprintf("could not find the leader: %v", err), http.StatusServiceUnavailable)
+				incrementRequestMetric("postExternalSeries", http.StatusServiceUnavailable)
+				return
+			}
+			url := r.URL
+			url.Host = fmt.Sprintf("%s:%d", leaderIP, config.Datadog.GetInt("cluster_agent.cmd_port"))
+			http.Redirect(w, r, url.String(), http.StatusTemporaryRedirect)
+			incrementRequestMetric("postExternalSeries", http.StatusTemporaryRedirect)
+			return
+		}
+
+		nodeName := mux.Vars(r)["nodeName"]
+		var payload apiv1.SeriesPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			incrementRequestMetric("postExternalSeries", http.StatusBadRequest)
+			return
+		}
+		log.Tracef("Received %d series from the node %s", len(payload.Series), nodeName)
+
+		response := apiv1.SeriesResponse{Metrics: sc.ExternalSeries.Push(nodeName, payload.Series)}
+		body, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			incrementRequestMetric("postExternalSeries", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+		incrementRequestMetric("postExternalSeries", http.StatusOK)
+	}
+}
+
+// externalSeriesDisabledHandler returns a 412 response when the node agent backend is disabled
+func externalSeriesDisabledHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusPreconditionFailed)
+	w.Write([]byte("The node agent backend of the external metrics is not enabled"))
+	incrementRequestMetric("postExternalSeries", http.StatusPreconditionFailed)
+}
@@ -35,8 +35,10 @@ func Install(r *mux.Router, sc clusteragent.ServerContext) {
 	r.HandleFunc("/tags/pod/{nodeName}", getPodMetadataForNode).Methods("GET")
 	r.HandleFunc("/tags/pod", getAllMetadata).Methods("GET")
 	r.HandleFunc("/tags/node/{nodeName}", getNodeMetadata).Methods("GET")
+	installMetadataStreamEndpoints(r, sc)
 	installClusterCheckEndpoints(r, sc)
 	installEndpointsCheckEndpoints(r, sc)
+	installExternalSeriesEndpoints(r, sc)
 }
 
 // getNodeMetadata is only used when the node agent hits the DCA for the list of labels
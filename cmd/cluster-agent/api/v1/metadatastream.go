@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// metadataStreamHeartbeatPeriod is the period of the heartbeats keeping the
+// connections alive and updating the tokens of the quiet nodes
+const metadataStreamHeartbeatPeriod = 30 * time.Second
+
+// installMetadataStreamEndpoints registers the endpoint streaming the cluster metadata to the node agents
+func installMetadataStreamEndpoints(r *mux.Router, sc clusteragent.ServerContext) {
+	r.HandleFunc("/tags/stream/{nodeName}", streamMetadata(sc)).Methods("GET")
+}
+
+// streamMetadata pushes the metadata of a node to the node agent as it changes
+func streamMetadata(sc clusteragent.ServerContext) func(w http.ResponseWriter, r *http.Request) {
+	/*
+		Input
+			localhost:5001/api/v1/tags/stream/localhost?token=k8xqv0r2-42
+		Outputs
+			Status: 200
+			Returns: one apiv1.MetadataEvent per line, until the client disconnects
+			Example: {"token":"k8xqv0r2-43","type":"set","kind":"pod","namespace":"default","name":"my-nginx-5d69","services":["my-nginx-service"]}
+
+			Status: 412
+			Returns: string
+			Example: "The metadata stream is not enabled"
+	*/
+	if sc.MetadataStream == nil {
+		return metadataStreamDisabledHandler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			incrementRequestMetric("streamMetadata", http.StatusInternalServerError)
+			return
+		}
+
+		nodeName := mux.Vars(r)["nodeName"]
+		events, sub := sc.MetadataStream.Subscribe(nodeName, r.URL.Query().Get("token"))
+		defer sc.MetadataStream.Unsubscribe(sub)
+		log.Debugf("Node %s subscribed to the metadata stream, sending %d events", nodeName, len(events))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		incrementRequestMetric("streamMetadata", http.StatusOK)
+
+		// Encode writes a newline after each event
+		encoder := json.NewEncoder(w)
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				log.Debugf("Could not send the metadata stream to the node %s: %v", nodeName, err)
+				return
+			}
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(metadataStreamHeartbeatPeriod)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				log.Debugf("Node %s unsubscribed from the metadata stream", nodeName)
+				return
+			case <-heartbeat.C:
+				sc.MetadataStream.Heartbeat(sub)
+			case event, ok := <-sub.Events():
+				if !ok {
+					// The node agent lagged behind, it will resume the stream with its last token
+					log.Debugf("Node %s lagged behind on the metadata stream, disconnecting it", nodeName)
+					return
+				}
+				if err := encoder.Encode(event); err != nil {
+					log.Debugf("Could not send the metadata stream to the node %s: %v", nodeName, err)
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// metadataStreamDisabledHandler returns a 412 response when the metadata stream is disabled
+func metadataStreamDisabledHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusPreconditionFailed)
+	w.Write([]byte("The metadata stream is not enabled"))
+	incrementRequestMetric("streamMetadata", http.StatusPreconditionFailed)
+}
@@ -14,6 +14,7 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -29,7 +30,11 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/api/healthprobe"
 	"github.com/DataDog/datadog-agent/pkg/clusteragent"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/admission"
 	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/externalseries"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/metadatastream"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/orchestrator"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
@@ -37,6 +42,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/clustername"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/DataDog/datadog-agent/pkg/version"
 )
@@ -172,6 +178,11 @@ func start(cmd *cobra.Command, args []string) error {
 
 	log.Infof("Datadog Cluster Agent is now running.")
 
+	var metadataStream *metadatastream.Store
+	var externalSeries *externalseries.Store
+	if config.Datadog.GetBool("external_metrics_provider.enabled") && config.Datadog.GetString("external_metrics_provider.backend") == "node_agent" {
+		externalSeries = externalseries.NewStore(config.Datadog.GetDuration("external_metrics_provider.max_age") * time.Second)
+	}
 	apiCl, err := apiserver.GetAPIClient() // make sure we can connect to the apiserver
 	if err != nil {
 		log.Errorf("Could not connect to the apiserver: %v", err)
@@ -196,11 +207,44 @@ func start(cmd *cobra.Command, args []string) error {
 			LeaderElector:      le,
 			EventRecorder:      eventRecorder,
 			StopCh:             stopCh,
+			ExternalSeries:     externalSeries,
 		}
 
 		if err := apiserver.StartControllers(ctx); err != nil {
 			log.Errorf("Could not start controllers: %v", err)
 		}
+
+		if config.Datadog.GetBool("orchestrator_explorer.enabled") {
+			orchestratorCtx := orchestrator.ControllerContext{
+				IsLeaderFunc:    le.IsLeader,
+				InformerFactory: apiCl.InformerFactory,
+				ClusterName:     clustername.GetClusterName(),
+				StopCh:          stopCh,
+			}
+			if err := orchestrator.StartController(orchestratorCtx); err != nil {
+				log.Errorf("Could not start orchestrator explorer controller: %v", err)
+			}
+		}
+
+		if config.Datadog.GetBool("admission_controller.enabled") {
+			admissionCtx := admission.ControllerContext{
+				IsLeaderFunc: le.IsLeader,
+				Client:       apiCl.Cl,
+				StopCh:       stopCh,
+			}
+			if err := admission.StartController(admissionCtx); err != nil {
+				log.Errorf("Could not start admission controller: %v", err)
+			}
+		}
+
+		if config.Datadog.GetBool("metadata_stream.enabled") {
+			metadataStream = metadatastream.NewStore(config.Datadog.GetInt("metadata_stream.log_size"))
+			metadatastream.StartController(metadatastream.ControllerContext{
+				Store:           metadataStream,
+				InformerFactory: apiCl.InformerFactory,
+				StopCh:          stopCh,
+			})
+		}
 	}
 
 	// Setup a channel to catch OS signals
@@ -227,6 +271,8 @@ func start(cmd *cobra.Command, args []string) error {
 	// as it's also used to perform the agent commands (e.g. agent status)
 	sc := clusteragent.ServerContext{
 		ClusterCheckHandler: clusterCheckHandler,
+		MetadataStream:      metadataStream,
+		ExternalSeries:      externalSeries,
 	}
 	if err = api.StartServer(sc); err != nil {
 		return log.Errorf("Error while starting agent API, exiting: %v", err)
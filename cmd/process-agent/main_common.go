@@ -109,7 +109,7 @@ func runAgent(exit chan bool) {
 	log.Infof("running version: %s", versionString(", "))
 
 	// Tagger must be initialized after agent config has been setup
-	tagger.Init()
+	tagger.InitRemote()
 	defer tagger.Stop()
 
 	err = initInfo(cfg)
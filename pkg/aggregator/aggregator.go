@@ -203,6 +203,13 @@ type BufferedAggregator struct {
 	stopChan           chan struct{}
 	health             *health.Handle
 	agentName          string // Name of the agent for telemetry metrics (agent / cluster-agent)
+	seriesForwarder    SeriesForwarder
+}
+
+// SeriesForwarder receives the series flushed by the aggregator, besides the serializer.
+// ForwardSeries must not block nor modify the series.
+type SeriesForwarder interface {
+	ForwardSeries(series metrics.Series)
 }
 
 // NewBufferedAggregator instantiates a BufferedAggregator
@@ -458,6 +465,13 @@ func (agg *BufferedAggregator) sendSeries(start time.Time, series metrics.Series
 
 	addFlushCount("Series", int64(len(series)))
 
+	agg.mu.Lock()
+	seriesForwarder := agg.seriesForwarder
+	agg.mu.Unlock()
+	if seriesForwarder != nil {
+		seriesForwarder.ForwardSeries(series)
+	}
+
 	// For debug purposes print out all metrics/tag combinations
 	if config.Datadog.GetBool("log_payloads") {
 		log.Debug("Flushing the following metrics:")
@@ -485,6 +499,13 @@ func (agg *BufferedAggregator) sendSketches(start time.Time, sketches metrics.Sk
 	}
 }
 
+// SetSeriesForwarder sets the forwarder receiving the series on each flush
+func (agg *BufferedAggregator) SetSeriesForwarder(f SeriesForwarder) {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	agg.seriesForwarder = f
+}
+
 func (agg *BufferedAggregator) flushSeriesAndSketches(start time.Time, waitForSerializer bool) {
 	series, sketches := agg.GetSeriesAndSketches()
 
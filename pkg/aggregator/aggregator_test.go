@@ -13,6 +13,7 @@ import (
 
 	// 3p
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
@@ -256,3 +257,27 @@ func TestRecurentSeries(t *testing.T) {
 	s.AssertNotCalled(t, "SendSketch")
 
 }
+
+type recordingSeriesForwarder struct {
+	series metrics.Series
+}
+
+func (f *recordingSeriesForwarder) ForwardSeries(series metrics.Series) {
+	f.series = series
+}
+
+func TestSeriesForwarder(t *testing.T) {
+	resetAggregator()
+	s := &serializer.MockSerializer{}
+	agg := NewBufferedAggregator(s, nil, "hostname", "agent", DefaultFlushInterval)
+	forwarder := &recordingSeriesForwarder{}
+	agg.SetSeriesForwarder(forwarder)
+
+	start := time.Now()
+	s.On("SendServiceChecks", mock.Anything).Return(nil).Times(1)
+	s.On("SendSeries", mock.Anything).Return(nil).Times(1)
+	agg.flush(start, true)
+
+	require.Len(t, forwarder.series, 2)
+	assert.Equal(t, fmt.Sprintf("datadog.%s.running", agg.agentName), forwarder.series[0].Name)
+}
@@ -75,3 +75,8 @@ func (s *dummyService) IsReady() bool {
 func (s *dummyService) GetCheckNames() []string {
 	return s.CheckNames
 }
+
+// GetExtraConfig isn't supported
+func (s *dummyService) GetExtraConfig(key []byte) ([]byte, error) {
+	return nil, listeners.ErrNotSupported
+}
@@ -11,8 +11,13 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/secrets"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/tmplvar"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/listeners"
@@ -22,10 +27,40 @@ import (
 type variableGetter func(key []byte, svc listeners.Service) ([]byte, error)
 
 var templateVariables = map[string]variableGetter{
-	"host":     getHost,
-	"pid":      getPid,
-	"port":     getPort,
-	"hostname": getHostname,
+	"host":      getHost,
+	"pid":       getPid,
+	"port":      getPort,
+	"hostname":  getHostname,
+	"kube":      getKubeMetadata,
+	"container": getContainerName,
+	"tag":       getTag,
+}
+
+// secretVariable is substituted in the parsed templates by SubstituteTemplateSecrets,
+// as secrets can hold characters with a meaning in YAML
+const secretVariable = "secret"
+
+// testing purpose
+var secretGetter variableGetter = getSecret
+
+// variableFilter transforms the value of a template variable. It's called with
+// the error of the variable getter, which it can recover from.
+type variableFilter func(arg, value []byte, err error) ([]byte, error)
+
+var templateFilters = map[string]variableFilter{
+	"lowercase": func(_, value []byte, err error) ([]byte, error) {
+		return bytes.ToLower(value), err
+	},
+	"uppercase": func(_, value []byte, err error) ([]byte, error) {
+		return bytes.ToUpper(value), err
+	},
+	// default replaces missing or empty values
+	"default": func(arg, value []byte, err error) ([]byte, error) {
+		if err != nil || len(value) == 0 {
+			return arg, nil
+		}
+		return value, nil
+	},
 }
 
 // SubstituteTemplateVariables replaces %%VARIABLES%% using the variableGetters passed in
@@ -34,7 +69,7 @@ func SubstituteTemplateVariables(config *integration.Config, getters map[string]
 		vars := config.GetTemplateVariablesForInstance(i)
 		for _, v := range vars {
 			if f, found := getters[string(v.Name)]; found {
-				resolvedVar, err := f(v.Key, svc)
+				resolvedVar, err := applyFilters(v, func() ([]byte, error) { return f(v.Key, svc) })
 				if err != nil {
 					return err
 				}
@@ -54,7 +89,7 @@ func SubstituteTemplateEnvVars(config *integration.Config) error {
 		vars := config.GetTemplateVariablesForInstance(i)
 		for _, v := range vars {
 			if "env" == string(v.Name) {
-				resolvedVar, err := getEnvvar(v.Key)
+				resolvedVar, err := applyFilters(v, func() ([]byte, error) { return getEnvvar(v.Key) })
 				if err != nil {
 					log.Warnf("variable not replaced: %s", err)
 					if retErr == nil {
@@ -71,6 +106,94 @@ func SubstituteTemplateEnvVars(config *integration.Config) error {
 	return retErr
 }
 
+// SubstituteTemplateSecrets replaces %%secret_<handle>%% in the string values of
+// the templates. The templates holding secrets are parsed and marshalled back,
+// so that secret values are quoted as needed.
+func SubstituteTemplateSecrets(config *integration.Config, svc listeners.Service) error {
+	var err error
+	if config.InitConfig, err = substituteSecrets(config.InitConfig, svc); err != nil {
+		return err
+	}
+	for i := range config.Instances {
+		if config.Instances[i], err = substituteSecrets(config.Instances[i], svc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// substituteSecrets replaces the secret variables of a template
+func substituteSecrets(data integration.Data, svc listeners.Service) (integration.Data, error) {
+	hasSecret := false
+	for _, v := range tmplvar.Parse(data) {
+		if string(v.Name) == secretVariable {
+			hasSecret = true
+			break
+		}
+	}
+	if !hasSecret {
+		return data, nil
+	}
+
+	var tree interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("cannot parse template to substitute secrets: %s", err)
+	}
+	tree, err := walkStrings(tree, func(s string) (string, error) {
+		for _, v := range tmplvar.ParseString(s) {
+			if string(v.Name) != secretVariable {
+				continue
+			}
+			secret, err := applyFilters(v, func() ([]byte, error) { return secretGetter(v.Key, svc) })
+			if err != nil {
+				return "", err
+			}
+			s = strings.Replace(s, string(v.Raw), string(secret), -1)
+		}
+		return s, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(tree)
+}
+
+// walkStrings calls f on the strings of a parsed YAML document and returns
+// the document with the values it returned
+func walkStrings(data interface{}, f func(string) (string, error)) (interface{}, error) {
+	var err error
+	switch v := data.(type) {
+	case string:
+		return f(v)
+	case map[interface{}]interface{}:
+		for key, value := range v {
+			if v[key], err = walkStrings(value, f); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			if v[i], err = walkStrings(value, f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return data, nil
+}
+
+// applyFilters gets the value of a template variable and applies its filters in order
+func applyFilters(v tmplvar.TemplateVar, get func() ([]byte, error)) ([]byte, error) {
+	value, err := get()
+	for _, filter := range v.Filters {
+		f, found := templateFilters[string(filter.Name)]
+		if !found {
+			return nil, fmt.Errorf("unknown filter %q for template variable %s", filter.Name, v.Raw)
+		}
+		value, err = f(filter.Arg, value, err)
+	}
+	return value, err
+}
+
 // Resolve takes a template and a service and generates a config with
 // valid connection info and relevant tags.
 func Resolve(tpl integration.Config, svc listeners.Service) (integration.Config, error) {
@@ -88,6 +211,7 @@ func Resolve(tpl integration.Config, svc listeners.Service) (integration.Config,
 		CreationTime:  svc.GetCreationTime(),
 		NodeName:      tpl.NodeName,
 		Source:        tpl.Source,
+		Placement:     tpl.Placement,
 	}
 	copy(resolvedConfig.InitConfig, tpl.InitConfig)
 	copy(resolvedConfig.Instances, tpl.Instances)
@@ -125,6 +249,11 @@ func Resolve(tpl integration.Config, svc listeners.Service) (integration.Config,
 		return resolvedConfig, fmt.Errorf("%s, skipping service %s", err, svc.GetEntity())
 	}
 
+	// Secrets are substituted last, so that their values aren't parsed for variables
+	if err := SubstituteTemplateSecrets(&resolvedConfig, svc); err != nil {
+		return resolvedConfig, err
+	}
+
 	if !tpl.IgnoreAutodiscoveryTags {
 		if err := addServiceTags(&resolvedConfig, svc); err != nil {
 			return resolvedConfig, fmt.Errorf("unable to add tags for service '%s', err: %s", svc.GetEntity(), err)
@@ -251,3 +380,55 @@ func getEnvvar(envVar []byte) ([]byte, error) {
 	}
 	return []byte(value), nil
 }
+
+// getKubeMetadata returns the Kubernetes metadata of the service: its namespace,
+// name, labels and annotations
+func getKubeMetadata(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	value, err := svc.GetExtraConfig(tplVar)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kube_%s for service %s, skipping config - %s", tplVar, svc.GetEntity(), err)
+	}
+	return value, nil
+}
+
+// getContainerName returns the name of the container of the service
+func getContainerName(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	if string(tplVar) != "name" {
+		return nil, fmt.Errorf("unknown template variable container_%s, skipping service %s", tplVar, svc.GetEntity())
+	}
+	name, err := svc.GetExtraConfig([]byte("container_name"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container name for service %s, skipping config - %s", svc.GetEntity(), err)
+	}
+	return name, nil
+}
+
+// getTag returns the value of a tag of the service, e.g. %%tag_env%% for env:prod
+func getTag(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	if len(tplVar) == 0 {
+		return nil, fmt.Errorf("tag name is missing")
+	}
+	tags, err := svc.GetTags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags for service %s, skipping config - %s", svc.GetEntity(), err)
+	}
+	prefix := string(tplVar) + ":"
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, prefix) {
+			return []byte(tag[len(prefix):]), nil
+		}
+	}
+	return nil, fmt.Errorf("tag %s not found for service %s", tplVar, svc.GetEntity())
+}
+
+// getSecret returns a secret from the secret backend
+func getSecret(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	if len(tplVar) == 0 {
+		return nil, fmt.Errorf("secret handle is missing")
+	}
+	secret, err := secrets.DecryptHandle(string(tplVar), svc.GetEntity())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret %s for service %s, skipping config - %s", tplVar, svc.GetEntity(), err)
+	}
+	return []byte(secret), nil
+}
@@ -29,6 +29,8 @@ type dummyService struct {
 	Hostname      string
 	CreationTime  integration.CreationTime
 	CheckNames    []string
+	Tags          []string
+	ExtraConfig   map[string]string
 }
 
 // GetEntity returns the service entity name
@@ -58,7 +60,7 @@ func (s *dummyService) GetPorts() ([]listeners.ContainerPort, error) {
 
 // GetTags returns mil
 func (s *dummyService) GetTags() ([]string, error) {
-	return nil, nil
+	return s.Tags, nil
 }
 
 // GetPid return a dummy pid
@@ -86,6 +88,14 @@ func (s *dummyService) GetCheckNames() []string {
 	return s.CheckNames
 }
 
+// GetExtraConfig returns extra configuration
+func (s *dummyService) GetExtraConfig(key []byte) ([]byte, error) {
+	if value, found := s.ExtraConfig[string(key)]; found {
+		return []byte(value), nil
+	}
+	return nil, listeners.ErrNotSupported
+}
+
 func TestGetFallbackHost(t *testing.T) {
 	ip, err := getFallbackHost(map[string]string{"bridge": "172.17.0.1"})
 	assert.Equal(t, "172.17.0.1", ip)
@@ -555,3 +565,106 @@ func newFakeContainerPorts() []listeners.ContainerPort {
 		{Port: 3, Name: "baz"},
 	}
 }
+
+func TestSubstituteTemplateVariablesExtensions(t *testing.T) {
+	svc := &dummyService{
+		ID:   "a5901276aed1",
+		Tags: []string{"env:prod", "kube_deployment:web"},
+		ExtraConfig: map[string]string{
+			"namespace":        "Payments",
+			"pod_name":         "web-6b6c9d-x2fz7",
+			"label_app":        "web",
+			"container_name":   "nginx",
+			"annotation_empty": "",
+		},
+	}
+
+	for _, tc := range []struct {
+		instance    string
+		out         string
+		errorString string
+	}{
+		{
+			instance: "url: http://%%kube_label_app%%.%%kube_namespace|lowercase%%.svc",
+			out:      "url: http://web.payments.svc",
+		},
+		{
+			instance: "pod: %%kube_pod_name%%, container: %%container_name%%",
+			out:      "pod: web-6b6c9d-x2fz7, container: nginx",
+		},
+		{
+			instance: "env: %%tag_env|uppercase%%, deployment: %%tag_kube_deployment%%",
+			out:      "env: PROD, deployment: web",
+		},
+		{
+			instance: "tier: %%kube_label_tier|default:backend%%, empty: %%kube_annotation_empty | default: none%%",
+			out:      "tier: backend, empty: none",
+		},
+		{
+			instance:    "tier: %%kube_label_tier%%",
+			errorString: "failed to get kube_label_tier for service a5901276aed1, skipping config - AD: variable not supported by listener",
+		},
+		{
+			instance:    "team: %%tag_team%%",
+			errorString: "tag team not found for service a5901276aed1",
+		},
+		{
+			instance:    "name: %%container_id%%",
+			errorString: "unknown template variable container_id, skipping service a5901276aed1",
+		},
+		{
+			instance:    "app: %%kube_label_app|reverse%%",
+			errorString: "unknown filter \"reverse\" for template variable %%kube_label_app|reverse%%",
+		},
+	} {
+		t.Run(tc.instance, func(t *testing.T) {
+			config := &integration.Config{Instances: []integration.Data{integration.Data(tc.instance)}}
+			err := SubstituteTemplateVariables(config, templateVariables, svc)
+			if tc.errorString != "" {
+				assert.EqualError(t, err, tc.errorString)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.out, string(config.Instances[0]))
+			}
+		})
+	}
+}
+
+func TestSubstituteTemplateEnvVarsDefault(t *testing.T) {
+	os.Unsetenv("test_envvar_not_set")
+	config := &integration.Config{Instances: []integration.Data{integration.Data("test: %%env_test_envvar_not_set|default:fallback%%")}}
+	assert.NoError(t, SubstituteTemplateEnvVars(config))
+	assert.Equal(t, "test: fallback", string(config.Instances[0]))
+}
+
+func TestSubstituteTemplateSecrets(t *testing.T) {
+	defer func(getter variableGetter) { secretGetter = getter }(secretGetter)
+	secretGetter = func(handle []byte, svc listeners.Service) ([]byte, error) {
+		switch string(handle) {
+		case "password":
+			return []byte("p@ss: word, #1"), nil
+		case "user":
+			return []byte("admin"), nil
+		}
+		return nil, fmt.Errorf("unknown secret %s", handle)
+	}
+	svc := &dummyService{ID: "a5901276aed1"}
+
+	config := &integration.Config{
+		InitConfig: integration.Data("{}"),
+		Instances: []integration.Data{
+			integration.Data("password: %%secret_password%%\nurl: http://%%secret_user%%@localhost\n"),
+			integration.Data("host: localhost\n"),
+		},
+	}
+	require.NoError(t, SubstituteTemplateSecrets(config, svc))
+	assert.Equal(t, "{}", string(config.InitConfig))
+	assert.Equal(t, "password: 'p@ss: word, #1'\nurl: http://admin@localhost\n", string(config.Instances[0]))
+	assert.Equal(t, "host: localhost\n", string(config.Instances[1]))
+
+	config = &integration.Config{Instances: []integration.Data{integration.Data("password: %%secret_token%%")}}
+	assert.EqualError(t, SubstituteTemplateSecrets(config, svc), "unknown secret token")
+	config = &integration.Config{Instances: []integration.Data{integration.Data("password: %%secret_token|default:none%%")}}
+	require.NoError(t, SubstituteTemplateSecrets(config, svc))
+	assert.Equal(t, "password: none\n", string(config.Instances[0]))
+}
@@ -52,15 +52,41 @@ type Config struct {
 	CreationTime            CreationTime `json:"-"`                         // creation time of service
 	Source                  string       `json:"source"`                    // the source of the configuration
 	IgnoreAutodiscoveryTags bool         `json:"ignore_autodiscovery_tags"` // Use to ignore tags coming from autodiscovery
+	Placement               *Placement   `json:"placement"`                 // placement hints of a cluster check (optional)
 }
 
+// Placement holds the hints the cluster agent follows to pick the node a
+// cluster check is dispatched to
+type Placement struct {
+	NodeSelector string `json:"node_selector" yaml:"node_selector"` // Kubernetes label selector the node labels must match
+	Spread       string `json:"spread" yaml:"spread"`               // spread the configs of the same check across nodes or zones
+	RunnerPool   string `json:"runner_pool" yaml:"runner_pool"`     // CLC runner pool the check is pinned to
+}
+
+// Placement spread values
+const (
+	SpreadNode = "node"
+	SpreadZone = "zone"
+)
+
 // CommonInstanceConfig holds the reserved fields for the yaml instance data
 type CommonInstanceConfig struct {
-	MinCollectionInterval int      `yaml:"min_collection_interval"`
-	EmptyDefaultHostname  bool     `yaml:"empty_default_hostname"`
-	Tags                  []string `yaml:"tags"`
-	Name                  string   `yaml:"name"`
-	Namespace             string   `yaml:"namespace"`
+	MinCollectionInterval int              `yaml:"min_collection_interval"`
+	EmptyDefaultHostname  bool             `yaml:"empty_default_hostname"`
+	Tags                  []string         `yaml:"tags"`
+	Name                  string           `yaml:"name"`
+	Namespace             string           `yaml:"namespace"`
+	Scheduling            SchedulingConfig `yaml:"scheduling"`
+}
+
+// SchedulingConfig holds the reserved fields controlling when the scheduler runs
+// a check instance
+type SchedulingConfig struct {
+	Jitter     int      `yaml:"jitter"`      // maximum random delay of every run, in seconds
+	Windows    []string `yaml:"windows"`     // cron-style time windows outside of which runs are skipped
+	Host       string   `yaml:"host"`        // the host the check connects to
+	MinSpacing int      `yaml:"min_spacing"` // minimum delay between the runs of checks connecting to the same host, in seconds
+	DependsOn  []string `yaml:"depends_on"`  // names of the checks enqueued before this one when they are due at the same time
 }
 
 // Equal determines whether the passed config is the same
@@ -275,6 +301,11 @@ func (c *Config) Digest() string {
 	}
 	h.Write([]byte(c.NodeName))
 	h.Write([]byte(c.LogsConfig))
+	if c.Placement != nil {
+		h.Write([]byte(c.Placement.NodeSelector))
+		h.Write([]byte(c.Placement.Spread))
+		h.Write([]byte(c.Placement.RunnerPool))
+	}
 
 	return strconv.FormatUint(h.Sum64(), 16)
 }
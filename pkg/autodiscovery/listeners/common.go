@@ -6,6 +6,7 @@
 package listeners
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 
@@ -61,3 +62,36 @@ func getCheckNamesFromLabels(labels map[string]string) ([]string, error) {
 	}
 	return nil, nil
 }
+
+// kubeMetadata holds the Kubernetes metadata of a service, exposed to templates
+// as extra configuration: namespace, <kind>_name, label_<name> and annotation_<name>
+type kubeMetadata struct {
+	kind        string // pod or service
+	namespace   string
+	name        string
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// getExtraConfig returns the metadata for a %%kube_*%% template variable key
+func (m *kubeMetadata) getExtraConfig(key []byte) ([]byte, error) {
+	switch {
+	case string(key) == "namespace":
+		return []byte(m.namespace), nil
+	case string(key) == m.kind+"_name":
+		return []byte(m.name), nil
+	case bytes.HasPrefix(key, []byte("label_")):
+		name := string(key[len("label_"):])
+		if value, found := m.labels[name]; found {
+			return []byte(value), nil
+		}
+		return nil, fmt.Errorf("label %q not found on %s %s/%s", name, m.kind, m.namespace, m.name)
+	case bytes.HasPrefix(key, []byte("annotation_")):
+		name := string(key[len("annotation_"):])
+		if value, found := m.annotations[name]; found {
+			return []byte(value), nil
+		}
+		return nil, fmt.Errorf("annotation %q not found on %s %s/%s", name, m.kind, m.namespace, m.name)
+	}
+	return nil, ErrNotSupported
+}
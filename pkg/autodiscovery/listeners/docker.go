@@ -53,6 +53,7 @@ type DockerService struct {
 	hostname      string
 	creationTime  integration.CreationTime
 	checkNames    []string
+	containerName string
 }
 
 // Make sure DockerService implements the Service interface
@@ -614,3 +615,25 @@ func (s *DockerService) GetCheckNames() []string {
 
 	return s.checkNames
 }
+
+// GetExtraConfig returns the name of the container
+func (s *DockerService) GetExtraConfig(key []byte) ([]byte, error) {
+	if string(key) != "container_name" {
+		return nil, ErrNotSupported
+	}
+	if s.containerName != "" {
+		return []byte(s.containerName), nil
+	}
+
+	du, err := docker.GetDockerUtil()
+	if err != nil {
+		return nil, err
+	}
+	cInspect, err := du.Inspect(s.cID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s", s.cID[:12])
+	}
+
+	s.containerName = strings.TrimPrefix(cInspect.Name, "/")
+	return []byte(s.containerName), nil
+}
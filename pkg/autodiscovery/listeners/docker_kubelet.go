@@ -114,3 +114,17 @@ func (s *DockerKubeletService) IsReady() bool {
 func (s *DockerKubeletService) GetCheckNames() []string {
 	return nil
 }
+
+// GetExtraConfig returns the name of the container and the metadata of its pod
+func (s *DockerKubeletService) GetExtraConfig(key []byte) ([]byte, error) {
+	if string(key) == "container_name" {
+		return s.DockerService.GetExtraConfig(key)
+	}
+
+	pod, err := s.getPod()
+	if err != nil {
+		return nil, err
+	}
+	meta := podKubeMetadata(pod)
+	return meta.getExtraConfig(key)
+}
@@ -264,3 +264,8 @@ func (s *ECSService) IsReady() bool {
 func (s *ECSService) GetCheckNames() []string {
 	return s.checkNames
 }
+
+// GetExtraConfig is not supported by ECSService
+func (s *ECSService) GetExtraConfig(key []byte) ([]byte, error) {
+	return nil, ErrNotSupported
+}
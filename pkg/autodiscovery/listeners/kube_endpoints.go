@@ -3,8 +3,7 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-2020 Datadog, Inc.
 
-// +build clusterchecks
-// +build kubeapiserver
+// +build clusterchecks,kubeapiserver
 
 package listeners
 
@@ -17,8 +16,10 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	infov1 "k8s.io/client-go/informers/core/v1"
 	listv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
@@ -32,12 +33,21 @@ const (
 type KubeEndpointsListener struct {
 	endpointsInformer infov1.EndpointsInformer
 	endpointsLister   listv1.EndpointsLister
-	serviceInformer   infov1.ServiceInformer
-	serviceLister     listv1.ServiceLister
-	endpoints         map[types.UID][]*KubeEndpointService
-	newService        chan<- Service
-	delService        chan<- Service
-	m                 sync.RWMutex
+	// endpointSliceInformer is only set when reading the EndpointSlices instead of the Endpoints
+	endpointSliceInformer cache.SharedIndexInformer
+	endpointSliceLister   cache.GenericLister
+	serviceInformer       infov1.ServiceInformer
+	serviceLister         listv1.ServiceLister
+	podInformer           infov1.PodInformer
+	podLister             listv1.PodLister
+	// endpoints holds the AD services by Endpoints or EndpointSlice UID
+	endpoints map[types.UID][]*KubeEndpointService
+	// entities counts the AD services of every entity, an address of a service
+	// can be part of several EndpointSlices but is only sent once
+	entities   map[string]int
+	newService chan<- Service
+	delService chan<- Service
+	m          sync.RWMutex
 }
 
 // KubeEndpointService represents an endpoint in a Kubernetes Endpoints
@@ -47,6 +57,7 @@ type KubeEndpointService struct {
 	hosts        map[string]string
 	ports        []ContainerPort
 	creationTime integration.CreationTime
+	kubeMeta     kubeMetadata
 }
 
 // Make sure KubeEndpointService implements the Service interface
@@ -61,21 +72,38 @@ func NewKubeEndpointsListener() (ServiceListener, error) {
 	if err != nil {
 		return nil, fmt.Errorf("cannot connect to apiserver: %s", err)
 	}
-	endpointsInformer := ac.InformerFactory.Core().V1().Endpoints()
-	if endpointsInformer == nil {
-		return nil, fmt.Errorf("cannot get endpoints informer: %s", err)
-	}
 	serviceInformer := ac.InformerFactory.Core().V1().Services()
 	if serviceInformer == nil {
 		return nil, fmt.Errorf("cannot get service informer: %s", err)
 	}
-	return &KubeEndpointsListener{
-		endpoints:         make(map[types.UID][]*KubeEndpointService),
-		endpointsInformer: endpointsInformer,
-		endpointsLister:   endpointsInformer.Lister(),
-		serviceInformer:   serviceInformer,
-		serviceLister:     serviceInformer.Lister(),
-	}, nil
+	podInformer := ac.InformerFactory.Core().V1().Pods()
+	if podInformer == nil {
+		return nil, fmt.Errorf("cannot get pod informer: %s", err)
+	}
+	l := &KubeEndpointsListener{
+		endpoints:       make(map[types.UID][]*KubeEndpointService),
+		entities:        make(map[string]int),
+		serviceInformer: serviceInformer,
+		serviceLister:   serviceInformer.Lister(),
+		podInformer:     podInformer,
+		podLister:       podInformer.Lister(),
+	}
+
+	if apiserver.UseEndpointSlices(ac) {
+		endpointSliceInformer := ac.DynamicInformerFactory.ForResource(apiserver.EndpointSliceGVR)
+		l.endpointSliceInformer = endpointSliceInformer.Informer()
+		l.endpointSliceLister = endpointSliceInformer.Lister()
+		ac.DynamicInformerFactory.Start(wait.NeverStop)
+		return l, nil
+	}
+
+	endpointsInformer := ac.InformerFactory.Core().V1().Endpoints()
+	if endpointsInformer == nil {
+		return nil, fmt.Errorf("cannot get endpoints informer: %s", err)
+	}
+	l.endpointsInformer = endpointsInformer
+	l.endpointsLister = endpointsInformer.Lister()
+	return l, nil
 }
 
 func (l *KubeEndpointsListener) Listen(newSvc chan<- Service, delSvc chan<- Service) {
@@ -83,16 +111,43 @@ func (l *KubeEndpointsListener) Listen(newSvc chan<- Service, delSvc chan<- Serv
 	l.newService = newSvc
 	l.delService = delSvc
 
+	l.serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: l.serviceUpdated,
+	})
+
+	l.podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: l.podUpdated,
+	})
+
+	if l.endpointSliceInformer != nil {
+		l.endpointSliceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    l.endpointSliceAdded,
+			DeleteFunc: l.endpointSliceDeleted,
+			UpdateFunc: l.endpointSliceUpdated,
+		})
+
+		// Initial fill
+		slices, err := l.endpointSliceLister.List(labels.Everything())
+		if err != nil {
+			log.Errorf("Cannot list Kubernetes endpoint slices: %s", err)
+		}
+		for _, obj := range slices {
+			slice, err := apiserver.ParseEndpointSlice(obj)
+			if err != nil {
+				log.Errorf("Cannot parse Kubernetes endpoint slice: %s", err)
+				continue
+			}
+			l.createSliceService(slice, true, true)
+		}
+		return
+	}
+
 	l.endpointsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    l.endpointsAdded,
 		DeleteFunc: l.endpointsDeleted,
 		UpdateFunc: l.endpointsUpdated,
 	})
 
-	l.serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		UpdateFunc: l.serviceUpdated,
-	})
-
 	// Initial fill
 	endpoints, err := l.endpointsLister.List(labels.Everything())
 	if err != nil {
@@ -123,7 +178,7 @@ func (l *KubeEndpointsListener) endpointsDeleted(obj interface{}) {
 		log.Errorf("Expected an Endpoints type, got: %v", obj)
 		return
 	}
-	l.removeService(castedObj)
+	l.removeService(castedObj.UID)
 }
 
 func (l *KubeEndpointsListener) endpointsUpdated(old, obj interface{}) {
@@ -141,7 +196,7 @@ func (l *KubeEndpointsListener) endpointsUpdated(old, obj interface{}) {
 		return
 	}
 	if l.endpointsDiffer(castedObj, castedOld) {
-		l.removeService(castedObj)
+		l.removeService(castedObj.UID)
 		l.createService(castedObj, false, true)
 	}
 }
@@ -158,13 +213,82 @@ func (l *KubeEndpointsListener) serviceUpdated(old, obj interface{}) {
 	castedOld, ok := old.(*v1.Service)
 	if !ok {
 		log.Errorf("Expected a Service type, got: %v", old)
-		l.createService(l.endpointsForService(castedObj), true, false)
+		l.createServicesForService(castedObj)
 		return
 	}
 
 	if !isServiceEndpointsAnnotated(castedOld) && isServiceEndpointsAnnotated(castedObj) {
-		l.createService(l.endpointsForService(castedObj), true, false)
+		l.createServicesForService(castedObj)
+	}
+}
+
+// createServicesForService creates the AD services of the Endpoints or the
+// EndpointSlices of a service
+func (l *KubeEndpointsListener) createServicesForService(ksvc *v1.Service) {
+	if l.endpointSliceInformer != nil {
+		for _, slice := range l.endpointSlicesForService(ksvc) {
+			l.createSliceService(slice, true, false)
+		}
+		return
 	}
+	l.createService(l.endpointsForService(ksvc), true, false)
+}
+
+// podUpdated refreshes the endpoints targeting a pod when its readiness changes,
+// if they are not filtered on readiness by Kubernetes
+func (l *KubeEndpointsListener) podUpdated(old, obj interface{}) {
+	castedObj, ok := obj.(*v1.Pod)
+	if !ok {
+		log.Errorf("Expected a Pod type, got: %v", obj)
+		return
+	}
+	castedOld, ok := old.(*v1.Pod)
+	if !ok {
+		log.Errorf("Expected a Pod type, got: %v", old)
+		return
+	}
+	if apiserver.IsPodReady(castedObj) == apiserver.IsPodReady(castedOld) {
+		return
+	}
+
+	services, err := l.serviceLister.Services(castedObj.Namespace).List(labels.Everything())
+	if err != nil {
+		log.Tracef("Cannot list Kubernetes services: %s", err)
+		return
+	}
+	for _, ksvc := range services {
+		if !ksvc.Spec.PublishNotReadyAddresses || !isServiceEndpointsAnnotated(ksvc) {
+			continue
+		}
+		if l.endpointSliceInformer != nil {
+			for _, slice := range l.endpointSlicesForService(ksvc) {
+				if sliceTargetsPod(slice, castedObj.Name) {
+					l.removeService(slice.UID)
+					l.createSliceService(slice, false, false)
+				}
+			}
+			continue
+		}
+		kep := l.endpointsForService(ksvc)
+		if kep != nil && endpointsTargetPod(kep, castedObj.Name) {
+			l.removeService(kep.UID)
+			l.createService(kep, false, false)
+		}
+	}
+}
+
+func endpointsTargetPod(kep *v1.Endpoints, podName string) bool {
+	_, err := apiserver.SearchTargetPerName(kep, podName)
+	return err == nil
+}
+
+func sliceTargetsPod(slice *apiserver.EndpointSlice, podName string) bool {
+	for _, endpoint := range slice.Endpoints {
+		if endpoint.TargetRef != nil && endpoint.TargetRef.Kind == "Pod" && endpoint.TargetRef.Name == podName {
+			return true
+		}
+	}
+	return false
 }
 
 func (l *KubeEndpointsListener) endpointsForService(service *v1.Service) *v1.Endpoints {
@@ -177,6 +301,82 @@ func (l *KubeEndpointsListener) endpointsForService(service *v1.Service) *v1.End
 	return kendpoints
 }
 
+func (l *KubeEndpointsListener) endpointSlicesForService(service *v1.Service) []*apiserver.EndpointSlice {
+	selector := labels.SelectorFromSet(labels.Set{apiserver.EndpointSliceServiceNameLabel: service.Name})
+	objects, err := l.endpointSliceLister.ByNamespace(service.Namespace).List(selector)
+	if err != nil {
+		log.Warnf("Cannot get Kubernetes endpoint slices - Endpoints services won't be created - error: %s", err)
+		return nil
+	}
+	var slices []*apiserver.EndpointSlice
+	for _, obj := range objects {
+		slice, err := apiserver.ParseEndpointSlice(obj)
+		if err != nil {
+			log.Errorf("Cannot parse Kubernetes endpoint slice: %s", err)
+			continue
+		}
+		slices = append(slices, slice)
+	}
+	return slices
+}
+
+func (l *KubeEndpointsListener) endpointSliceAdded(obj interface{}) {
+	slice, err := apiserver.ParseEndpointSlice(obj)
+	if err != nil {
+		log.Errorf("Cannot parse Kubernetes endpoint slice: %s", err)
+		return
+	}
+	l.createSliceService(slice, false, true)
+}
+
+func (l *KubeEndpointsListener) endpointSliceDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	slice, err := apiserver.ParseEndpointSlice(obj)
+	if err != nil {
+		log.Errorf("Cannot parse Kubernetes endpoint slice: %s", err)
+		return
+	}
+	l.removeService(slice.UID)
+}
+
+func (l *KubeEndpointsListener) endpointSliceUpdated(old, obj interface{}) {
+	// Parse the updated object or return on failure
+	slice, err := apiserver.ParseEndpointSlice(obj)
+	if err != nil {
+		log.Errorf("Cannot parse Kubernetes endpoint slice: %s", err)
+		return
+	}
+	// Parse the old object, consider it an add on failure
+	oldSlice, err := apiserver.ParseEndpointSlice(old)
+	if err != nil {
+		log.Errorf("Cannot parse Kubernetes endpoint slice: %s", err)
+		l.createSliceService(slice, false, true)
+		return
+	}
+	if l.endpointSlicesDiffer(slice, oldSlice) {
+		l.removeService(slice.UID)
+		l.createSliceService(slice, false, true)
+	}
+}
+
+// endpointSlicesDiffer compares two endpoint slices to only go forward
+// when relevant fields are changed. This logic must be
+// updated if more fields are used.
+func (l *KubeEndpointsListener) endpointSlicesDiffer(first, second *apiserver.EndpointSlice) bool {
+	// Quick exit if resversion did not change
+	if first.ResourceVersion == second.ResourceVersion {
+		return false
+	}
+	// AD annotations on the corresponding service
+	if l.isServiceAnnotated(first.Namespace, first.ServiceName()) != l.isServiceAnnotated(second.Namespace, second.ServiceName()) {
+		return true
+	}
+	return !equality.Semantic.DeepEqual(first.Endpoints, second.Endpoints) ||
+		!equality.Semantic.DeepEqual(first.Ports, second.Ports)
+}
+
 // endpointsDiffer compares two endpoints to only go forward
 // when relevant fields are changed. This logic must be
 // updated if more fields are used.
@@ -202,16 +402,28 @@ func subsetsDiffer(first, second *v1.Endpoints) bool {
 // isEndpointsAnnotated looks for the corresponding service of a kubernetes endpoints object
 // and returns true if the service has endpoints annotations, otherwise returns false.
 func (l *KubeEndpointsListener) isEndpointsAnnotated(kep *v1.Endpoints) bool {
-	ksvc, err := l.serviceLister.Services(kep.Namespace).Get(kep.Name)
+	return l.isServiceAnnotated(kep.Namespace, kep.Name)
+}
+
+// isServiceAnnotated returns true if the service has endpoints annotations
+func (l *KubeEndpointsListener) isServiceAnnotated(namespace, name string) bool {
+	return isServiceEndpointsAnnotated(l.getService(namespace, name))
+}
+
+// publishesNotReadyAddresses returns true if the endpoints of the service include
+// the not ready pods, usually for headless services
+func (l *KubeEndpointsListener) publishesNotReadyAddresses(namespace, name string) bool {
+	ksvc := l.getService(namespace, name)
+	return ksvc != nil && ksvc.Spec.PublishNotReadyAddresses
+}
+
+func (l *KubeEndpointsListener) getService(namespace, name string) *v1.Service {
+	ksvc, err := l.serviceLister.Services(namespace).Get(name)
 	if err != nil {
 		log.Tracef("Cannot get Kubernetes service: %s", err)
+		return nil
 	}
-	if ksvc != nil {
-		if _, found := ksvc.Annotations[kubeEndpointsAnnotationFormat]; found {
-			return true
-		}
-	}
-	return false
+	return ksvc
 }
 
 func isServiceEndpointsAnnotated(ksvc *v1.Service) bool {
@@ -234,13 +446,64 @@ func (l *KubeEndpointsListener) createService(kep *v1.Endpoints, alreadyExisting
 		return
 	}
 
-	eps := processEndpoints(kep, alreadyExistingService)
+	if l.publishesNotReadyAddresses(kep.Namespace, kep.Name) {
+		kep = l.withReadyPods(kep)
+	}
+	l.addServices(kep.UID, processEndpoints(kep, alreadyExistingService))
+}
+
+// withReadyPods returns a copy of the endpoints without the addresses of the pods which are not ready
+func (l *KubeEndpointsListener) withReadyPods(kep *v1.Endpoints) *v1.Endpoints {
+	kep = kep.DeepCopy()
+	for i := range kep.Subsets {
+		var ready []v1.EndpointAddress
+		for _, address := range kep.Subsets[i].Addresses {
+			if apiserver.IsTargetPodReady(l.podLister, address.TargetRef) {
+				ready = append(ready, address)
+			}
+		}
+		kep.Subsets[i].Addresses = ready
+	}
+	return kep
+}
+
+func (l *KubeEndpointsListener) createSliceService(slice *apiserver.EndpointSlice, alreadyExistingService, checkServiceAnnotations bool) {
+	if slice == nil {
+		return
+	}
+
+	serviceName := slice.ServiceName()
+	if serviceName == "" {
+		// Not managed by a service
+		return
+	}
+	if checkServiceAnnotations && !l.isServiceAnnotated(slice.Namespace, serviceName) {
+		// Ignore endpoint slices with no AD annotation on their corresponding service if checkServiceAnnotations
+		return
+	}
+
+	addresses := slice.ReadyAddresses()
+	if l.publishesNotReadyAddresses(slice.Namespace, serviceName) {
+		addresses = apiserver.FilterReadyPods(l.podLister, addresses)
+	}
+	l.addServices(slice.UID, processEndpointSlice(slice, addresses, alreadyExistingService))
+}
 
+// addServices stores and sends the AD services of an Endpoints or EndpointSlice.
+// The services of entities already sent for another object are not sent again.
+func (l *KubeEndpointsListener) addServices(uid types.UID, eps []*KubeEndpointService) {
+	var added []*KubeEndpointService
 	l.m.Lock()
-	l.endpoints[kep.UID] = eps
+	l.endpoints[uid] = eps
+	for _, ep := range eps {
+		l.entities[ep.entity]++
+		if l.entities[ep.entity] == 1 {
+			added = append(added, ep)
+		}
+	}
 	l.m.Unlock()
 
-	for _, ep := range eps {
+	for _, ep := range added {
 		log.Debugf("Creating a new AD service: %s", ep.entity)
 		l.newService <- ep
 	}
@@ -259,43 +522,84 @@ func processEndpoints(kep *v1.Endpoints, alreadyExistingService bool) []*KubeEnd
 		// Hosts
 		for _, host := range kep.Subsets[i].Addresses {
 			// create a separate AD service per host
-			ep := &KubeEndpointService{
-				entity:       apiserver.EntityForEndpoints(kep.Namespace, kep.Name, host.IP),
-				creationTime: integration.After,
-				hosts:        map[string]string{"endpoint": host.IP},
-				ports:        ports,
-				tags: []string{
-					fmt.Sprintf("kube_service:%s", kep.Name),
-					fmt.Sprintf("kube_namespace:%s", kep.Namespace),
-					fmt.Sprintf("kube_endpoint_ip:%s", host.IP),
-				},
-			}
-			if alreadyExistingService {
-				ep.creationTime = integration.Before
-			}
-			eps = append(eps, ep)
+			eps = append(eps, newKubeEndpointService(kep.ObjectMeta, kep.Name, host.IP, ports, alreadyExistingService))
 		}
 	}
 	return eps
 }
 
-func (l *KubeEndpointsListener) removeService(kep *v1.Endpoints) {
-	if kep == nil {
-		return
+// processEndpointSlice returns a slice of KubeEndpointService per ready address
+// of a kubernetes EndpointSlice, IPv4 and IPv6 addresses alike
+func processEndpointSlice(slice *apiserver.EndpointSlice, addresses []apiserver.EndpointAddress, alreadyExistingService bool) []*KubeEndpointService {
+	ports := []ContainerPort{}
+	for _, port := range slice.Ports {
+		if port.Port == nil {
+			// All the ports of the service
+			continue
+		}
+		name := ""
+		if port.Name != nil {
+			name = *port.Name
+		}
+		ports = append(ports, ContainerPort{int(*port.Port), name})
 	}
-	l.m.RLock()
-	eps, ok := l.endpoints[kep.UID]
-	l.m.RUnlock()
-	if ok {
-		l.m.Lock()
-		delete(l.endpoints, kep.UID)
-		l.m.Unlock()
-		for _, ep := range eps {
-			log.Debugf("Deleting AD service: %s", ep.entity)
-			l.delService <- ep
+
+	var eps []*KubeEndpointService
+	for _, address := range addresses {
+		eps = append(eps, newKubeEndpointService(slice.ObjectMeta, slice.ServiceName(), address.IP, ports, alreadyExistingService))
+	}
+	return eps
+}
+
+// newKubeEndpointService returns the AD service of an endpoint of a service
+func newKubeEndpointService(meta metav1.ObjectMeta, serviceName, ip string, ports []ContainerPort, alreadyExistingService bool) *KubeEndpointService {
+	ep := &KubeEndpointService{
+		entity:       apiserver.EntityForEndpoints(meta.Namespace, serviceName, ip),
+		creationTime: integration.After,
+		hosts:        map[string]string{"endpoint": ip},
+		ports:        ports,
+		tags: []string{
+			fmt.Sprintf("kube_service:%s", serviceName),
+			fmt.Sprintf("kube_namespace:%s", meta.Namespace),
+			fmt.Sprintf("kube_endpoint_ip:%s", ip),
+		},
+		kubeMeta: kubeMetadata{
+			kind:        "service",
+			namespace:   meta.Namespace,
+			name:        serviceName,
+			labels:      meta.Labels,
+			annotations: meta.Annotations,
+		},
+	}
+	if alreadyExistingService {
+		ep.creationTime = integration.Before
+	}
+	return ep
+}
+
+// removeService deletes the AD services of an Endpoints or EndpointSlice. The
+// services of entities still part of another object are not deleted.
+func (l *KubeEndpointsListener) removeService(uid types.UID) {
+	var removed []*KubeEndpointService
+	l.m.Lock()
+	eps, ok := l.endpoints[uid]
+	delete(l.endpoints, uid)
+	for _, ep := range eps {
+		l.entities[ep.entity]--
+		if l.entities[ep.entity] <= 0 {
+			delete(l.entities, ep.entity)
+			removed = append(removed, ep)
 		}
-	} else {
-		log.Debugf("Entity %s not found, not removing", kep.UID)
+	}
+	l.m.Unlock()
+
+	if !ok {
+		log.Debugf("Entity %s not found, not removing", uid)
+		return
+	}
+	for _, ep := range removed {
+		log.Debugf("Deleting AD service: %s", ep.entity)
+		l.delService <- ep
 	}
 }
 
@@ -363,3 +667,8 @@ func (s *KubeEndpointService) IsReady() bool {
 func (s *KubeEndpointService) GetCheckNames() []string {
 	return nil
 }
+
+// GetExtraConfig returns the metadata of the endpoints, named after their service
+func (s *KubeEndpointService) GetExtraConfig(key []byte) ([]byte, error) {
+	return s.kubeMeta.getExtraConfig(key)
+}
@@ -16,8 +16,11 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	listv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
 )
 
 func TestProcessEndpoints(t *testing.T) {
@@ -101,6 +104,118 @@ func TestProcessEndpoints(t *testing.T) {
 	assert.Equal(t, integration.After, eps[1].GetCreationTime())
 }
 
+func TestProcessEndpointSlice(t *testing.T) {
+	portName := "http"
+	port := int32(80)
+	slice := &apiserver.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			ResourceVersion: "123",
+			UID:             types.UID("slice-uid"),
+			Name:            "myservice-abcde",
+			Namespace:       "default",
+			Labels:          map[string]string{apiserver.EndpointSliceServiceNameLabel: "myservice"},
+		},
+		AddressType: "IPv6",
+		Ports: []apiserver.EndpointSlicePort{
+			{Name: &portName, Port: &port},
+			// All the ports of the service
+			{},
+		},
+	}
+	addresses := []apiserver.EndpointAddress{{IP: "fd00::1"}, {IP: "fd00::2"}}
+
+	eps := processEndpointSlice(slice, addresses, true)
+	assert.Len(t, eps, 2)
+
+	assert.Equal(t, "kube_endpoint_uid://default/myservice/fd00::1", eps[0].GetEntity())
+	assert.Equal(t, integration.Before, eps[0].GetCreationTime())
+
+	hosts, err := eps[0].GetHosts()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"endpoint": "fd00::1"}, hosts)
+
+	ports, err := eps[0].GetPorts()
+	assert.NoError(t, err)
+	assert.Equal(t, []ContainerPort{{80, "http"}}, ports)
+
+	tags, err := eps[0].GetTags()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kube_service:myservice", "kube_namespace:default", "kube_endpoint_ip:fd00::1"}, tags)
+
+	assert.Equal(t, "kube_endpoint_uid://default/myservice/fd00::2", eps[1].GetEntity())
+
+	eps = processEndpointSlice(slice, addresses, false)
+	assert.Equal(t, integration.After, eps[0].GetCreationTime())
+	assert.Equal(t, integration.After, eps[1].GetCreationTime())
+}
+
+func TestAddressInSeveralSlices(t *testing.T) {
+	newSvc := make(chan Service, 10)
+	delSvc := make(chan Service, 10)
+	l := &KubeEndpointsListener{
+		endpoints:  make(map[types.UID][]*KubeEndpointService),
+		entities:   make(map[string]int),
+		newService: newSvc,
+		delService: delSvc,
+	}
+	slice := func(name string) *apiserver.EndpointSlice {
+		return &apiserver.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				UID:       types.UID(name),
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{apiserver.EndpointSliceServiceNameLabel: "myservice"},
+			},
+		}
+	}
+
+	// 10.0.0.2 is moving from slice a to slice b
+	l.addServices("a", processEndpointSlice(slice("a"), []apiserver.EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}}, false))
+	l.addServices("b", processEndpointSlice(slice("b"), []apiserver.EndpointAddress{{IP: "10.0.0.2"}}, false))
+	assert.Len(t, newSvc, 2)
+
+	l.removeService("a")
+	assert.Len(t, delSvc, 1)
+	assert.Equal(t, "kube_endpoint_uid://default/myservice/10.0.0.1", (<-delSvc).GetEntity())
+
+	l.removeService("b")
+	assert.Len(t, delSvc, 1)
+	assert.Equal(t, "kube_endpoint_uid://default/myservice/10.0.0.2", (<-delSvc).GetEntity())
+	assert.Len(t, l.entities, 0)
+}
+
+func TestWithReadyPods(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for name, ready := range map[string]v1.ConditionStatus{"ready": v1.ConditionTrue, "notready": v1.ConditionFalse} {
+		assert.NoError(t, indexer.Add(&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status: v1.PodStatus{
+				Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: ready}},
+			},
+		}))
+	}
+	l := &KubeEndpointsListener{podLister: listv1.NewPodLister(indexer)}
+
+	podRef := func(name string) *v1.ObjectReference {
+		return &v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: name}
+	}
+	kep := &v1.Endpoints{
+		Subsets: []v1.EndpointSubset{
+			{
+				Addresses: []v1.EndpointAddress{
+					{IP: "10.0.0.1", TargetRef: podRef("ready")},
+					{IP: "10.0.0.2", TargetRef: podRef("notready")},
+				},
+			},
+		},
+	}
+
+	filtered := l.withReadyPods(kep)
+	assert.Equal(t, []v1.EndpointAddress{{IP: "10.0.0.1", TargetRef: podRef("ready")}}, filtered.Subsets[0].Addresses)
+	// The informer cache must not be modified
+	assert.Len(t, kep.Subsets[0].Addresses, 2)
+}
+
 func TestSubsetsDiffer(t *testing.T) {
 	for name, tc := range map[string]struct {
 		first  *v1.Endpoints
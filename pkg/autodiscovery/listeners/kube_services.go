@@ -10,6 +10,7 @@ package listeners
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 	"sync"
 
@@ -44,6 +45,7 @@ type KubeServiceService struct {
 	hosts        map[string]string
 	ports        []ContainerPort
 	creationTime integration.CreationTime
+	kubeMeta     kubeMetadata
 }
 
 // Make sure KubeServiceService implements the Service interface
@@ -144,6 +146,10 @@ func servicesDiffer(first, second *v1.Service) bool {
 	if isServiceAnnotated(first) != isServiceAnnotated(second) {
 		return true
 	}
+	// Labels, exposed to templates
+	if !reflect.DeepEqual(first.Labels, second.Labels) {
+		return true
+	}
 	// Cluster IP
 	if first.Spec.ClusterIP != second.Spec.ClusterIP {
 		return true
@@ -186,6 +192,13 @@ func processService(ksvc *v1.Service, firstRun bool) *KubeServiceService {
 	svc := &KubeServiceService{
 		entity:       apiserver.EntityForService(ksvc),
 		creationTime: integration.After,
+		kubeMeta: kubeMetadata{
+			kind:        "service",
+			namespace:   ksvc.Namespace,
+			name:        ksvc.Name,
+			labels:      ksvc.Labels,
+			annotations: ksvc.Annotations,
+		},
 	}
 	if firstRun {
 		svc.creationTime = integration.Before
@@ -198,7 +211,11 @@ func processService(ksvc *v1.Service, firstRun bool) *KubeServiceService {
 	}
 
 	// Hosts, only use internal ClusterIP for now
-	svc.hosts = map[string]string{"cluster": ksvc.Spec.ClusterIP}
+	// Headless services have no cluster IP, their pods are monitored by endpoints checks
+	svc.hosts = map[string]string{}
+	if ksvc.Spec.ClusterIP != "" && ksvc.Spec.ClusterIP != v1.ClusterIPNone {
+		svc.hosts["cluster"] = ksvc.Spec.ClusterIP
+	}
 
 	// Ports
 	var ports []ContainerPort
@@ -293,6 +310,11 @@ func (s *KubeServiceService) GetCheckNames() []string {
 	return nil
 }
 
+// GetExtraConfig returns the metadata of the service
+func (s *KubeServiceService) GetExtraConfig(key []byte) ([]byte, error) {
+	return s.kubeMeta.getExtraConfig(key)
+}
+
 func isServiceAnnotated(ksvc *v1.Service) bool {
 	_, found := ksvc.Annotations[kubeServiceAnnotationFormat]
 	return found
@@ -29,6 +29,7 @@ func TestProcessService(t *testing.T) {
 				"ad.datadoghq.com/service.init_configs": "[{}]",
 				"ad.datadoghq.com/service.instances":    "[{\"name\": \"My service\", \"url\": \"http://%%host%%\", \"timeout\": 1}]",
 			},
+			Labels:    map[string]string{"app": "web"},
 			Name:      "myservice",
 			Namespace: "default",
 		},
@@ -61,8 +62,30 @@ func TestProcessService(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"kube_service:myservice", "kube_namespace:default"}, tags)
 
+	for key, expected := range map[string]string{
+		"namespace":    "default",
+		"service_name": "myservice",
+		"label_app":    "web",
+		"annotation_ad.datadoghq.com/service.check_names": "[\"http_check\"]",
+	} {
+		value, err := svc.GetExtraConfig([]byte(key))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(value))
+	}
+	_, err = svc.GetExtraConfig([]byte("label_tier"))
+	assert.Error(t, err)
+	_, err = svc.GetExtraConfig([]byte("pod_name"))
+	assert.Equal(t, ErrNotSupported, err)
+
 	svc = processService(ksvc, false)
 	assert.Equal(t, integration.After, svc.GetCreationTime())
+
+	// Headless service
+	ksvc.Spec.ClusterIP = v1.ClusterIPNone
+	svc = processService(ksvc, false)
+	hosts, err = svc.GetHosts()
+	assert.NoError(t, err)
+	assert.Empty(t, hosts)
 }
 
 func TestServicesDiffer(t *testing.T) {
@@ -111,6 +134,21 @@ func TestServicesDiffer(t *testing.T) {
 			},
 			result: false,
 		},
+		"Change labels": {
+			first: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					ResourceVersion: "123",
+					Labels:          map[string]string{"app": "web"},
+				},
+			},
+			second: &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					ResourceVersion: "124",
+					Labels:          map[string]string{"app": "api"},
+				},
+			},
+			result: true,
+		},
 		"Change IP": {
 			first: &v1.Service{
 				ObjectMeta: metav1.ObjectMeta{
@@ -52,6 +52,8 @@ type KubeContainerService struct {
 	creationTime  integration.CreationTime
 	ready         bool
 	checkNames    []string
+	containerName string
+	kubeMeta      kubeMetadata
 }
 
 // Make sure KubeContainerService implements the Service interface
@@ -65,6 +67,7 @@ type KubePodService struct {
 	hosts         map[string]string
 	ports         []ContainerPort
 	creationTime  integration.CreationTime
+	kubeMeta      kubeMetadata
 }
 
 // Make sure KubePodService implements the Service interface
@@ -190,6 +193,7 @@ func (l *KubeletListener) createPodService(pod *kubelet.Pod, firstRun bool) {
 		hosts:         map[string]string{"pod": podIP},
 		ports:         ports,
 		creationTime:  crTime,
+		kubeMeta:      podKubeMetadata(pod),
 	}
 
 	l.m.Lock()
@@ -210,6 +214,7 @@ func (l *KubeletListener) createService(entity string, pod *kubelet.Pod, firstRu
 		entity:       entity,
 		creationTime: crTime,
 		ready:        kubelet.IsPodReady(pod),
+		kubeMeta:     podKubeMetadata(pod),
 	}
 	podName := pod.Metadata.Name
 
@@ -222,6 +227,7 @@ func (l *KubeletListener) createService(entity string, pod *kubelet.Pod, firstRu
 				return
 			}
 			containerName = container.Name
+			svc.containerName = containerName
 
 			// Add container uid as ID
 			svc.adIdentifiers = append(svc.adIdentifiers, entity)
@@ -316,6 +322,17 @@ func getCheckNamesFromAnnotations(annotations map[string]string, containerName s
 	return nil, nil
 }
 
+// podKubeMetadata returns the metadata of a pod exposed to templates
+func podKubeMetadata(pod *kubelet.Pod) kubeMetadata {
+	return kubeMetadata{
+		kind:        "pod",
+		namespace:   pod.Metadata.Namespace,
+		name:        pod.Metadata.Name,
+		labels:      pod.Metadata.Labels,
+		annotations: pod.Metadata.Annotations,
+	}
+}
+
 func (l *KubeletListener) removeService(entity string) {
 	l.m.RLock()
 	svc, ok := l.services[entity]
@@ -391,6 +408,14 @@ func (s *KubeContainerService) GetCheckNames() []string {
 	return s.checkNames
 }
 
+// GetExtraConfig returns the name of the container and the metadata of its pod
+func (s *KubeContainerService) GetExtraConfig(key []byte) ([]byte, error) {
+	if string(key) == "container_name" {
+		return []byte(s.containerName), nil
+	}
+	return s.kubeMeta.getExtraConfig(key)
+}
+
 // GetEntity returns the unique entity name linked to that service
 func (s *KubePodService) GetEntity() string {
 	return s.entity
@@ -450,3 +475,8 @@ func (s *KubePodService) IsReady() bool {
 func (s *KubePodService) GetCheckNames() []string {
 	return nil
 }
+
+// GetExtraConfig returns the metadata of the pod
+func (s *KubePodService) GetExtraConfig(key []byte) ([]byte, error) {
+	return s.kubeMeta.getExtraConfig(key)
+}
@@ -0,0 +1,269 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build linux
+
+package listeners
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/procmatch"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ProcessListener discovers the processes running on the host by scanning procfs
+// periodically. Processes listening on TCP ports or matching a known integration
+// are reported as services, unless their parent process runs the same executable,
+// so that worker processes do not get their own checks.
+type ProcessListener struct {
+	scanner    procScanner
+	matcher    procmatch.Matcher
+	services   map[string]*ProcessService // keyed by hostProcess.key()
+	newService chan<- Service
+	delService chan<- Service
+	stop       chan bool
+	ticker     *time.Ticker
+	health     *health.Handle
+	m          sync.RWMutex
+}
+
+// ProcessService implements the Service interface for a process running on the host.
+type ProcessService struct {
+	entity        string
+	pid           int
+	adIdentifiers []string
+	hosts         map[string]string
+	ports         []ContainerPort
+	tags          []string
+	creationTime  integration.CreationTime
+}
+
+// Make sure ProcessService implements the Service interface
+var _ Service = &ProcessService{}
+
+func init() {
+	Register("process", NewProcessListener)
+}
+
+// NewProcessListener creates a ProcessListener
+func NewProcessListener() (ServiceListener, error) {
+	matcher, err := procmatch.NewDefault()
+	if err != nil {
+		return nil, err
+	}
+	return &ProcessListener{
+		scanner:  procScanner{root: config.Datadog.GetString("container_proc_root")},
+		matcher:  matcher,
+		services: make(map[string]*ProcessService),
+		ticker:   time.NewTicker(config.Datadog.GetDuration("process_listener_polling_interval") * time.Second),
+		stop:     make(chan bool),
+		health:   health.Register("ad-processlistener"),
+	}, nil
+}
+
+// Listen scans the running processes periodically and reports new and exited
+// ones as services.
+func (l *ProcessListener) Listen(newSvc chan<- Service, delSvc chan<- Service) {
+	// setup the I/O channels
+	l.newService = newSvc
+	l.delService = delSvc
+
+	go func() {
+		l.refreshServices(true)
+		for {
+			select {
+			case <-l.stop:
+				l.health.Deregister()
+				return
+			case <-l.health.C:
+			case <-l.ticker.C:
+				l.refreshServices(false)
+			}
+		}
+	}()
+}
+
+// Stop queues a shutdown of ProcessListener
+func (l *ProcessListener) Stop() {
+	l.ticker.Stop()
+	l.stop <- true
+}
+
+// refreshServices scans the running processes, compares them to the cached
+// services and sends new and dead services over newService and delService.
+func (l *ProcessListener) refreshServices(firstRun bool) {
+	procs, err := l.scanner.scan()
+	if err != nil {
+		log.Errorf("failed to scan processes, not refreshing services - %s", err)
+		return
+	}
+
+	notSeen := make(map[string]bool, len(l.services))
+	for key := range l.services {
+		notSeen[key] = true
+	}
+
+	for _, p := range l.discover(procs) {
+		key := p.key()
+		delete(notSeen, key)
+		if _, found := l.services[key]; found {
+			continue
+		}
+		svc := l.createService(p, firstRun)
+		log.Debugf("new process service %s with identifiers %v", svc.entity, svc.adIdentifiers)
+		l.m.Lock()
+		l.services[key] = svc
+		l.m.Unlock()
+		l.newService <- svc
+	}
+
+	for key := range notSeen {
+		l.m.Lock()
+		svc := l.services[key]
+		delete(l.services, key)
+		l.m.Unlock()
+		l.delService <- svc
+	}
+}
+
+// discover returns the processes to report as services among the given ones.
+// Services are not updated when the ports of their process change.
+func (l *ProcessListener) discover(procs []*hostProcess) []*hostProcess {
+	byPid := make(map[int]*hostProcess, len(procs))
+	for _, p := range procs {
+		byPid[p.pid] = p
+	}
+	var discovered []*hostProcess
+	for _, p := range procs {
+		if len(p.listens) == 0 && l.integration(p) == "" {
+			continue
+		}
+		if parent, ok := byPid[p.ppid]; ok && parent.name == p.name {
+			// a worker of another process
+			continue
+		}
+		discovered = append(discovered, p)
+	}
+	return discovered
+}
+
+// integration returns the name of the integration matching the process, if any.
+func (l *ProcessListener) integration(p *hostProcess) string {
+	return strings.ToLower(l.matcher.Match(p.cmdline).Name)
+}
+
+func (l *ProcessListener) createService(p *hostProcess, firstRun bool) *ProcessService {
+	crTime := integration.After
+	if firstRun {
+		crTime = integration.Before
+	}
+	svc := &ProcessService{
+		entity:        collectors.BuildProcessEntityName(p.pid, p.startTime),
+		pid:           p.pid,
+		adIdentifiers: processADIdentifiers(p.name, l.integration(p)),
+		hosts:         make(map[string]string),
+		creationTime:  crTime,
+	}
+
+	for _, addr := range p.listens {
+		svc.ports = append(svc.ports, ContainerPort{Port: addr.port})
+	}
+	if len(p.listens) > 0 {
+		// checks reach the process on the address of its lowest port
+		host := "127.0.0.1"
+		if ip := p.listens[0].ip; !ip.IsUnspecified() {
+			host = ip.String()
+		}
+		svc.hosts["host"] = host
+	}
+
+	tags, err := tagger.Tag(svc.GetTaggerEntity(), tagger.ChecksCardinality)
+	if err != nil {
+		log.Debugf("Failed to extract tags for process %d - %s", p.pid, err)
+	}
+	svc.tags = tags
+	return svc
+}
+
+// processADIdentifiers returns the AD identifiers of a process: the name of its
+// executable, then the name of the integration matching its command line, if different.
+func processADIdentifiers(name, integration string) []string {
+	ids := []string{name}
+	if integration != "" && integration != name {
+		ids = append(ids, integration)
+	}
+	return ids
+}
+
+// GetEntity returns the unique entity name linked to that service
+func (s *ProcessService) GetEntity() string {
+	return s.entity
+}
+
+// GetTaggerEntity returns the tagger entity name linked to that service
+func (s *ProcessService) GetTaggerEntity() string {
+	return s.entity
+}
+
+// GetADIdentifiers returns the name of the process executable, followed by the
+// name of the integration matching its command line, if any.
+func (s *ProcessService) GetADIdentifiers() ([]string, error) {
+	return s.adIdentifiers, nil
+}
+
+// GetHosts returns the address the process listens on, if any. Processes listening
+// on all interfaces are reached through the loopback interface.
+func (s *ProcessService) GetHosts() (map[string]string, error) {
+	return s.hosts, nil
+}
+
+// GetPorts returns the TCP ports the process listens on, sorted
+func (s *ProcessService) GetPorts() ([]ContainerPort, error) {
+	return s.ports, nil
+}
+
+// GetTags retrieves the process tags
+func (s *ProcessService) GetTags() ([]string, error) {
+	return s.tags, nil
+}
+
+// GetPid returns the process identifier
+func (s *ProcessService) GetPid() (int, error) {
+	return s.pid, nil
+}
+
+// GetHostname returns nil and an error because hostname is not supported for processes
+func (s *ProcessService) GetHostname() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetCreationTime returns the creation time of the process compare to the agent start.
+func (s *ProcessService) GetCreationTime() integration.CreationTime {
+	return s.creationTime
+}
+
+// IsReady returns if the service is ready
+func (s *ProcessService) IsReady() bool {
+	return true
+}
+
+// GetCheckNames returns slice of check names defined in kubernetes annotations or docker labels
+// ProcessService doesn't implement this method
+func (s *ProcessService) GetCheckNames() []string {
+	return nil
+}
+
+// GetExtraConfig is not supported by ProcessService
+func (s *ProcessService) GetExtraConfig(key []byte) ([]byte, error) {
+	return nil, ErrNotSupported
+}
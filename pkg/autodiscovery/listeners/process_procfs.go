@@ -0,0 +1,199 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build linux
+
+package listeners
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the state of listening sockets in /proc/net/tcp{,6}
+const tcpListen = "0A"
+
+// hostProcess holds what the process listener needs to know about a process.
+type hostProcess struct {
+	pid       int
+	ppid      int
+	startTime uint64 // in clock ticks since boot, tells apart processes reusing a pid
+	name      string // base name of the executable
+	cmdline   string // arguments separated by spaces
+	listens   []listenAddr
+}
+
+// key returns a key identifying the process during its whole lifetime.
+func (p *hostProcess) key() string {
+	return fmt.Sprintf("%d:%d", p.pid, p.startTime)
+}
+
+// listenAddr is the address of a listening TCP socket.
+type listenAddr struct {
+	ip   net.IP
+	port int
+}
+
+// procScanner reads the processes running in the host network namespace
+// from a procfs mount.
+type procScanner struct {
+	root string
+}
+
+// scan returns the processes running in the host network namespace along with the
+// TCP ports they listen on. Ports are only found for processes whose file descriptors
+// can be read by the agent.
+func (s procScanner) scan() ([]*hostProcess, error) {
+	dirs, err := ioutil.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+	hostNetNS, _ := os.Readlink(filepath.Join(s.root, "1", "ns", "net"))
+	sockets := s.listeningSockets()
+
+	var procs []*hostProcess
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil || !d.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.root, d.Name())
+		if netNS, err := os.Readlink(filepath.Join(dir, "ns", "net")); err == nil && hostNetNS != "" && netNS != hostNetNS {
+			// containerized processes are handled by the container listeners
+			continue
+		}
+		p, err := readProcess(dir, pid)
+		if err != nil {
+			// the process exited, or is a kernel thread
+			continue
+		}
+		p.listens = processListens(dir, sockets)
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
+
+// readProcess reads the process with the given pid from its procfs directory.
+func readProcess(dir string, pid int) (*hostProcess, error) {
+	cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	cmdline = bytes.TrimRight(cmdline, "\x00")
+	if len(cmdline) == 0 {
+		return nil, fmt.Errorf("process %d has no command line", pid)
+	}
+	stat, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	// the command name, between parentheses, may hold spaces
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid stat file for process %d", pid)
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	// fields start at the 3rd one, the state
+	if len(fields) < 20 {
+		return nil, fmt.Errorf("invalid stat file for process %d", pid)
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	startTime, _ := strconv.ParseUint(fields[19], 10, 64)
+
+	args := strings.Split(string(cmdline), "\x00")
+	name := filepath.Base(args[0])
+	if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+		name = filepath.Base(strings.TrimSuffix(exe, " (deleted)"))
+	}
+	return &hostProcess{
+		pid:       pid,
+		ppid:      ppid,
+		startTime: startTime,
+		name:      name,
+		cmdline:   strings.Join(args, " "),
+	}, nil
+}
+
+// processListens returns the listening sockets, among the given ones, which the process
+// in dir holds a file descriptor to.
+func processListens(dir string, sockets map[string]listenAddr) []listenAddr {
+	fds, err := ioutil.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil {
+		return nil
+	}
+	var listens []listenAddr
+	seen := make(map[int]bool)
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		addr, ok := sockets[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")]
+		if !ok || seen[addr.port] {
+			continue
+		}
+		seen[addr.port] = true
+		listens = append(listens, addr)
+	}
+	sort.Slice(listens, func(i, j int) bool { return listens[i].port < listens[j].port })
+	return listens
+}
+
+// listeningSockets returns the listening TCP sockets of the host network namespace,
+// by inode.
+func (s procScanner) listeningSockets() map[string]listenAddr {
+	sockets := make(map[string]listenAddr)
+	for _, file := range []string{"tcp", "tcp6"} {
+		f, err := os.Open(filepath.Join(s.root, "1", "net", file))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Scan() // header
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 || fields[3] != tcpListen {
+				continue
+			}
+			addr, err := parseProcNetAddr(fields[1])
+			if err != nil {
+				continue
+			}
+			sockets[fields[9]] = addr
+		}
+		f.Close()
+	}
+	return sockets
+}
+
+// parseProcNetAddr parses an address from /proc/net/tcp{,6}, such as "0100007F:1F90".
+// The IP is made of 32 bits words in host byte order, the port is in network byte order.
+func parseProcNetAddr(s string) (listenAddr, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return listenAddr{}, fmt.Errorf("invalid address %q", s)
+	}
+	ip, err := hex.DecodeString(parts[0])
+	if err != nil || (len(ip) != net.IPv4len && len(ip) != net.IPv6len) {
+		return listenAddr{}, fmt.Errorf("invalid address %q", s)
+	}
+	for i := 0; i < len(ip); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = ip[i+3], ip[i+2], ip[i+1], ip[i]
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return listenAddr{}, fmt.Errorf("invalid address %q", s)
+	}
+	return listenAddr{ip: net.IP(ip), port: int(port)}, nil
+}
@@ -0,0 +1,169 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build linux
+
+package listeners
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/procmatch"
+)
+
+type fakeProcess struct {
+	pid, ppid int
+	args      []string
+	exe       string
+	netNS     string
+	sockets   []string
+}
+
+// writeFakeProcfs writes a minimal procfs holding the given processes in dir.
+func writeFakeProcfs(t *testing.T, dir string, procs []fakeProcess) {
+	for _, p := range procs {
+		pdir := filepath.Join(dir, fmt.Sprint(p.pid))
+		require.NoError(t, os.MkdirAll(filepath.Join(pdir, "fd"), 0755))
+		require.NoError(t, os.MkdirAll(filepath.Join(pdir, "ns"), 0755))
+		cmdline := strings.Join(p.args, "\x00")
+		if cmdline != "" {
+			cmdline += "\x00"
+		}
+		require.NoError(t, ioutil.WriteFile(filepath.Join(pdir, "cmdline"), []byte(cmdline), 0644))
+		stat := fmt.Sprintf("%d (comm with spaces) S %d %s%d 0 0\n", p.pid, p.ppid, strings.Repeat("0 ", 17), 1000+p.pid)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(pdir, "stat"), []byte(stat), 0644))
+		if p.exe != "" {
+			require.NoError(t, os.Symlink(p.exe, filepath.Join(pdir, "exe")))
+		}
+		netNS := p.netNS
+		if netNS == "" {
+			netNS = "net:[1]"
+		}
+		require.NoError(t, os.Symlink(netNS, filepath.Join(pdir, "ns", "net")))
+		for i, inode := range p.sockets {
+			require.NoError(t, os.Symlink(fmt.Sprintf("socket:[%s]", inode), filepath.Join(pdir, "fd", fmt.Sprint(i+3))))
+		}
+	}
+
+	header := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	line := "   0: %s 00000000:0000 %s 00000000:00000000 00:00000000 00000000   100        0 %s 1 0000000000000000 100 0 0 10 0\n"
+	tcp := header +
+		fmt.Sprintf(line, "00000000:18EB", "0A", "100") + // 0.0.0.0:6379
+		fmt.Sprintf(line, "0100007F:1538", "0A", "101") + // 127.0.0.1:5432
+		fmt.Sprintf(line, "0100007F:1538", "01", "103") // established
+	tcp6 := header +
+		fmt.Sprintf(line, "00000000000000000000000000000000:1F90", "0A", "102") // [::]:8080
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "1", "net"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "1", "net", "tcp"), []byte(tcp), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "1", "net", "tcp6"), []byte(tcp6), 0644))
+}
+
+func TestParseProcNetAddr(t *testing.T) {
+	addr, err := parseProcNetAddr("0100007F:1F90")
+	assert.NoError(t, err)
+	assert.Equal(t, listenAddr{ip: net.IPv4(127, 0, 0, 1).To4(), port: 8080}, addr)
+
+	addr, err = parseProcNetAddr("0000000000000000FFFF00000100007F:0050")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", addr.ip.String())
+	assert.Equal(t, 80, addr.port)
+
+	for _, s := range []string{"", "0100007F", "01007F:0050", "0100007F:XYZ"} {
+		_, err = parseProcNetAddr(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestProcessListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "procfs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFakeProcfs(t, dir, []fakeProcess{
+		{pid: 1, args: []string{"/sbin/init"}},
+		{pid: 10, ppid: 1, args: []string{"/usr/bin/redis-server", "*:6379"}, exe: "/usr/bin/redis-server", sockets: []string{"100"}},
+		{pid: 20, ppid: 1, args: []string{"/usr/lib/postgresql/12/bin/postgres", "-D", "/var/lib/postgresql"}, exe: "/usr/lib/postgresql/12/bin/postgres", sockets: []string{"101", "103"}},
+		{pid: 21, ppid: 20, args: []string{"postgres: checkpointer"}, exe: "/usr/lib/postgresql/12/bin/postgres"},
+		{pid: 30, ppid: 1, args: []string{"redis-server"}, exe: "/usr/bin/redis-server", netNS: "net:[2]"},
+		{pid: 40, ppid: 1, args: []string{"/bin/bash"}, exe: "/bin/bash"},
+		{pid: 50, ppid: 2},
+		{pid: 60, ppid: 1, args: []string{"/usr/bin/java", "-jar", "app.jar"}, sockets: []string{"102"}},
+	})
+
+	matcher, err := procmatch.NewDefault()
+	require.NoError(t, err)
+	newSvc := make(chan Service, 10)
+	delSvc := make(chan Service, 10)
+	l := &ProcessListener{
+		scanner:    procScanner{root: dir},
+		matcher:    matcher,
+		services:   make(map[string]*ProcessService),
+		newService: newSvc,
+		delService: delSvc,
+	}
+
+	l.refreshServices(true)
+	require.Len(t, newSvc, 3)
+	services := make(map[string]Service)
+	for i := 0; i < 3; i++ {
+		svc := <-newSvc
+		services[svc.GetEntity()] = svc
+	}
+
+	redis := services["process://10:1010"]
+	require.NotNil(t, redis)
+	ids, _ := redis.GetADIdentifiers()
+	assert.Equal(t, []string{"redis-server", "redisdb"}, ids)
+	hosts, _ := redis.GetHosts()
+	assert.Equal(t, map[string]string{"host": "127.0.0.1"}, hosts)
+	ports, _ := redis.GetPorts()
+	assert.Equal(t, []ContainerPort{{Port: 6379}}, ports)
+	pid, _ := redis.GetPid()
+	assert.Equal(t, 10, pid)
+	assert.Equal(t, integration.Before, redis.GetCreationTime())
+
+	postgres := services["process://20:1020"]
+	require.NotNil(t, postgres)
+	ids, _ = postgres.GetADIdentifiers()
+	assert.Equal(t, []string{"postgres"}, ids)
+	ports, _ = postgres.GetPorts()
+	assert.Equal(t, []ContainerPort{{Port: 5432}}, ports)
+
+	java := services["process://60:1060"]
+	require.NotNil(t, java)
+	ids, _ = java.GetADIdentifiers()
+	assert.Equal(t, []string{"java"}, ids)
+	hosts, _ = java.GetHosts()
+	assert.Equal(t, map[string]string{"host": "127.0.0.1"}, hosts)
+
+	// redis exits, a new redis starts
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "10")))
+	writeFakeProcfs(t, dir, []fakeProcess{
+		{pid: 11, ppid: 1, args: []string{"/usr/bin/redis-server", "*:6379"}, exe: "/usr/bin/redis-server", sockets: []string{"100"}},
+	})
+	l.refreshServices(false)
+	select {
+	case svc := <-delSvc:
+		assert.Equal(t, "process://10:1010", svc.GetEntity())
+	case <-time.After(time.Second):
+		t.Fatal("redis service was not removed")
+	}
+	require.Len(t, newSvc, 1)
+	svc := <-newSvc
+	assert.Equal(t, "process://11:1011", svc.GetEntity())
+	assert.Equal(t, integration.After, svc.GetCreationTime())
+	assert.Len(t, delSvc, 0)
+}
@@ -36,6 +36,7 @@ type Service interface {
 	GetCreationTime() integration.CreationTime // created before or after the agent start
 	IsReady() bool                             // is the service ready
 	GetCheckNames() []string                   // slice of check names defined in kubernetes annotations or docker labels
+	GetExtraConfig(key []byte) ([]byte, error) // extra configuration, e.g. kubernetes namespace and labels
 }
 
 // ServiceListener monitors running services and triggers check (un)scheduling
@@ -14,10 +14,15 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util"
 	"github.com/DataDog/datadog-agent/pkg/util/clusteragent"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/hostinfo"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
-const defaultGraceDuration = 60 * time.Second
+const (
+	defaultGraceDuration = 60 * time.Second
+	// nodeLabelsTTL is how long the node labels, or the failure to get them, are cached
+	nodeLabelsTTL = 5 * time.Minute
+)
 
 // ClusterChecksConfigProvider implements the ConfigProvider interface
 // for the cluster check feature.
@@ -27,6 +32,9 @@ type ClusterChecksConfigProvider struct {
 	heartbeat      time.Time
 	lastChange     int64
 	nodeName       string
+	nodeLabels     map[string]string
+	nodeLabelsAge  time.Time // when the node labels were last retrieved
+	runnerPool     string
 	flushedConfigs bool
 }
 
@@ -39,6 +47,7 @@ func NewClusterChecksConfigProvider(cfg config.ConfigurationProviders) (ConfigPr
 	}
 
 	c.nodeName, _ = util.GetHostname()
+	c.runnerPool = config.Datadog.GetString("clc_runner_pool")
 	if cfg.GraceTimeSeconds > 0 {
 		c.graceDuration = time.Duration(cfg.GraceTimeSeconds) * time.Second
 	}
@@ -76,8 +85,15 @@ func (c *ClusterChecksConfigProvider) IsUpToDate() (bool, error) {
 		}
 	}
 
+	if time.Since(c.nodeLabelsAge) > nodeLabelsTTL {
+		c.nodeLabels = getNodeLabels(c.nodeLabels)
+		c.nodeLabelsAge = time.Now()
+	}
+
 	status := types.NodeStatus{
 		LastChange: c.lastChange,
+		Labels:     c.nodeLabels,
+		RunnerPool: c.runnerPool,
 	}
 
 	reply, err := c.dcaClient.PostClusterCheckStatus(c.nodeName, status)
@@ -126,6 +142,18 @@ func (c *ClusterChecksConfigProvider) Collect() ([]integration.Config, error) {
 	return reply.Configs, nil
 }
 
+// getNodeLabels returns the labels of the node, which the cluster-agent
+// matches against the placement of cluster checks. It returns the previous
+// labels on error, they are retrieved again once nodeLabelsTTL expired.
+func getNodeLabels(previous map[string]string) map[string]string {
+	labels, err := hostinfo.GetNodeLabels()
+	if err != nil {
+		log.Debugf("Cannot get the node labels, cluster checks with a node selector may not be dispatched to this agent: %s", err)
+		return previous
+	}
+	return labels
+}
+
 func init() {
 	RegisterProvider("clusterchecks", NewClusterChecksConfigProvider)
 }
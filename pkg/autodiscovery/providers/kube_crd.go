@@ -0,0 +1,388 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/providers/names"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	datadogCheckTargetPod     = "Pod"
+	datadogCheckTargetService = "Service"
+)
+
+// datadogCheckGVR identifies the DatadogCheck custom resources
+var datadogCheckGVR = schema.GroupVersionResource{
+	Group:    "datadoghq.com",
+	Version:  "v1alpha1",
+	Resource: "datadogchecks",
+}
+
+// datadogCheck is a check configuration held in a DatadogCheck custom resource
+type datadogCheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   datadogCheckSpec   `json:"spec"`
+	Status datadogCheckStatus `json:"status,omitempty"`
+}
+
+type datadogCheckSpec struct {
+	CheckName  string                   `json:"checkName"`
+	InitConfig map[string]interface{}   `json:"initConfig,omitempty"`
+	Instances  []map[string]interface{} `json:"instances"`
+	// Target selects the pods or services of the namespace of the resource the
+	// check runs against. Without target, the check runs as a cluster check.
+	Target *datadogCheckTarget `json:"target,omitempty"`
+	// Placement holds the dispatching hints of the cluster checks
+	Placement *integration.Placement `json:"placement,omitempty"`
+}
+
+type datadogCheckTarget struct {
+	Kind     string                `json:"kind"`
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// datadogCheckStatus is written back on the resource by the leader agent
+type datadogCheckStatus struct {
+	ObservedGeneration int64    `json:"observedGeneration,omitempty"`
+	Errors             []string `json:"errors,omitempty"`
+}
+
+// targetPod is a pod running on the node a DatadogCheck can target
+type targetPod struct {
+	entity    string
+	namespace string
+	labels    map[string]string
+}
+
+// KubeCRDConfigProvider implements the ConfigProvider interface for the check
+// configurations held in DatadogCheck custom resources. In the Cluster Agent, it
+// generates the cluster checks of the resources targeting services or no
+// resource, on node agents the checks of the resources targeting local pods.
+type KubeCRDConfigProvider struct {
+	sync.RWMutex
+	client        dynamic.Interface
+	checkLister   cache.GenericLister
+	serviceLister listersv1.ServiceLister // only set in the Cluster Agent
+	upToDate      bool
+}
+
+// NewKubeCRDConfigProvider returns a new ConfigProvider watching the DatadogCheck resources.
+// Connectivity is not checked at this stage to allow for retries, Collect will do it.
+func NewKubeCRDConfigProvider(cfg config.ConfigurationProviders) (ConfigProvider, error) {
+	ac, err := apiserver.GetAPIClient()
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to apiserver: %s", err)
+	}
+
+	checksInformer := ac.DynamicInformerFactory.ForResource(datadogCheckGVR)
+	p := &KubeCRDConfigProvider{
+		client:      ac.DynamicCl,
+		checkLister: checksInformer.Lister(),
+	}
+	checksInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    p.invalidate,
+		UpdateFunc: p.invalidateIfSpecChanged,
+		DeleteFunc: p.invalidate,
+	})
+	ac.DynamicInformerFactory.Start(wait.NeverStop)
+
+	if config.Datadog.GetBool("cluster_checks.enabled") {
+		servicesInformer := ac.InformerFactory.Core().V1().Services()
+		p.serviceLister = servicesInformer.Lister()
+		servicesInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    p.invalidate,
+			UpdateFunc: p.invalidateIfLabelsChanged,
+			DeleteFunc: p.invalidate,
+		})
+	}
+
+	return p, nil
+}
+
+// String returns a string representation of the KubeCRDConfigProvider
+func (k *KubeCRDConfigProvider) String() string {
+	return names.KubeCRD
+}
+
+// IsUpToDate allows to cache configs as long as no changes are detected in the
+// apiserver. Configs of resources targeting pods are collected again on every poll,
+// as the pods of the node are not watched.
+func (k *KubeCRDConfigProvider) IsUpToDate() (bool, error) {
+	k.RLock()
+	defer k.RUnlock()
+	return k.upToDate, nil
+}
+
+// Collect retrieves the DatadogCheck resources, builds Config objects and returns them
+func (k *KubeCRDConfigProvider) Collect() ([]integration.Config, error) {
+	objects, err := k.checkLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var pods []targetPod
+	podsListed := false
+	writeStatus := isStatusWriter()
+	upToDate := true
+
+	var configs []integration.Config
+	for _, obj := range objects {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			log.Debugf("Ignoring unexpected object %v", obj)
+			continue
+		}
+		dc, err := parseDatadogCheck(u)
+		if err != nil {
+			log.Errorf("Cannot parse DatadogCheck %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+			if writeStatus {
+				k.updateStatus(u, []error{err})
+			}
+			continue
+		}
+
+		if dc.Spec.Target != nil && dc.Spec.Target.Kind == datadogCheckTargetPod {
+			upToDate = false
+			if !podsListed {
+				if pods, err = listTargetPods(); err != nil {
+					log.Debugf("Cannot list the pods of the node: %s", err)
+				}
+				podsListed = true
+			}
+		}
+
+		dcConfigs, errs := k.generateConfigs(dc, pods)
+		for _, err := range errs {
+			log.Errorf("Invalid DatadogCheck %s/%s: %s", dc.Namespace, dc.Name, err)
+		}
+		if writeStatus {
+			k.updateStatus(u, errs)
+		}
+		configs = append(configs, dcConfigs...)
+	}
+
+	k.Lock()
+	k.upToDate = upToDate
+	k.Unlock()
+	return configs, nil
+}
+
+// parseDatadogCheck converts a DatadogCheck resource
+func parseDatadogCheck(u *unstructured.Unstructured) (*datadogCheck, error) {
+	dc := &datadogCheck{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), dc); err != nil {
+		return nil, fmt.Errorf("invalid resource: %s", err)
+	}
+	return dc, nil
+}
+
+// generateConfigs returns the configs of a DatadogCheck, one per instance and
+// target, along with the errors of its spec.
+func (k *KubeCRDConfigProvider) generateConfigs(dc *datadogCheck, pods []targetPod) ([]integration.Config, []error) {
+	spec := dc.Spec
+	var errs []error
+	if spec.CheckName == "" {
+		errs = append(errs, fmt.Errorf("checkName is required"))
+	}
+	if len(spec.Instances) == 0 {
+		errs = append(errs, fmt.Errorf("at least one instance is required"))
+	}
+
+	selector := labels.Everything()
+	if spec.Target != nil {
+		switch spec.Target.Kind {
+		case datadogCheckTargetPod, datadogCheckTargetService:
+		default:
+			errs = append(errs, fmt.Errorf("unknown target kind %q, expected %s or %s", spec.Target.Kind, datadogCheckTargetPod, datadogCheckTargetService))
+		}
+		if spec.Target.Selector != nil {
+			var err error
+			if selector, err = metav1.LabelSelectorAsSelector(spec.Target.Selector); err != nil {
+				errs = append(errs, fmt.Errorf("invalid target selector: %s", err))
+			}
+		}
+	}
+
+	var initConfig integration.Data
+	var instances []integration.Data
+	if spec.InitConfig != nil {
+		data, err := json.Marshal(spec.InitConfig)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid initConfig: %s", err))
+		}
+		initConfig = data
+	}
+	for i, instance := range spec.Instances {
+		data, err := json.Marshal(instance)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid instance #%d: %s", i+1, err))
+			continue
+		}
+		instances = append(instances, data)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	source := fmt.Sprintf("kube_crd:%s/%s", dc.Namespace, dc.Name)
+	newConfig := func(adIdentifiers []string, clusterCheck bool) []integration.Config {
+		configs := make([]integration.Config, 0, len(instances))
+		for _, instance := range instances {
+			config := integration.Config{
+				Name:          spec.CheckName,
+				InitConfig:    initConfig,
+				Instances:     []integration.Data{instance},
+				ADIdentifiers: adIdentifiers,
+				ClusterCheck:  clusterCheck,
+				Source:        source,
+			}
+			if clusterCheck {
+				config.Placement = spec.Placement
+			}
+			configs = append(configs, config)
+		}
+		return configs
+	}
+
+	var configs []integration.Config
+	switch {
+	case spec.Target == nil:
+		if k.serviceLister != nil {
+			configs = newConfig(nil, true)
+		}
+	case spec.Target.Kind == datadogCheckTargetService:
+		if k.serviceLister == nil {
+			break
+		}
+		services, err := k.serviceLister.Services(dc.Namespace).List(selector)
+		if err != nil {
+			return nil, []error{fmt.Errorf("cannot list services: %s", err)}
+		}
+		for _, svc := range services {
+			configs = append(configs, newConfig([]string{apiserver.EntityForService(svc)}, true)...)
+		}
+	case spec.Target.Kind == datadogCheckTargetPod:
+		for _, pod := range pods {
+			if pod.namespace == dc.Namespace && selector.Matches(labels.Set(pod.labels)) {
+				configs = append(configs, newConfig([]string{pod.entity}, false)...)
+			}
+		}
+	}
+	return configs, nil
+}
+
+// isStatusWriter returns whether this agent writes the status of the resources:
+// only the leader does, when leader election is enabled.
+func isStatusWriter() bool {
+	if !config.Datadog.GetBool("leader_election") {
+		return false
+	}
+	engine, err := leaderelection.GetLeaderEngine()
+	if err != nil {
+		return false
+	}
+	if err := engine.EnsureLeaderElectionRuns(); err != nil {
+		return false
+	}
+	return engine.IsLeader()
+}
+
+// currentStatus returns the status of a resource. It is read from the raw
+// object, as resources that cannot be parsed have a status too.
+func currentStatus(u *unstructured.Unstructured) datadogCheckStatus {
+	var status datadogCheckStatus
+	status.ObservedGeneration, _, _ = unstructured.NestedInt64(u.Object, "status", "observedGeneration")
+	status.Errors, _, _ = unstructured.NestedStringSlice(u.Object, "status", "errors")
+	return status
+}
+
+// updateStatus writes the errors of a resource in its status, if they changed
+func (k *KubeCRDConfigProvider) updateStatus(u *unstructured.Unstructured, errs []error) {
+	status := datadogCheckStatus{ObservedGeneration: u.GetGeneration()}
+	for _, err := range errs {
+		status.Errors = append(status.Errors, err.Error())
+	}
+	if reflect.DeepEqual(status, currentStatus(u)) {
+		return
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		log.Debugf("Cannot convert the status of DatadogCheck %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+		return
+	}
+	updated := u.DeepCopy()
+	updated.Object["status"] = content
+	_, err = k.client.Resource(datadogCheckGVR).Namespace(u.GetNamespace()).UpdateStatus(updated, metav1.UpdateOptions{})
+	if err != nil {
+		log.Warnf("Cannot update the status of DatadogCheck %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+	}
+}
+
+func (k *KubeCRDConfigProvider) invalidate(obj interface{}) {
+	if obj != nil {
+		log.Trace("Invalidating configs on new/deleted DatadogCheck or service")
+		k.Lock()
+		k.upToDate = false
+		k.Unlock()
+	}
+}
+
+// invalidateIfSpecChanged ignores the updates of the status of the resources
+func (k *KubeCRDConfigProvider) invalidateIfSpecChanged(old, obj interface{}) {
+	castedObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		log.Errorf("Expected an Unstructured type, got: %v", obj)
+		return
+	}
+	castedOld, ok := old.(*unstructured.Unstructured)
+	if !ok || castedObj.GetGeneration() != castedOld.GetGeneration() {
+		k.invalidate(obj)
+	}
+}
+
+// invalidateIfLabelsChanged invalidates configs when the labels of a service
+// change, as DatadogChecks select services by label
+func (k *KubeCRDConfigProvider) invalidateIfLabelsChanged(old, obj interface{}) {
+	castedObj, ok := obj.(*v1.Service)
+	if !ok {
+		log.Errorf("Expected a Service type, got: %v", obj)
+		return
+	}
+	castedOld, ok := old.(*v1.Service)
+	if !ok || !reflect.DeepEqual(castedObj.Labels, castedOld.Labels) {
+		k.invalidate(obj)
+	}
+}
+
+func init() {
+	RegisterProvider("kube_crd", NewKubeCRDConfigProvider)
+}
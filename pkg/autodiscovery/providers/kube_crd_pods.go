@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver,kubelet
+
+package providers
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
+)
+
+// listTargetPods returns the pods running on the node, which DatadogChecks
+// targeting pods are scheduled on
+func listTargetPods() ([]targetPod, error) {
+	ku, err := kubelet.GetKubeUtil()
+	if err != nil {
+		return nil, err
+	}
+	pods, err := ku.GetLocalPodList()
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]targetPod, 0, len(pods))
+	for _, pod := range pods {
+		targets = append(targets, targetPod{
+			entity:    kubelet.PodUIDToEntityName(pod.Metadata.UID),
+			namespace: pod.Metadata.Namespace,
+			labels:    pod.Metadata.Labels,
+		})
+	}
+	return targets, nil
+}
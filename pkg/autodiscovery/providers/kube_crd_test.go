@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package providers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+)
+
+func newDatadogCheck(t *testing.T, spec map[string]interface{}) *datadogCheck {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "datadoghq.com/v1alpha1",
+		"kind":       "DatadogCheck",
+		"metadata": map[string]interface{}{
+			"name":       "redis",
+			"namespace":  "default",
+			"generation": int64(2),
+		},
+		"spec": spec,
+	}}
+	dc, err := parseDatadogCheck(u)
+	require.NoError(t, err)
+	return dc
+}
+
+func newServiceLister(t *testing.T, services ...*v1.Service) listersv1.ServiceLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, svc := range services {
+		require.NoError(t, indexer.Add(svc))
+	}
+	return listersv1.NewServiceLister(indexer)
+}
+
+func TestKubeCRDGenerateConfigs(t *testing.T) {
+	services := []*v1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default", UID: types.UID("svc-1"), Labels: map[string]string{"app": "redis"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: types.UID("svc-2"), Labels: map[string]string{"app": "web"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "other", UID: types.UID("svc-3"), Labels: map[string]string{"app": "redis"}}},
+	}
+	pods := []targetPod{
+		{entity: "kubernetes_pod://pod-1", namespace: "default", labels: map[string]string{"app": "redis"}},
+		{entity: "kubernetes_pod://pod-2", namespace: "default", labels: map[string]string{"app": "web"}},
+		{entity: "kubernetes_pod://pod-3", namespace: "other", labels: map[string]string{"app": "redis"}},
+	}
+	selector := map[string]interface{}{"matchLabels": map[string]interface{}{"app": "redis"}}
+
+	for name, tc := range map[string]struct {
+		spec          map[string]interface{}
+		clusterAgent  bool
+		expectedOut   []integration.Config
+		expectedError bool
+	}{
+		"cluster check": {
+			spec: map[string]interface{}{
+				"checkName":  "http_check",
+				"initConfig": map[string]interface{}{},
+				"instances":  []interface{}{map[string]interface{}{"url": "http://example.com"}},
+			},
+			clusterAgent: true,
+			expectedOut: []integration.Config{{
+				Name:         "http_check",
+				InitConfig:   integration.Data("{}"),
+				Instances:    []integration.Data{integration.Data(`{"url":"http://example.com"}`)},
+				ClusterCheck: true,
+				Source:       "kube_crd:default/redis",
+			}},
+		},
+		"cluster check ignored by node agents": {
+			spec: map[string]interface{}{
+				"checkName": "http_check",
+				"instances": []interface{}{map[string]interface{}{"url": "http://example.com"}},
+			},
+		},
+		"service target": {
+			spec: map[string]interface{}{
+				"checkName": "redisdb",
+				"instances": []interface{}{map[string]interface{}{"host": "%%host%%"}, map[string]interface{}{"host": "%%host%%", "db": int64(1)}},
+				"target":    map[string]interface{}{"kind": "Service", "selector": selector},
+			},
+			clusterAgent: true,
+			expectedOut: []integration.Config{
+				{
+					Name:          "redisdb",
+					Instances:     []integration.Data{integration.Data(`{"host":"%%host%%"}`)},
+					ADIdentifiers: []string{"kube_service_uid://svc-1"},
+					ClusterCheck:  true,
+					Source:        "kube_crd:default/redis",
+				},
+				{
+					Name:          "redisdb",
+					Instances:     []integration.Data{integration.Data(`{"db":1,"host":"%%host%%"}`)},
+					ADIdentifiers: []string{"kube_service_uid://svc-1"},
+					ClusterCheck:  true,
+					Source:        "kube_crd:default/redis",
+				},
+			},
+		},
+		"pod target": {
+			spec: map[string]interface{}{
+				"checkName": "redisdb",
+				"instances": []interface{}{map[string]interface{}{"host": "%%host%%"}},
+				"target":    map[string]interface{}{"kind": "Pod", "selector": selector},
+			},
+			expectedOut: []integration.Config{{
+				Name:          "redisdb",
+				Instances:     []integration.Data{integration.Data(`{"host":"%%host%%"}`)},
+				ADIdentifiers: []string{"kubernetes_pod://pod-1"},
+				Source:        "kube_crd:default/redis",
+			}},
+		},
+		"missing check name": {
+			spec: map[string]interface{}{
+				"instances": []interface{}{map[string]interface{}{}},
+			},
+			expectedError: true,
+		},
+		"no instances": {
+			spec: map[string]interface{}{
+				"checkName": "redisdb",
+			},
+			expectedError: true,
+		},
+		"unknown target kind": {
+			spec: map[string]interface{}{
+				"checkName": "redisdb",
+				"instances": []interface{}{map[string]interface{}{}},
+				"target":    map[string]interface{}{"kind": "Node"},
+			},
+			expectedError: true,
+		},
+		"invalid selector": {
+			spec: map[string]interface{}{
+				"checkName": "redisdb",
+				"instances": []interface{}{map[string]interface{}{}},
+				"target": map[string]interface{}{"kind": "Pod", "selector": map[string]interface{}{
+					"matchExpressions": []interface{}{map[string]interface{}{"key": "app", "operator": "Around"}},
+				}},
+			},
+			expectedError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := &KubeCRDConfigProvider{}
+			if tc.clusterAgent {
+				p.serviceLister = newServiceLister(t, services...)
+			}
+			configs, errs := p.generateConfigs(newDatadogCheck(t, tc.spec), pods)
+			if tc.expectedError {
+				assert.NotEmpty(t, errs)
+				assert.Empty(t, configs)
+				return
+			}
+			assert.Empty(t, errs)
+			assert.EqualValues(t, tc.expectedOut, configs)
+		})
+	}
+}
+
+func TestKubeCRDInvalidateIfSpecChanged(t *testing.T) {
+	newObject := func(generation int64) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGeneration(generation)
+		return u
+	}
+	p := &KubeCRDConfigProvider{upToDate: true}
+
+	// Status updates don't change the generation
+	p.invalidateIfSpecChanged(newObject(1), newObject(1))
+	upToDate, err := p.IsUpToDate()
+	assert.NoError(t, err)
+	assert.True(t, upToDate)
+
+	p.invalidateIfSpecChanged(newObject(1), newObject(2))
+	upToDate, err = p.IsUpToDate()
+	assert.NoError(t, err)
+	assert.False(t, upToDate)
+}
+
+func TestKubeCRDUpdateStatusUnchanged(t *testing.T) {
+	// A resource that cannot be parsed, whose status already holds the error
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "datadoghq.com/v1alpha1",
+		"kind":       "DatadogCheck",
+		"metadata": map[string]interface{}{
+			"name":       "redis",
+			"namespace":  "default",
+			"generation": int64(3),
+		},
+		"spec": "invalid",
+		"status": map[string]interface{}{
+			"observedGeneration": int64(3),
+			"errors":             []interface{}{"invalid resource"},
+		},
+	}}
+	_, err := parseDatadogCheck(u)
+	require.Error(t, err)
+
+	assert.Equal(t, datadogCheckStatus{ObservedGeneration: 3, Errors: []string{"invalid resource"}}, currentStatus(u))
+
+	// The provider has no client, updating the status would panic
+	k := &KubeCRDConfigProvider{}
+	k.updateStatus(u, []error{fmt.Errorf("invalid resource")})
+}
@@ -15,6 +15,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
 	listersv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 
@@ -34,10 +35,14 @@ const (
 // kubeEndpointsConfigProvider implements the ConfigProvider interface for the apiserver.
 type kubeEndpointsConfigProvider struct {
 	sync.RWMutex
-	serviceLister      listersv1.ServiceLister
-	endpointsLister    listersv1.EndpointsLister
-	upToDate           bool
-	monitoredEndpoints map[string]bool
+	serviceLister       listersv1.ServiceLister
+	endpointsLister     listersv1.EndpointsLister
+	endpointSliceLister cache.GenericLister // only set when reading the EndpointSlices
+	podLister           listersv1.PodLister
+	upToDate            bool
+	monitoredEndpoints  map[string]bool
+	// checkPodsReadiness is set when a monitored service publishes its not ready addresses
+	checkPodsReadiness bool
 }
 
 // configInfo contains an endpoint check config template with its name and namespace
@@ -45,6 +50,8 @@ type configInfo struct {
 	tpl       integration.Config
 	namespace string
 	name      string
+	// publishNotReady is set when the service publishes the addresses of its not ready pods
+	publishNotReady bool
 }
 
 // NewKubeEndpointsConfigProvider returns a new ConfigProvider connected to apiserver.
@@ -70,14 +77,36 @@ func NewKubeEndpointsConfigProvider(config config.ConfigurationProviders) (Confi
 		DeleteFunc: p.invalidate,
 	})
 
-	endpointsInformer := ac.InformerFactory.Core().V1().Endpoints()
-	if endpointsInformer == nil {
-		return nil, fmt.Errorf("cannot get endpoint informer: %s", err)
+	if apiserver.UseEndpointSlices(ac) {
+		// The EndpointSlices of a service are created and deleted as it scales
+		endpointSlicesInformer := ac.DynamicInformerFactory.ForResource(apiserver.EndpointSliceGVR)
+		p.endpointSliceLister = endpointSlicesInformer.Lister()
+		endpointSlicesInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    p.invalidateIfMonitoredEndpointSlice,
+			UpdateFunc: p.invalidateIfChangedEndpointSlice,
+			DeleteFunc: p.invalidateIfMonitoredEndpointSlice,
+		})
+		ac.DynamicInformerFactory.Start(wait.NeverStop)
+	} else {
+		endpointsInformer := ac.InformerFactory.Core().V1().Endpoints()
+		if endpointsInformer == nil {
+			return nil, fmt.Errorf("cannot get endpoint informer: %s", err)
+		}
+		p.endpointsLister = endpointsInformer.Lister()
+
+		endpointsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: p.invalidateIfChangedEndpoints,
+		})
+	}
+
+	podsInformer := ac.InformerFactory.Core().V1().Pods()
+	if podsInformer == nil {
+		return nil, fmt.Errorf("cannot get pod informer: %s", err)
 	}
-	p.endpointsLister = endpointsInformer.Lister()
+	p.podLister = podsInformer.Lister()
 
-	endpointsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		UpdateFunc: p.invalidateIfChangedEndpoints,
+	podsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: p.invalidateIfChangedPodReadiness,
 	})
 
 	return p, nil
@@ -97,22 +126,58 @@ func (k *kubeEndpointsConfigProvider) Collect() ([]integration.Config, error) {
 	k.setUpToDate(true)
 
 	var generatedConfigs []integration.Config
+	checkPodsReadiness := false
 	parsedConfigsInfo := parseServiceAnnotationsForEndpoints(services)
 	for _, config := range parsedConfigsInfo {
-		kep, err := k.endpointsLister.Endpoints(config.namespace).Get(config.name)
+		addresses, err := k.readyAddresses(config.namespace, config.name)
 		if err != nil {
 			log.Errorf("Cannot get Kubernetes endpoints: %s", err)
 			continue
 		}
-		generatedConfigs = append(generatedConfigs, generateConfigs(config.tpl, kep)...)
+		if config.publishNotReady {
+			checkPodsReadiness = true
+			addresses = apiserver.FilterReadyPods(k.podLister, addresses)
+		}
+		generatedConfigs = append(generatedConfigs, generateConfigs(config.tpl, config.namespace, config.name, addresses)...)
 		endpointsID := apiserver.EntityForEndpoints(config.namespace, config.name, "")
 		k.Lock()
 		k.monitoredEndpoints[endpointsID] = true
 		k.Unlock()
 	}
+	k.Lock()
+	k.checkPodsReadiness = checkPodsReadiness
+	k.Unlock()
 	return generatedConfigs, nil
 }
 
+// readyAddresses returns the ready addresses of the endpoints of a service,
+// from its Endpoints or EndpointSlices
+func (k *kubeEndpointsConfigProvider) readyAddresses(namespace, name string) ([]apiserver.EndpointAddress, error) {
+	if k.endpointSliceLister == nil {
+		kep, err := k.endpointsLister.Endpoints(namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return apiserver.ReadyAddressesForEndpoints(kep), nil
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{apiserver.EndpointSliceServiceNameLabel: name})
+	objects, err := k.endpointSliceLister.ByNamespace(namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+	var slices []*apiserver.EndpointSlice
+	for _, obj := range objects {
+		slice, err := apiserver.ParseEndpointSlice(obj)
+		if err != nil {
+			log.Errorf("Cannot parse Kubernetes endpoint slice: %s", err)
+			continue
+		}
+		slices = append(slices, slice)
+	}
+	return apiserver.ReadyAddressesForSlices(slices), nil
+}
+
 // IsUpToDate allows to cache configs as long as no changes are detected in the apiserver
 func (k *kubeEndpointsConfigProvider) IsUpToDate() (bool, error) {
 	return k.upToDate, nil
@@ -188,6 +253,73 @@ func (k *kubeEndpointsConfigProvider) invalidateIfChangedEndpoints(old, obj inte
 	return
 }
 
+func (k *kubeEndpointsConfigProvider) invalidateIfMonitoredEndpointSlice(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	slice, err := apiserver.ParseEndpointSlice(obj)
+	if err != nil {
+		log.Errorf("Cannot parse Kubernetes endpoint slice: %s", err)
+		return
+	}
+	endpointsID := apiserver.EntityForEndpoints(slice.Namespace, slice.ServiceName(), "")
+	k.Lock()
+	defer k.Unlock()
+	if found := k.monitoredEndpoints[endpointsID]; found {
+		log.Tracef("Invalidating configs on new/deleted endpoint slice, endpoints entity: %s", endpointsID)
+		k.upToDate = false
+	}
+}
+
+func (k *kubeEndpointsConfigProvider) invalidateIfChangedEndpointSlice(old, obj interface{}) {
+	// Cast the updated object, don't invalidate on casting error.
+	castedObj, err := apiserver.ParseEndpointSlice(obj)
+	if err != nil {
+		log.Errorf("Cannot parse Kubernetes endpoint slice: %s", err)
+		return
+	}
+	// Cast the old object, invalidate on casting error
+	castedOld, err := apiserver.ParseEndpointSlice(old)
+	if err != nil {
+		log.Errorf("Cannot parse Kubernetes endpoint slice: %s", err)
+		k.setUpToDate(false)
+		return
+	}
+	// Quick exit if resversion did not change
+	if castedObj.ResourceVersion == castedOld.ResourceVersion {
+		return
+	}
+	// Make sure we invalidate a monitored endpoints object
+	endpointsID := apiserver.EntityForEndpoints(castedObj.Namespace, castedObj.ServiceName(), "")
+	k.Lock()
+	defer k.Unlock()
+	if found := k.monitoredEndpoints[endpointsID]; found {
+		// Invalidate only when the endpoints change
+		k.upToDate = k.upToDate && equality.Semantic.DeepEqual(castedObj.Endpoints, castedOld.Endpoints)
+	}
+}
+
+func (k *kubeEndpointsConfigProvider) invalidateIfChangedPodReadiness(old, obj interface{}) {
+	castedObj, ok := obj.(*v1.Pod)
+	if !ok {
+		log.Errorf("Expected a Pod type, got: %T", obj)
+		return
+	}
+	castedOld, ok := old.(*v1.Pod)
+	if !ok {
+		log.Errorf("Expected a Pod type, got: %T", old)
+		return
+	}
+	k.Lock()
+	defer k.Unlock()
+	// The endpoints of the services publishing their not ready addresses don't
+	// change with the readiness of their pods
+	if k.checkPodsReadiness && apiserver.IsPodReady(castedObj) != apiserver.IsPodReady(castedOld) {
+		log.Tracef("Invalidating configs on readiness change of pod %s/%s", castedObj.Namespace, castedObj.Name)
+		k.upToDate = false
+	}
+}
+
 // setUpToDate is a thread-safe method to update the upToDate value
 func (k *kubeEndpointsConfigProvider) setUpToDate(v bool) {
 	k.Lock()
@@ -210,55 +342,46 @@ func parseServiceAnnotationsForEndpoints(services []*v1.Service) []configInfo {
 		for i := range endptConf {
 			endptConf[i].Source = "kube_endpoints:" + endpointsID
 			configsInfo = append(configsInfo, configInfo{
-				tpl:       endptConf[i],
-				namespace: svc.Namespace,
-				name:      svc.Name,
+				tpl:             endptConf[i],
+				namespace:       svc.Namespace,
+				name:            svc.Name,
+				publishNotReady: svc.Spec.PublishNotReadyAddresses,
 			})
 		}
 	}
 	return configsInfo
 }
 
-// generateConfigs creates a config template for each Endpoints IP
-func generateConfigs(tpl integration.Config, kep *v1.Endpoints) []integration.Config {
-	if kep == nil {
-		log.Warn("Nil Kubernetes Endpoints object, cannot generate config templates")
-		return []integration.Config{tpl}
-	}
+// generateConfigs creates a config template for each ready address of the endpoints of a service
+func generateConfigs(tpl integration.Config, namespace, name string, addresses []apiserver.EndpointAddress) []integration.Config {
 	generatedConfigs := []integration.Config{}
-	namespace := kep.Namespace
-	name := kep.Name
-	for i := range kep.Subsets {
-		for j := range kep.Subsets[i].Addresses {
-			// Set a new entity containing the endpoint's IP
-			entity := apiserver.EntityForEndpoints(namespace, name, kep.Subsets[i].Addresses[j].IP)
-			newConfig := integration.Config{
-				Entity:        entity,
-				Name:          tpl.Name,
-				Instances:     tpl.Instances,
-				InitConfig:    tpl.InitConfig,
-				MetricConfig:  tpl.MetricConfig,
-				LogsConfig:    tpl.LogsConfig,
-				ADIdentifiers: []string{entity},
-				ClusterCheck:  true,
-				Provider:      tpl.Provider,
-				Source:        tpl.Source,
-			}
-			if targetRef := kep.Subsets[i].Addresses[j].TargetRef; targetRef != nil {
-				if targetRef.Kind == kubePodKind {
-					// The endpoint is backed by a pod.
-					// We add the pod uid as AD identifiers so the check can get the pod tags.
-					podUID := string(targetRef.UID)
-					newConfig.ADIdentifiers = append(newConfig.ADIdentifiers, getPodEntity(podUID))
-					if nodeName := kep.Subsets[i].Addresses[j].NodeName; nodeName != nil {
-						// Set the node name to schedule the endpoint check on the correct node.
-						// This field needs to be set only when the endpoint is backed by a pod.
-						newConfig.NodeName = *nodeName
-					}
-				}
+	for _, address := range addresses {
+		// Set a new entity containing the endpoint's IP
+		entity := apiserver.EntityForEndpoints(namespace, name, address.IP)
+		newConfig := integration.Config{
+			Entity:        entity,
+			Name:          tpl.Name,
+			Instances:     tpl.Instances,
+			InitConfig:    tpl.InitConfig,
+			MetricConfig:  tpl.MetricConfig,
+			LogsConfig:    tpl.LogsConfig,
+			ADIdentifiers: []string{entity},
+			ClusterCheck:  true,
+			Provider:      tpl.Provider,
+			Source:        tpl.Source,
+		}
+		if targetRef := address.TargetRef; targetRef != nil {
+			if targetRef.Kind == kubePodKind {
+				// The endpoint is backed by a pod.
+				// We add the pod uid as AD identifiers so the check can get the pod tags.
+				podUID := string(targetRef.UID)
+				newConfig.ADIdentifiers = append(newConfig.ADIdentifiers, getPodEntity(podUID))
+				// Set the node name to schedule the endpoint check on the correct node.
+				// This field needs to be set only when the endpoint is backed by a pod.
+				newConfig.NodeName = address.NodeName
 			}
-			generatedConfigs = append(generatedConfigs, newConfig)
 		}
+		generatedConfigs = append(generatedConfigs, newConfig)
 	}
 	return generatedConfigs
 }
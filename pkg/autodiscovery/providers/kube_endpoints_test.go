@@ -15,7 +15,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
@@ -79,10 +81,17 @@ func TestGenerateConfigs(t *testing.T) {
 		expectedOut []integration.Config
 	}{
 		{
-			name:        "nil kubernetes Endpoints",
-			endpoints:   nil,
+			name: "Endpoints without addresses",
+			endpoints: &v1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{
+					ResourceVersion: "123",
+					UID:             types.UID("endpoints-uid"),
+					Name:            "myservice",
+					Namespace:       "default",
+				},
+			},
 			template:    integration.Config{},
-			expectedOut: []integration.Config{{}},
+			expectedOut: []integration.Config{},
 		},
 		{
 			name: "Endpoints without podRef",
@@ -190,7 +199,8 @@ func TestGenerateConfigs(t *testing.T) {
 		},
 	} {
 		t.Run(fmt.Sprintf(tc.name), func(t *testing.T) {
-			cfgs := generateConfigs(tc.template, tc.endpoints)
+			addresses := apiserver.ReadyAddressesForEndpoints(tc.endpoints)
+			cfgs := generateConfigs(tc.template, tc.endpoints.Namespace, tc.endpoints.Name, addresses)
 			assert.EqualValues(t, tc.expectedOut, cfgs)
 		})
 	}
@@ -612,3 +622,134 @@ func TestInvalidateIfChangedEndpoints(t *testing.T) {
 		})
 	}
 }
+
+func newFakeEndpointSlice(name, service, resourceVersion string, ips ...string) *unstructured.Unstructured {
+	var endpoints []interface{}
+	for _, ip := range ips {
+		endpoints = append(endpoints, map[string]interface{}{
+			"addresses":  []interface{}{ip},
+			"conditions": map[string]interface{}{"ready": true},
+		})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "discovery.k8s.io/v1beta1",
+		"kind":       "EndpointSlice",
+		"metadata": map[string]interface{}{
+			"name":            name,
+			"namespace":       "default",
+			"resourceVersion": resourceVersion,
+			"labels":          map[string]interface{}{apiserver.EndpointSliceServiceNameLabel: service},
+		},
+		"addressType": "IPv4",
+		"endpoints":   endpoints,
+	}}
+}
+
+func TestReadyAddressesFromEndpointSlices(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.NoError(t, indexer.Add(newFakeEndpointSlice("myservice-abcde", "myservice", "123", "10.0.0.1", "10.0.0.2")))
+	assert.NoError(t, indexer.Add(newFakeEndpointSlice("myservice-fghij", "myservice", "123", "10.0.0.3")))
+	assert.NoError(t, indexer.Add(newFakeEndpointSlice("otherservice-abcde", "otherservice", "123", "10.0.0.4")))
+	provider := &kubeEndpointsConfigProvider{
+		endpointSliceLister: cache.NewGenericLister(indexer, apiserver.EndpointSliceGVR.GroupResource()),
+	}
+
+	addresses, err := provider.readyAddresses("default", "myservice")
+	assert.NoError(t, err)
+	var ips []string
+	for _, address := range addresses {
+		ips = append(ips, address.IP)
+	}
+	assert.ElementsMatch(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, ips)
+}
+
+func TestInvalidateIfChangedEndpointSlice(t *testing.T) {
+	for name, tc := range map[string]struct {
+		first    *unstructured.Unstructured
+		second   *unstructured.Unstructured
+		upToDate bool
+	}{
+		"Same resversion": {
+			first:    newFakeEndpointSlice("myservice-abcde", "myservice", "123", "10.0.0.1"),
+			second:   newFakeEndpointSlice("myservice-abcde", "myservice", "123", "10.0.0.2"),
+			upToDate: true,
+		},
+		"Change resversion, same endpoints": {
+			first:    newFakeEndpointSlice("myservice-abcde", "myservice", "123", "10.0.0.1"),
+			second:   newFakeEndpointSlice("myservice-abcde", "myservice", "124", "10.0.0.1"),
+			upToDate: true,
+		},
+		"Change IP": {
+			first:    newFakeEndpointSlice("myservice-abcde", "myservice", "123", "10.0.0.1"),
+			second:   newFakeEndpointSlice("myservice-abcde", "myservice", "124", "10.0.0.2"),
+			upToDate: false,
+		},
+		"Change IP for not monitored service": {
+			first:    newFakeEndpointSlice("notmonitored-abcde", "notmonitored", "123", "10.0.0.1"),
+			second:   newFakeEndpointSlice("notmonitored-abcde", "notmonitored", "124", "10.0.0.2"),
+			upToDate: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			provider := &kubeEndpointsConfigProvider{
+				upToDate: true,
+				monitoredEndpoints: map[string]bool{
+					apiserver.EntityForEndpoints("default", "myservice", ""): true,
+				},
+			}
+			provider.invalidateIfChangedEndpointSlice(tc.first, tc.second)
+
+			upToDate, err := provider.IsUpToDate()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.upToDate, upToDate)
+		})
+	}
+}
+
+func TestInvalidateIfChangedPodReadiness(t *testing.T) {
+	newPod := func(ready v1.ConditionStatus) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+			Status: v1.PodStatus{
+				Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: ready}},
+			},
+		}
+	}
+	for name, tc := range map[string]struct {
+		checkPodsReadiness bool
+		first              *v1.Pod
+		second             *v1.Pod
+		upToDate           bool
+	}{
+		"Same readiness": {
+			checkPodsReadiness: true,
+			first:              newPod(v1.ConditionTrue),
+			second:             newPod(v1.ConditionTrue),
+			upToDate:           true,
+		},
+		"Change readiness": {
+			checkPodsReadiness: true,
+			first:              newPod(v1.ConditionTrue),
+			second:             newPod(v1.ConditionFalse),
+			upToDate:           false,
+		},
+		"Change readiness, no service publishing not ready addresses": {
+			checkPodsReadiness: false,
+			first:              newPod(v1.ConditionTrue),
+			second:             newPod(v1.ConditionFalse),
+			upToDate:           true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			provider := &kubeEndpointsConfigProvider{
+				upToDate:           true,
+				checkPodsReadiness: tc.checkPodsReadiness,
+			}
+			provider.invalidateIfChangedPodReadiness(tc.first, tc.second)
+
+			upToDate, err := provider.IsUpToDate()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.upToDate, upToDate)
+		})
+	}
+}
@@ -17,5 +17,6 @@ const (
 	Kubernetes      = "kubernetes"
 	KubeServices    = "kubernetes-services"
 	KubeEndpoints   = "kubernetes-endpoints"
+	KubeCRD         = "kubernetes-crd"
 	Zookeeper       = "zookeeper"
 )
@@ -0,0 +1,260 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+// Package admission implements the mutating admission webhook of the cluster
+// agent, injecting the agent configuration in the application pods.
+package admission
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"time"
+
+	admiv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/common"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	reconcilePeriod = time.Minute
+	certKey         = "cert.pem"
+	keyKey          = "key.pem"
+	webhookName     = "config.admission.datadoghq.com"
+)
+
+// ControllerContext holds the necessary context for the admission controller
+type ControllerContext struct {
+	IsLeaderFunc func() bool
+	Client       kubernetes.Interface
+	StopCh       chan struct{}
+}
+
+// controller maintains the certificate of the webhook and its configuration
+// from the leader, and loads the certificate in the server of every replica.
+type controller struct {
+	client       kubernetes.Interface
+	isLeaderFunc func() bool
+	server       *server
+
+	namespace               string
+	secretName              string
+	serviceName             string
+	webhookConfigName       string
+	certValidity            time.Duration
+	certExpirationThreshold time.Duration
+	mutateUnlabelled        bool
+
+	servedCert []byte
+}
+
+// StartController starts the webhook server and the reconciliation of its certificate and configuration
+func StartController(ctx ControllerContext) error {
+	mutation := &mutationConfig{
+		mutateUnlabelled: config.Datadog.GetBool("admission_controller.mutate_unlabelled"),
+		injectConfig:     config.Datadog.GetBool("admission_controller.inject_config.enabled"),
+		injectTags:       config.Datadog.GetBool("admission_controller.inject_tags.enabled"),
+		configMode:       config.Datadog.GetString("admission_controller.inject_config.mode"),
+		socketPath:       config.Datadog.GetString("admission_controller.inject_config.socket_path"),
+	}
+	if mutation.configMode != injectConfigModeHostIP && mutation.configMode != injectConfigModeSocket {
+		return fmt.Errorf("invalid admission_controller.inject_config.mode %q, choose from [%s,%s]", mutation.configMode, injectConfigModeHostIP, injectConfigModeSocket)
+	}
+
+	c := &controller{
+		client:                  ctx.Client,
+		isLeaderFunc:            ctx.IsLeaderFunc,
+		server:                  newServer(mutation),
+		namespace:               common.GetResourcesNamespace(),
+		secretName:              config.Datadog.GetString("admission_controller.certificate.secret_name"),
+		serviceName:             config.Datadog.GetString("admission_controller.service_name"),
+		webhookConfigName:       config.Datadog.GetString("admission_controller.webhook_name"),
+		certValidity:            time.Duration(config.Datadog.GetInt("admission_controller.certificate.validity_bound")) * time.Hour,
+		certExpirationThreshold: time.Duration(config.Datadog.GetInt("admission_controller.certificate.expiration_threshold")) * time.Hour,
+		mutateUnlabelled:        mutation.mutateUnlabelled,
+	}
+
+	go c.server.run(config.Datadog.GetInt("admission_controller.port"), ctx.StopCh)
+	go c.run(ctx.StopCh)
+	return nil
+}
+
+func (c *controller) run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(reconcilePeriod)
+	defer ticker.Stop()
+	for {
+		c.reconcile()
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile renews the certificate and updates the webhook from the leader,
+// every replica serves the certificate stored in the secret.
+func (c *controller) reconcile() {
+	if c.isLeaderFunc() {
+		if err := c.reconcileSecret(); err != nil {
+			log.Errorf("Could not reconcile the admission controller certificate: %v", err)
+		}
+	}
+
+	secret, err := c.client.CoreV1().Secrets(c.namespace).Get(c.secretName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Debugf("The admission controller certificate is not created yet")
+		} else {
+			log.Errorf("Could not get the admission controller certificate: %v", err)
+		}
+		return
+	}
+	certPEM := secret.Data[certKey]
+	if !bytes.Equal(certPEM, c.servedCert) {
+		if err = c.server.setCertificate(certPEM, secret.Data[keyKey]); err != nil {
+			log.Errorf("Invalid admission controller certificate: %v", err)
+			return
+		}
+		c.servedCert = certPEM
+		log.Infof("Loaded the admission controller certificate")
+	}
+
+	if c.isLeaderFunc() {
+		if err = c.reconcileWebhook(certPEM); err != nil {
+			log.Errorf("Could not reconcile the admission controller webhook: %v", err)
+		}
+	}
+}
+
+// reconcileSecret creates the certificate, or renews it when it is about to expire
+func (c *controller) reconcileSecret() error {
+	secrets := c.client.CoreV1().Secrets(c.namespace)
+	secret, err := secrets.Get(c.secretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.secretName,
+				Namespace: c.namespace,
+			},
+		}
+		if secret.Data, err = c.newCertificateData(); err != nil {
+			return err
+		}
+		log.Infof("Creating the admission controller certificate in the secret %s/%s", c.namespace, c.secretName)
+		_, err = secrets.Create(secret)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	cert, err := parseCertificate(secret.Data[certKey])
+	if err == nil && time.Until(cert.NotAfter) > c.certExpirationThreshold {
+		return nil
+	}
+	if secret.Data, err = c.newCertificateData(); err != nil {
+		return err
+	}
+	log.Infof("Renewing the admission controller certificate in the secret %s/%s", c.namespace, c.secretName)
+	_, err = secrets.Update(secret)
+	return err
+}
+
+func (c *controller) newCertificateData() (map[string][]byte, error) {
+	dnsNames := []string{
+		c.serviceName,
+		fmt.Sprintf("%s.%s", c.serviceName, c.namespace),
+		fmt.Sprintf("%s.%s.svc", c.serviceName, c.namespace),
+	}
+	certPEM, keyPEM, err := generateCertificate(dnsNames, c.certValidity)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{certKey: certPEM, keyKey: keyPEM}, nil
+}
+
+// reconcileWebhook creates the webhook configuration, or updates it if it
+// does not match the certificate and the configuration of the cluster agent
+func (c *controller) reconcileWebhook(caBundle []byte) error {
+	webhookConfigs := c.client.AdmissionregistrationV1beta1().MutatingWebhookConfigurations()
+	desired := c.newWebhook(caBundle)
+
+	current, err := webhookConfigs.Get(c.webhookConfigName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		log.Infof("Creating the admission controller webhook %s", c.webhookConfigName)
+		_, err = webhookConfigs.Create(&admiv1beta1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: c.webhookConfigName},
+			Webhooks:   []admiv1beta1.MutatingWebhook{desired},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	// The apiserver sets defaults, only the fields we care about are compared
+	if len(current.Webhooks) == 1 &&
+		current.Webhooks[0].Name == desired.Name &&
+		bytes.Equal(current.Webhooks[0].ClientConfig.CABundle, desired.ClientConfig.CABundle) &&
+		reflect.DeepEqual(current.Webhooks[0].ObjectSelector, desired.ObjectSelector) {
+		return nil
+	}
+	log.Infof("Updating the admission controller webhook %s", c.webhookConfigName)
+	current.Webhooks = []admiv1beta1.MutatingWebhook{desired}
+	_, err = webhookConfigs.Update(current)
+	return err
+}
+
+func (c *controller) newWebhook(caBundle []byte) admiv1beta1.MutatingWebhook {
+	path := injectConfigPath
+	failurePolicy := admiv1beta1.Ignore
+	sideEffects := admiv1beta1.SideEffectClassNone
+
+	// Only send the pods that can be mutated to the webhook
+	selector := &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      enabledLabelKey,
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   []string{"false"},
+		}},
+	}
+	if !c.mutateUnlabelled {
+		selector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{enabledLabelKey: "true"},
+		}
+	}
+
+	return admiv1beta1.MutatingWebhook{
+		Name: webhookName,
+		ClientConfig: admiv1beta1.WebhookClientConfig{
+			Service: &admiv1beta1.ServiceReference{
+				Namespace: c.namespace,
+				Name:      c.serviceName,
+				Path:      &path,
+			},
+			CABundle: caBundle,
+		},
+		Rules: []admiv1beta1.RuleWithOperations{{
+			Operations: []admiv1beta1.OperationType{admiv1beta1.Create},
+			Rule: admiv1beta1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+			},
+		}},
+		FailurePolicy:  &failurePolicy,
+		SideEffects:    &sideEffects,
+		ObjectSelector: selector,
+	}
+}
@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package admission
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCertificate(t *testing.T) {
+	dnsNames := []string{"datadog-admission-controller", "datadog-admission-controller.default.svc"}
+	certPEM, keyPEM, err := generateCertificate(dnsNames, 24*time.Hour)
+	require.NoError(t, err)
+
+	_, err = tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	cert, err := parseCertificate(certPEM)
+	require.NoError(t, err)
+	assert.Equal(t, dnsNames, cert.DNSNames)
+	assert.Equal(t, 24*time.Hour, cert.NotAfter.Sub(cert.NotBefore))
+	assert.True(t, cert.IsCA)
+
+	// The certificate is its own CA bundle
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName: "datadog-admission-controller.default.svc",
+		Roots:   roots,
+	})
+	assert.NoError(t, err)
+}
+
+func TestParseCertificateInvalid(t *testing.T) {
+	_, err := parseCertificate([]byte("not a certificate"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,229 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// enabledLabelKey allows pods to opt in or out of the mutation
+	enabledLabelKey = "admission.datadoghq.com/enabled"
+
+	envLabelKey     = "tags.datadoghq.com/env"
+	serviceLabelKey = "tags.datadoghq.com/service"
+	versionLabelKey = "tags.datadoghq.com/version"
+
+	agentHostEnvVarName    = "DD_AGENT_HOST"
+	entityIDEnvVarName     = "DD_ENTITY_ID"
+	dogstatsdURLEnvVarName = "DD_DOGSTATSD_URL"
+
+	socketVolumeName = "datadog-dsd-socket"
+
+	// injectConfigModeHostIP injects the host IP of the node in DD_AGENT_HOST
+	injectConfigModeHostIP = "hostip"
+	// injectConfigModeSocket mounts the DogStatsD socket of the node
+	injectConfigModeSocket = "socket"
+)
+
+// standardTagLabels maps the standard tag labels to their environment variable
+var standardTagLabels = map[string]string{
+	envLabelKey:     "DD_ENV",
+	serviceLabelKey: "DD_SERVICE",
+	versionLabelKey: "DD_VERSION",
+}
+
+// mutationConfig holds what is injected in the pods
+type mutationConfig struct {
+	mutateUnlabelled bool
+	injectConfig     bool
+	injectTags       bool
+	configMode       string
+	socketPath       string
+}
+
+// patchOperation is a JSON patch operation, see https://tools.ietf.org/html/rfc6902
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// shouldMutate returns whether the pod opted in, or did not opt out if unlabelled pods are mutated
+func (m *mutationConfig) shouldMutate(pod *corev1.Pod) bool {
+	switch pod.Labels[enabledLabelKey] {
+	case "true":
+		return true
+	case "false":
+		return false
+	default:
+		return m.mutateUnlabelled
+	}
+}
+
+// mutatePod returns the JSON patch injecting the configuration in the pod, nil if there is nothing to change
+func (m *mutationConfig) mutatePod(raw []byte) ([]byte, error) {
+	var pod corev1.Pod
+	if err := json.Unmarshal(raw, &pod); err != nil {
+		return nil, err
+	}
+	if !m.shouldMutate(&pod) {
+		return nil, nil
+	}
+
+	original := pod.DeepCopy()
+	if m.injectTags {
+		injectTags(&pod)
+	}
+	if m.injectConfig {
+		m.injectAgentConfig(&pod)
+	}
+	return createPatch(original, &pod)
+}
+
+// injectTags injects the standard tags from the pod labels
+func injectTags(pod *corev1.Pod) {
+	for label, envVarName := range standardTagLabels {
+		value, found := pod.Labels[label]
+		if !found {
+			continue
+		}
+		injectEnv(pod, corev1.EnvVar{Name: envVarName, Value: value})
+	}
+}
+
+// injectAgentConfig injects the address of the node agent and the entity ID.
+// The host IP is injected in both modes, the socket only carries DogStatsD.
+func (m *mutationConfig) injectAgentConfig(pod *corev1.Pod) {
+	if m.configMode == injectConfigModeSocket {
+		injectSocketVolume(pod, filepath.Dir(m.socketPath))
+		injectEnv(pod, corev1.EnvVar{Name: dogstatsdURLEnvVarName, Value: "unix://" + m.socketPath})
+	}
+	injectEnv(pod, corev1.EnvVar{
+		Name: agentHostEnvVarName,
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.hostIP"},
+		},
+	})
+	injectEnv(pod, corev1.EnvVar{
+		Name: entityIDEnvVarName,
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.uid"},
+		},
+	})
+}
+
+// podContainers returns the init containers and the containers of the pod
+func podContainers(pod *corev1.Pod) []*corev1.Container {
+	containers := make([]*corev1.Container, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	for i := range pod.Spec.InitContainers {
+		containers = append(containers, &pod.Spec.InitContainers[i])
+	}
+	for i := range pod.Spec.Containers {
+		containers = append(containers, &pod.Spec.Containers[i])
+	}
+	return containers
+}
+
+// injectEnv adds an environment variable to the containers that do not define it yet
+func injectEnv(pod *corev1.Pod, env corev1.EnvVar) {
+	for _, container := range podContainers(pod) {
+		if containerHasEnv(container, env.Name) {
+			continue
+		}
+		container.Env = append(container.Env, env)
+	}
+}
+
+func containerHasEnv(container *corev1.Container, name string) bool {
+	for _, env := range container.Env {
+		if env.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// injectSocketVolume mounts the directory of the DogStatsD socket of the node in the containers
+func injectSocketVolume(pod *corev1.Pod, socketDir string) {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == socketVolumeName {
+			return
+		}
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: socketVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{Path: socketDir},
+		},
+	})
+	for _, container := range podContainers(pod) {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      socketVolumeName,
+			MountPath: socketDir,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// createPatch returns the JSON patch adding the environment variables, volume
+// mounts and volumes injected in the pod. Injection only appends to these lists,
+// the patch adds each new item without replacing the lists, so that the fields
+// unknown to the vendored Kubernetes types are left as they are.
+func createPatch(original, mutated *corev1.Pod) ([]byte, error) {
+	var ops []patchOperation
+	for i := range mutated.Spec.InitContainers {
+		ops = addContainerItems(ops, fmt.Sprintf("/spec/initContainers/%d", i), &original.Spec.InitContainers[i], &mutated.Spec.InitContainers[i])
+	}
+	for i := range mutated.Spec.Containers {
+		ops = addContainerItems(ops, fmt.Sprintf("/spec/containers/%d", i), &original.Spec.Containers[i], &mutated.Spec.Containers[i])
+	}
+	var volumes []interface{}
+	for _, volume := range mutated.Spec.Volumes[len(original.Spec.Volumes):] {
+		volumes = append(volumes, volume)
+	}
+	ops = addItems(ops, "/spec/volumes", len(original.Spec.Volumes), volumes)
+
+	if len(ops) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(ops)
+}
+
+// addContainerItems appends the operations adding the environment variables and
+// volume mounts injected in the container at path
+func addContainerItems(ops []patchOperation, path string, original, mutated *corev1.Container) []patchOperation {
+	var env []interface{}
+	for _, e := range mutated.Env[len(original.Env):] {
+		env = append(env, e)
+	}
+	ops = addItems(ops, path+"/env", len(original.Env), env)
+
+	var mounts []interface{}
+	for _, mount := range mutated.VolumeMounts[len(original.VolumeMounts):] {
+		mounts = append(mounts, mount)
+	}
+	return addItems(ops, path+"/volumeMounts", len(original.VolumeMounts), mounts)
+}
+
+// addItems appends the operations adding the items at the end of the list at
+// path, which is created by the first operation if it was empty
+func addItems(ops []patchOperation, path string, originalLen int, items []interface{}) []patchOperation {
+	for i, item := range items {
+		if originalLen == 0 && i == 0 {
+			ops = append(ops, patchOperation{Op: "add", Path: path, Value: []interface{}{item}})
+			continue
+		}
+		ops = append(ops, patchOperation{Op: "add", Path: path + "/-", Value: item})
+	}
+	return ops
+}
@@ -0,0 +1,233 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package admission
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestPod(labels map[string]string, env ...corev1.EnvVar) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "foo",
+			Labels: labels,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", Env: env},
+				{Name: "sidecar"},
+			},
+		},
+	}
+}
+
+func envByName(container corev1.Container) map[string]corev1.EnvVar {
+	env := make(map[string]corev1.EnvVar)
+	for _, e := range container.Env {
+		env[e.Name] = e
+	}
+	return env
+}
+
+func TestShouldMutate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		mutateUnlabelled bool
+		labels           map[string]string
+		expected         bool
+	}{
+		"unlabelled, opt-in":              {false, nil, false},
+		"unlabelled, opt-out":             {true, nil, true},
+		"enabled, opt-in":                 {false, map[string]string{enabledLabelKey: "true"}, true},
+		"disabled, opt-out":               {true, map[string]string{enabledLabelKey: "false"}, false},
+		"invalid label value, opt-in":     {false, map[string]string{enabledLabelKey: "yes"}, false},
+		"invalid label value, opt-out":    {true, map[string]string{enabledLabelKey: "no"}, true},
+		"unrelated label, opt-in":         {false, map[string]string{"app": "foo"}, false},
+		"enabled, mutate unlabelled also": {true, map[string]string{enabledLabelKey: "true"}, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := &mutationConfig{mutateUnlabelled: tc.mutateUnlabelled}
+			assert.Equal(t, tc.expected, m.shouldMutate(newTestPod(tc.labels)))
+		})
+	}
+}
+
+func TestInjectTags(t *testing.T) {
+	pod := newTestPod(map[string]string{
+		envLabelKey:     "prod",
+		serviceLabelKey: "web",
+	}, corev1.EnvVar{Name: "DD_SERVICE", Value: "custom"})
+
+	injectTags(pod)
+
+	app := envByName(pod.Spec.Containers[0])
+	assert.Equal(t, "prod", app["DD_ENV"].Value)
+	assert.Equal(t, "custom", app["DD_SERVICE"].Value)
+	assert.NotContains(t, app, "DD_VERSION")
+	assert.Len(t, pod.Spec.Containers[0].Env, 2)
+
+	sidecar := envByName(pod.Spec.Containers[1])
+	assert.Equal(t, "prod", sidecar["DD_ENV"].Value)
+	assert.Equal(t, "web", sidecar["DD_SERVICE"].Value)
+	assert.NotContains(t, sidecar, "DD_VERSION")
+}
+
+func TestInjectAgentConfigHostIP(t *testing.T) {
+	m := &mutationConfig{configMode: injectConfigModeHostIP}
+	pod := newTestPod(nil, corev1.EnvVar{Name: agentHostEnvVarName, Value: "10.0.0.1"})
+	pod.Spec.InitContainers = []corev1.Container{{Name: "init"}}
+
+	m.injectAgentConfig(pod)
+
+	app := envByName(pod.Spec.Containers[0])
+	assert.Equal(t, "10.0.0.1", app[agentHostEnvVarName].Value)
+	assert.Nil(t, app[agentHostEnvVarName].ValueFrom)
+	require.NotNil(t, app[entityIDEnvVarName].ValueFrom)
+	assert.Equal(t, "metadata.uid", app[entityIDEnvVarName].ValueFrom.FieldRef.FieldPath)
+
+	sidecar := envByName(pod.Spec.Containers[1])
+	require.NotNil(t, sidecar[agentHostEnvVarName].ValueFrom)
+	assert.Equal(t, "status.hostIP", sidecar[agentHostEnvVarName].ValueFrom.FieldRef.FieldPath)
+	assert.NotContains(t, sidecar, dogstatsdURLEnvVarName)
+	assert.Empty(t, pod.Spec.Volumes)
+
+	init := envByName(pod.Spec.InitContainers[0])
+	require.NotNil(t, init[agentHostEnvVarName].ValueFrom)
+	assert.Equal(t, "status.hostIP", init[agentHostEnvVarName].ValueFrom.FieldRef.FieldPath)
+	assert.Contains(t, init, entityIDEnvVarName)
+}
+
+func TestInjectAgentConfigSocket(t *testing.T) {
+	m := &mutationConfig{configMode: injectConfigModeSocket, socketPath: "/var/run/datadog/dsd.socket"}
+	pod := newTestPod(nil)
+	pod.Spec.InitContainers = []corev1.Container{{Name: "init"}}
+
+	m.injectAgentConfig(pod)
+	// Injecting twice must not duplicate the volume
+	m.injectAgentConfig(pod)
+
+	require.Len(t, pod.Spec.Volumes, 1)
+	assert.Equal(t, socketVolumeName, pod.Spec.Volumes[0].Name)
+	require.NotNil(t, pod.Spec.Volumes[0].HostPath)
+	assert.Equal(t, "/var/run/datadog", pod.Spec.Volumes[0].HostPath.Path)
+
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		require.Len(t, container.VolumeMounts, 1)
+		assert.Equal(t, "/var/run/datadog", container.VolumeMounts[0].MountPath)
+		assert.True(t, container.VolumeMounts[0].ReadOnly)
+
+		env := envByName(container)
+		assert.Equal(t, "unix:///var/run/datadog/dsd.socket", env[dogstatsdURLEnvVarName].Value)
+		// The host IP is still needed by the tracers
+		require.NotNil(t, env[agentHostEnvVarName].ValueFrom)
+		assert.Equal(t, "status.hostIP", env[agentHostEnvVarName].ValueFrom.FieldRef.FieldPath)
+		assert.Contains(t, env, entityIDEnvVarName)
+		assert.Len(t, container.Env, 3)
+	}
+}
+
+func TestMutatePod(t *testing.T) {
+	m := &mutationConfig{
+		mutateUnlabelled: true,
+		injectConfig:     true,
+		injectTags:       true,
+		configMode:       injectConfigModeSocket,
+		socketPath:       "/var/run/datadog/dsd.socket",
+	}
+
+	pod := newTestPod(map[string]string{versionLabelKey: "1.2.3"}, corev1.EnvVar{Name: "FOO", Value: "bar"})
+	pod.Spec.InitContainers = []corev1.Container{{Name: "init"}}
+	raw, err := json.Marshal(pod)
+	require.NoError(t, err)
+	patch, err := m.mutatePod(raw)
+	require.NoError(t, err)
+
+	var ops []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal(patch, &ops))
+	paths := make([]string, 0, len(ops))
+	for _, op := range ops {
+		// The lists are never replaced, so that the fields unknown to the
+		// vendored Kubernetes types are kept
+		assert.Equal(t, "add", op.Op)
+		paths = append(paths, op.Path)
+	}
+	assert.Equal(t, []string{
+		// The first item creates the lists missing from the pod
+		"/spec/initContainers/0/env",
+		"/spec/initContainers/0/env/-",
+		"/spec/initContainers/0/env/-",
+		"/spec/initContainers/0/env/-",
+		"/spec/initContainers/0/volumeMounts",
+		// The items are appended to the existing lists
+		"/spec/containers/0/env/-",
+		"/spec/containers/0/env/-",
+		"/spec/containers/0/env/-",
+		"/spec/containers/0/env/-",
+		"/spec/containers/0/volumeMounts",
+		"/spec/containers/1/env",
+		"/spec/containers/1/env/-",
+		"/spec/containers/1/env/-",
+		"/spec/containers/1/env/-",
+		"/spec/containers/1/volumeMounts",
+		"/spec/volumes",
+	}, paths)
+
+	var created []corev1.EnvVar
+	require.NoError(t, json.Unmarshal(ops[0].Value, &created))
+	assert.Equal(t, []corev1.EnvVar{{Name: "DD_VERSION", Value: "1.2.3"}}, created)
+
+	var appended corev1.EnvVar
+	require.NoError(t, json.Unmarshal(ops[5].Value, &appended))
+	assert.Equal(t, corev1.EnvVar{Name: "DD_VERSION", Value: "1.2.3"}, appended)
+
+	var mounts []corev1.VolumeMount
+	require.NoError(t, json.Unmarshal(ops[4].Value, &mounts))
+	require.Len(t, mounts, 1)
+	assert.Equal(t, socketVolumeName, mounts[0].Name)
+
+	var volumes []corev1.Volume
+	require.NoError(t, json.Unmarshal(ops[15].Value, &volumes))
+	require.Len(t, volumes, 1)
+	assert.Equal(t, socketVolumeName, volumes[0].Name)
+}
+
+func TestMutatePodNoop(t *testing.T) {
+	m := &mutationConfig{injectConfig: true, injectTags: true, configMode: injectConfigModeHostIP}
+
+	// The pod did not opt in
+	raw, err := json.Marshal(newTestPod(nil))
+	require.NoError(t, err)
+	patch, err := m.mutatePod(raw)
+	require.NoError(t, err)
+	assert.Nil(t, patch)
+
+	// Everything is already defined by the pod
+	pod := newTestPod(
+		map[string]string{enabledLabelKey: "true"},
+		corev1.EnvVar{Name: agentHostEnvVarName, Value: "10.0.0.1"},
+		corev1.EnvVar{Name: entityIDEnvVarName, Value: "foo"},
+	)
+	pod.Spec.Containers = pod.Spec.Containers[:1]
+	raw, err = json.Marshal(pod)
+	require.NoError(t, err)
+	patch, err = m.mutatePod(raw)
+	require.NoError(t, err)
+	assert.Nil(t, patch)
+
+	_, err = m.mutatePod([]byte("not a pod"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package admission
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	stdLog "log"
+	"net/http"
+	"sync"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	injectConfigPath = "/injectconfig"
+	maxRequestSize   = 10 * 1024 * 1024
+)
+
+// server serves the mutating webhook, its certificate is loaded from the
+// secret maintained by the leader.
+type server struct {
+	mutation *mutationConfig
+
+	m    sync.RWMutex
+	cert *tls.Certificate
+}
+
+func newServer(mutation *mutationConfig) *server {
+	return &server{mutation: mutation}
+}
+
+// setCertificate updates the certificate served
+func (s *server) setCertificate(certPEM, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.cert = &cert
+	return nil
+}
+
+func (s *server) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	if s.cert == nil {
+		return nil, errors.New("the webhook certificate is not available yet")
+	}
+	return s.cert, nil
+}
+
+// run serves the webhook until the stop channel is closed
+func (s *server) run(port int, stopCh <-chan struct{}) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(injectConfigPath, s.handleInjectConfig)
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: mux,
+		ErrorLog: stdLog.New(&config.ErrorLogWriter{
+			AdditionalDepth: 4, // Use a stack depth of 4 on top of the default one to get a relevant filename in the stdlib
+		}, "Error from the admission controller http server: ", 0), // log errors to seelog,
+		TLSConfig: &tls.Config{GetCertificate: s.getCertificate},
+	}
+	go func() {
+		<-stopCh
+		srv.Close()
+	}()
+
+	log.Infof("Starting the admission controller webhook server on port %d", port)
+	if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+		log.Errorf("Admission controller webhook server stopped: %v", err)
+	}
+}
+
+// handleInjectConfig mutates the pods, the pod creation is always allowed
+func (s *server) handleInjectConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, fmt.Sprintf("invalid method %s, only POST requests are allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not read the request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	review := admissionv1beta1.AdmissionReview{}
+	if err = json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, fmt.Sprintf("could not decode the admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	response := &admissionv1beta1.AdmissionResponse{
+		UID:     review.Request.UID,
+		Allowed: true,
+	}
+	patch, err := s.mutation.mutatePod(review.Request.Object.Raw)
+	if err != nil {
+		log.Warnf("Could not mutate the pod %s/%s: %v", review.Request.Namespace, review.Request.Name, err)
+		response.Result = &metav1.Status{Message: err.Error()}
+	} else if patch != nil {
+		patchType := admissionv1beta1.PatchTypeJSONPatch
+		response.Patch = patch
+		response.PatchType = &patchType
+	}
+
+	review.Response = response
+	review.Request = nil
+	resp, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not encode the admission review: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
@@ -0,0 +1,26 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package v1
+
+// SeriesPoint is the latest point of a series aggregated by a node agent
+type SeriesPoint struct {
+	Name      string   `json:"name"`
+	Tags      []string `json:"tags,omitempty"`
+	Timestamp int64    `json:"timestamp"`
+	Value     float64  `json:"value"`
+}
+
+// SeriesPayload is posted by the node agents on /api/v1/autoscalers/series/{nodeName}
+// after each flush, it holds the points of the series requested by the cluster agent.
+type SeriesPayload struct {
+	Series []SeriesPoint `json:"series"`
+}
+
+// SeriesResponse is the response of the cluster agent to a SeriesPayload, it lists
+// the names of the metrics to send on the next flush.
+type SeriesResponse struct {
+	Metrics []string `json:"metrics"`
+}
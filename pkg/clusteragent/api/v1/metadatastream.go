@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package v1
+
+// MetadataEventType is the type of an event of the metadata stream
+type MetadataEventType string
+
+const (
+	// MetadataEventSet creates or replaces an object
+	MetadataEventSet MetadataEventType = "set"
+	// MetadataEventDelete deletes an object
+	MetadataEventDelete MetadataEventType = "delete"
+	// MetadataEventReset tells the subscriber to drop its state, it is followed by a snapshot
+	MetadataEventReset MetadataEventType = "reset"
+	// MetadataEventSynced tells the subscriber the snapshot following a reset is complete
+	MetadataEventSynced MetadataEventType = "synced"
+	// MetadataEventHeartbeat keeps the connection alive and carries the latest token
+	MetadataEventHeartbeat MetadataEventType = "heartbeat"
+)
+
+// MetadataKind is the kind of object an event of the metadata stream refers to
+type MetadataKind string
+
+const (
+	// MetadataKindNode carries the labels of a node
+	MetadataKindNode MetadataKind = "node"
+	// MetadataKindNamespace carries the labels of a namespace
+	MetadataKindNamespace MetadataKind = "namespace"
+	// MetadataKindPod carries the services targeting a pod and its owners
+	MetadataKindPod MetadataKind = "pod"
+)
+
+// OwnerReference identifies the owner of a pod
+type OwnerReference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// MetadataEvent is an event of the metadata stream served to the node agents
+// on /api/v1/tags/stream/{nodeName}, encoded as one JSON object per line.
+//
+// The token of the last event received can be sent back by the node agent
+// when reconnecting to resume the stream where it stopped. If the stream can't
+// be resumed, the cluster agent sends a reset event followed by a snapshot.
+type MetadataEvent struct {
+	Token     string            `json:"token,omitempty"`
+	Type      MetadataEventType `json:"type"`
+	Kind      MetadataKind      `json:"kind,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Services  []string          `json:"services,omitempty"`
+	Owners    []OwnerReference  `json:"owners,omitempty"`
+}
@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build clusterchecks
+// +build kubeapiserver
+
+package clusterchecks
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/common"
+)
+
+const checkpointConfigMapKey = "state"
+
+// configMapCheckpointStore persists the checkpoints in a ConfigMap
+type configMapCheckpointStore struct {
+	namespace string
+	name      string
+	client    corev1.CoreV1Interface
+}
+
+func newCheckpointStore() (checkpointStore, error) {
+	cl, err := apiserver.GetAPIClient()
+	if err != nil {
+		return nil, err
+	}
+	return &configMapCheckpointStore{
+		namespace: common.GetResourcesNamespace(),
+		name:      config.Datadog.GetString("cluster_checks.checkpoint_configmap_name"),
+		client:    cl.Cl.CoreV1(),
+	}, nil
+}
+
+// load returns the checkpoint stored in the ConfigMap, or nil if there is none
+func (s *configMapCheckpointStore) load() (*checkpoint, error) {
+	cm, err := s.client.ConfigMaps(s.namespace).Get(s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, found := cm.Data[checkpointConfigMapKey]
+	if !found {
+		return nil, nil
+	}
+	cp := &checkpoint{}
+	if err := json.Unmarshal([]byte(data), cp); err != nil {
+		return nil, fmt.Errorf("invalid content in the ConfigMap %s/%s: %s", s.namespace, s.name, err)
+	}
+	return cp, nil
+}
+
+// save writes the checkpoint in the ConfigMap, creating it if needed
+func (s *configMapCheckpointStore) save(cp *checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	cm, err := s.client.ConfigMaps(s.namespace).Get(s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = s.client.ConfigMaps(s.namespace).Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+			},
+			Data: map[string]string{checkpointConfigMapKey: string(data)},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[checkpointConfigMapKey] = string(data)
+	_, err = s.client.ConfigMaps(s.namespace).Update(cm)
+	return err
+}
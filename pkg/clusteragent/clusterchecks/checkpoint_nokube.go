@@ -0,0 +1,17 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build clusterchecks
+// +build !kubeapiserver
+
+package clusterchecks
+
+import (
+	"errors"
+)
+
+func newCheckpointStore() (checkpointStore, error) {
+	return nil, errors.New("No checkpoint store compiled in")
+}
@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// checkpoint is the dispatching state persisted by the leader, for a new
+// leader to adopt the existing assignment without waiting for a warmup
+type checkpoint struct {
+	Timestamp int64                     `json:"timestamp"`
+	Nodes     map[string]checkpointNode `json:"nodes"`
+}
+
+// checkpointNode holds the configurations dispatched to a node
+type checkpointNode struct {
+	ClientIP string   `json:"client_ip,omitempty"`
+	Digests  []string `json:"digests"`
+}
+
+// checkpointStore persists the checkpoints, see checkpoint_kube.go
+type checkpointStore interface {
+	load() (*checkpoint, error)
+	save(*checkpoint) error
+}
+
+// buildCheckpoint returns the current dispatching state
+func (d *dispatcher) buildCheckpoint() *checkpoint {
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	cp := &checkpoint{
+		Timestamp: timestampNow(),
+		Nodes:     make(map[string]checkpointNode),
+	}
+	for name, node := range d.store.nodes {
+		if name == "" {
+			continue
+		}
+		node.RLock()
+		n := checkpointNode{
+			ClientIP: node.clientIP,
+			Digests:  make([]string, 0, len(node.digestToConfig)),
+		}
+		for digest := range node.digestToConfig {
+			n.Digests = append(n.Digests, digest)
+		}
+		node.RUnlock()
+		sort.Strings(n.Digests)
+		cp.Nodes[name] = n
+	}
+	return cp
+}
+
+// saveCheckpoint persists the dispatching state if it changed since the
+// last checkpoint, or if the last checkpoint is about to be too old to be
+// restored.
+func (d *dispatcher) saveCheckpoint() {
+	if d.checkpoints == nil {
+		return
+	}
+
+	cp := d.buildCheckpoint()
+	if d.lastCheckpoint != nil &&
+		reflect.DeepEqual(cp.Nodes, d.lastCheckpoint.Nodes) &&
+		cp.Timestamp-d.lastCheckpoint.Timestamp < d.checkpointMaxAgeSeconds/2 {
+		return
+	}
+
+	if err := d.checkpoints.save(cp); err != nil {
+		log.Warnf("Cannot save the cluster checks dispatching state: %v", err)
+		return
+	}
+	log.Debugf("Saved the cluster checks dispatching state for %d nodes", len(cp.Nodes))
+	d.lastCheckpoint = cp
+}
+
+// restoreCheckpoint loads the last checkpoint and, if it is recent enough,
+// registers its nodes and assignment. Configurations scheduled afterwards are
+// dispatched to the node they were running on. It returns whether a
+// checkpoint was restored.
+func (d *dispatcher) restoreCheckpoint() bool {
+	if d.checkpoints == nil {
+		return false
+	}
+
+	cp, err := d.checkpoints.load()
+	if err != nil {
+		log.Warnf("Cannot load the cluster checks dispatching state: %v", err)
+		return false
+	}
+	if cp == nil {
+		log.Debug("No cluster checks dispatching state to restore")
+		return false
+	}
+	if age := timestampNow() - cp.Timestamp; age > d.checkpointMaxAgeSeconds {
+		log.Infof("Ignoring the cluster checks dispatching state saved %d seconds ago", age)
+		return false
+	}
+
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	now := timestampNow()
+	for name, n := range cp.Nodes {
+		node := d.store.getOrCreateNodeStore(name, n.ClientIP)
+		node.Lock()
+		// Give node-agents the usual expiration timeout to report
+		node.heartbeat = now
+		node.Unlock()
+		for _, digest := range n.Digests {
+			d.store.restoredDigestToNode[digest] = name
+		}
+	}
+	d.lastCheckpoint = cp
+	log.Infof("Restored the cluster checks dispatching state of %d nodes", len(cp.Nodes))
+	return true
+}
+
+// getRestoredNode returns the node a configuration was dispatched to
+// according to the restored checkpoint, if it still exists and matches its
+// placement. The restored assignment is only used once per configuration.
+func (d *dispatcher) getRestoredNode(digest string, filter placementFilter) string {
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	name, found := d.store.restoredDigestToNode[digest]
+	if !found {
+		return ""
+	}
+	delete(d.store.restoredDigestToNode, digest)
+
+	node, found := d.store.getNodeStore(name)
+	if !found {
+		return ""
+	}
+	node.RLock()
+	defer node.RUnlock()
+	if !filter.allows(node) {
+		return ""
+	}
+	return name
+}
+
+// pruneRestoredAssignment forgets the restored assignment of the configurations
+// that were not scheduled again, once the configurations had time to be
+// replayed. Their digests would otherwise be kept until the next leader change.
+func (d *dispatcher) pruneRestoredAssignment() {
+	d.store.Lock()
+	defer d.store.Unlock()
+
+	if len(d.store.restoredDigestToNode) == 0 {
+		return
+	}
+	log.Debugf("Forgetting the restored assignment of %d configurations that were not scheduled again", len(d.store.restoredDigestToNode))
+	d.store.restoredDigestToNode = make(map[string]string)
+}
@@ -0,0 +1,170 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+type memoryCheckpointStore struct {
+	cp    *checkpoint
+	saves int
+}
+
+func (s *memoryCheckpointStore) load() (*checkpoint, error) {
+	return s.cp, nil
+}
+
+func (s *memoryCheckpointStore) save(cp *checkpoint) error {
+	s.cp = cp
+	s.saves++
+	return nil
+}
+
+func newCheckpointingDispatcher(store checkpointStore) *dispatcher {
+	d := newDispatcher()
+	d.checkpoints = store
+	d.checkpointMaxAgeSeconds = 300
+	return d
+}
+
+func TestCheckpointRestore(t *testing.T) {
+	store := &memoryCheckpointStore{}
+	var configs []integration.Config
+	for i := 0; i < 6; i++ {
+		configs = append(configs, generateIntegration(fmt.Sprintf("check%d", i)))
+	}
+
+	// Previous leader
+	previous := newCheckpointingDispatcher(store)
+	previous.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	previous.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+	previous.processNodeStatus("nodeC", "10.0.0.3", types.NodeStatus{})
+	previous.Schedule(configs)
+	previous.saveCheckpoint()
+	require.NotNil(t, store.cp)
+	assert.Len(t, store.cp.Nodes, 3)
+	assert.Equal(t, "10.0.0.2", store.cp.Nodes["nodeB"].ClientIP)
+
+	// Unchanged state is not saved again
+	previous.saveCheckpoint()
+	assert.Equal(t, 1, store.saves)
+
+	// New leader
+	current := newCheckpointingDispatcher(store)
+	assert.True(t, current.restoreCheckpoint())
+	current.Schedule(configs)
+
+	for _, node := range []string{"nodeA", "nodeB", "nodeC"} {
+		previousConfigs, _, err := previous.getNodeConfigs(node)
+		assert.NoError(t, err)
+		currentConfigs, _, err := current.getNodeConfigs(node)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, extractCheckNames(previousConfigs), extractCheckNames(currentConfigs))
+	}
+
+	// Restored assignments are only used once
+	current.store.RLock()
+	assert.Len(t, current.store.restoredDigestToNode, 0)
+	current.store.RUnlock()
+
+	requireNotLocked(t, previous.store)
+	requireNotLocked(t, current.store)
+}
+
+func TestCheckpointRestoreStale(t *testing.T) {
+	store := &memoryCheckpointStore{
+		cp: &checkpoint{
+			Timestamp: timestampNow() - 600,
+			Nodes: map[string]checkpointNode{
+				"nodeA": {ClientIP: "10.0.0.1", Digests: []string{"abcdef"}},
+			},
+		},
+	}
+
+	d := newCheckpointingDispatcher(store)
+	assert.False(t, d.restoreCheckpoint())
+	_, found := d.store.getNodeStore("nodeA")
+	assert.False(t, found)
+
+	// No checkpoint
+	d = newCheckpointingDispatcher(&memoryCheckpointStore{})
+	assert.False(t, d.restoreCheckpoint())
+
+	// Checkpoints disabled
+	d = newDispatcher()
+	assert.False(t, d.restoreCheckpoint())
+}
+
+func TestCheckpointRestorePlacement(t *testing.T) {
+	config := generatePlacedIntegration("http_check", "url: a", &integration.Placement{NodeSelector: "subnet=private"})
+	store := &memoryCheckpointStore{
+		cp: &checkpoint{
+			Timestamp: timestampNow(),
+			Nodes: map[string]checkpointNode{
+				"nodeA": {Digests: []string{config.Digest()}},
+			},
+		},
+	}
+
+	d := newCheckpointingDispatcher(store)
+	assert.True(t, d.restoreCheckpoint())
+	d.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{Labels: map[string]string{"subnet": "private"}})
+
+	// nodeA doesn't report its labels yet, the placement is honored
+	d.add(config)
+	configs, _, err := d.getNodeConfigs("nodeB")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http_check"}, extractCheckNames(configs))
+
+	requireNotLocked(t, d.store)
+}
+
+func TestCheckpointPruneRestoredAssignment(t *testing.T) {
+	config := generateIntegration("http_check")
+	store := &memoryCheckpointStore{
+		cp: &checkpoint{
+			Timestamp: timestampNow(),
+			Nodes: map[string]checkpointNode{
+				"nodeA": {Digests: []string{config.Digest(), "deleted"}},
+			},
+		},
+	}
+
+	d := newCheckpointingDispatcher(store)
+	assert.True(t, d.restoreCheckpoint())
+	d.add(config)
+
+	// The configuration deleted while there was no leader is forgotten
+	d.store.RLock()
+	assert.Equal(t, map[string]string{"deleted": "nodeA"}, d.store.restoredDigestToNode)
+	d.store.RUnlock()
+	d.pruneRestoredAssignment()
+	d.store.RLock()
+	assert.Len(t, d.store.restoredDigestToNode, 0)
+	d.store.RUnlock()
+
+	requireNotLocked(t, d.store)
+}
+
+func TestCheckpointInvalidInterval(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("cluster_checks.checkpoint_enabled", true)
+	mockConfig.Set("cluster_checks.checkpoint_interval", 0)
+
+	d := newDispatcher()
+	assert.Equal(t, defaultCheckpointInterval, d.checkpointInterval)
+}
@@ -24,13 +24,19 @@ const firstRunnerStatsMinutes = 2  // collect runner stats after the first 2 min
 const secondRunnerStatsMinutes = 5 // collect runner stats after the first 7 minutes
 const finalRunnerStatsMinutes = 10 // collect runner stats endlessly every 10 minutes
 
+const defaultCheckpointInterval = 10 * time.Second // used when cluster_checks.checkpoint_interval is not positive
+
 // dispatcher holds the management logic for cluster-checks
 type dispatcher struct {
-	store                 *clusterStore
-	nodeExpirationSeconds int64
-	extraTags             []string
-	clcRunnersClient      clusteragent.CLCRunnerClientInterface
-	advancedDispatching   bool
+	store                   *clusterStore
+	nodeExpirationSeconds   int64
+	extraTags               []string
+	clcRunnersClient        clusteragent.CLCRunnerClientInterface
+	advancedDispatching     bool
+	checkpoints             checkpointStore
+	checkpointInterval      time.Duration
+	checkpointMaxAgeSeconds int64
+	lastCheckpoint          *checkpoint
 }
 
 func newDispatcher() *dispatcher {
@@ -46,6 +52,20 @@ func newDispatcher() *dispatcher {
 		d.extraTags = append(d.extraTags, fmt.Sprintf("%s:%s", clusterTagName, clusterTagValue))
 	}
 
+	if config.Datadog.GetBool("cluster_checks.checkpoint_enabled") {
+		var err error
+		d.checkpoints, err = newCheckpointStore()
+		if err != nil {
+			log.Warnf("Cannot create the checkpoint store, the dispatching state will not be persisted: %v", err)
+		}
+		d.checkpointInterval = config.Datadog.GetDuration("cluster_checks.checkpoint_interval") * time.Second
+		if d.checkpointInterval <= 0 {
+			log.Warnf("Invalid cluster_checks.checkpoint_interval %v, using %s instead", config.Datadog.Get("cluster_checks.checkpoint_interval"), defaultCheckpointInterval)
+			d.checkpointInterval = defaultCheckpointInterval
+		}
+		d.checkpointMaxAgeSeconds = config.Datadog.GetInt64("cluster_checks.checkpoint_max_age")
+	}
+
 	d.advancedDispatching = config.Datadog.GetBool("cluster_checks.advanced_dispatching_enabled")
 	if !d.advancedDispatching {
 		return d
@@ -81,6 +101,10 @@ func (d *dispatcher) Schedule(configs []integration.Config) {
 			d.addEndpointConfig(patched, c.NodeName)
 			continue
 		}
+		if err := validatePlacement(c.Placement); err != nil {
+			log.Warnf("Invalid placement for configuration %s: %s", c.Digest(), err)
+			continue
+		}
 		patched, err := d.patchConfiguration(c)
 		if err != nil {
 			log.Warnf("Cannot patch configuration %s: %s", c.Digest(), err)
@@ -124,10 +148,13 @@ func (d *dispatcher) reschedule(configs []integration.Config) {
 
 // add stores and delegates a given configuration
 func (d *dispatcher) add(config integration.Config) {
-	target := d.getLeastBusyNode()
+	target := d.getRestoredNode(config.Digest(), newPlacementFilter(config.Placement))
+	if target == "" {
+		target = d.getNodeForConfig(config)
+	}
 	if target == "" {
 		// If no node is found, store it in the danglingConfigs map for retrying later.
-		log.Warnf("No available node to dispatch %s:%s on, will retry later", config.Name, config.Digest())
+		log.Warnf("No available node matching the placement of %s:%s to dispatch it on, will retry later", config.Name, config.Digest())
 	} else {
 		log.Infof("Dispatching configuration %s:%s to node %s", config.Name, config.Digest(), target)
 	}
@@ -147,6 +174,7 @@ func (d *dispatcher) reset() {
 	d.store.Lock()
 	defer d.store.Unlock()
 	d.store.reset()
+	d.lastCheckpoint = nil
 }
 
 // run is the main management goroutine for the dispatcher
@@ -161,6 +189,14 @@ func (d *dispatcher) run(ctx context.Context) {
 	cleanupTicker := time.NewTicker(time.Duration(d.nodeExpirationSeconds/2) * time.Second)
 	defer cleanupTicker.Stop()
 
+	// A nil channel blocks forever when checkpoints are disabled
+	var checkpointTick <-chan time.Time
+	if d.checkpoints != nil {
+		checkpointTicker := time.NewTicker(d.checkpointInterval)
+		defer checkpointTicker.Stop()
+		checkpointTick = checkpointTicker.C
+	}
+
 	runnerStatsMinutes := firstRunnerStatsMinutes
 	runnerStatsTicker := time.NewTicker(time.Duration(runnerStatsMinutes) * time.Minute)
 	defer runnerStatsTicker.Stop()
@@ -175,11 +211,16 @@ func (d *dispatcher) run(ctx context.Context) {
 			// Expire old nodes, orphaned configs are moved to dangling
 			d.expireNodes()
 
+			// Configurations were replayed since the checkpoint was restored
+			d.pruneRestoredAssignment()
+
 			// Re-dispatch dangling configs
 			if d.shouldDispatchDanling() {
 				danglingConfs := d.retrieveAndClearDangling()
 				d.reschedule(danglingConfs)
 			}
+		case <-checkpointTick:
+			d.saveCheckpoint()
 		case <-runnerStatsTicker.C:
 			// Collect stats with an exponential backoff 2 - 5 - 10 minutes
 			if runnerStatsMinutes == firstRunnerStatsMinutes {
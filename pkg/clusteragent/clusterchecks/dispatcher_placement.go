@@ -0,0 +1,244 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+)
+
+// zoneLabels are the node labels holding the zone of a node, by order of preference
+var zoneLabels = []string{
+	"topology.kubernetes.io/zone",
+	"failure-domain.beta.kubernetes.io/zone",
+}
+
+// validatePlacement checks the placement hints of a configuration
+func validatePlacement(placement *integration.Placement) error {
+	if placement == nil {
+		return nil
+	}
+	if _, err := labels.Parse(placement.NodeSelector); err != nil {
+		return fmt.Errorf("invalid node selector: %s", err)
+	}
+	switch placement.Spread {
+	case "", integration.SpreadNode, integration.SpreadZone:
+	default:
+		return fmt.Errorf("unknown spread %q, expected %s or %s", placement.Spread, integration.SpreadNode, integration.SpreadZone)
+	}
+	return nil
+}
+
+// nodeZone returns the zone of a node from its labels, or an empty string
+func nodeZone(nodeLabels map[string]string) string {
+	for _, label := range zoneLabels {
+		if zone, found := nodeLabels[label]; found {
+			return zone
+		}
+	}
+	return ""
+}
+
+// placementFilter holds the placement constraints of a configuration
+type placementFilter struct {
+	placement *integration.Placement
+	selector  labels.Selector
+}
+
+func newPlacementFilter(placement *integration.Placement) placementFilter {
+	f := placementFilter{placement: placement}
+	if placement != nil && placement.NodeSelector != "" {
+		// Placements are validated when scheduled
+		f.selector, _ = labels.Parse(placement.NodeSelector)
+	}
+	return f
+}
+
+// allows returns whether a node satisfies the node selector and runner pool
+// of the configuration. The node must be locked by the caller.
+func (f placementFilter) allows(node *nodeStore) bool {
+	if f.placement == nil {
+		return true
+	}
+	if f.placement.RunnerPool != "" && node.lastStatus.RunnerPool != f.placement.RunnerPool {
+		return false
+	}
+	if f.selector != nil && !f.selector.Matches(labels.Set(node.lastStatus.Labels)) {
+		return false
+	}
+	return true
+}
+
+// spreadKey returns the key the configs of the same check are spread across,
+// or an empty string if they aren't spread. The node must be locked by the caller.
+func (f placementFilter) spreadKey(node *nodeStore) string {
+	if f.placement == nil {
+		return ""
+	}
+	switch f.placement.Spread {
+	case integration.SpreadNode:
+		return node.name
+	case integration.SpreadZone:
+		return nodeZone(node.lastStatus.Labels)
+	}
+	return ""
+}
+
+// countCheckConfigs returns the number of configs of a check dispatched to a
+// node, excluding a given digest. The node must be locked by the caller.
+func countCheckConfigs(node *nodeStore, checkName, excludedDigest string) int {
+	count := 0
+	for digest, config := range node.digestToConfig {
+		if config.Name == checkName && digest != excludedDigest {
+			count++
+		}
+	}
+	return count
+}
+
+// nodeLoad returns the load of a node used to compare nodes: its busyness if
+// known, the number of configs dispatched to it otherwise. The node must be
+// locked by the caller.
+func (d *dispatcher) nodeLoad(node *nodeStore) int {
+	if d.advancedDispatching && node.busyness > defaultBusynessValue {
+		return node.busyness
+	}
+	return len(node.digestToConfig)
+}
+
+// getNodeForConfig returns the name of the node a configuration should be
+// dispatched to. Configurations without placement hints go to the least busy
+// node. Otherwise, the node must match the node selector and runner pool of the
+// configuration, and the nodes or zones running the fewest configs of the same
+// check are preferred when the configuration is spread, then the least busy.
+// It returns an empty string if no node matches.
+func (d *dispatcher) getNodeForConfig(config integration.Config) string {
+	if config.Placement == nil {
+		return d.getLeastBusyNode()
+	}
+
+	filter := newPlacementFilter(config.Placement)
+	digest := config.Digest()
+
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	type candidate struct {
+		name      string
+		spreadKey string
+		onNode    int
+		load      int
+	}
+	var candidates []candidate
+	onSpreadKey := make(map[string]int)
+	for name, node := range d.store.nodes {
+		if name == "" {
+			continue
+		}
+		node.RLock()
+		if filter.allows(node) {
+			c := candidate{
+				name:      name,
+				spreadKey: filter.spreadKey(node),
+				onNode:    countCheckConfigs(node, config.Name, digest),
+				load:      d.nodeLoad(node),
+			}
+			onSpreadKey[c.spreadKey] += c.onNode
+			candidates = append(candidates, c)
+		}
+		node.RUnlock()
+	}
+
+	var picked *candidate
+	for i := range candidates {
+		c := &candidates[i]
+		if picked == nil {
+			picked = c
+			continue
+		}
+		if config.Placement.Spread != "" {
+			if onSpreadKey[c.spreadKey] != onSpreadKey[picked.spreadKey] {
+				if onSpreadKey[c.spreadKey] < onSpreadKey[picked.spreadKey] {
+					picked = c
+				}
+				continue
+			}
+			if c.onNode != picked.onNode {
+				if c.onNode < picked.onNode {
+					picked = c
+				}
+				continue
+			}
+		}
+		if c.load < picked.load {
+			picked = c
+		}
+	}
+
+	if picked == nil {
+		return ""
+	}
+	return picked.name
+}
+
+// canMoveConfig returns whether moving a configuration from a node to another
+// keeps satisfying its placement: the destination must match its node selector
+// and runner pool, and moving a spread configuration must not put it on a node
+// or zone running more configs of the same check than the source.
+func (d *dispatcher) canMoveConfig(config integration.Config, src, dest string) bool {
+	if config.Placement == nil {
+		return true
+	}
+
+	filter := newPlacementFilter(config.Placement)
+	digest := config.Digest()
+
+	d.store.RLock()
+	defer d.store.RUnlock()
+
+	destNode, destFound := d.store.getNodeStore(dest)
+	srcNode, srcFound := d.store.getNodeStore(src)
+	if !destFound || !srcFound {
+		return false
+	}
+
+	destNode.RLock()
+	allowed := filter.allows(destNode)
+	destKey := filter.spreadKey(destNode)
+	destNode.RUnlock()
+	if !allowed {
+		return false
+	}
+	if config.Placement.Spread == "" {
+		return true
+	}
+
+	srcNode.RLock()
+	srcKey := filter.spreadKey(srcNode)
+	srcNode.RUnlock()
+	if srcKey == destKey {
+		// Moving within the same zone doesn't change the spread
+		return true
+	}
+
+	onSrc, onDest := 0, 0
+	for _, node := range d.store.nodes {
+		node.RLock()
+		key := filter.spreadKey(node)
+		if key == srcKey {
+			onSrc += countCheckConfigs(node, config.Name, digest)
+		} else if key == destKey {
+			onDest += countCheckConfigs(node, config.Name, digest)
+		}
+		node.RUnlock()
+	}
+	return onDest <= onSrc
+}
@@ -0,0 +1,159 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build clusterchecks
+
+package clusterchecks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks/types"
+)
+
+func generatePlacedIntegration(name, instance string, placement *integration.Placement) integration.Config {
+	return integration.Config{
+		Name:         name,
+		Instances:    []integration.Data{integration.Data(instance)},
+		ClusterCheck: true,
+		Placement:    placement,
+	}
+}
+
+func zoneStatus(zone string, extraLabels ...string) types.NodeStatus {
+	status := types.NodeStatus{Labels: map[string]string{"topology.kubernetes.io/zone": zone}}
+	for _, label := range extraLabels {
+		status.Labels[label] = "true"
+	}
+	return status
+}
+
+func TestValidatePlacement(t *testing.T) {
+	assert.NoError(t, validatePlacement(nil))
+	assert.NoError(t, validatePlacement(&integration.Placement{NodeSelector: "subnet=private,!spot", Spread: "zone"}))
+	assert.Error(t, validatePlacement(&integration.Placement{NodeSelector: "subnet in"}))
+	assert.Error(t, validatePlacement(&integration.Placement{Spread: "region"}))
+}
+
+func TestNodeSelectorAndRunnerPool(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{Labels: map[string]string{"subnet": "public"}})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{Labels: map[string]string{"subnet": "private"}})
+	dispatcher.processNodeStatus("nodeC", "10.0.0.3", types.NodeStatus{Labels: map[string]string{"subnet": "private", "spot": "true"}, RunnerPool: "databases"})
+
+	// Affinity and anti-affinity
+	dispatcher.Schedule([]integration.Config{
+		generatePlacedIntegration("http_check", "url: a", &integration.Placement{NodeSelector: "subnet=private,!spot"}),
+	})
+	configs, _, err := dispatcher.getNodeConfigs("nodeB")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http_check"}, extractCheckNames(configs))
+
+	// Runner pool
+	dispatcher.Schedule([]integration.Config{
+		generatePlacedIntegration("postgres", "host: a", &integration.Placement{RunnerPool: "databases"}),
+	})
+	configs, _, err = dispatcher.getNodeConfigs("nodeC")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"postgres"}, extractCheckNames(configs))
+
+	// No matching node: dangling
+	dispatcher.Schedule([]integration.Config{
+		generatePlacedIntegration("redis", "host: a", &integration.Placement{NodeSelector: "subnet=vpn"}),
+	})
+	assert.Len(t, dispatcher.store.danglingConfigs, 1)
+
+	// Invalid placement: ignored
+	dispatcher.Schedule([]integration.Config{
+		generatePlacedIntegration("redis", "host: b", &integration.Placement{Spread: "region"}),
+	})
+	allConfigs, err := dispatcher.getAllConfigs()
+	assert.NoError(t, err)
+	assert.Len(t, allConfigs, 3)
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestSpreadAcrossZones(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA1", "10.0.0.1", zoneStatus("a"))
+	dispatcher.processNodeStatus("nodeA2", "10.0.0.2", zoneStatus("a"))
+	dispatcher.processNodeStatus("nodeB1", "10.0.0.3", zoneStatus("b"))
+
+	// Load nodeB1 with other checks, zone spreading takes precedence over load
+	dispatcher.addConfig(generateIntegration("A"), "nodeB1")
+	dispatcher.addConfig(generateIntegration("B"), "nodeB1")
+
+	placement := &integration.Placement{Spread: integration.SpreadZone}
+	for i := 0; i < 4; i++ {
+		dispatcher.Schedule([]integration.Config{
+			generatePlacedIntegration("http_check", fmt.Sprintf("url: %d", i), placement),
+		})
+	}
+
+	countChecks := func(node string) int {
+		configs, _, err := dispatcher.getNodeConfigs(node)
+		assert.NoError(t, err)
+		count := 0
+		for _, config := range configs {
+			if config.Name == "http_check" {
+				count++
+			}
+		}
+		return count
+	}
+	assert.Equal(t, 2, countChecks("nodeB1"))
+	// Within a zone, instances are spread across nodes
+	assert.Equal(t, 1, countChecks("nodeA1"))
+	assert.Equal(t, 1, countChecks("nodeA2"))
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestSpreadAcrossNodes(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA", "10.0.0.1", types.NodeStatus{})
+	dispatcher.processNodeStatus("nodeB", "10.0.0.2", types.NodeStatus{})
+
+	// nodeA is less busy, but already runs an instance of the check
+	dispatcher.addConfig(generatePlacedIntegration("http_check", "url: a", nil), "nodeA")
+	dispatcher.addConfig(generateIntegration("B"), "nodeB")
+	dispatcher.addConfig(generateIntegration("C"), "nodeB")
+
+	config := generatePlacedIntegration("http_check", "url: b", &integration.Placement{Spread: integration.SpreadNode})
+	assert.Equal(t, "nodeB", dispatcher.getNodeForConfig(config))
+
+	requireNotLocked(t, dispatcher.store)
+}
+
+func TestCanMoveConfig(t *testing.T) {
+	dispatcher := newDispatcher()
+	dispatcher.processNodeStatus("nodeA1", "10.0.0.1", zoneStatus("a", "private"))
+	dispatcher.processNodeStatus("nodeA2", "10.0.0.2", zoneStatus("a"))
+	dispatcher.processNodeStatus("nodeB1", "10.0.0.3", zoneStatus("b", "private"))
+	dispatcher.processNodeStatus("nodeB2", "10.0.0.4", zoneStatus("b", "private"))
+
+	placement := &integration.Placement{NodeSelector: "private", Spread: integration.SpreadZone}
+	moved := generatePlacedIntegration("http_check", "url: a", placement)
+	dispatcher.addConfig(moved, "nodeA1")
+	dispatcher.addConfig(generatePlacedIntegration("http_check", "url: b", placement), "nodeB1")
+
+	// Nodes not matching the node selector are excluded
+	assert.False(t, dispatcher.canMoveConfig(moved, "nodeA1", "nodeA2"))
+	// Zone b already runs an instance
+	assert.False(t, dispatcher.canMoveConfig(moved, "nodeA1", "nodeB2"))
+	// Configs without placement can move anywhere
+	assert.True(t, dispatcher.canMoveConfig(generateIntegration("A"), "nodeA1", "nodeA2"))
+
+	dispatcher.addConfig(generatePlacedIntegration("http_check", "url: c", placement), "nodeA1")
+	// Zone a now runs two instances, moving one to zone b balances them
+	assert.True(t, dispatcher.canMoveConfig(moved, "nodeA1", "nodeB2"))
+
+	requireNotLocked(t, dispatcher.store)
+}
@@ -109,12 +109,13 @@ func (d *dispatcher) pickCheckToMove(nodeName string) (string, int, error) {
 // if it satisfies the following
 // Diff(Ni) < Diff(Nj) (for each j != i, 0 <= j < len(nodes))
 // where Diff(N) is the difference between the busyness on N and the total average busyness.
-func pickNode(diffMap map[string]int, sourceNode string) string {
+// Only the nodes accepted by canMove are considered.
+func pickNode(diffMap map[string]int, sourceNode string, canMove func(node string) bool) string {
 	firstItr := true
 	minDiff := 0
 	pickedNode := ""
 	for _, node := range orderedKeys(diffMap) {
-		if node == sourceNode {
+		if node == sourceNode || !canMove(node) {
 			continue
 		}
 		if diffMap[node] < minDiff || firstItr {
@@ -186,7 +187,15 @@ func (d *dispatcher) rebalance() {
 				break
 			}
 
-			pickedNodeName := pickNode(diffMap, sourceNodeName)
+			config, _ := d.getConfigAndDigest(checkID)
+			pickedNodeName := pickNode(diffMap, sourceNodeName, func(node string) bool {
+				// Don't break the placement of the check
+				return d.canMoveConfig(config, sourceNodeName, node)
+			})
+			if pickedNodeName == "" {
+				log.Debugf("No node to move check %s from node %s to", checkID, sourceNodeName)
+				break
+			}
 			if diffMap[pickedNodeName]+checkWeight < int(float64(diffMap[sourceNodeName])*tolerationMargin) {
 				// move a check to a new node only if it keeps the busyness of the new node
 				// lower than the original node's busyness multiplied by the tolerationMargin value
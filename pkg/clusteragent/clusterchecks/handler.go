@@ -109,8 +109,15 @@ func (h *Handler) Run(ctx context.Context) {
 			}
 		}
 
-		// Leading, start warmup
-		log.Infof("Becoming leader, waiting %s for node-agents to report", h.warmupDuration)
+		// Leading, adopt the assignment of the previous leader if available,
+		// otherwise start warmup
+		warmupDuration := h.warmupDuration
+		if h.dispatcher.restoreCheckpoint() {
+			log.Info("Becoming leader, restored the dispatching state of the previous leader")
+			warmupDuration = 0
+		} else {
+			log.Infof("Becoming leader, waiting %s for node-agents to report", warmupDuration)
+		}
 		select {
 		case <-ctx.Done():
 			return
@@ -118,7 +125,7 @@ func (h *Handler) Run(ctx context.Context) {
 			if newState != leader {
 				continue
 			}
-		case <-time.After(h.warmupDuration):
+		case <-time.After(warmupDuration):
 			break
 		}
 
@@ -22,13 +22,14 @@ import (
 // operations involving several calls.
 type clusterStore struct {
 	sync.RWMutex
-	active           bool
-	digestToConfig   map[string]integration.Config            // All configurations to dispatch
-	digestToNode     map[string]string                        // Node running a config
-	nodes            map[string]*nodeStore                    // All nodes known to the cluster-agent
-	danglingConfigs  map[string]integration.Config            // Configs we could not dispatch to any node
-	endpointsConfigs map[string]map[string]integration.Config // Endpoints configs to be consumed by node agents
-	idToDigest       map[check.ID]string                      // link check IDs to check configs
+	active               bool
+	digestToConfig       map[string]integration.Config            // All configurations to dispatch
+	digestToNode         map[string]string                        // Node running a config
+	nodes                map[string]*nodeStore                    // All nodes known to the cluster-agent
+	danglingConfigs      map[string]integration.Config            // Configs we could not dispatch to any node
+	endpointsConfigs     map[string]map[string]integration.Config // Endpoints configs to be consumed by node agents
+	idToDigest           map[check.ID]string                      // link check IDs to check configs
+	restoredDigestToNode map[string]string                        // Node a config ran on according to the restored checkpoint
 }
 
 func newClusterStore() *clusterStore {
@@ -46,6 +47,7 @@ func (s *clusterStore) reset() {
 	s.danglingConfigs = make(map[string]integration.Config)
 	s.endpointsConfigs = make(map[string]map[string]integration.Config)
 	s.idToDigest = make(map[check.ID]string)
+	s.restoredDigestToNode = make(map[string]string)
 }
 
 // getNodeStore retrieves the store struct for a given node name, if it exists
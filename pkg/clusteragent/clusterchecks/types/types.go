@@ -11,7 +11,9 @@ import (
 
 // NodeStatus holds the status report from the node-agent
 type NodeStatus struct {
-	LastChange int64 `json:"last_change"`
+	LastChange int64             `json:"last_change"`
+	Labels     map[string]string `json:"labels,omitempty"`      // labels of the node, matched by placement node selectors
+	RunnerPool string            `json:"runner_pool,omitempty"` // CLC runner pool of the agent
 }
 
 // StatusResponse holds the DCA response for a status report
@@ -14,6 +14,27 @@ type ExternalMetricValue struct {
 	Ref        ObjectReference   `json:"reference"`
 	Value      float64           `json:"value"`
 	Valid      bool              `json:"valid"`
+	// Query is a full Datadog query expression used instead of the metric name and labels
+	Query    string          `json:"query,omitempty"`
+	Fallback *MetricFallback `json:"fallback,omitempty"`
+}
+
+// FallbackType is the behavior applied when an external metric cannot be retrieved from Datadog
+type FallbackType string
+
+const (
+	// FallbackFail invalidates the metric, this is the default behavior
+	FallbackFail FallbackType = "fail"
+	// FallbackLastValue keeps serving the last value retrieved from Datadog
+	FallbackLastValue FallbackType = "last_value"
+	// FallbackStatic serves a static value
+	FallbackStatic FallbackType = "static"
+)
+
+// MetricFallback describes the fallback behavior of an external metric
+type MetricFallback struct {
+	Type  FallbackType `json:"type"`
+	Value float64      `json:"value,omitempty"`
 }
 
 type DeprecatedExternalMetricValue struct {
@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// Package externalseries holds the latest points of the series aggregated by
+// the node agents, so that the cluster agent can serve them as external metrics
+// without querying the Datadog API.
+package externalseries
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+)
+
+type seriesKey struct {
+	node string
+	name string
+	tags string
+}
+
+type series struct {
+	tags      map[string]struct{}
+	timestamp int64
+	value     float64
+}
+
+// Store holds the latest point of each series sent by the node agents and the
+// names of the metrics they must send.
+type Store struct {
+	mu     sync.RWMutex
+	maxAge time.Duration
+	series map[seriesKey]*series
+	wanted map[string]struct{}
+}
+
+// NewStore returns a new Store, points older than maxAge are discarded
+func NewStore(maxAge time.Duration) *Store {
+	return &Store{
+		maxAge: maxAge,
+		series: make(map[seriesKey]*series),
+		wanted: make(map[string]struct{}),
+	}
+}
+
+// SetWanted replaces the names of the metrics the node agents must send,
+// the series of the other metrics are discarded.
+func (s *Store) SetWanted(names []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.wanted = make(map[string]struct{}, len(names))
+	for _, name := range names {
+		s.wanted[name] = struct{}{}
+	}
+	for key := range s.series {
+		if _, found := s.wanted[key.name]; !found {
+			delete(s.series, key)
+		}
+	}
+}
+
+// Wanted returns the sorted names of the metrics the node agents must send
+func (s *Store) Wanted() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.wantedNames()
+}
+
+func (s *Store) wantedNames() []string {
+	names := make([]string, 0, len(s.wanted))
+	for name := range s.wanted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Push stores the points sent by a node agent and returns the names of the
+// metrics to send on its next flush. Points of unwanted metrics, stale points
+// and non-finite values are ignored.
+func (s *Store) Push(node string, points []apiv1.SeriesPoint) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	minTimestamp := time.Now().Add(-s.maxAge).Unix()
+	for _, p := range points {
+		if _, found := s.wanted[p.Name]; !found {
+			continue
+		}
+		if p.Timestamp < minTimestamp || math.IsNaN(p.Value) || math.IsInf(p.Value, 0) {
+			continue
+		}
+		tags := make([]string, len(p.Tags))
+		copy(tags, p.Tags)
+		sort.Strings(tags)
+		key := seriesKey{node: node, name: p.Name, tags: strings.Join(tags, ",")}
+		if current, found := s.series[key]; found && current.timestamp > p.Timestamp {
+			continue
+		}
+		tagSet := make(map[string]struct{}, len(tags))
+		for _, tag := range tags {
+			tagSet[tag] = struct{}{}
+		}
+		s.series[key] = &series{tags: tagSet, timestamp: p.Timestamp, value: p.Value}
+	}
+
+	for key, serie := range s.series {
+		if serie.timestamp < minTimestamp {
+			delete(s.series, key)
+		}
+	}
+
+	return s.wantedNames()
+}
+
+// Query aggregates the latest points of the series of a metric having all the
+// given tags, across all the nodes. The returned timestamp is the one of the
+// oldest point aggregated. It returns an error if no point matches.
+func (s *Store) Query(name string, tags []string, aggregator string) (float64, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	minTimestamp := time.Now().Add(-s.maxAge).Unix()
+	var values []float64
+	var timestamp int64
+	for key, serie := range s.series {
+		if key.name != name || serie.timestamp < minTimestamp || !hasTags(serie, tags) {
+			continue
+		}
+		if len(values) == 0 || serie.timestamp < timestamp {
+			timestamp = serie.timestamp
+		}
+		values = append(values, serie.value)
+	}
+	if len(values) == 0 {
+		return 0, 0, fmt.Errorf("no recent point for the metric %s with the tags %v", name, tags)
+	}
+
+	value, err := aggregate(values, aggregator)
+	return value, timestamp, err
+}
+
+func hasTags(serie *series, tags []string) bool {
+	for _, tag := range tags {
+		if _, found := serie.tags[tag]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+func aggregate(values []float64, aggregator string) (float64, error) {
+	result := values[0]
+	switch aggregator {
+	case "avg", "sum":
+		for _, v := range values[1:] {
+			result += v
+		}
+		if aggregator == "avg" {
+			result /= float64(len(values))
+		}
+	case "max":
+		for _, v := range values[1:] {
+			result = math.Max(result, v)
+		}
+	case "min":
+		for _, v := range values[1:] {
+			result = math.Min(result, v)
+		}
+	default:
+		return 0, fmt.Errorf("unknown aggregator %q, choose from [avg,sum,max,min]", aggregator)
+	}
+	return result, nil
+}
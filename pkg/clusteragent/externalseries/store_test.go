@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package externalseries
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+)
+
+func TestStorePushAndQuery(t *testing.T) {
+	now := time.Now().Unix()
+	store := NewStore(time.Minute)
+	assert.Empty(t, store.Push("node1", []apiv1.SeriesPoint{{Name: "requests", Timestamp: now, Value: 1}}))
+
+	store.SetWanted([]string{"requests", "latency"})
+	wanted := store.Push("node1", []apiv1.SeriesPoint{
+		{Name: "requests", Tags: []string{"service:web", "env:prod"}, Timestamp: now - 10, Value: 10},
+		{Name: "requests", Tags: []string{"service:db", "env:prod"}, Timestamp: now, Value: 100},
+		{Name: "latency", Tags: []string{"service:web"}, Timestamp: now, Value: math.NaN()},
+		{Name: "errors", Tags: []string{"service:web"}, Timestamp: now, Value: 1},
+	})
+	assert.Equal(t, []string{"latency", "requests"}, wanted)
+	store.Push("node2", []apiv1.SeriesPoint{
+		{Name: "requests", Tags: []string{"env:prod", "service:web"}, Timestamp: now, Value: 30},
+		{Name: "requests", Tags: []string{"env:staging", "service:web"}, Timestamp: now - 120, Value: 1000},
+	})
+	// Older points don't replace newer ones
+	store.Push("node2", []apiv1.SeriesPoint{
+		{Name: "requests", Tags: []string{"env:prod", "service:web"}, Timestamp: now - 5, Value: 50},
+	})
+
+	value, timestamp, err := store.Query("requests", []string{"service:web"}, "avg")
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, value)
+	assert.Equal(t, now-10, timestamp)
+
+	value, _, err = store.Query("requests", []string{"env:prod"}, "sum")
+	require.NoError(t, err)
+	assert.Equal(t, 140.0, value)
+
+	value, _, err = store.Query("requests", nil, "max")
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, value)
+
+	_, _, err = store.Query("requests", []string{"env:staging"}, "avg")
+	assert.Error(t, err)
+	_, _, err = store.Query("latency", nil, "avg")
+	assert.Error(t, err)
+	_, _, err = store.Query("requests", nil, "median")
+	assert.Error(t, err)
+
+	store.SetWanted([]string{"latency"})
+	_, _, err = store.Query("requests", nil, "avg")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"latency"}, store.Wanted())
+}
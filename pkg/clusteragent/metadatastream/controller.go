@@ -0,0 +1,292 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package metadatastream
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ControllerContext holds the necessary context for the controller
+type ControllerContext struct {
+	Store           *Store
+	InformerFactory informers.SharedInformerFactory
+	StopCh          chan struct{}
+}
+
+// Controller feeds the store from the informers of the nodes, namespaces,
+// pods and endpoints of the cluster.
+type Controller struct {
+	store *Store
+
+	podLister corelisters.PodLister
+
+	// mu protects the services of the pods, the events of the pods and of the
+	// endpoints are handled by different goroutines of the informers.
+	mu sync.Mutex
+	// endpointsPods maps the endpoints to the pods they target, by namespace/name keys
+	endpointsPods map[string]sets.String
+	// podServices maps the pods to the services targeting them, by namespace/name keys
+	podServices map[string]sets.String
+}
+
+// StartController registers the controller on the informers and starts them
+func StartController(ctx ControllerContext) {
+	newController(ctx.Store, ctx.InformerFactory)
+
+	// Start the informers registered by the controller
+	ctx.InformerFactory.Start(ctx.StopCh)
+	log.Infof("Started the metadata stream controller")
+}
+
+func newController(store *Store, informerFactory informers.SharedInformerFactory) *Controller {
+	c := &Controller{
+		store:         store,
+		endpointsPods: make(map[string]sets.String),
+		podServices:   make(map[string]sets.String),
+	}
+
+	informerFactory.Core().V1().Nodes().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.setNode,
+		UpdateFunc: func(_, obj interface{}) { c.setNode(obj) },
+		DeleteFunc: c.deleteNode,
+	})
+	informerFactory.Core().V1().Namespaces().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.setNamespace,
+		UpdateFunc: func(_, obj interface{}) { c.setNamespace(obj) },
+		DeleteFunc: c.deleteNamespace,
+	})
+
+	podInformer := informerFactory.Core().V1().Pods()
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.setPod,
+		UpdateFunc: func(_, obj interface{}) { c.setPod(obj) },
+		DeleteFunc: c.deletePod,
+	})
+	c.podLister = podInformer.Lister()
+
+	informerFactory.Core().V1().Endpoints().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.setEndpoints,
+		UpdateFunc: func(_, obj interface{}) { c.setEndpoints(obj) },
+		DeleteFunc: c.deleteEndpoints,
+	})
+
+	return c
+}
+
+func (c *Controller) setNode(obj interface{}) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return
+	}
+	c.store.Set(node.Name, apiv1.MetadataEvent{
+		Kind:   apiv1.MetadataKindNode,
+		Name:   node.Name,
+		Labels: node.Labels,
+	})
+}
+
+func (c *Controller) deleteNode(obj interface{}) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			log.Debugf("Couldn't get object from tombstone %#v", obj)
+			return
+		}
+		node, ok = tombstone.Obj.(*corev1.Node)
+		if !ok {
+			log.Debugf("Tombstone contained object that is not a node %#v", obj)
+			return
+		}
+	}
+	c.store.Delete(apiv1.MetadataKindNode, "", node.Name)
+}
+
+func (c *Controller) setNamespace(obj interface{}) {
+	namespace, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	c.store.Set("", apiv1.MetadataEvent{
+		Kind:   apiv1.MetadataKindNamespace,
+		Name:   namespace.Name,
+		Labels: namespace.Labels,
+	})
+}
+
+func (c *Controller) deleteNamespace(obj interface{}) {
+	namespace, ok := obj.(*corev1.Namespace)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			log.Debugf("Couldn't get object from tombstone %#v", obj)
+			return
+		}
+		namespace, ok = tombstone.Obj.(*corev1.Namespace)
+		if !ok {
+			log.Debugf("Tombstone contained object that is not a namespace %#v", obj)
+			return
+		}
+	}
+	c.store.Delete(apiv1.MetadataKindNamespace, "", namespace.Name)
+}
+
+func (c *Controller) setPod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.publishPod(pod)
+}
+
+// publishPod sends the services and the owners of a pod to its node, c.mu must be held
+func (c *Controller) publishPod(pod *corev1.Pod) {
+	if pod.Spec.NodeName == "" {
+		// Not scheduled yet
+		return
+	}
+
+	event := apiv1.MetadataEvent{
+		Kind:      apiv1.MetadataKindPod,
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+	}
+	if services, found := c.podServices[namespacedKey(pod.Namespace, pod.Name)]; found {
+		event.Services = services.List()
+	}
+	for _, owner := range pod.OwnerReferences {
+		event.Owners = append(event.Owners, apiv1.OwnerReference{Kind: owner.Kind, Name: owner.Name})
+	}
+	c.store.Set(pod.Spec.NodeName, event)
+}
+
+func (c *Controller) deletePod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			log.Debugf("Couldn't get object from tombstone %#v", obj)
+			return
+		}
+		pod, ok = tombstone.Obj.(*corev1.Pod)
+		if !ok {
+			log.Debugf("Tombstone contained object that is not a pod %#v", obj)
+			return
+		}
+	}
+	c.store.Delete(apiv1.MetadataKindPod, pod.Namespace, pod.Name)
+}
+
+func (c *Controller) setEndpoints(obj interface{}) {
+	endpoints, ok := obj.(*corev1.Endpoints)
+	if !ok {
+		return
+	}
+
+	pods := sets.NewString()
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			// Endpoints are also used by the control plane as resource locks for leader election.
+			// These endpoints will not have a TargetRef and can be ignored.
+			if address.TargetRef == nil || address.TargetRef.Kind != "Pod" {
+				continue
+			}
+			if address.TargetRef.Namespace == "" || address.TargetRef.Name == "" {
+				continue
+			}
+			pods.Insert(namespacedKey(address.TargetRef.Namespace, address.TargetRef.Name))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.mapEndpoints(endpoints.Namespace, endpoints.Name, pods)
+}
+
+func (c *Controller) deleteEndpoints(obj interface{}) {
+	endpoints, ok := obj.(*corev1.Endpoints)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			log.Debugf("Couldn't get object from tombstone %#v", obj)
+			return
+		}
+		endpoints, ok = tombstone.Obj.(*corev1.Endpoints)
+		if !ok {
+			log.Debugf("Tombstone contained object that is not an endpoint %#v", obj)
+			return
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.mapEndpoints(endpoints.Namespace, endpoints.Name, sets.NewString())
+}
+
+// mapEndpoints updates the services of the pods added to or removed from the
+// endpoints and publishes them, c.mu must be held
+func (c *Controller) mapEndpoints(namespace, service string, pods sets.String) {
+	endpointsKey := namespacedKey(namespace, service)
+	previous := c.endpointsPods[endpointsKey]
+	if pods.Len() == 0 {
+		delete(c.endpointsPods, endpointsKey)
+	} else {
+		c.endpointsPods[endpointsKey] = pods
+	}
+
+	changed := pods.Difference(previous).Union(previous.Difference(pods))
+	for key := range changed {
+		services, found := c.podServices[key]
+		if !found {
+			services = sets.NewString()
+			c.podServices[key] = services
+		}
+		if pods.Has(key) {
+			services.Insert(service)
+		} else {
+			services.Delete(service)
+		}
+		if services.Len() == 0 {
+			delete(c.podServices, key)
+		}
+
+		podNamespace, podName, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			continue
+		}
+		pod, err := c.podLister.Pods(podNamespace).Get(podName)
+		if errors.IsNotFound(err) {
+			// The pod is published with its services when it is added
+			continue
+		} else if err != nil {
+			log.Debugf("Unable to retrieve pod %s from store: %v", key, err)
+			continue
+		}
+		c.publishPod(pod)
+	}
+}
+
+func namespacedKey(namespace, name string) string {
+	return namespace + "/" + name
+}
@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package metadatastream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+)
+
+func newFakeController() (*Controller, informers.SharedInformerFactory, *Store) {
+	store := NewStore(100)
+	informerFactory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	return newController(store, informerFactory), informerFactory, store
+}
+
+func newFakePod(name, nodeName string, owners ...metav1.OwnerReference) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			OwnerReferences: owners,
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+func newFakeEndpoints(name string, pods ...*corev1.Pod) *corev1.Endpoints {
+	var addresses []corev1.EndpointAddress
+	for _, pod := range pods {
+		addresses = append(addresses, corev1.EndpointAddress{
+			TargetRef: &corev1.ObjectReference{Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name},
+		})
+	}
+	return &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Subsets:    []corev1.EndpointSubset{{Addresses: addresses}},
+	}
+}
+
+// snapshotOf returns the objects sent to a node, without the reset and synced events
+func snapshotOf(store *Store, node string) []apiv1.MetadataEvent {
+	events, sub := store.Subscribe(node, "")
+	store.Unsubscribe(sub)
+	return events[1 : len(events)-1]
+}
+
+func TestControllerNodesAndNamespaces(t *testing.T) {
+	c, _, store := newFakeController()
+
+	c.setNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"zone": "a"}}})
+	c.setNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}})
+	c.setNamespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"team": "b"}}})
+
+	events := snapshotOf(store, "node1")
+	require.Len(t, events, 2)
+	assert.Equal(t, apiv1.MetadataKindNode, events[0].Kind)
+	assert.Equal(t, map[string]string{"zone": "a"}, events[0].Labels)
+	assert.Equal(t, apiv1.MetadataKindNamespace, events[1].Kind)
+	assert.Equal(t, map[string]string{"team": "b"}, events[1].Labels)
+
+	c.deleteNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}})
+	c.deleteNamespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	assert.Empty(t, snapshotOf(store, "node1"))
+	assert.Len(t, snapshotOf(store, "node2"), 1)
+}
+
+func TestControllerPods(t *testing.T) {
+	c, informerFactory, store := newFakeController()
+	podIndexer := informerFactory.Core().V1().Pods().Informer().GetIndexer()
+
+	pod1 := newFakePod("pod1", "node1", metav1.OwnerReference{Kind: "ReplicaSet", Name: "rs"})
+	pod2 := newFakePod("pod2", "node2")
+	unscheduled := newFakePod("pod3", "")
+	for _, pod := range []*corev1.Pod{pod1, pod2, unscheduled} {
+		require.NoError(t, podIndexer.Add(pod))
+		c.setPod(pod)
+	}
+
+	events := snapshotOf(store, "node1")
+	require.Len(t, events, 1)
+	assert.Equal(t, "pod1", events[0].Name)
+	assert.Equal(t, []apiv1.OwnerReference{{Kind: "ReplicaSet", Name: "rs"}}, events[0].Owners)
+	assert.Empty(t, events[0].Services)
+
+	// Endpoints targeting the pods update their services
+	c.setEndpoints(newFakeEndpoints("svc1", pod1, pod2))
+	c.setEndpoints(newFakeEndpoints("svc2", pod1))
+	events = snapshotOf(store, "node1")
+	require.Len(t, events, 1)
+	assert.Equal(t, []string{"svc1", "svc2"}, events[0].Services)
+	events = snapshotOf(store, "node2")
+	require.Len(t, events, 1)
+	assert.Equal(t, []string{"svc1"}, events[0].Services)
+
+	// The pods removed from the endpoints lose the service
+	c.setEndpoints(newFakeEndpoints("svc1", pod2))
+	assert.Equal(t, []string{"svc2"}, snapshotOf(store, "node1")[0].Services)
+	c.deleteEndpoints(newFakeEndpoints("svc2", pod1))
+	assert.Empty(t, snapshotOf(store, "node1")[0].Services)
+
+	// The services are known before the pod is added
+	pod4 := newFakePod("pod4", "node1")
+	c.setEndpoints(newFakeEndpoints("svc3", pod4))
+	require.NoError(t, podIndexer.Add(pod4))
+	c.setPod(pod4)
+	events = snapshotOf(store, "node1")
+	require.Len(t, events, 2)
+	assert.Equal(t, "pod4", events[1].Name)
+	assert.Equal(t, []string{"svc3"}, events[1].Services)
+
+	c.deletePod(pod1)
+	events = snapshotOf(store, "node1")
+	require.Len(t, events, 1)
+	assert.Equal(t, "pod4", events[0].Name)
+}
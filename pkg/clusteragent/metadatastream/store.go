@@ -0,0 +1,268 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// Package metadatastream pushes the cluster level metadata used to tag the
+// pods (node labels, namespace labels, services and owners of the pods) to
+// the node agents subscribed to the cluster agent.
+package metadatastream
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+)
+
+// subscriptionBufferSize is the number of events a subscriber can lag behind
+// before being disconnected, it then resumes the stream from the event log.
+const subscriptionBufferSize = 1000
+
+type objectKey struct {
+	kind      apiv1.MetadataKind
+	namespace string
+	name      string
+}
+
+type object struct {
+	// node is the node the object is sent to, all the nodes if empty
+	node  string
+	event apiv1.MetadataEvent
+}
+
+type logEntry struct {
+	seq   uint64
+	node  string
+	event apiv1.MetadataEvent
+}
+
+// Store holds the current metadata of the cluster and a log of its latest
+// changes, so that the subscribers can resume the stream after a disconnection.
+//
+// Tokens are made of the epoch of the store, different for every cluster agent
+// replica and restart, and of the sequence number of the last event received.
+type Store struct {
+	mu          sync.Mutex
+	epoch       string
+	seq         uint64
+	objects     map[objectKey]*object
+	log         []logEntry
+	logSize     int
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events sent to a node
+type Subscription struct {
+	node   string
+	events chan apiv1.MetadataEvent
+}
+
+// Events returns the channel of the events of the subscription, it is closed
+// if the subscriber lags behind or is unsubscribed.
+func (s *Subscription) Events() <-chan apiv1.MetadataEvent {
+	return s.events
+}
+
+// NewStore returns a new Store keeping at least the last logSize events
+func NewStore(logSize int) *Store {
+	return &Store{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		objects:     make(map[objectKey]*object),
+		logSize:     logSize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Set creates or replaces an object, node is the node the object is sent to,
+// all the nodes if empty. Nothing is sent if the object did not change.
+func (s *Store) Set(node string, event apiv1.MetadataEvent) {
+	event.Type = apiv1.MetadataEventSet
+	event.Token = ""
+	key := objectKey{kind: event.Kind, namespace: event.Namespace, name: event.Name}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, found := s.objects[key]; found && current.node == node && reflect.DeepEqual(current.event, event) {
+		return
+	}
+	s.objects[key] = &object{node: node, event: event}
+	s.publish(node, event)
+}
+
+// Delete deletes an object
+func (s *Store) Delete(kind apiv1.MetadataKind, namespace, name string) {
+	key := objectKey{kind: kind, namespace: namespace, name: name}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, found := s.objects[key]
+	if !found {
+		return
+	}
+	delete(s.objects, key)
+	s.publish(current.node, apiv1.MetadataEvent{
+		Type:      apiv1.MetadataEventDelete,
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+	})
+}
+
+// publish appends the event to the log and sends it to the subscribers of the node
+func (s *Store) publish(node string, event apiv1.MetadataEvent) {
+	s.seq++
+	event.Token = s.token()
+
+	s.log = append(s.log, logEntry{seq: s.seq, node: node, event: event})
+	// Trim the log by batches to avoid copying it on every event
+	if len(s.log) >= 2*s.logSize {
+		s.log = append([]logEntry(nil), s.log[len(s.log)-s.logSize:]...)
+	}
+
+	for sub := range s.subscribers {
+		if node != "" && node != sub.node {
+			continue
+		}
+		s.send(sub, event)
+	}
+}
+
+// send sends an event to a subscriber, which is disconnected if it lags behind
+func (s *Store) send(sub *Subscription, event apiv1.MetadataEvent) {
+	select {
+	case sub.events <- event:
+	default:
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Subscribe subscribes to the events of a node. It returns the events missed
+// since the given token, or a reset and a snapshot of the objects of the node
+// if the stream can't be resumed.
+func (s *Store) Subscribe(node, token string) ([]apiv1.MetadataEvent, *Subscription) {
+	sub := &Subscription{
+		node:   node,
+		events: make(chan apiv1.MetadataEvent, subscriptionBufferSize),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribers[sub] = struct{}{}
+
+	if events, ok := s.resume(node, token); ok {
+		return events, sub
+	}
+	return s.snapshot(node), sub
+}
+
+// resume returns the events of the node following the token, if they are still in the log
+func (s *Store) resume(node, token string) ([]apiv1.MetadataEvent, bool) {
+	epoch, seq, err := parseToken(token)
+	if err != nil || epoch != s.epoch || seq > s.seq {
+		return nil, false
+	}
+	// The log must start right after the token for no event to be missed
+	if seq < s.seq && (len(s.log) == 0 || s.log[0].seq > seq+1) {
+		return nil, false
+	}
+
+	var events []apiv1.MetadataEvent
+	for _, entry := range s.log {
+		if entry.seq <= seq || entry.node != "" && entry.node != node {
+			continue
+		}
+		events = append(events, entry.event)
+	}
+	return events, true
+}
+
+// snapshot returns a reset event, the objects of the node and a synced event
+func (s *Store) snapshot(node string) []apiv1.MetadataEvent {
+	token := s.token()
+	objects := make([]apiv1.MetadataEvent, 0, len(s.objects))
+	for _, obj := range s.objects {
+		if obj.node != "" && obj.node != node {
+			continue
+		}
+		event := obj.event
+		event.Token = token
+		objects = append(objects, event)
+	}
+	// Send the nodes and the namespaces before the pods
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Kind != objects[j].Kind {
+			return kindOrder(objects[i].Kind) < kindOrder(objects[j].Kind)
+		}
+		if objects[i].Namespace != objects[j].Namespace {
+			return objects[i].Namespace < objects[j].Namespace
+		}
+		return objects[i].Name < objects[j].Name
+	})
+
+	events := make([]apiv1.MetadataEvent, 0, len(objects)+2)
+	events = append(events, apiv1.MetadataEvent{Type: apiv1.MetadataEventReset})
+	events = append(events, objects...)
+	return append(events, apiv1.MetadataEvent{Type: apiv1.MetadataEventSynced, Token: token})
+}
+
+// Heartbeat sends a heartbeat carrying the latest token to a subscriber.
+// It goes through the channel of the subscription so that the token is only
+// received after the events preceding it.
+func (s *Store) Heartbeat(sub *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.subscribers[sub]; !found {
+		return
+	}
+	s.send(sub, apiv1.MetadataEvent{Type: apiv1.MetadataEventHeartbeat, Token: s.token()})
+}
+
+// Unsubscribe stops sending events to a subscriber
+func (s *Store) Unsubscribe(sub *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.subscribers[sub]; !found {
+		return
+	}
+	delete(s.subscribers, sub)
+	close(sub.events)
+}
+
+func (s *Store) token() string {
+	return fmt.Sprintf("%s-%d", s.epoch, s.seq)
+}
+
+func parseToken(token string) (string, uint64, error) {
+	i := strings.LastIndex(token, "-")
+	if i < 0 {
+		return "", 0, fmt.Errorf("invalid token %q", token)
+	}
+	seq, err := strconv.ParseUint(token[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid token %q: %v", token, err)
+	}
+	return token[:i], seq, nil
+}
+
+func kindOrder(kind apiv1.MetadataKind) int {
+	switch kind {
+	case apiv1.MetadataKindNode:
+		return 0
+	case apiv1.MetadataKindNamespace:
+		return 1
+	default:
+		return 2
+	}
+}
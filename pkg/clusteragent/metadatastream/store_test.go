@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package metadatastream
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+)
+
+func podEvent(namespace, name string, services ...string) apiv1.MetadataEvent {
+	return apiv1.MetadataEvent{
+		Kind:      apiv1.MetadataKindPod,
+		Namespace: namespace,
+		Name:      name,
+		Services:  services,
+	}
+}
+
+func eventNames(events []apiv1.MetadataEvent) []string {
+	var names []string
+	for _, event := range events {
+		names = append(names, string(event.Type)+":"+event.Namespace+"/"+event.Name)
+	}
+	return names
+}
+
+func TestStoreSnapshot(t *testing.T) {
+	store := NewStore(100)
+	store.Set("node1", podEvent("default", "pod2", "svc"))
+	store.Set("node2", podEvent("default", "pod3"))
+	store.Set("node1", podEvent("default", "pod1"))
+	store.Set("", apiv1.MetadataEvent{Kind: apiv1.MetadataKindNamespace, Name: "default", Labels: map[string]string{"team": "a"}})
+	store.Set("node1", apiv1.MetadataEvent{Kind: apiv1.MetadataKindNode, Name: "node1"})
+
+	events, sub := store.Subscribe("node1", "")
+	defer store.Unsubscribe(sub)
+
+	assert.Equal(t, []string{
+		"reset:/",
+		"set:/node1",
+		"set:/default",
+		"set:default/pod1",
+		"set:default/pod2",
+		"synced:/",
+	}, eventNames(events))
+	for _, event := range events[1:] {
+		assert.Equal(t, store.token(), event.Token)
+	}
+	assert.Equal(t, []string{"svc"}, events[4].Services)
+}
+
+func TestStoreRouting(t *testing.T) {
+	store := NewStore(100)
+	_, sub1 := store.Subscribe("node1", "")
+	defer store.Unsubscribe(sub1)
+	_, sub2 := store.Subscribe("node2", "")
+	defer store.Unsubscribe(sub2)
+
+	store.Set("node1", podEvent("default", "pod1"))
+	store.Set("", apiv1.MetadataEvent{Kind: apiv1.MetadataKindNamespace, Name: "default"})
+	store.Delete(apiv1.MetadataKindPod, "default", "pod1")
+	// Unknown objects are not deleted
+	store.Delete(apiv1.MetadataKindPod, "default", "pod2")
+
+	require.Len(t, sub1.Events(), 3)
+	assert.Equal(t, "set:default/pod1", eventNames([]apiv1.MetadataEvent{<-sub1.Events()})[0])
+	assert.Equal(t, "set:/default", eventNames([]apiv1.MetadataEvent{<-sub1.Events()})[0])
+	deleted := <-sub1.Events()
+	assert.Equal(t, apiv1.MetadataEventDelete, deleted.Type)
+	assert.Equal(t, apiv1.MetadataKindPod, deleted.Kind)
+	assert.Equal(t, store.token(), deleted.Token)
+
+	require.Len(t, sub2.Events(), 1)
+	assert.Equal(t, "set:/default", eventNames([]apiv1.MetadataEvent{<-sub2.Events()})[0])
+}
+
+func TestStoreSetUnchanged(t *testing.T) {
+	store := NewStore(100)
+	_, sub := store.Subscribe("node1", "")
+	defer store.Unsubscribe(sub)
+
+	store.Set("node1", podEvent("default", "pod1", "svc"))
+	store.Set("node1", podEvent("default", "pod1", "svc"))
+	assert.Len(t, sub.Events(), 1)
+
+	store.Set("node1", podEvent("default", "pod1", "svc", "svc2"))
+	assert.Len(t, sub.Events(), 2)
+}
+
+func TestStoreResume(t *testing.T) {
+	store := NewStore(2)
+	store.Set("node1", podEvent("default", "pod1"))
+	token := store.token()
+
+	store.Set("node2", podEvent("default", "pod2"))
+	store.Set("node1", podEvent("default", "pod3"))
+
+	// Only the events of the node following the token are sent
+	events, sub := store.Subscribe("node1", token)
+	store.Unsubscribe(sub)
+	assert.Equal(t, []string{"set:default/pod3"}, eventNames(events))
+
+	// Nothing is missed from the latest token
+	events, sub = store.Subscribe("node1", store.token())
+	store.Unsubscribe(sub)
+	assert.Empty(t, events)
+
+	// Trim the log past the token
+	store.Set("node1", podEvent("default", "pod4"))
+	store.Set("node1", podEvent("default", "pod5"))
+	events, sub = store.Subscribe("node1", token)
+	store.Unsubscribe(sub)
+	assert.Equal(t, apiv1.MetadataEventReset, events[0].Type)
+	assert.Len(t, events, 6)
+
+	for _, token := range []string{
+		"invalid",
+		"otherepoch-1",
+		store.epoch + "-42",
+	} {
+		events, sub = store.Subscribe("node1", token)
+		store.Unsubscribe(sub)
+		assert.Equal(t, apiv1.MetadataEventReset, events[0].Type, token)
+	}
+}
+
+func TestStoreLaggingSubscriber(t *testing.T) {
+	store := NewStore(10)
+	_, sub := store.Subscribe("node1", "")
+
+	for i := 0; i <= subscriptionBufferSize; i++ {
+		store.Set("node1", podEvent("default", "pod", strconv.Itoa(i)))
+	}
+
+	// The subscription is closed once its buffer is full
+	received := 0
+	for range sub.Events() {
+		received++
+	}
+	assert.Equal(t, subscriptionBufferSize, received)
+
+	// Unsubscribing a closed subscription is a no-op
+	store.Unsubscribe(sub)
+	store.Heartbeat(sub)
+}
+
+func TestStoreHeartbeat(t *testing.T) {
+	store := NewStore(10)
+	_, sub := store.Subscribe("node1", "")
+	defer store.Unsubscribe(sub)
+
+	store.Set("node2", podEvent("default", "pod1"))
+	store.Heartbeat(sub)
+
+	require.Len(t, sub.Events(), 1)
+	heartbeat := <-sub.Events()
+	assert.Equal(t, apiv1.MetadataEventHeartbeat, heartbeat.Type)
+	assert.Equal(t, store.token(), heartbeat.Token)
+}
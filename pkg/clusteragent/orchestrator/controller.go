@@ -0,0 +1,210 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver,orchestrator
+
+package orchestrator
+
+import (
+	"sync/atomic"
+	"time"
+
+	model "github.com/DataDog/agent-payload/process"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	procconfig "github.com/DataDog/datadog-agent/pkg/process/config"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	collectionInterval    = 10 * time.Second
+	defaultMaxPerMessage  = 100
+	maxMaxPerMessage      = 10000
+	orchestratorCheckPath = "/api/v1/orchestrator"
+)
+
+// ControllerContext holds the necessary context for the controller
+type ControllerContext struct {
+	IsLeaderFunc    func() bool
+	InformerFactory informers.SharedInformerFactory
+	ClusterName     string
+	StopCh          chan struct{}
+}
+
+// Controller collects the Kubernetes resources of the cluster from the
+// informers of the cluster agent and sends them to the orchestrator explorer.
+type Controller struct {
+	deployLister        appslisters.DeploymentLister
+	deployListerSynced  cache.InformerSynced
+	rsLister            appslisters.ReplicaSetLister
+	rsListerSynced      cache.InformerSynced
+	serviceLister       corelisters.ServiceLister
+	serviceListerSynced cache.InformerSynced
+	nodeLister          corelisters.NodeLister
+	nodeListerSynced    cache.InformerSynced
+	jobLister           batchlisters.JobLister
+	jobListerSynced     cache.InformerSynced
+	isLeaderFunc        func() bool
+	processConfig       processConfig
+	sender              *sender
+	groupID             int32
+}
+
+// processConfig holds the settings used to build the messages
+type processConfig struct {
+	clusterName   string
+	maxPerMessage int
+	scrubber      *procconfig.DataScrubber
+}
+
+// StartController creates the orchestrator explorer controller and starts it
+func StartController(ctx ControllerContext) error {
+	endpoints, err := procconfig.GetOrchestratorEndpoints()
+	if err != nil {
+		return err
+	}
+	hostName, err := util.GetHostname()
+	if err != nil {
+		return err
+	}
+
+	c := newController(ctx, newSender(endpoints, hostName))
+	go c.Run(ctx.StopCh)
+
+	// Start the informers registered by the controller
+	ctx.InformerFactory.Start(ctx.StopCh)
+	return nil
+}
+
+func newController(ctx ControllerContext, s *sender) *Controller {
+	deployInformer := ctx.InformerFactory.Apps().V1().Deployments()
+	rsInformer := ctx.InformerFactory.Apps().V1().ReplicaSets()
+	serviceInformer := ctx.InformerFactory.Core().V1().Services()
+	nodeInformer := ctx.InformerFactory.Core().V1().Nodes()
+	jobInformer := ctx.InformerFactory.Batch().V1().Jobs()
+
+	maxPerMessage := config.Datadog.GetInt("process_config.max_per_message")
+	if maxPerMessage <= 0 || maxPerMessage > maxMaxPerMessage {
+		maxPerMessage = defaultMaxPerMessage
+	}
+
+	return &Controller{
+		deployLister:        deployInformer.Lister(),
+		deployListerSynced:  deployInformer.Informer().HasSynced,
+		rsLister:            rsInformer.Lister(),
+		rsListerSynced:      rsInformer.Informer().HasSynced,
+		serviceLister:       serviceInformer.Lister(),
+		serviceListerSynced: serviceInformer.Informer().HasSynced,
+		nodeLister:          nodeInformer.Lister(),
+		nodeListerSynced:    nodeInformer.Informer().HasSynced,
+		jobLister:           jobInformer.Lister(),
+		jobListerSynced:     jobInformer.Informer().HasSynced,
+		isLeaderFunc:        ctx.IsLeaderFunc,
+		processConfig: processConfig{
+			clusterName:   ctx.ClusterName,
+			maxPerMessage: maxPerMessage,
+			scrubber:      procconfig.NewDefaultDataScrubber(),
+		},
+		sender: s,
+	}
+}
+
+// Run starts the collection loop, only the leader sends the resources
+func (c *Controller) Run(stopCh <-chan struct{}) {
+	log.Infof("Starting orchestrator explorer controller")
+	defer log.Infof("Stopping orchestrator explorer controller")
+
+	if !cache.WaitForCacheSync(stopCh, c.deployListerSynced, c.rsListerSynced, c.serviceListerSynced, c.nodeListerSynced, c.jobListerSynced) {
+		log.Error("Could not sync the orchestrator explorer informers")
+		return
+	}
+
+	ticker := time.NewTicker(collectionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			if !c.isLeaderFunc() {
+				continue
+			}
+			c.collect()
+		}
+	}
+}
+
+// collect processes all the resources and sends them
+func (c *Controller) collect() {
+	// The resources are always sent in the same order
+	for _, resource := range []struct {
+		name    string
+		process func(groupID int32) ([]model.MessageBody, error)
+	}{
+		{"deployments", c.processDeployments},
+		{"replicasets", c.processReplicaSets},
+		{"services", c.processServices},
+		{"nodes", c.processNodes},
+		{"jobs", c.processJobs},
+	} {
+		start := time.Now()
+		messages, err := resource.process(atomic.AddInt32(&c.groupID, 1))
+		if err != nil {
+			log.Errorf("Unable to process %s: %v", resource.name, err)
+			continue
+		}
+		for _, m := range messages {
+			c.sender.send(orchestratorCheckPath, m)
+		}
+		log.Debugf("Collected and sent %s in %s", resource.name, time.Now().Sub(start))
+	}
+}
+
+func (c *Controller) processDeployments(groupID int32) ([]model.MessageBody, error) {
+	deploys, err := c.deployLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return processDeploymentList(deploys, groupID, c.processConfig)
+}
+
+func (c *Controller) processReplicaSets(groupID int32) ([]model.MessageBody, error) {
+	rsList, err := c.rsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return processReplicaSetList(rsList, groupID, c.processConfig)
+}
+
+func (c *Controller) processServices(groupID int32) ([]model.MessageBody, error) {
+	services, err := c.serviceLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return processServiceList(services, groupID, c.processConfig)
+}
+
+func (c *Controller) processNodes(groupID int32) ([]model.MessageBody, error) {
+	nodes, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return processNodeList(nodes, groupID, c.processConfig)
+}
+
+func (c *Controller) processJobs(groupID int32) ([]model.MessageBody, error) {
+	jobs, err := c.jobLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return processJobList(jobs, groupID, c.processConfig)
+}
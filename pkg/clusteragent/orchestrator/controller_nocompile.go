@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver,!orchestrator
+
+package orchestrator
+
+import (
+	"errors"
+
+	"k8s.io/client-go/informers"
+)
+
+// ControllerContext holds the necessary context for the controller
+type ControllerContext struct {
+	IsLeaderFunc    func() bool
+	InformerFactory informers.SharedInformerFactory
+	ClusterName     string
+	StopCh          chan struct{}
+}
+
+// StartController is not implemented when the orchestrator build tag is omitted
+func StartController(ctx ControllerContext) error {
+	return errors.New("orchestrator explorer support not compiled in")
+}
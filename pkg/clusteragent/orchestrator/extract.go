@@ -0,0 +1,304 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver,orchestrator
+
+package orchestrator
+
+import (
+	"sort"
+	"strings"
+
+	model "github.com/DataDog/agent-payload/process"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/orchestrator"
+)
+
+const (
+	// nodeRoleLabelPrefix is the prefix of the labels setting the roles of a node,
+	// e.g. node-role.kubernetes.io/master
+	nodeRoleLabelPrefix = "node-role.kubernetes.io/"
+	// nodeRoleLabel is the legacy label setting the role of a node
+	nodeRoleLabel = "kubernetes.io/role"
+)
+
+// extractDeployment extracts deployment info into the proto model
+func extractDeployment(d *appsv1.Deployment) *model.Deployment {
+	deploy := model.Deployment{
+		Metadata: orchestrator.ExtractMetadata(&d.ObjectMeta),
+	}
+	// spec
+	deploy.ReplicasDesired = 1 // default
+	if d.Spec.Replicas != nil {
+		deploy.ReplicasDesired = *d.Spec.Replicas
+	}
+	deploy.DeploymentStrategy = string(d.Spec.Strategy.Type)
+	deploy.Paused = d.Spec.Paused
+
+	// status
+	deploy.Replicas = d.Status.Replicas
+	deploy.UpdatedReplicas = d.Status.UpdatedReplicas
+	deploy.ReadyReplicas = d.Status.ReadyReplicas
+	deploy.AvailableReplicas = d.Status.AvailableReplicas
+	deploy.UnavailableReplicas = d.Status.UnavailableReplicas
+
+	return &deploy
+}
+
+// extractReplicaSet extracts replica set info into the proto model
+func extractReplicaSet(rs *appsv1.ReplicaSet) *model.ReplicaSet {
+	replicaSet := model.ReplicaSet{
+		Metadata: orchestrator.ExtractMetadata(&rs.ObjectMeta),
+	}
+	// spec
+	replicaSet.ReplicasDesired = 1 // default
+	if rs.Spec.Replicas != nil {
+		replicaSet.ReplicasDesired = *rs.Spec.Replicas
+	}
+
+	// status
+	replicaSet.Replicas = rs.Status.Replicas
+	replicaSet.FullyLabeledReplicas = rs.Status.FullyLabeledReplicas
+	replicaSet.ReadyReplicas = rs.Status.ReadyReplicas
+	replicaSet.AvailableReplicas = rs.Status.AvailableReplicas
+
+	return &replicaSet
+}
+
+// extractService extracts service info into the proto model
+func extractService(s *v1.Service) *model.Service {
+	service := model.Service{
+		Metadata: orchestrator.ExtractMetadata(&s.ObjectMeta),
+		Spec: &model.ServiceSpec{
+			ExternalIPs:              s.Spec.ExternalIPs,
+			ExternalTrafficPolicy:    string(s.Spec.ExternalTrafficPolicy),
+			PublishNotReadyAddresses: s.Spec.PublishNotReadyAddresses,
+			SessionAffinity:          string(s.Spec.SessionAffinity),
+			Type:                     string(s.Spec.Type),
+		},
+		Status: &model.ServiceStatus{},
+	}
+
+	// spec
+	if s.Spec.Type == v1.ServiceTypeExternalName {
+		service.Spec.ExternalName = s.Spec.ExternalName
+	} else {
+		service.Spec.ClusterIP = s.Spec.ClusterIP
+	}
+	if s.Spec.Type == v1.ServiceTypeLoadBalancer {
+		service.Spec.LoadBalancerIP = s.Spec.LoadBalancerIP
+		service.Spec.LoadBalancerSourceRanges = s.Spec.LoadBalancerSourceRanges
+		if s.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal {
+			service.Spec.HealthCheckNodePort = s.Spec.HealthCheckNodePort
+		}
+	}
+	for key, value := range s.Spec.Selector {
+		service.Spec.Selectors = append(service.Spec.Selectors, &model.LabelSelectorRequirement{
+			Key:      key,
+			Operator: string(metav1.LabelSelectorOpIn),
+			Values:   []string{value},
+		})
+	}
+	for _, port := range s.Spec.Ports {
+		service.Spec.Ports = append(service.Spec.Ports, &model.ServicePort{
+			Name:       port.Name,
+			Protocol:   string(port.Protocol),
+			Port:       port.Port,
+			TargetPort: port.TargetPort.String(),
+			NodePort:   port.NodePort,
+		})
+	}
+
+	// status
+	for _, ingress := range s.Status.LoadBalancer.Ingress {
+		if ingress.Hostname != "" {
+			service.Status.LoadBalancerIngress = append(service.Status.LoadBalancerIngress, ingress.Hostname)
+		} else if ingress.IP != "" {
+			service.Status.LoadBalancerIngress = append(service.Status.LoadBalancerIngress, ingress.IP)
+		}
+	}
+
+	return &service
+}
+
+// extractNode extracts node info into the proto model
+func extractNode(n *v1.Node) *model.Node {
+	node := model.Node{
+		Metadata:      orchestrator.ExtractMetadata(&n.ObjectMeta),
+		PodCIDR:       n.Spec.PodCIDR,
+		PodCIDRs:      n.Spec.PodCIDRs,
+		ProviderID:    n.Spec.ProviderID,
+		Unschedulable: n.Spec.Unschedulable,
+		Roles:         extractNodeRoles(n.Labels),
+		Status: &model.NodeStatus{
+			Capacity:                make(map[string]int64, len(n.Status.Capacity)),
+			Allocatable:             make(map[string]int64, len(n.Status.Allocatable)),
+			NodeAddresses:           make(map[string]string, len(n.Status.Addresses)),
+			Status:                  computeNodeStatus(n),
+			KubeletVersion:          n.Status.NodeInfo.KubeletVersion,
+			KubeProxyVersion:        n.Status.NodeInfo.KubeProxyVersion,
+			ContainerRuntimeVersion: n.Status.NodeInfo.ContainerRuntimeVersion,
+			KernelVersion:           n.Status.NodeInfo.KernelVersion,
+			OsImage:                 n.Status.NodeInfo.OSImage,
+			OperatingSystem:         n.Status.NodeInfo.OperatingSystem,
+			Architecture:            n.Status.NodeInfo.Architecture,
+		},
+	}
+
+	// spec
+	for _, taint := range n.Spec.Taints {
+		t := &model.Taint{
+			Key:    taint.Key,
+			Value:  taint.Value,
+			Effect: string(taint.Effect),
+		}
+		if taint.TimeAdded != nil {
+			t.TimeAdded = taint.TimeAdded.Unix()
+		}
+		node.Taints = append(node.Taints, t)
+	}
+
+	// status
+	for name, quantity := range n.Status.Capacity {
+		node.Status.Capacity[name.String()] = quantity.Value()
+	}
+	for name, quantity := range n.Status.Allocatable {
+		node.Status.Allocatable[name.String()] = quantity.Value()
+	}
+	for _, address := range n.Status.Addresses {
+		node.Status.NodeAddresses[string(address.Type)] = address.Address
+	}
+	for _, condition := range n.Status.Conditions {
+		c := &model.NodeCondition{
+			Type:    string(condition.Type),
+			Status:  string(condition.Status),
+			Reason:  condition.Reason,
+			Message: condition.Message,
+		}
+		if !condition.LastTransitionTime.IsZero() {
+			c.LastTransitionTime = condition.LastTransitionTime.Unix()
+		}
+		node.Status.Conditions = append(node.Status.Conditions, c)
+	}
+
+	return &node
+}
+
+// extractNodeRoles returns the roles of a node from its role labels
+func extractNodeRoles(labels map[string]string) []string {
+	var roles []string
+	for label, value := range labels {
+		if role := strings.TrimPrefix(label, nodeRoleLabelPrefix); role != label && role != "" {
+			roles = append(roles, role)
+		} else if label == nodeRoleLabel && value != "" {
+			roles = append(roles, value)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// computeNodeStatus returns the status of a node as kubectl displays it,
+// e.g. Ready,SchedulingDisabled
+func computeNodeStatus(n *v1.Node) string {
+	status := "Unknown"
+	for _, condition := range n.Status.Conditions {
+		if condition.Type != v1.NodeReady {
+			continue
+		}
+		if condition.Status == v1.ConditionTrue {
+			status = "Ready"
+		} else {
+			status = "NotReady"
+		}
+	}
+	if n.Spec.Unschedulable {
+		status += ",SchedulingDisabled"
+	}
+	return status
+}
+
+// extractJob extracts job info into the proto model
+func extractJob(j *batchv1.Job) *model.Job {
+	job := model.Job{
+		Metadata: orchestrator.ExtractMetadata(&j.ObjectMeta),
+		Spec:     &model.JobSpec{},
+		Status: &model.JobStatus{
+			Active:    j.Status.Active,
+			Succeeded: j.Status.Succeeded,
+			Failed:    j.Status.Failed,
+		},
+	}
+
+	// spec
+	if j.Spec.Parallelism != nil {
+		job.Spec.Parallelism = *j.Spec.Parallelism
+	}
+	if j.Spec.Completions != nil {
+		job.Spec.Completions = *j.Spec.Completions
+	}
+	if j.Spec.ActiveDeadlineSeconds != nil {
+		job.Spec.ActiveDeadlineSeconds = *j.Spec.ActiveDeadlineSeconds
+	}
+	if j.Spec.BackoffLimit != nil {
+		job.Spec.BackoffLimit = *j.Spec.BackoffLimit
+	}
+	if j.Spec.ManualSelector != nil {
+		job.Spec.ManualSelector = *j.Spec.ManualSelector
+	}
+	if j.Spec.Selector != nil {
+		job.Spec.Selectors = extractLabelSelector(j.Spec.Selector)
+	}
+
+	// status
+	if j.Status.StartTime != nil {
+		job.Status.StartTime = j.Status.StartTime.Unix()
+	}
+	if j.Status.CompletionTime != nil {
+		job.Status.CompletionTime = j.Status.CompletionTime.Unix()
+	}
+	job.Status.ConditionMessage = extractJobConditionMessage(j.Status.Conditions)
+
+	return &job
+}
+
+// extractJobConditionMessage returns the message of the condition explaining
+// why a job failed, if it did
+func extractJobConditionMessage(conditions []batchv1.JobCondition) string {
+	for _, condition := range conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == v1.ConditionTrue {
+			if condition.Message != "" {
+				return condition.Message
+			}
+			return condition.Reason
+		}
+	}
+	return ""
+}
+
+// extractLabelSelector converts a label selector into the proto model, its
+// matchLabels becoming In requirements
+func extractLabelSelector(ls *metav1.LabelSelector) []*model.LabelSelectorRequirement {
+	requirements := make([]*model.LabelSelectorRequirement, 0, len(ls.MatchLabels)+len(ls.MatchExpressions))
+	for key, value := range ls.MatchLabels {
+		requirements = append(requirements, &model.LabelSelectorRequirement{
+			Key:      key,
+			Operator: string(metav1.LabelSelectorOpIn),
+			Values:   []string{value},
+		})
+	}
+	for _, expression := range ls.MatchExpressions {
+		requirements = append(requirements, &model.LabelSelectorRequirement{
+			Key:      expression.Key,
+			Operator: string(expression.Operator),
+			Values:   expression.Values,
+		})
+	}
+	return requirements
+}
@@ -0,0 +1,196 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver,orchestrator
+
+package orchestrator
+
+import (
+	model "github.com/DataDog/agent-payload/process"
+	"gopkg.in/yaml.v2"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/orchestrator"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// processDeploymentList extracts, scrubs and chunks the deployments
+func processDeploymentList(deploys []*appsv1.Deployment, groupID int32, cfg processConfig) ([]model.MessageBody, error) {
+	deployMsgs := make([]*model.Deployment, 0, len(deploys))
+	for d := 0; d < len(deploys); d++ {
+		// objects from the listers are shared with the informer cache
+		deploy := deploys[d].DeepCopy()
+		orchestrator.RemoveLastAppliedConfigurationAnnotation(&deploy.ObjectMeta)
+		deployModel := extractDeployment(deploy)
+
+		// scrub & generate YAML
+		orchestrator.ScrubPodTemplateSpec(&deploy.Spec.Template, cfg.scrubber)
+		yamlDeploy, err := yaml.Marshal(deploy)
+		if err != nil {
+			log.Debugf("Could not marshal deployment %s/%s: %s", deploy.Namespace, deploy.Name, err)
+			continue
+		}
+		deployModel.Yaml = yamlDeploy
+
+		deployMsgs = append(deployMsgs, deployModel)
+	}
+
+	groupSize := orchestrator.GroupSize(len(deployMsgs), cfg.maxPerMessage)
+	messages := make([]model.MessageBody, 0, groupSize)
+	for i := 0; i < groupSize; i++ {
+		start, end := orchestrator.ChunkRange(len(deployMsgs), cfg.maxPerMessage, i)
+		messages = append(messages, &model.CollectorDeployment{
+			ClusterName: cfg.clusterName,
+			Deployments: deployMsgs[start:end],
+			GroupId:     groupID,
+			GroupSize:   int32(groupSize),
+		})
+	}
+
+	log.Debugf("Collected %d deployments", len(deployMsgs))
+	return messages, nil
+}
+
+// processReplicaSetList extracts, scrubs and chunks the replica sets
+func processReplicaSetList(rsList []*appsv1.ReplicaSet, groupID int32, cfg processConfig) ([]model.MessageBody, error) {
+	rsMsgs := make([]*model.ReplicaSet, 0, len(rsList))
+	for r := 0; r < len(rsList); r++ {
+		rs := rsList[r].DeepCopy()
+		orchestrator.RemoveLastAppliedConfigurationAnnotation(&rs.ObjectMeta)
+		rsModel := extractReplicaSet(rs)
+
+		// scrub & generate YAML
+		orchestrator.ScrubPodTemplateSpec(&rs.Spec.Template, cfg.scrubber)
+		yamlRs, err := yaml.Marshal(rs)
+		if err != nil {
+			log.Debugf("Could not marshal replica set %s/%s: %s", rs.Namespace, rs.Name, err)
+			continue
+		}
+		rsModel.Yaml = yamlRs
+
+		rsMsgs = append(rsMsgs, rsModel)
+	}
+
+	groupSize := orchestrator.GroupSize(len(rsMsgs), cfg.maxPerMessage)
+	messages := make([]model.MessageBody, 0, groupSize)
+	for i := 0; i < groupSize; i++ {
+		start, end := orchestrator.ChunkRange(len(rsMsgs), cfg.maxPerMessage, i)
+		messages = append(messages, &model.CollectorReplicaSet{
+			ClusterName: cfg.clusterName,
+			ReplicaSets: rsMsgs[start:end],
+			GroupId:     groupID,
+			GroupSize:   int32(groupSize),
+		})
+	}
+
+	log.Debugf("Collected %d replica sets", len(rsMsgs))
+	return messages, nil
+}
+
+// processServiceList extracts, scrubs and chunks the services
+func processServiceList(services []*v1.Service, groupID int32, cfg processConfig) ([]model.MessageBody, error) {
+	serviceMsgs := make([]*model.Service, 0, len(services))
+	for s := 0; s < len(services); s++ {
+		service := services[s].DeepCopy()
+		orchestrator.RemoveLastAppliedConfigurationAnnotation(&service.ObjectMeta)
+		serviceModel := extractService(service)
+
+		yamlService, err := yaml.Marshal(service)
+		if err != nil {
+			log.Debugf("Could not marshal service %s/%s: %s", service.Namespace, service.Name, err)
+			continue
+		}
+		serviceModel.Yaml = yamlService
+
+		serviceMsgs = append(serviceMsgs, serviceModel)
+	}
+
+	groupSize := orchestrator.GroupSize(len(serviceMsgs), cfg.maxPerMessage)
+	messages := make([]model.MessageBody, 0, groupSize)
+	for i := 0; i < groupSize; i++ {
+		start, end := orchestrator.ChunkRange(len(serviceMsgs), cfg.maxPerMessage, i)
+		messages = append(messages, &model.CollectorService{
+			ClusterName: cfg.clusterName,
+			Services:    serviceMsgs[start:end],
+			GroupId:     groupID,
+			GroupSize:   int32(groupSize),
+		})
+	}
+
+	log.Debugf("Collected %d services", len(serviceMsgs))
+	return messages, nil
+}
+
+// processNodeList extracts, scrubs and chunks the nodes
+func processNodeList(nodes []*v1.Node, groupID int32, cfg processConfig) ([]model.MessageBody, error) {
+	nodeMsgs := make([]*model.Node, 0, len(nodes))
+	for n := 0; n < len(nodes); n++ {
+		node := nodes[n].DeepCopy()
+		orchestrator.RemoveLastAppliedConfigurationAnnotation(&node.ObjectMeta)
+		nodeModel := extractNode(node)
+
+		yamlNode, err := yaml.Marshal(node)
+		if err != nil {
+			log.Debugf("Could not marshal node %s: %s", node.Name, err)
+			continue
+		}
+		nodeModel.Yaml = yamlNode
+
+		nodeMsgs = append(nodeMsgs, nodeModel)
+	}
+
+	groupSize := orchestrator.GroupSize(len(nodeMsgs), cfg.maxPerMessage)
+	messages := make([]model.MessageBody, 0, groupSize)
+	for i := 0; i < groupSize; i++ {
+		start, end := orchestrator.ChunkRange(len(nodeMsgs), cfg.maxPerMessage, i)
+		messages = append(messages, &model.CollectorNode{
+			ClusterName: cfg.clusterName,
+			Nodes:       nodeMsgs[start:end],
+			GroupId:     groupID,
+			GroupSize:   int32(groupSize),
+		})
+	}
+
+	log.Debugf("Collected %d nodes", len(nodeMsgs))
+	return messages, nil
+}
+
+// processJobList extracts, scrubs and chunks the jobs
+func processJobList(jobs []*batchv1.Job, groupID int32, cfg processConfig) ([]model.MessageBody, error) {
+	jobMsgs := make([]*model.Job, 0, len(jobs))
+	for j := 0; j < len(jobs); j++ {
+		job := jobs[j].DeepCopy()
+		orchestrator.RemoveLastAppliedConfigurationAnnotation(&job.ObjectMeta)
+		jobModel := extractJob(job)
+
+		// scrub & generate YAML
+		orchestrator.ScrubPodTemplateSpec(&job.Spec.Template, cfg.scrubber)
+		yamlJob, err := yaml.Marshal(job)
+		if err != nil {
+			log.Debugf("Could not marshal job %s/%s: %s", job.Namespace, job.Name, err)
+			continue
+		}
+		jobModel.Yaml = yamlJob
+
+		jobMsgs = append(jobMsgs, jobModel)
+	}
+
+	groupSize := orchestrator.GroupSize(len(jobMsgs), cfg.maxPerMessage)
+	messages := make([]model.MessageBody, 0, groupSize)
+	for i := 0; i < groupSize; i++ {
+		start, end := orchestrator.ChunkRange(len(jobMsgs), cfg.maxPerMessage, i)
+		messages = append(messages, &model.CollectorJob{
+			ClusterName: cfg.clusterName,
+			Jobs:        jobMsgs[start:end],
+			GroupId:     groupID,
+			GroupSize:   int32(groupSize),
+		})
+	}
+
+	log.Debugf("Collected %d jobs", len(jobMsgs))
+	return messages, nil
+}
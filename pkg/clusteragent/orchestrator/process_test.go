@@ -0,0 +1,244 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver,orchestrator
+
+package orchestrator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	model "github.com/DataDog/agent-payload/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	procconfig "github.com/DataDog/datadog-agent/pkg/process/config"
+)
+
+func TestExtractDeployment(t *testing.T) {
+	replicas := int32(3)
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "prod",
+			UID:       "e42e5adc-0749-11e8-a2b8-000c29dea4f6",
+			Labels:    map[string]string{"app": "web"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType},
+		},
+		Status: appsv1.DeploymentStatus{
+			Replicas:            3,
+			UpdatedReplicas:     3,
+			ReadyReplicas:       2,
+			AvailableReplicas:   2,
+			UnavailableReplicas: 1,
+		},
+	}
+
+	assert.Equal(t, &model.Deployment{
+		Metadata: &model.Metadata{
+			Name:      "web",
+			Namespace: "prod",
+			Uid:       "e42e5adc-0749-11e8-a2b8-000c29dea4f6",
+			Labels:    []string{"app:web"},
+		},
+		ReplicasDesired:     3,
+		DeploymentStrategy:  "RollingUpdate",
+		Replicas:            3,
+		UpdatedReplicas:     3,
+		ReadyReplicas:       2,
+		AvailableReplicas:   2,
+		UnavailableReplicas: 1,
+	}, extractDeployment(deploy))
+
+	// replicas default to 1 when unset
+	deploy.Spec.Replicas = nil
+	assert.Equal(t, int32(1), extractDeployment(deploy).ReplicasDesired)
+}
+
+func TestProcessReplicaSetList(t *testing.T) {
+	cfg := processConfig{
+		clusterName:   "test-cluster",
+		maxPerMessage: 2,
+		scrubber:      procconfig.NewDefaultDataScrubber(),
+	}
+
+	var rsList []*appsv1.ReplicaSet
+	for i := 0; i < 5; i++ {
+		rsList = append(rsList, &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("rs-%d", i),
+				Namespace: "default",
+				Annotations: map[string]string{
+					"kubectl.kubernetes.io/last-applied-configuration": `{"spec":{"template":{"spec":{"containers":[{"command":["mysql","--password","afztyerbzio1234"]}]}}}}`,
+				},
+			},
+			Spec: appsv1.ReplicaSetSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{{
+							Name:    "db",
+							Command: []string{"mysql", "--password", "afztyerbzio1234"},
+						}},
+					},
+				},
+			},
+		})
+	}
+
+	messages, err := processReplicaSetList(rsList, 42, cfg)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+
+	total := 0
+	for _, m := range messages {
+		collector, ok := m.(*model.CollectorReplicaSet)
+		require.True(t, ok)
+		assert.Equal(t, "test-cluster", collector.ClusterName)
+		assert.Equal(t, int32(42), collector.GroupId)
+		assert.Equal(t, int32(3), collector.GroupSize)
+		for _, rs := range collector.ReplicaSets {
+			assert.Contains(t, string(rs.Yaml), "********")
+			assert.NotContains(t, string(rs.Yaml), "afztyerbzio1234")
+			assert.NotContains(t, string(rs.Yaml), "last-applied-configuration")
+			assert.Empty(t, rs.Metadata.Annotations)
+		}
+		total += len(collector.ReplicaSets)
+	}
+	assert.Equal(t, 5, total)
+
+	// objects coming from the informer cache must not be modified
+	assert.Equal(t, "afztyerbzio1234", rsList[0].Spec.Template.Spec.Containers[0].Command[2])
+	assert.Len(t, rsList[0].Annotations, 1)
+}
+
+func TestExtractService(t *testing.T) {
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default"},
+		Spec: v1.ServiceSpec{
+			Type:                  v1.ServiceTypeLoadBalancer,
+			ClusterIP:             "10.0.0.1",
+			ExternalTrafficPolicy: v1.ServiceExternalTrafficPolicyTypeLocal,
+			HealthCheckNodePort:   31000,
+			Selector:              map[string]string{"app": "redis"},
+			Ports: []v1.ServicePort{
+				{Name: "redis", Protocol: v1.ProtocolTCP, Port: 6379, TargetPort: intstr.FromString("redis"), NodePort: 30379},
+			},
+		},
+		Status: v1.ServiceStatus{
+			LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "1.2.3.4"}}},
+		},
+	}
+
+	assert.Equal(t, &model.Service{
+		Metadata: &model.Metadata{Name: "redis", Namespace: "default"},
+		Spec: &model.ServiceSpec{
+			Type:                  "LoadBalancer",
+			ClusterIP:             "10.0.0.1",
+			ExternalTrafficPolicy: "Local",
+			HealthCheckNodePort:   31000,
+			Selectors:             []*model.LabelSelectorRequirement{{Key: "app", Operator: "In", Values: []string{"redis"}}},
+			Ports: []*model.ServicePort{
+				{Name: "redis", Protocol: "TCP", Port: 6379, TargetPort: "redis", NodePort: 30379},
+			},
+		},
+		Status: &model.ServiceStatus{LoadBalancerIngress: []string{"1.2.3.4"}},
+	}, extractService(service))
+}
+
+func TestExtractNode(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-1",
+			Labels: map[string]string{"node-role.kubernetes.io/master": "", "kubernetes.io/role": "etcd"},
+		},
+		Spec: v1.NodeSpec{
+			PodCIDR:       "10.1.0.0/24",
+			Unschedulable: true,
+			Taints:        []v1.Taint{{Key: "dedicated", Value: "db", Effect: v1.TaintEffectNoSchedule}},
+		},
+		Status: v1.NodeStatus{
+			Capacity: v1.ResourceList{
+				v1.ResourceCPU:  resource.MustParse("4"),
+				v1.ResourcePods: resource.MustParse("110"),
+			},
+			Allocatable: v1.ResourceList{
+				v1.ResourcePods: resource.MustParse("100"),
+			},
+			Addresses:  []v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "10.0.0.10"}},
+			Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue, Reason: "KubeletReady"}},
+			NodeInfo:   v1.NodeSystemInfo{KubeletVersion: "v1.17.3", OperatingSystem: "linux", Architecture: "amd64"},
+		},
+	}
+
+	nodeModel := extractNode(node)
+	assert.ElementsMatch(t, []string{"node-role.kubernetes.io/master:", "kubernetes.io/role:etcd"}, nodeModel.Metadata.Labels)
+	nodeModel.Metadata.Labels = nil
+
+	assert.Equal(t, &model.Node{
+		Metadata:      &model.Metadata{Name: "node-1"},
+		PodCIDR:       "10.1.0.0/24",
+		Unschedulable: true,
+		Roles:         []string{"etcd", "master"},
+		Taints:        []*model.Taint{{Key: "dedicated", Value: "db", Effect: "NoSchedule"}},
+		Status: &model.NodeStatus{
+			Capacity:        map[string]int64{"cpu": 4, "pods": 110},
+			Allocatable:     map[string]int64{"pods": 100},
+			NodeAddresses:   map[string]string{"InternalIP": "10.0.0.10"},
+			Status:          "Ready,SchedulingDisabled",
+			Conditions:      []*model.NodeCondition{{Type: "Ready", Status: "True", Reason: "KubeletReady"}},
+			KubeletVersion:  "v1.17.3",
+			OperatingSystem: "linux",
+			Architecture:    "amd64",
+		},
+	}, nodeModel)
+}
+
+func TestExtractJob(t *testing.T) {
+	completions := int32(3)
+	backoffLimit := int32(6)
+	start := metav1.NewTime(time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC))
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+		Spec: batchv1.JobSpec{
+			Completions:  &completions,
+			BackoffLimit: &backoffLimit,
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": "backup"}},
+		},
+		Status: batchv1.JobStatus{
+			StartTime: &start,
+			Succeeded: 1,
+			Failed:    6,
+			Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit"},
+			},
+		},
+	}
+
+	assert.Equal(t, &model.Job{
+		Metadata: &model.Metadata{Name: "backup", Namespace: "default"},
+		Spec: &model.JobSpec{
+			Completions:  3,
+			BackoffLimit: 6,
+			Selectors:    []*model.LabelSelectorRequirement{{Key: "job-name", Operator: "In", Values: []string{"backup"}}},
+		},
+		Status: &model.JobStatus{
+			StartTime:        start.Unix(),
+			Succeeded:        1,
+			Failed:           6,
+			ConditionMessage: "Job has reached the specified backoff limit",
+		},
+	}, extractJob(job))
+}
@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver,orchestrator
+
+package orchestrator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	model "github.com/DataDog/agent-payload/process"
+
+	procconfig "github.com/DataDog/datadog-agent/pkg/process/config"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+const httpTimeout = 20 * time.Second
+
+// sender posts the orchestrator messages to all the configured endpoints
+type sender struct {
+	endpoints  []procconfig.APIEndpoint
+	hostName   string
+	httpClient http.Client
+}
+
+func newSender(endpoints []procconfig.APIEndpoint, hostName string) *sender {
+	return &sender{
+		endpoints:  endpoints,
+		hostName:   hostName,
+		httpClient: http.Client{Timeout: httpTimeout, Transport: httputils.CreateHTTPTransport()},
+	}
+}
+
+// send encodes the message and posts it to every endpoint, errors are logged
+func (s *sender) send(checkPath string, m model.MessageBody) {
+	msgType, err := model.DetectMessageType(m)
+	if err != nil {
+		log.Errorf("Unable to detect message type: %s", err)
+		return
+	}
+
+	body, err := model.EncodeMessage(model.Message{
+		Header: model.MessageHeader{
+			Version:  model.MessageV3,
+			Encoding: model.MessageEncodingZstdPB,
+			Type:     msgType,
+		}, Body: m})
+	if err != nil {
+		log.Errorf("Unable to encode message: %s", err)
+		return
+	}
+
+	for _, endpoint := range s.endpoints {
+		if err := s.post(endpoint, checkPath, body); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+func (s *sender) post(endpoint procconfig.APIEndpoint, checkPath string, body []byte) error {
+	url := endpoint.GetCheckURL(checkPath)
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not create request to %s: %s", url, err)
+	}
+
+	req.Header.Add("X-Dd-APIKey", endpoint.APIKey)
+	req.Header.Add("X-Dd-Hostname", s.hostName)
+	req.Header.Add("X-Dd-Processagentversion", version.AgentVersion)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error submitting payload to %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body) //nolint:errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode > 300 {
+		return fmt.Errorf("unexpected response from %s. Status: %s", url, resp.Status)
+	}
+	return nil
+}
@@ -5,9 +5,15 @@
 
 package clusteragent
 
-import "github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
+import (
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/clusterchecks"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/externalseries"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/metadatastream"
+)
 
 // ServerContext holds business logic classes required to setup API endpoints
 type ServerContext struct {
 	ClusterCheckHandler *clusterchecks.Handler
+	MetadataStream      *metadatastream.Store
+	ExternalSeries      *externalseries.Store
 }
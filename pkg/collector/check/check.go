@@ -25,3 +25,16 @@ type Check interface {
 	ConfigSource() string                                               // return the configuration source of the check
 	IsTelemetryEnabled() bool                                           // return if telemetry is enabled for this check
 }
+
+// SchedulingConfigProvider is implemented by checks whose instance can hold
+// options controlling when the scheduler runs them
+type SchedulingConfigProvider interface {
+	SchedulingConfig() integration.SchedulingConfig
+}
+
+// Canceler is implemented by checks holding resources between their runs, such
+// as a process. Cancel releases them when the check is unscheduled, whether it
+// is running or not, the check is not run again afterwards
+type Canceler interface {
+	Cancel()
+}
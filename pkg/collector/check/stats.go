@@ -42,17 +42,27 @@ type Stats struct {
 	TotalMetricSamples   int64
 	TotalEvents          int64
 	TotalServiceChecks   int64
-	ExecutionTimes       [32]int64 // circular buffer of recent run durations, most recent at [(TotalRuns+31) % 32]
-	AverageExecutionTime int64     // average run duration
-	LastExecutionTime    int64     // most recent run duration, provided for convenience
-	LastSuccessDate      int64     // most recent successful execution date, unix timestamp in seconds
-	LastError            string    // error that occurred in the last run, if any
-	LastWarnings         []string  // warnings that occurred in the last run, if any
-	UpdateTimestamp      int64     // latest update to this instance, unix timestamp in seconds
+	ExecutionTimes       [32]int64        // circular buffer of recent run durations, most recent at [(TotalRuns+31) % 32]
+	AverageExecutionTime int64            // average run duration
+	LastExecutionTime    int64            // most recent run duration, provided for convenience
+	LastSuccessDate      int64            // most recent successful execution date, unix timestamp in seconds
+	LastError            string           // error that occurred in the last run, if any
+	LastWarnings         []string         // warnings that occurred in the last run, if any
+	UpdateTimestamp      int64            // latest update to this instance, unix timestamp in seconds
+	ExtraStats           map[string]int64 // check-specific statistics, see ExtraStatsProvider
+	Health               string           // healthy, degraded, failing or quarantined
+	ConsecutiveErrors    uint64           // number of errors since the last successful run
+	NextRunDate          int64            // date until which the runs of a failing check are skipped, unix timestamp in seconds
 	m                    sync.Mutex
 	telemetry            bool // do we want telemetry on this Check
 }
 
+// ExtraStatsProvider is implemented by checks reporting runtime statistics specific
+// to how they run, such as the number of times an external check process restarted.
+type ExtraStatsProvider interface {
+	ExtraStats() map[string]int64
+}
+
 // NewStats returns a new check stats instance
 func NewStats(c Check) *Stats {
 	return &Stats{
@@ -139,3 +149,22 @@ func (cs *Stats) Add(t time.Duration, err error, warnings []error, metricStats m
 		}
 	}
 }
+
+// SetExtraStats replaces the check-specific statistics.
+func (cs *Stats) SetExtraStats(stats map[string]int64) {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	cs.ExtraStats = stats
+}
+
+// SetHealth records the health of the check, as tracked by the runner.
+func (cs *Stats) SetHealth(health string, consecutiveErrors uint64, nextRun time.Time) {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	cs.Health = health
+	cs.ConsecutiveErrors = consecutiveErrors
+	cs.NextRunDate = 0
+	if !nextRun.IsZero() {
+		cs.NextRunDate = nextRun.Unix()
+	}
+}
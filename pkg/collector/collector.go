@@ -82,6 +82,9 @@ func (c *Collector) Stop() {
 	c.scheduler = nil
 	c.runner.Stop()
 	c.runner = nil
+	for _, ch := range c.checks {
+		cancel(ch)
+	}
 	pyTeardown()
 	c.state = stopped
 }
@@ -184,6 +187,11 @@ func (c *Collector) StopCheck(id check.ID) error {
 		return fmt.Errorf("an error occurred while stopping the check: %s", err)
 	}
 
+	// release what the check holds between its runs, the runner only stops running checks
+	c.m.RLock()
+	cancel(c.checks[id])
+	c.m.RUnlock()
+
 	// remove the check from the stats map
 	runner.RemoveCheckStats(id)
 
@@ -193,6 +201,13 @@ func (c *Collector) StopCheck(id check.ID) error {
 	return nil
 }
 
+// cancel calls the Cancel method of the checks implementing check.Canceler
+func cancel(ch check.Check) {
+	if canceler, ok := ch.(check.Canceler); ok {
+		canceler.Cancel()
+	}
+}
+
 // check if the check is on the list
 func (c *Collector) find(id check.ID) bool {
 	c.m.RLock()
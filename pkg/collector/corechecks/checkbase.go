@@ -19,6 +19,12 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
+// sharedInstanceConfig holds the instance fields letting core check
+// instances share the results of their queries
+type sharedInstanceConfig struct {
+	SharedCacheTTL int `yaml:"shared_cache_ttl"` // in seconds
+}
+
 // CheckBase provides default implementations for most of the check.Check
 // interface to make it easier to bootstrap a new corecheck.
 //
@@ -37,11 +43,17 @@ import (
 //
 // If custom tags are set in the instance configuration, they will
 // be automatically appended to each send done by this check.
+//
+// Instances setting `shared_cache_ttl` share the results of the
+// queries made through FetchShared with the other instances querying
+// the same endpoint.
 type CheckBase struct {
 	checkName      string
 	checkID        check.ID
 	latestWarnings []error
 	checkInterval  time.Duration
+	scheduling     integration.SchedulingConfig
+	sharedCacheTTL time.Duration
 	source         string
 	telemetry      bool
 }
@@ -87,6 +99,20 @@ func (c *CheckBase) CommonConfigure(instance integration.Data, source string) er
 	if commonOptions.MinCollectionInterval > 0 {
 		c.checkInterval = time.Duration(commonOptions.MinCollectionInterval) * time.Second
 	}
+	c.scheduling = commonOptions.Scheduling
+
+	sharedOptions := sharedInstanceConfig{}
+	if err := yaml.Unmarshal(instance, &sharedOptions); err != nil {
+		log.Errorf("invalid instance section for check %s: %s", string(c.ID()), err)
+		return err
+	}
+	c.sharedCacheTTL = time.Duration(sharedOptions.SharedCacheTTL) * time.Second
+	// An instance must not reuse the result it fetched during its previous run,
+	// its rates would be 0, so the ttl is kept below half of the interval
+	if maxTTL := c.checkInterval / 2; c.checkInterval > 0 && c.sharedCacheTTL > maxTTL {
+		log.Warnf("shared_cache_ttl of check %s is capped to %s, half of its collection interval", string(c.ID()), maxTTL)
+		c.sharedCacheTTL = maxTTL
+	}
 
 	// Disable default hostname if specified
 	if commonOptions.EmptyDefaultHostname {
@@ -112,6 +138,21 @@ func (c *CheckBase) CommonConfigure(instance integration.Data, source string) er
 	return nil
 }
 
+// SchedulingConfig returns the scheduling options of the check instance.
+func (c *CheckBase) SchedulingConfig() integration.SchedulingConfig {
+	return c.scheduling
+}
+
+// FetchShared returns the result of fetch, running the given query on the endpoint.
+// If the instance sets `shared_cache_ttl`, the result is shared with the other instances
+// making the same query for that many seconds.
+func (c *CheckBase) FetchShared(endpoint, query string, fetch func() (interface{}, error)) (interface{}, error) {
+	if c.sharedCacheTTL <= 0 {
+		return fetch()
+	}
+	return sharedResults.get(endpoint, query, c.sharedCacheTTL, fetch)
+}
+
 // Warn sends an integration warning to logs + agent status.
 func (c *CheckBase) Warn(v ...interface{}) error {
 	w := log.Warn(v...)
@@ -8,6 +8,7 @@
 package cluster
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -22,6 +23,8 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/logs"
+	logsConfig "github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver/leaderelection"
@@ -37,6 +40,7 @@ const (
 	maxEventCardinality           = 300
 	defaultResyncPeriodInSecond   = 300
 	defaultTimeoutEventCollection = 2000
+	eventLogsBufferSize           = 1000
 )
 
 // KubeASConfig is the config of the API server.
@@ -48,6 +52,12 @@ type KubeASConfig struct {
 	MaxEventCollection       int      `yaml:"max_events_per_run"`
 	LeaderSkip               bool     `yaml:"skip_leader_election"`
 	ResyncPeriodEvents       int      `yaml:"kubernetes_event_resync_period_s"`
+
+	IncludeEvents       []eventFilterRule    `yaml:"include_events"`
+	ExcludeEvents       []eventFilterRule    `yaml:"exclude_events"`
+	EventDedupWindow    int                  `yaml:"event_dedup_window_s"`
+	EventAlertTypes     []eventAlertTypeRule `yaml:"event_alert_types"`
+	ForwardEventsAsLogs bool                 `yaml:"forward_events_as_logs"`
 }
 
 // EventC holds the information pertaining to which event we collected last and when we last re-synced.
@@ -63,6 +73,10 @@ type KubeASCheck struct {
 	KubeAPIServerHostname string
 	eventCollection       EventC
 	ignoredEvents         string
+	eventFilter           *eventFilter
+	alertTypes            alertTypeMapper
+	eventLogs             chan string
+	eventLogsSource       *logsConfig.LogSource
 	ac                    *apiserver.APIClient
 	oshiftAPILevel        apiserver.OpenShiftAPILevel
 }
@@ -97,9 +111,55 @@ func (k *KubeASCheck) Configure(config, initConfig integration.Data, source stri
 		k.instance.MaxEventCollection = maxEventCardinality
 	}
 	k.ignoredEvents = convertFilter(k.instance.FilteredEventTypes)
+
+	k.eventFilter, err = newEventFilter(k.instance)
+	if err != nil {
+		return err
+	}
+	k.alertTypes, err = newAlertTypeMapper(k.instance.EventAlertTypes)
+	if err != nil {
+		return err
+	}
+
+	if k.instance.CollectEvent && k.instance.ForwardEventsAsLogs {
+		k.setupEventLogs()
+	}
 	return nil
 }
 
+// setupEventLogs registers, once per check instance, a logs source to forward
+// the events to the logs pipeline. Events are not forwarded when the logs agent
+// is not running.
+func (k *KubeASCheck) setupEventLogs() {
+	if k.eventLogsSource != nil {
+		return
+	}
+	scheduler := logs.GetScheduler()
+	if scheduler == nil {
+		log.Warn("The logs agent is not running, Kubernetes events won't be forwarded as logs")
+		return
+	}
+	k.eventLogs = make(chan string, eventLogsBufferSize)
+	k.eventLogsSource = logsConfig.NewLogSource(kubernetesAPIServerCheckName, &logsConfig.LogsConfig{
+		Type:    logsConfig.StringChannelType,
+		Source:  "kubernetes",
+		Service: kubernetesAPIServerCheckName,
+		Channel: k.eventLogs,
+	})
+	scheduler.AddSource(k.eventLogsSource)
+}
+
+// Cancel removes the logs source of the events when the check is unscheduled
+func (k *KubeASCheck) Cancel() {
+	if k.eventLogsSource == nil {
+		return
+	}
+	if scheduler := logs.GetScheduler(); scheduler != nil {
+		scheduler.RemoveSource(k.eventLogsSource)
+	}
+	k.eventLogsSource = nil
+}
+
 func convertFilter(conf []string) string {
 	var formatedFilters []string
 	for _, filter := range conf {
@@ -132,7 +192,7 @@ func (k *KubeASCheck) Run() error {
 		if !config.Datadog.GetBool("leader_election") {
 			return log.Error("Leader Election not enabled. Not running Kubernetes API Server check or collecting Kubernetes Events.")
 		}
-		errLeader := k.runLeaderElection()
+		errLeader := runLeaderElection(&k.CheckBase)
 		if errLeader != nil {
 			if errLeader == apiserver.ErrNotLeader {
 				// Only the leader can instantiate the apiserver client.
@@ -157,11 +217,13 @@ func (k *KubeASCheck) Run() error {
 	}
 
 	// Running the Control Plane status check.
-	componentsStatus, err := k.ac.ComponentStatuses()
+	componentsStatus, err := k.FetchShared("apiserver", "componentstatuses", func() (interface{}, error) {
+		return k.ac.ComponentStatuses()
+	})
 	if err != nil {
 		k.Warnf("Could not retrieve the status from the control plane's components %s", err.Error())
 	} else {
-		err = k.parseComponentStatus(sender, componentsStatus)
+		err = k.parseComponentStatus(sender, componentsStatus.(*v1.ComponentStatusList))
 		if err != nil {
 			k.Warnf("Could not collect API Server component status: %s", err.Error())
 		}
@@ -188,6 +250,13 @@ func (k *KubeASCheck) Run() error {
 		return err
 	}
 
+	if k.eventFilter != nil {
+		events = k.eventFilter.filter(events, time.Now())
+	}
+	if k.eventLogs != nil {
+		k.forwardEventLogs(events)
+	}
+
 	// Process the events to have a Datadog format.
 	err = k.processEvents(sender, events)
 	if err != nil {
@@ -204,17 +273,19 @@ func KubernetesASFactory() check.Check {
 	}
 }
 
-func (k *KubeASCheck) runLeaderElection() error {
+// runLeaderElection returns apiserver.ErrNotLeader if this agent is not the
+// leader, in which case cluster level checks must not run.
+func runLeaderElection(c *core.CheckBase) error {
 
 	leaderEngine, err := leaderelection.GetLeaderEngine()
 	if err != nil {
-		k.Warn("Failed to instantiate the Leader Elector. Not running the Kubernetes API Server check or collecting Kubernetes Events.")
+		c.Warnf("Failed to instantiate the Leader Elector. Not running the %s check.", c.String())
 		return err
 	}
 
 	err = leaderEngine.EnsureLeaderElectionRuns()
 	if err != nil {
-		k.Warn("Leader Election process failed to start")
+		c.Warn("Leader Election process failed to start")
 		return err
 	}
 
@@ -306,7 +377,9 @@ func (k *KubeASCheck) processEvents(sender aggregator.Sender, events []*v1.Event
 		err := bundle.addEvent(event)
 		if err != nil {
 			k.Warnf("Error while bundling events, %s.", err.Error())
+			continue
 		}
+		bundle.setAlertType(k.alertTypes.alertType(event))
 	}
 	clusterName := clustername.GetClusterName()
 	for _, bundle := range eventsByObject {
@@ -320,6 +393,75 @@ func (k *KubeASCheck) processEvents(sender aggregator.Sender, events []*v1.Event
 	return nil
 }
 
+// kubernetesEventLog is the format of the events forwarded as logs
+type kubernetesEventLog struct {
+	Timestamp       int64  `json:"timestamp,omitempty"`
+	Status          string `json:"status"`
+	Message         string `json:"message"`
+	Reason          string `json:"reason"`
+	Type            string `json:"type"`
+	Count           int32  `json:"count"`
+	Kind            string `json:"kind"`
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	SourceComponent string `json:"source_component,omitempty"`
+	SourceHost      string `json:"source_host,omitempty"`
+}
+
+// forwardEventLogs sends the events to the logs pipeline, without blocking
+// the check when the pipeline can't keep up.
+func (k *KubeASCheck) forwardEventLogs(events []*v1.Event) {
+	dropped := 0
+	for _, event := range events {
+		status := "info"
+		if event.Type == v1.EventTypeWarning {
+			status = "warning"
+		}
+		var timestamp int64
+		if t := eventTimestamp(event); !t.IsZero() {
+			timestamp = t.UnixNano() / int64(time.Millisecond)
+		}
+		content, err := json.Marshal(kubernetesEventLog{
+			Timestamp:       timestamp,
+			Status:          status,
+			Message:         event.Message,
+			Reason:          event.Reason,
+			Type:            event.Type,
+			Count:           event.Count,
+			Kind:            event.InvolvedObject.Kind,
+			Name:            event.InvolvedObject.Name,
+			Namespace:       event.InvolvedObject.Namespace,
+			SourceComponent: event.Source.Component,
+			SourceHost:      event.Source.Host,
+		})
+		if err != nil {
+			log.Debugf("Could not serialize the event %s/%s: %v", event.Namespace, event.Name, err)
+			continue
+		}
+		select {
+		case k.eventLogs <- string(content):
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		k.Warnf("The logs pipeline is full, %d Kubernetes events were not forwarded as logs", dropped)
+	}
+}
+
+// eventTimestamp returns when an event last occurred. LastTimestamp is not set
+// by the events API, EventTime is used instead, or FirstTimestamp.
+func eventTimestamp(event *v1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.FirstTimestamp.Time
+	}
+}
+
 func init() {
 	core.RegisterCheck(kubernetesAPIServerCheckName, KubernetesASFactory)
 }
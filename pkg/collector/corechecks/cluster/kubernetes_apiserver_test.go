@@ -302,3 +302,142 @@ func TestConvertFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestEventFilter(t *testing.T) {
+	scheduled := createEvent(1, "default", "dca-789976f5d7-2ljx6", "Pod", "e6417a7f-f566-11e7-9749-0e4863e1cbf4", "default-scheduler", "machine-blue", "Scheduled", "Successfully assigned dca-789976f5d7-2ljx6 to ip-10-0-0-54", 709662600)
+	backOff := createEvent(5, "default", "dca-789976f5d7-2ljx6", "Pod", "e6417a7f-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "BackOff", "Back-off restarting failed container", 709662600)
+	systemBackOff := createEvent(5, "kube-system", "kube-dns-5877696fb4-m6cvp", "Pod", "e6418fe2-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "BackOff", "Back-off restarting failed container", 709662600)
+	nodeEvent := createEvent(1, "", "machine-blue", "Node", "e63e74fa-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "NodeReady", "Node machine-blue status is now: NodeReady", 709662600)
+	events := []*v1.Event{scheduled, backOff, systemBackOff, nodeEvent}
+	now := time.Now()
+
+	for n, tc := range []struct {
+		caseName string
+		config   KubeASConfig
+		expected []*v1.Event
+	}{
+		{
+			caseName: "no filter",
+			expected: events,
+		},
+		{
+			caseName: "include reasons",
+			config:   KubeASConfig{IncludeEvents: []eventFilterRule{{Reason: "^BackOff$"}, {Kind: "Node"}}},
+			expected: []*v1.Event{backOff, systemBackOff, nodeEvent},
+		},
+		{
+			caseName: "exclude namespace and message",
+			config:   KubeASConfig{ExcludeEvents: []eventFilterRule{{Namespace: "kube-system"}, {Message: "^Successfully assigned"}}},
+			expected: []*v1.Event{backOff, nodeEvent},
+		},
+		{
+			caseName: "exclusion takes precedence",
+			config: KubeASConfig{
+				IncludeEvents: []eventFilterRule{{Reason: "BackOff"}},
+				ExcludeEvents: []eventFilterRule{{Reason: "BackOff", Namespace: "kube-system"}},
+			},
+			expected: []*v1.Event{backOff},
+		},
+	} {
+		t.Run(fmt.Sprintf("case %d: %s", n, tc.caseName), func(t *testing.T) {
+			filter, err := newEventFilter(&tc.config)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, filter.filter(events, now))
+		})
+	}
+
+	_, err := newEventFilter(&KubeASConfig{IncludeEvents: []eventFilterRule{{}}})
+	assert.Error(t, err)
+	_, err = newEventFilter(&KubeASConfig{ExcludeEvents: []eventFilterRule{{Reason: "("}}})
+	assert.Error(t, err)
+}
+
+func TestEventFilterDedup(t *testing.T) {
+	backOff := createEvent(5, "default", "dca-789976f5d7-2ljx6", "Pod", "e6417a7f-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "BackOff", "Back-off restarting failed container", 709662600)
+	updatedBackOff := createEvent(6, "default", "dca-789976f5d7-2ljx6", "Pod", "e6417a7f-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "BackOff", "Back-off restarting failed container", 709662660)
+	pulled := createEvent(6, "default", "dca-789976f5d7-2ljx6", "Pod", "e6417a7f-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "Pulled", "Container image already present on machine", 709662660)
+
+	filter, err := newEventFilter(&KubeASConfig{EventDedupWindow: 60})
+	assert.NoError(t, err)
+	now := time.Now()
+
+	// Repeated events are only submitted once per window
+	assert.Equal(t, []*v1.Event{backOff}, filter.filter([]*v1.Event{backOff, updatedBackOff}, now))
+	assert.Equal(t, []*v1.Event{pulled}, filter.filter([]*v1.Event{updatedBackOff, pulled}, now.Add(30*time.Second)))
+	assert.Equal(t, []*v1.Event{updatedBackOff}, filter.filter([]*v1.Event{updatedBackOff}, now.Add(60*time.Second)))
+	assert.Len(t, filter.seen, 2)
+}
+
+func TestEventAlertTypes(t *testing.T) {
+	ev1 := createEvent(1, "default", "dca-789976f5d7-2ljx6", "Pod", "e6417a7f-f566-11e7-9749-0e4863e1cbf4", "default-scheduler", "machine-blue", "Scheduled", "Successfully assigned dca-789976f5d7-2ljx6 to ip-10-0-0-54", 709662600)
+	ev1.Type = v1.EventTypeNormal
+	ev2 := createEvent(5, "default", "dca-789976f5d7-2ljx6", "Pod", "e6417a7f-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "BackOff", "Back-off restarting failed container", 709662600)
+	ev2.Type = v1.EventTypeWarning
+	ev3 := createEvent(5, "staging", "dca-789976f5d7-bqx4n", "Pod", "e6418fe2-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "BackOff", "Back-off restarting failed container", 709662600)
+	ev3.Type = v1.EventTypeWarning
+
+	mapper, err := newAlertTypeMapper([]eventAlertTypeRule{
+		{Namespace: "^default$", Warning: "error"},
+		{Namespace: ".*", Warning: "info"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.EventAlertTypeInfo, mapper.alertType(ev1))
+	assert.Equal(t, metrics.EventAlertTypeError, mapper.alertType(ev2))
+	assert.Equal(t, metrics.EventAlertTypeInfo, mapper.alertType(ev3))
+
+	_, err = newAlertTypeMapper([]eventAlertTypeRule{{Namespace: "default", Normal: "critical"}})
+	assert.Error(t, err)
+
+	// The bundle takes the most severe alert type of its events
+	kubeASCheck := &KubeASCheck{
+		CheckBase:             core.NewCheckBase(kubernetesAPIServerCheckName),
+		KubeAPIServerHostname: "hostname",
+		alertTypes:            mapper,
+	}
+	mocked := mocksender.NewMockSender(kubeASCheck.ID())
+	mocked.On("Event", mock.AnythingOfType("metrics.Event"))
+	kubeASCheck.processEvents(mocked, []*v1.Event{ev1, ev2})
+	mocked.AssertNumberOfCalls(t, "Event", 1)
+	assert.Equal(t, metrics.EventAlertTypeError, (mocked.Calls[0].Arguments.Get(0)).(metrics.Event).AlertType)
+}
+
+func TestForwardEventLogs(t *testing.T) {
+	ev1 := createEvent(5, "default", "dca-789976f5d7-2ljx6", "Pod", "e6417a7f-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "BackOff", "Back-off restarting failed container", 709662600)
+	ev1.Type = v1.EventTypeWarning
+	ev2 := createEvent(1, "", "machine-blue", "Node", "e63e74fa-f566-11e7-9749-0e4863e1cbf4", "kubelet", "machine-blue", "NodeReady", "Node machine-blue status is now: NodeReady", 709662660)
+	ev2.Type = v1.EventTypeNormal
+
+	kubeASCheck := &KubeASCheck{
+		CheckBase: core.NewCheckBase(kubernetesAPIServerCheckName),
+		eventLogs: make(chan string, 1),
+	}
+	kubeASCheck.forwardEventLogs([]*v1.Event{ev1, ev2})
+
+	// The events are dropped when the pipeline is full
+	assert.Len(t, kubeASCheck.eventLogs, 1)
+	assert.Len(t, kubeASCheck.GetWarnings(), 1)
+	assert.Equal(t, `{"timestamp":709662600000,"status":"warning","message":"Back-off restarting failed container","reason":"BackOff","type":"Warning","count":5,"kind":"Pod","name":"dca-789976f5d7-2ljx6","namespace":"default","source_component":"kubelet","source_host":"machine-blue"}`, <-kubeASCheck.eventLogs)
+}
+
+func TestEventTimestamp(t *testing.T) {
+	last := time.Unix(709662660, 0)
+	first := time.Unix(709662600, 0)
+
+	ev := &v1.Event{LastTimestamp: obj.Time{Time: last}, FirstTimestamp: obj.Time{Time: first}}
+	assert.Equal(t, last, eventTimestamp(ev))
+
+	// events created through the events API have no LastTimestamp
+	ev = &v1.Event{EventTime: obj.MicroTime{Time: first}}
+	assert.Equal(t, first, eventTimestamp(ev))
+
+	ev = &v1.Event{FirstTimestamp: obj.Time{Time: first}}
+	assert.Equal(t, first, eventTimestamp(ev))
+
+	// events without any timestamp are forwarded without one
+	kubeASCheck := &KubeASCheck{
+		CheckBase: core.NewCheckBase(kubernetesAPIServerCheckName),
+		eventLogs: make(chan string, 1),
+	}
+	kubeASCheck.forwardEventLogs([]*v1.Event{{Reason: "Unknown"}})
+	assert.NotContains(t, <-kubeASCheck.eventLogs, "timestamp")
+}
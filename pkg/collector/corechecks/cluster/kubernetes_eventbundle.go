@@ -31,6 +31,9 @@ type kubernetesEventBundle struct {
 	lastTimestamp float64        // Used for the modified events in the bundle to specify when they last occurred
 	countByAction map[string]int // Map of count per action to aggregate several events from the same ObjUid in one event
 	nodename      string         // Stores the nodename that should be used to submit the events
+
+	// Most severe alert type of the events, empty if the alert types are not mapped
+	alertType metrics.EventAlertType
 }
 
 func newKubernetesEventBundler(objUID types.UID, compName string) *kubernetesEventBundle {
@@ -78,6 +81,13 @@ func (b *kubernetesEventBundle) addEvent(event *v1.Event) error {
 	return nil
 }
 
+// setAlertType keeps the most severe alert type of the events of the bundle
+func (b *kubernetesEventBundle) setAlertType(alertType metrics.EventAlertType) {
+	if alertTypeSeverity[alertType] > alertTypeSeverity[b.alertType] {
+		b.alertType = alertType
+	}
+}
+
 func (b *kubernetesEventBundle) formatEvents(clusterName string) (metrics.Event, error) {
 	if len(b.events) == 0 {
 		return metrics.Event{}, errors.New("no event to export")
@@ -97,6 +107,7 @@ func (b *kubernetesEventBundle) formatEvents(clusterName string) (metrics.Event,
 		Ts:             int64(b.lastTimestamp),
 		Tags:           []string{fmt.Sprintf("source_component:%s", b.component), fmt.Sprintf("kubernetes_kind:%s", b.kind), fmt.Sprintf("name:%s", b.name)},
 		AggregationKey: fmt.Sprintf("kubernetes_apiserver:%s", b.objUID),
+		AlertType:      b.alertType,
 	}
 	if b.namespace != "" {
 		// TODO remove the deprecated namespace tag, we should only rely on kube_namespace
@@ -0,0 +1,212 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// eventFilterRule matches the events of which every field set matches its regular expression.
+type eventFilterRule struct {
+	Reason    string `yaml:"reason"`
+	Kind      string `yaml:"kind"`
+	Namespace string `yaml:"namespace"`
+	Message   string `yaml:"message"`
+}
+
+// eventAlertTypeRule sets the alert type of the events of the namespaces
+// matching its regular expression, depending on the type of the Kubernetes event.
+type eventAlertTypeRule struct {
+	Namespace string `yaml:"namespace"`
+	Normal    string `yaml:"normal"`
+	Warning   string `yaml:"warning"`
+}
+
+type eventMatcher struct {
+	reason    *regexp.Regexp
+	kind      *regexp.Regexp
+	namespace *regexp.Regexp
+	message   *regexp.Regexp
+}
+
+func newEventMatcher(rule eventFilterRule) (*eventMatcher, error) {
+	if rule.Reason == "" && rule.Kind == "" && rule.Namespace == "" && rule.Message == "" {
+		return nil, errors.New("an event filter must set at least one of reason, kind, namespace or message")
+	}
+	m := &eventMatcher{}
+	for _, field := range []struct {
+		re   **regexp.Regexp
+		expr string
+	}{
+		{&m.reason, rule.Reason},
+		{&m.kind, rule.Kind},
+		{&m.namespace, rule.Namespace},
+		{&m.message, rule.Message},
+	} {
+		if field.expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(field.expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event filter %q: %v", field.expr, err)
+		}
+		*field.re = re
+	}
+	return m, nil
+}
+
+func (m *eventMatcher) match(event *v1.Event) bool {
+	return matchOptional(m.reason, event.Reason) &&
+		matchOptional(m.kind, event.InvolvedObject.Kind) &&
+		matchOptional(m.namespace, event.InvolvedObject.Namespace) &&
+		matchOptional(m.message, event.Message)
+}
+
+func matchOptional(re *regexp.Regexp, value string) bool {
+	return re == nil || re.MatchString(value)
+}
+
+// eventFilter drops the events excluded by the configuration, or already
+// submitted during the dedup window.
+type eventFilter struct {
+	include     []*eventMatcher
+	exclude     []*eventMatcher
+	dedupWindow time.Duration
+	// seen holds the last submission time of the events, by object UID, reason and message
+	seen map[string]time.Time
+}
+
+func newEventFilter(c *KubeASConfig) (*eventFilter, error) {
+	f := &eventFilter{
+		dedupWindow: time.Duration(c.EventDedupWindow) * time.Second,
+		seen:        make(map[string]time.Time),
+	}
+	for _, rule := range c.IncludeEvents {
+		m, err := newEventMatcher(rule)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, m)
+	}
+	for _, rule := range c.ExcludeEvents {
+		m, err := newEventMatcher(rule)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, m)
+	}
+	return f, nil
+}
+
+// filter returns the events to submit
+func (f *eventFilter) filter(events []*v1.Event, now time.Time) []*v1.Event {
+	// Forget the events seen before the dedup window
+	for key, last := range f.seen {
+		if now.Sub(last) >= f.dedupWindow {
+			delete(f.seen, key)
+		}
+	}
+
+	var filtered []*v1.Event
+	for _, event := range events {
+		if event == nil || !f.isIncluded(event) {
+			continue
+		}
+		if f.dedupWindow > 0 {
+			key := fmt.Sprintf("%s/%s/%s", event.InvolvedObject.UID, event.Reason, event.Message)
+			if _, found := f.seen[key]; found {
+				continue
+			}
+			f.seen[key] = now
+		}
+		filtered = append(filtered, event)
+	}
+	return filtered
+}
+
+func (f *eventFilter) isIncluded(event *v1.Event) bool {
+	for _, m := range f.exclude {
+		if m.match(event) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, m := range f.include {
+		if m.match(event) {
+			return true
+		}
+	}
+	return false
+}
+
+type alertTypeMatcher struct {
+	namespace *regexp.Regexp
+	normal    metrics.EventAlertType
+	warning   metrics.EventAlertType
+}
+
+// alertTypeMapper maps the Kubernetes events to Datadog alert types, the first
+// rule matching the namespace of an event applies.
+type alertTypeMapper []alertTypeMatcher
+
+func newAlertTypeMapper(rules []eventAlertTypeRule) (alertTypeMapper, error) {
+	var mapper alertTypeMapper
+	for _, rule := range rules {
+		m := alertTypeMatcher{
+			normal:  metrics.EventAlertTypeInfo,
+			warning: metrics.EventAlertTypeWarning,
+		}
+		var err error
+		if m.namespace, err = regexp.Compile(rule.Namespace); err != nil {
+			return nil, fmt.Errorf("invalid namespace %q in event_alert_types: %v", rule.Namespace, err)
+		}
+		if rule.Normal != "" {
+			if m.normal, err = metrics.GetAlertTypeFromString(rule.Normal); err != nil {
+				return nil, err
+			}
+		}
+		if rule.Warning != "" {
+			if m.warning, err = metrics.GetAlertTypeFromString(rule.Warning); err != nil {
+				return nil, err
+			}
+		}
+		mapper = append(mapper, m)
+	}
+	return mapper, nil
+}
+
+// alertType returns the alert type of the event, empty if no rule applies.
+func (mapper alertTypeMapper) alertType(event *v1.Event) metrics.EventAlertType {
+	for _, m := range mapper {
+		if !m.namespace.MatchString(event.InvolvedObject.Namespace) {
+			continue
+		}
+		if event.Type == v1.EventTypeWarning {
+			return m.warning
+		}
+		return m.normal
+	}
+	return ""
+}
+
+// alertTypeSeverity orders the alert types to keep the most severe one in a bundle
+var alertTypeSeverity = map[metrics.EventAlertType]int{
+	metrics.EventAlertTypeSuccess: 1,
+	metrics.EventAlertTypeInfo:    2,
+	metrics.EventAlertTypeWarning: 3,
+	metrics.EventAlertTypeError:   4,
+}
@@ -0,0 +1,267 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	kubernetesStateCheckName = "kubernetes_state_core"
+	kubernetesStateNamespace = "kubernetes_state"
+	cacheSyncTimeout         = 10 * time.Second
+)
+
+// KubeStateConfig is the config of the Kubernetes state check.
+type KubeStateConfig struct {
+	LeaderSkip bool     `yaml:"skip_leader_election"`
+	Collectors []string `yaml:"collectors"`
+}
+
+// stateCollector reports the state of one kind of Kubernetes objects
+type stateCollector struct {
+	synced cache.InformerSynced
+	report func(sender aggregator.Sender) error
+}
+
+// stateCollectorFactories holds the collectors by name, they register their
+// informer on the shared informer factory when created.
+var stateCollectorFactories = map[string]func(k *KubeStateCheck, f informers.SharedInformerFactory) stateCollector{
+	"deployments":              newDeploymentCollector,
+	"statefulsets":             newStatefulSetCollector,
+	"daemonsets":               newDaemonSetCollector,
+	"jobs":                     newJobCollector,
+	"cronjobs":                 newCronJobCollector,
+	"nodes":                    newNodeCollector,
+	"persistentvolumeclaims":   newPersistentVolumeClaimCollector,
+	"horizontalpodautoscalers": newHorizontalPodAutoscalerCollector,
+}
+
+// KubeStateCheck reports the state of Kubernetes objects from the informers
+// of the cluster-agent, as kube-state-metrics would.
+type KubeStateCheck struct {
+	core.CheckBase
+	instance   *KubeStateConfig
+	collectors []stateCollector
+	// Labels are mapped to tags as the tagger does, from kubernetes_pod_labels_as_tags
+	// for the workloads and kubernetes_node_labels_as_tags for the nodes
+	labelsAsTags     map[string]string
+	nodeLabelsAsTags map[string]string
+	// The pods of the workloads are tagged by the metadata mapper of the
+	// cluster-agent, e.g. with their kube_service
+	pods             corelisters.PodLister
+	podsSynced       cache.InformerSynced
+	podMetadataNames func(nodeName, ns, podName string) ([]string, error)
+}
+
+func (c *KubeStateConfig) parse(data []byte) error {
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return err
+	}
+	if len(c.Collectors) == 0 {
+		for name := range stateCollectorFactories {
+			c.Collectors = append(c.Collectors, name)
+		}
+	}
+	for _, name := range c.Collectors {
+		if _, found := stateCollectorFactories[name]; !found {
+			return fmt.Errorf("unknown collector %q", name)
+		}
+	}
+	return nil
+}
+
+// Configure parses the check configuration and init the check.
+func (k *KubeStateCheck) Configure(config, initConfig integration.Data, source string) error {
+	err := k.CommonConfigure(config, source)
+	if err != nil {
+		return err
+	}
+
+	err = k.instance.parse(config)
+	if err != nil {
+		log.Error("could not parse the config for the Kubernetes state check")
+		return err
+	}
+	return nil
+}
+
+// Run executes the check.
+func (k *KubeStateCheck) Run() error {
+	sender, err := aggregator.GetSender(k.ID())
+	if err != nil {
+		return err
+	}
+	defer sender.Commit()
+
+	// If the check is configured as a cluster check, the cluster check worker needs to skip the leader election section.
+	// The Cluster Agent will passed in the `skip_leader_election` bool.
+	if !k.instance.LeaderSkip {
+		if !config.Datadog.GetBool("leader_election") {
+			return log.Error("Leader Election not enabled. Not running the Kubernetes state check.")
+		}
+		if err = runLeaderElection(&k.CheckBase); err != nil {
+			if err == apiserver.ErrNotLeader {
+				return nil
+			}
+			return err
+		}
+	}
+
+	// Informers are registered on first run, the cache sync is awaited on
+	// every run until it succeeds.
+	if k.collectors == nil {
+		if err = k.setupCollectors(); err != nil {
+			k.Warnf("Could not setup the Kubernetes state collectors: %s", err)
+			return err
+		}
+	}
+	if !k.waitForCacheSync() {
+		return errors.New("informers are not synced yet, will retry at the next run")
+	}
+
+	for _, collector := range k.collectors {
+		if err := collector.report(sender); err != nil {
+			k.Warnf("Could not report the state of Kubernetes objects: %s", err)
+		}
+	}
+	return nil
+}
+
+func (k *KubeStateCheck) setupCollectors() error {
+	ac, err := apiserver.GetAPIClient()
+	if err != nil {
+		return err
+	}
+	if ac.InformerFactory == nil {
+		return errors.New("no informer factory available")
+	}
+
+	podInformer := ac.InformerFactory.Core().V1().Pods()
+	k.pods = podInformer.Lister()
+	k.podsSynced = podInformer.Informer().HasSynced
+
+	collectors := make([]stateCollector, 0, len(k.instance.Collectors))
+	for _, name := range k.instance.Collectors {
+		collectors = append(collectors, stateCollectorFactories[name](k, ac.InformerFactory))
+	}
+	// Only starts the informers that are not running yet
+	ac.InformerFactory.Start(wait.NeverStop)
+
+	k.collectors = collectors
+	return nil
+}
+
+func (k *KubeStateCheck) waitForCacheSync() bool {
+	synced := make([]cache.InformerSynced, 0, len(k.collectors)+1)
+	synced = append(synced, k.podsSynced)
+	for _, collector := range k.collectors {
+		synced = append(synced, collector.synced)
+	}
+	stopCh := make(chan struct{})
+	timer := time.AfterFunc(cacheSyncTimeout, func() { close(stopCh) })
+	defer timer.Stop()
+	return cache.WaitForCacheSync(stopCh, synced...)
+}
+
+// objectTags returns the tags of an object: its namespace and name, the tags
+// extracted from its labels with the labelsAsTags mapping, and, for workloads
+// selecting pods, the metadata mapper tags of their pods
+func (k *KubeStateCheck) objectTags(nameTag string, meta metav1.ObjectMeta, labelsAsTags map[string]string, selector *metav1.LabelSelector) []string {
+	tags := []string{fmt.Sprintf("%s:%s", nameTag, meta.Name)}
+	if meta.Namespace != "" {
+		tags = append(tags, fmt.Sprintf("kube_namespace:%s", meta.Namespace))
+	}
+	for label, value := range meta.Labels {
+		if tagName, found := labelsAsTags[strings.ToLower(label)]; found {
+			tags = append(tags, fmt.Sprintf("%s:%s", tagName, value))
+		}
+	}
+	return append(tags, k.podMetadataTags(meta.Namespace, selector)...)
+}
+
+// podMetadataTags returns the metadata mapper tags of the pods matching the
+// selector, such as the services they are part of
+func (k *KubeStateCheck) podMetadataTags(namespace string, selector *metav1.LabelSelector) []string {
+	if selector == nil || k.pods == nil {
+		return nil
+	}
+	podSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil || podSelector.Empty() {
+		return nil
+	}
+	pods, err := k.pods.Pods(namespace).List(podSelector)
+	if err != nil {
+		return nil
+	}
+	unique := make(map[string]struct{})
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		names, err := k.podMetadataNames(pod.Spec.NodeName, namespace, pod.Name)
+		if err != nil {
+			log.Debugf("Could not get the metadata of pod %s/%s: %s", namespace, pod.Name, err)
+			continue
+		}
+		for _, name := range names {
+			unique[name] = struct{}{}
+		}
+	}
+	tags := make([]string, 0, len(unique))
+	for tag := range unique {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// labelsAsTagsFromConfig returns a label to tag mapping of the agent
+// configuration, with lowercase label names
+func labelsAsTagsFromConfig(key string) map[string]string {
+	labelsAsTags := make(map[string]string)
+	// viper lower-cases map keys from yaml, but not from envvars
+	for label, tagName := range config.Datadog.GetStringMapString(key) {
+		labelsAsTags[strings.ToLower(label)] = tagName
+	}
+	return labelsAsTags
+}
+
+// KubernetesStateFactory is exported for integration testing.
+func KubernetesStateFactory() check.Check {
+	return &KubeStateCheck{
+		CheckBase:        core.NewCheckBase(kubernetesStateCheckName),
+		instance:         &KubeStateConfig{},
+		labelsAsTags:     labelsAsTagsFromConfig("kubernetes_pod_labels_as_tags"),
+		nodeLabelsAsTags: labelsAsTagsFromConfig("kubernetes_node_labels_as_tags"),
+		podMetadataNames: apiserver.GetPodMetadataNames,
+	}
+}
+
+func init() {
+	core.RegisterCheck(kubernetesStateCheckName, KubernetesStateFactory)
+}
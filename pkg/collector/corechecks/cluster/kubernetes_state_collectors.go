@@ -0,0 +1,274 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+)
+
+func boolToFloat64(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func int32PtrToFloat64(i *int32, defaultValue float64) float64 {
+	if i == nil {
+		return defaultValue
+	}
+	return float64(*i)
+}
+
+func stateMetric(name string) string {
+	return fmt.Sprintf("%s.%s", kubernetesStateNamespace, name)
+}
+
+func newDeploymentCollector(k *KubeStateCheck, f informers.SharedInformerFactory) stateCollector {
+	informer := f.Apps().V1().Deployments()
+	return stateCollector{
+		synced: informer.Informer().HasSynced,
+		report: func(sender aggregator.Sender) error {
+			deployments, err := informer.Lister().List(labels.Everything())
+			if err != nil {
+				return err
+			}
+			k.reportDeployments(sender, deployments)
+			return nil
+		},
+	}
+}
+
+func (k *KubeStateCheck) reportDeployments(sender aggregator.Sender, deployments []*appsv1.Deployment) {
+	for _, d := range deployments {
+		tags := k.objectTags("kube_deployment", d.ObjectMeta, k.labelsAsTags, d.Spec.Selector)
+		sender.Gauge(stateMetric("deployment.replicas"), float64(d.Status.Replicas), "", tags)
+		sender.Gauge(stateMetric("deployment.replicas_desired"), int32PtrToFloat64(d.Spec.Replicas, 1), "", tags)
+		sender.Gauge(stateMetric("deployment.replicas_available"), float64(d.Status.AvailableReplicas), "", tags)
+		sender.Gauge(stateMetric("deployment.replicas_unavailable"), float64(d.Status.UnavailableReplicas), "", tags)
+		sender.Gauge(stateMetric("deployment.replicas_updated"), float64(d.Status.UpdatedReplicas), "", tags)
+		sender.Gauge(stateMetric("deployment.paused"), boolToFloat64(d.Spec.Paused), "", tags)
+	}
+}
+
+func newStatefulSetCollector(k *KubeStateCheck, f informers.SharedInformerFactory) stateCollector {
+	informer := f.Apps().V1().StatefulSets()
+	return stateCollector{
+		synced: informer.Informer().HasSynced,
+		report: func(sender aggregator.Sender) error {
+			statefulSets, err := informer.Lister().List(labels.Everything())
+			if err != nil {
+				return err
+			}
+			k.reportStatefulSets(sender, statefulSets)
+			return nil
+		},
+	}
+}
+
+func (k *KubeStateCheck) reportStatefulSets(sender aggregator.Sender, statefulSets []*appsv1.StatefulSet) {
+	for _, s := range statefulSets {
+		tags := k.objectTags("kube_stateful_set", s.ObjectMeta, k.labelsAsTags, s.Spec.Selector)
+		sender.Gauge(stateMetric("statefulset.replicas"), float64(s.Status.Replicas), "", tags)
+		sender.Gauge(stateMetric("statefulset.replicas_desired"), int32PtrToFloat64(s.Spec.Replicas, 1), "", tags)
+		sender.Gauge(stateMetric("statefulset.replicas_ready"), float64(s.Status.ReadyReplicas), "", tags)
+		sender.Gauge(stateMetric("statefulset.replicas_current"), float64(s.Status.CurrentReplicas), "", tags)
+		sender.Gauge(stateMetric("statefulset.replicas_updated"), float64(s.Status.UpdatedReplicas), "", tags)
+	}
+}
+
+func newDaemonSetCollector(k *KubeStateCheck, f informers.SharedInformerFactory) stateCollector {
+	informer := f.Apps().V1().DaemonSets()
+	return stateCollector{
+		synced: informer.Informer().HasSynced,
+		report: func(sender aggregator.Sender) error {
+			daemonSets, err := informer.Lister().List(labels.Everything())
+			if err != nil {
+				return err
+			}
+			k.reportDaemonSets(sender, daemonSets)
+			return nil
+		},
+	}
+}
+
+func (k *KubeStateCheck) reportDaemonSets(sender aggregator.Sender, daemonSets []*appsv1.DaemonSet) {
+	for _, d := range daemonSets {
+		tags := k.objectTags("kube_daemon_set", d.ObjectMeta, k.labelsAsTags, d.Spec.Selector)
+		sender.Gauge(stateMetric("daemonset.scheduled"), float64(d.Status.CurrentNumberScheduled), "", tags)
+		sender.Gauge(stateMetric("daemonset.desired"), float64(d.Status.DesiredNumberScheduled), "", tags)
+		sender.Gauge(stateMetric("daemonset.misscheduled"), float64(d.Status.NumberMisscheduled), "", tags)
+		sender.Gauge(stateMetric("daemonset.ready"), float64(d.Status.NumberReady), "", tags)
+		sender.Gauge(stateMetric("daemonset.updated"), float64(d.Status.UpdatedNumberScheduled), "", tags)
+	}
+}
+
+func newJobCollector(k *KubeStateCheck, f informers.SharedInformerFactory) stateCollector {
+	informer := f.Batch().V1().Jobs()
+	return stateCollector{
+		synced: informer.Informer().HasSynced,
+		report: func(sender aggregator.Sender) error {
+			jobs, err := informer.Lister().List(labels.Everything())
+			if err != nil {
+				return err
+			}
+			k.reportJobs(sender, jobs)
+			return nil
+		},
+	}
+}
+
+func (k *KubeStateCheck) reportJobs(sender aggregator.Sender, jobs []*batchv1.Job) {
+	for _, j := range jobs {
+		tags := k.objectTags("kube_job", j.ObjectMeta, k.labelsAsTags, j.Spec.Selector)
+		for _, owner := range j.OwnerReferences {
+			if owner.Kind == "CronJob" {
+				tags = append(tags, fmt.Sprintf("kube_cronjob:%s", owner.Name))
+			}
+		}
+		sender.Gauge(stateMetric("job.active"), float64(j.Status.Active), "", tags)
+		sender.Gauge(stateMetric("job.succeeded"), float64(j.Status.Succeeded), "", tags)
+		sender.Gauge(stateMetric("job.failed"), float64(j.Status.Failed), "", tags)
+	}
+}
+
+func newCronJobCollector(k *KubeStateCheck, f informers.SharedInformerFactory) stateCollector {
+	informer := f.Batch().V1beta1().CronJobs()
+	return stateCollector{
+		synced: informer.Informer().HasSynced,
+		report: func(sender aggregator.Sender) error {
+			cronJobs, err := informer.Lister().List(labels.Everything())
+			if err != nil {
+				return err
+			}
+			k.reportCronJobs(sender, cronJobs, time.Now())
+			return nil
+		},
+	}
+}
+
+func (k *KubeStateCheck) reportCronJobs(sender aggregator.Sender, cronJobs []*batchv1beta1.CronJob, now time.Time) {
+	for _, c := range cronJobs {
+		tags := k.objectTags("kube_cronjob", c.ObjectMeta, k.labelsAsTags, nil)
+		sender.Gauge(stateMetric("cronjob.active"), float64(len(c.Status.Active)), "", tags)
+		sender.Gauge(stateMetric("cronjob.suspended"), boolToFloat64(c.Spec.Suspend != nil && *c.Spec.Suspend), "", tags)
+		if c.Status.LastScheduleTime != nil {
+			sender.Gauge(stateMetric("cronjob.duration_since_last_schedule"), now.Sub(c.Status.LastScheduleTime.Time).Seconds(), "", tags)
+		}
+	}
+}
+
+func newNodeCollector(k *KubeStateCheck, f informers.SharedInformerFactory) stateCollector {
+	informer := f.Core().V1().Nodes()
+	return stateCollector{
+		synced: informer.Informer().HasSynced,
+		report: func(sender aggregator.Sender) error {
+			nodes, err := informer.Lister().List(labels.Everything())
+			if err != nil {
+				return err
+			}
+			k.reportNodes(sender, nodes)
+			return nil
+		},
+	}
+}
+
+// nodeResources are the node capacity and allocatable resources reported
+var nodeResources = map[v1.ResourceName]string{
+	v1.ResourceCPU:    "cpu",
+	v1.ResourceMemory: "memory",
+	v1.ResourcePods:   "pods",
+}
+
+func (k *KubeStateCheck) reportNodes(sender aggregator.Sender, nodes []*v1.Node) {
+	for _, n := range nodes {
+		tags := k.objectTags("node", n.ObjectMeta, k.nodeLabelsAsTags, nil)
+		for resource, name := range nodeResources {
+			if qty, found := n.Status.Capacity[resource]; found {
+				sender.Gauge(stateMetric(fmt.Sprintf("node.%s_capacity", name)), quantityToFloat64(qty), "", tags)
+			}
+			if qty, found := n.Status.Allocatable[resource]; found {
+				sender.Gauge(stateMetric(fmt.Sprintf("node.%s_allocatable", name)), quantityToFloat64(qty), "", tags)
+			}
+		}
+		for _, condition := range n.Status.Conditions {
+			conditionTags := append([]string{
+				fmt.Sprintf("condition:%s", condition.Type),
+				fmt.Sprintf("status:%s", strings.ToLower(string(condition.Status))),
+			}, tags...)
+			sender.Gauge(stateMetric("node.by_condition"), 1, "", conditionTags)
+		}
+		sender.Gauge(stateMetric("node.unschedulable"), boolToFloat64(n.Spec.Unschedulable), "", tags)
+	}
+}
+
+func newPersistentVolumeClaimCollector(k *KubeStateCheck, f informers.SharedInformerFactory) stateCollector {
+	informer := f.Core().V1().PersistentVolumeClaims()
+	return stateCollector{
+		synced: informer.Informer().HasSynced,
+		report: func(sender aggregator.Sender) error {
+			claims, err := informer.Lister().List(labels.Everything())
+			if err != nil {
+				return err
+			}
+			k.reportPersistentVolumeClaims(sender, claims)
+			return nil
+		},
+	}
+}
+
+func (k *KubeStateCheck) reportPersistentVolumeClaims(sender aggregator.Sender, claims []*v1.PersistentVolumeClaim) {
+	for _, c := range claims {
+		tags := k.objectTags("persistentvolumeclaim", c.ObjectMeta, k.labelsAsTags, nil)
+		if c.Spec.StorageClassName != nil {
+			tags = append(tags, fmt.Sprintf("storageclass:%s", *c.Spec.StorageClassName))
+		}
+		phaseTags := append([]string{fmt.Sprintf("phase:%s", strings.ToLower(string(c.Status.Phase)))}, tags...)
+		sender.Gauge(stateMetric("persistentvolumeclaim.status"), 1, "", phaseTags)
+		if qty, found := c.Spec.Resources.Requests[v1.ResourceStorage]; found {
+			sender.Gauge(stateMetric("persistentvolumeclaim.request_storage"), quantityToFloat64(qty), "", tags)
+		}
+	}
+}
+
+func newHorizontalPodAutoscalerCollector(k *KubeStateCheck, f informers.SharedInformerFactory) stateCollector {
+	informer := f.Autoscaling().V2beta1().HorizontalPodAutoscalers()
+	return stateCollector{
+		synced: informer.Informer().HasSynced,
+		report: func(sender aggregator.Sender) error {
+			autoscalers, err := informer.Lister().List(labels.Everything())
+			if err != nil {
+				return err
+			}
+			k.reportHorizontalPodAutoscalers(sender, autoscalers)
+			return nil
+		},
+	}
+}
+
+func (k *KubeStateCheck) reportHorizontalPodAutoscalers(sender aggregator.Sender, autoscalers []*autoscalingv2.HorizontalPodAutoscaler) {
+	for _, h := range autoscalers {
+		tags := k.objectTags("hpa", h.ObjectMeta, k.labelsAsTags, nil)
+		sender.Gauge(stateMetric("hpa.min_replicas"), int32PtrToFloat64(h.Spec.MinReplicas, 1), "", tags)
+		sender.Gauge(stateMetric("hpa.max_replicas"), float64(h.Spec.MaxReplicas), "", tags)
+		sender.Gauge(stateMetric("hpa.desired_replicas"), float64(h.Status.DesiredReplicas), "", tags)
+		sender.Gauge(stateMetric("hpa.current_replicas"), float64(h.Status.CurrentReplicas), "", tags)
+	}
+}
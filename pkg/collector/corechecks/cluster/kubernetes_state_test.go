@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+// +build kubeapiserver
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	obj "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func newTestKubeStateCheck(t *testing.T, config string) *KubeStateCheck {
+	check := KubernetesStateFactory().(*KubeStateCheck)
+	assert.NoError(t, check.instance.parse([]byte(config)))
+	return check
+}
+
+func TestKubeStateConfigParse(t *testing.T) {
+	config := &KubeStateConfig{}
+	assert.NoError(t, config.parse([]byte("")))
+	assert.Len(t, config.Collectors, len(stateCollectorFactories))
+
+	config = &KubeStateConfig{}
+	assert.NoError(t, config.parse([]byte("collectors: [nodes, deployments]")))
+	assert.Equal(t, []string{"nodes", "deployments"}, config.Collectors)
+
+	config = &KubeStateConfig{}
+	assert.EqualError(t, config.parse([]byte("collectors: [pods]")), `unknown collector "pods"`)
+}
+
+func TestReportDeployments(t *testing.T) {
+	config.Datadog.Set("kubernetes_pod_labels_as_tags", map[string]string{"Team": "owner"})
+	defer config.Datadog.Set("kubernetes_pod_labels_as_tags", map[string]string{})
+	check := newTestKubeStateCheck(t, "")
+	pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, pod := range []*v1.Pod{
+		{
+			ObjectMeta: obj.ObjectMeta{Name: "web-1", Namespace: "prod", Labels: map[string]string{"app": "web"}},
+			Spec:       v1.PodSpec{NodeName: "node-1"},
+		},
+		{
+			ObjectMeta: obj.ObjectMeta{Name: "web-2", Namespace: "prod", Labels: map[string]string{"app": "web"}},
+			Spec:       v1.PodSpec{NodeName: "node-2"},
+		},
+		{
+			ObjectMeta: obj.ObjectMeta{Name: "api-1", Namespace: "prod", Labels: map[string]string{"app": "api"}},
+			Spec:       v1.PodSpec{NodeName: "node-1"},
+		},
+	} {
+		pods.Add(pod)
+	}
+	check.pods = corelisters.NewPodLister(pods)
+	check.podMetadataNames = func(nodeName, ns, podName string) ([]string, error) {
+		if podName == "api-1" {
+			return []string{"kube_service:api"}, nil
+		}
+		return []string{"kube_service:web"}, nil
+	}
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: obj.ObjectMeta{
+			Name:      "web",
+			Namespace: "prod",
+			Labels:    map[string]string{"team": "frontend"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &obj.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+		Status: appsv1.DeploymentStatus{
+			Replicas:            3,
+			AvailableReplicas:   2,
+			UnavailableReplicas: 1,
+			UpdatedReplicas:     3,
+		},
+	}
+
+	mocked := mocksender.NewMockSender(check.ID())
+	mocked.SetupAcceptAll()
+	check.reportDeployments(mocked, []*appsv1.Deployment{deployment})
+
+	tags := []string{"kube_deployment:web", "kube_namespace:prod", "owner:frontend", "kube_service:web"}
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.deployment.replicas", 3, "", tags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.deployment.replicas_desired", 3, "", tags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.deployment.replicas_available", 2, "", tags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.deployment.replicas_unavailable", 1, "", tags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.deployment.replicas_updated", 3, "", tags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.deployment.paused", 0, "", tags)
+}
+
+func TestReportJobsAndCronJobs(t *testing.T) {
+	check := newTestKubeStateCheck(t, "")
+	job := &batchv1.Job{
+		ObjectMeta: obj.ObjectMeta{
+			Name:            "backup-1582000000",
+			Namespace:       "default",
+			OwnerReferences: []obj.OwnerReference{{Kind: "CronJob", Name: "backup"}},
+		},
+		Status: batchv1.JobStatus{Active: 1, Failed: 2},
+	}
+	now := time.Now()
+	suspend := true
+	cronJob := &batchv1beta1.CronJob{
+		ObjectMeta: obj.ObjectMeta{Name: "backup", Namespace: "default"},
+		Spec:       batchv1beta1.CronJobSpec{Suspend: &suspend},
+		Status: batchv1beta1.CronJobStatus{
+			Active:           []v1.ObjectReference{{Name: "backup-1582000000"}},
+			LastScheduleTime: &obj.Time{Time: now.Add(-time.Minute)},
+		},
+	}
+
+	mocked := mocksender.NewMockSender(check.ID())
+	mocked.SetupAcceptAll()
+	check.reportJobs(mocked, []*batchv1.Job{job})
+	check.reportCronJobs(mocked, []*batchv1beta1.CronJob{cronJob}, now)
+
+	jobTags := []string{"kube_job:backup-1582000000", "kube_cronjob:backup", "kube_namespace:default"}
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.job.active", 1, "", jobTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.job.succeeded", 0, "", jobTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.job.failed", 2, "", jobTags)
+
+	cronJobTags := []string{"kube_cronjob:backup", "kube_namespace:default"}
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.cronjob.active", 1, "", cronJobTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.cronjob.suspended", 1, "", cronJobTags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.cronjob.duration_since_last_schedule", 60, "", cronJobTags)
+}
+
+func TestReportNodes(t *testing.T) {
+	config.Datadog.Set("kubernetes_node_labels_as_tags", map[string]string{"node.kubernetes.io/instance-type": "instance_type"})
+	defer config.Datadog.Set("kubernetes_node_labels_as_tags", map[string]string{})
+	check := newTestKubeStateCheck(t, "")
+	node := &v1.Node{
+		ObjectMeta: obj.ObjectMeta{
+			Name:   "node-1",
+			Labels: map[string]string{"node.kubernetes.io/instance-type": "m5.large"},
+		},
+		Spec: v1.NodeSpec{Unschedulable: true},
+		Status: v1.NodeStatus{
+			Capacity: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("16Gi"),
+			},
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("3500m"),
+			},
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: v1.ConditionTrue},
+			},
+		},
+	}
+
+	mocked := mocksender.NewMockSender(check.ID())
+	mocked.SetupAcceptAll()
+	check.reportNodes(mocked, []*v1.Node{node})
+
+	tags := []string{"node:node-1", "instance_type:m5.large"}
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.node.cpu_capacity", 4, "", tags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.node.memory_capacity", 16*1024*1024*1024, "", tags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.node.cpu_allocatable", 3.5, "", tags)
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.node.by_condition", 1, "", []string{"node:node-1", "instance_type:m5.large", "condition:Ready", "status:true"})
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.node.unschedulable", 1, "", tags)
+}
+
+func TestReportPersistentVolumeClaims(t *testing.T) {
+	check := newTestKubeStateCheck(t, "")
+	class := "ssd"
+	claim := &v1.PersistentVolumeClaim{
+		ObjectMeta: obj.ObjectMeta{Name: "data", Namespace: "db"},
+		Spec: v1.PersistentVolumeClaimSpec{
+			StorageClassName: &class,
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+		},
+		Status: v1.PersistentVolumeClaimStatus{Phase: v1.ClaimBound},
+	}
+
+	mocked := mocksender.NewMockSender(check.ID())
+	mocked.SetupAcceptAll()
+	check.reportPersistentVolumeClaims(mocked, []*v1.PersistentVolumeClaim{claim})
+
+	tags := []string{"persistentvolumeclaim:data", "kube_namespace:db", "storageclass:ssd"}
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.persistentvolumeclaim.status", 1, "", append([]string{"phase:bound"}, tags...))
+	mocked.AssertMetric(t, "Gauge", "kubernetes_state.persistentvolumeclaim.request_storage", 1024*1024*1024, "", tags)
+}
@@ -165,12 +165,21 @@ func (d *DockerCheck) Run() error {
 		d.Warnf("Error initialising check: %s", err)
 		return err
 	}
-	cList, err := du.ListContainers(&docker.ContainerListConfig{IncludeExited: true, FlagExcluded: true})
+	res, err := d.FetchShared("docker", "containers", func() (interface{}, error) {
+		return du.ListContainers(&docker.ContainerListConfig{IncludeExited: true, FlagExcluded: true})
+	})
 	if err != nil {
 		sender.ServiceCheck(DockerServiceUp, metrics.ServiceCheckCritical, "", nil, err.Error())
 		d.Warnf("Error collecting containers: %s", err)
 		return err
 	}
+	// The list may be shared with the other instances, copy the containers before using them
+	sharedList := res.([]*containers.Container)
+	cList := make([]*containers.Container, 0, len(sharedList))
+	for _, c := range sharedList {
+		container := *c
+		cList = append(cList, &container)
+	}
 
 	collectingContainerSizeDuringThisRun := d.instance.CollectContainerSize && d.collectContainerSizeCounter == 0
 
@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package corechecks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+var (
+	sharedResults = newResultCache()
+
+	tlmSharedCacheHits = telemetry.NewCounter("checks", "shared_cache_hits",
+		[]string{"endpoint"}, "Count of query results served from the cache shared by check instances")
+	tlmSharedCacheMisses = telemetry.NewCounter("checks", "shared_cache_misses",
+		[]string{"endpoint"}, "Count of query results fetched from their endpoint by check instances")
+)
+
+// resultsPurgeInterval is the minimum interval between two purges of the
+// expired results of the cache.
+const resultsPurgeInterval = time.Minute
+
+// sharedResult is the last result of a query, along with when it was fetched.
+type sharedResult struct {
+	sync.Mutex
+	value     interface{}
+	fetchedAt time.Time
+	expiresAt time.Time // guarded by the resultCache lock
+}
+
+// resultCache holds the results of the queries of check instances, by endpoint
+// and query, so that instances querying the same endpoint do not fetch the same
+// data over and over.
+type resultCache struct {
+	m          sync.Mutex
+	results    map[string]*sharedResult
+	lastPurged time.Time
+}
+
+func newResultCache() *resultCache {
+	return &resultCache{results: make(map[string]*sharedResult), lastPurged: time.Now()}
+}
+
+// get returns the result of the query on the endpoint if it was fetched less
+// than ttl ago, or fetches it. Concurrent callers of the same query wait for
+// the first one to fetch it. Errors are not cached.
+func (rc *resultCache) get(endpoint, query string, ttl time.Duration, fetch func() (interface{}, error)) (interface{}, error) {
+	key := endpoint + "/" + query
+	now := time.Now()
+	rc.m.Lock()
+	if now.Sub(rc.lastPurged) >= resultsPurgeInterval {
+		rc.purgeLocked(now)
+	}
+	result, found := rc.results[key]
+	if !found {
+		result = &sharedResult{}
+		rc.results[key] = result
+	}
+	// The result is kept as long as the callers may reuse it
+	if expiresAt := now.Add(ttl); expiresAt.After(result.expiresAt) {
+		result.expiresAt = expiresAt
+	}
+	rc.m.Unlock()
+
+	result.Lock()
+	defer result.Unlock()
+
+	if !result.fetchedAt.IsZero() && time.Since(result.fetchedAt) < ttl {
+		tlmSharedCacheHits.Inc(endpoint)
+		return result.value, nil
+	}
+
+	tlmSharedCacheMisses.Inc(endpoint)
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	result.value = value
+	result.fetchedAt = time.Now()
+	return value, nil
+}
+
+// purgeLocked drops the results which were not requested during their ttl, so
+// that the cache does not grow with the queries no instance makes anymore.
+// rc.m must be held.
+func (rc *resultCache) purgeLocked(now time.Time) {
+	for key, result := range rc.results {
+		if now.After(result.expiresAt) {
+			delete(rc.results, key)
+		}
+	}
+	rc.lastPurged = now
+}
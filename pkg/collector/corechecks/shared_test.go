@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package corechecks
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchShared(t *testing.T) {
+	sharedResults = newResultCache()
+	fetches := 0
+	fetch := func() (interface{}, error) {
+		fetches++
+		return fetches, nil
+	}
+
+	// instances without a shared_cache_ttl do not share their results
+	base := NewCheckBase("test")
+	require.NoError(t, base.CommonConfigure([]byte("{}"), "test"))
+	for i := 1; i <= 2; i++ {
+		v, err := base.FetchShared("endpoint", "query", fetch)
+		assert.NoError(t, err)
+		assert.Equal(t, i, v)
+	}
+
+	fetches = 0
+	instances := make([]CheckBase, 3)
+	for i := range instances {
+		instances[i] = NewCheckBase("test")
+		require.NoError(t, instances[i].CommonConfigure([]byte("shared_cache_ttl: 60"), "test"))
+	}
+	var wg sync.WaitGroup
+	for i := range instances {
+		wg.Add(1)
+		go func(c *CheckBase) {
+			defer wg.Done()
+			v, err := c.FetchShared("endpoint", "query", fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, v)
+		}(&instances[i])
+	}
+	wg.Wait()
+	assert.Equal(t, 1, fetches)
+
+	// other queries are cached separately
+	v, err := instances[0].FetchShared("endpoint", "other", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+
+	// the ttl is capped below the collection interval
+	capped := NewCheckBase("test")
+	require.NoError(t, capped.CommonConfigure([]byte("shared_cache_ttl: 60\nmin_collection_interval: 20"), "test"))
+	assert.Equal(t, 10*time.Second, capped.sharedCacheTTL)
+}
+
+func TestResultCacheExpiry(t *testing.T) {
+	rc := newResultCache()
+	fetches := 0
+	fetch := func() (interface{}, error) {
+		fetches++
+		return fetches, nil
+	}
+
+	v, _ := rc.get("endpoint", "query", 20*time.Millisecond, fetch)
+	assert.Equal(t, 1, v)
+	v, _ = rc.get("endpoint", "query", 20*time.Millisecond, fetch)
+	assert.Equal(t, 1, v)
+	// the ttl is the one of the caller
+	v, _ = rc.get("endpoint", "query", time.Nanosecond, fetch)
+	assert.Equal(t, 2, v)
+	time.Sleep(30 * time.Millisecond)
+	v, _ = rc.get("endpoint", "query", 20*time.Millisecond, fetch)
+	assert.Equal(t, 3, v)
+
+	// errors are not cached
+	_, err := rc.get("endpoint", "failing", time.Minute, func() (interface{}, error) { return nil, errors.New("failed") })
+	assert.Error(t, err)
+	v, err = rc.get("endpoint", "failing", time.Minute, fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, v)
+}
+
+func TestResultCachePurge(t *testing.T) {
+	rc := newResultCache()
+	fetch := func() (interface{}, error) { return 1, nil }
+
+	rc.get("endpoint", "short", 20*time.Millisecond, fetch)
+	rc.get("endpoint", "long", time.Hour, fetch)
+	rc.get("endpoint", "failing", 20*time.Millisecond, func() (interface{}, error) { return nil, errors.New("failed") })
+	assert.Len(t, rc.results, 3)
+
+	// results are only purged once per purge interval
+	time.Sleep(30 * time.Millisecond)
+	rc.get("endpoint", "long", time.Hour, fetch)
+	assert.Len(t, rc.results, 3)
+
+	rc.m.Lock()
+	rc.lastPurged = time.Now().Add(-resultsPurgeInterval)
+	rc.m.Unlock()
+	rc.get("endpoint", "long", time.Hour, fetch)
+	assert.Len(t, rc.results, 1)
+	assert.Contains(t, rc.results, "endpoint/long")
+}
@@ -0,0 +1,288 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package external
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Restart policies, deciding what happens when a check process exits or times out.
+const (
+	// RestartAlways restarts the process on the next run, backing off exponentially
+	// while it keeps failing.
+	RestartAlways = "always"
+	// RestartNever stops running the check once its process failed.
+	RestartNever = "never"
+)
+
+const (
+	minRestartBackoff = time.Second
+	maxRestartBackoff = 5 * time.Minute
+)
+
+// instanceConfig holds the instance options specific to external checks.
+type instanceConfig struct {
+	Args          []string `yaml:"args"`
+	Timeout       int      `yaml:"timeout"`
+	RestartPolicy string   `yaml:"restart_policy"`
+}
+
+// Check runs a check instance in an external process, which it keeps running
+// between runs. See protocol.go for how the agent and the process communicate.
+type Check struct {
+	corechecks.CheckBase
+
+	path          string
+	args          []string
+	timeout       time.Duration
+	restartPolicy string
+	configure     request
+	runs          uint64 // sequence number of the last run request
+
+	m        sync.Mutex // guards below
+	proc     *process
+	failures int       // consecutive failed runs, reset by a successful run
+	retryAt  time.Time // time before which the process is not restarted
+	restarts int64
+	timeouts int64
+	stopped  bool
+}
+
+// NewCheck returns a check running the executable at path.
+func NewCheck(name, path string, timeout time.Duration) *Check {
+	return &Check{
+		CheckBase:     corechecks.NewCheckBase(name),
+		path:          path,
+		timeout:       timeout,
+		restartPolicy: RestartAlways,
+	}
+}
+
+// Configure configures the check, the configuration is sent to the process
+// when it starts.
+func (c *Check) Configure(data integration.Data, initConfig integration.Data, source string) error {
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data, source); err != nil {
+		return err
+	}
+
+	var conf instanceConfig
+	if err := yaml.Unmarshal(data, &conf); err != nil {
+		return err
+	}
+	c.args = conf.Args
+	if conf.Timeout > 0 {
+		c.timeout = time.Duration(conf.Timeout) * time.Second
+	}
+	switch conf.RestartPolicy {
+	case "":
+	case RestartAlways, RestartNever:
+		c.restartPolicy = conf.RestartPolicy
+	default:
+		return fmt.Errorf("invalid restart_policy %q, must be %q or %q", conf.RestartPolicy, RestartAlways, RestartNever)
+	}
+
+	instance, err := toJSONMap(data)
+	if err != nil {
+		return err
+	}
+	init, err := toJSONMap(initConfig)
+	if err != nil {
+		return err
+	}
+	c.configure = request{
+		Type:       requestConfigure,
+		Name:       c.String(),
+		ID:         string(c.ID()),
+		Instance:   instance,
+		InitConfig: init,
+	}
+	return nil
+}
+
+// toJSONMap converts YAML configuration data to a value which can be encoded to JSON.
+func toJSONMap(data integration.Data) (interface{}, error) {
+	raw := integration.RawMap{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return util.GetJSONSerializableMap(raw), nil
+}
+
+// Run asks the process to run the check, starting it if needed, and forwards what it
+// sends to the aggregator until it is done.
+func (c *Check) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+	p, err := c.process()
+	if err != nil {
+		return err
+	}
+	if !p.discard() {
+		return c.fail(p, false, errors.New("process exited between runs"))
+	}
+	c.runs++
+	if err := p.startRun(c.runs); err != nil {
+		return c.fail(p, false, fmt.Errorf("could not send run request: %s", err))
+	}
+
+	timeout := time.NewTimer(c.timeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return c.fail(p, false, errors.New("process exited while running"))
+			}
+			if line.run != c.runs {
+				log.Debugf("Discarding output of check %s written before run %d: %s", c.ID(), c.runs, line.data)
+				continue
+			}
+			msg, err := parseMessage(line.data)
+			if err != nil {
+				c.Warnf("Invalid message from check process: %s", err)
+				continue
+			}
+			if msg.Run != 0 && msg.Run != c.runs {
+				log.Debugf("Discarding message of run %d of check %s during run %d", msg.Run, c.ID(), c.runs)
+				continue
+			}
+			switch msg.Type {
+			case messageWarning:
+				c.Warn(msg.Message)
+			case messageDone:
+				sender.Commit()
+				c.succeed()
+				if msg.Error != "" {
+					return errors.New(msg.Error)
+				}
+				return nil
+			default:
+				if err := msg.submit(sender); err != nil {
+					c.Warn(err)
+				}
+			}
+		case <-timeout.C:
+			return c.fail(p, true, fmt.Errorf("run timed out after %s", c.timeout))
+		}
+	}
+}
+
+// process returns the running process, starting it if needed.
+func (c *Check) process() (*process, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.stopped {
+		return nil, errors.New("check is stopped")
+	}
+	if c.proc != nil {
+		return c.proc, nil
+	}
+	if c.failures > 0 {
+		if c.restartPolicy == RestartNever {
+			return nil, fmt.Errorf("process failed and restart_policy is %q", RestartNever)
+		}
+		if wait := time.Until(c.retryAt); wait > 0 {
+			return nil, fmt.Errorf("process failed, restarting in %s", wait.Round(time.Second))
+		}
+		c.restarts++
+	}
+
+	p, err := startProcess(string(c.ID()), c.path, c.args)
+	if err != nil {
+		c.backoffLocked()
+		return nil, fmt.Errorf("could not start %s: %s", c.path, err)
+	}
+	if err := p.send(c.configure); err != nil {
+		p.kill()
+		c.backoffLocked()
+		return nil, fmt.Errorf("could not configure %s: %s", c.path, err)
+	}
+	log.Debugf("Started process %d for check %s", p.cmd.Process.Pid, c.ID())
+	c.proc = p
+	return p, nil
+}
+
+// fail kills the process p after a failed run and returns err.
+func (c *Check) fail(p *process, timedOut bool, err error) error {
+	c.m.Lock()
+	if c.proc == p {
+		c.proc = nil
+	}
+	if timedOut {
+		c.timeouts++
+	}
+	c.backoffLocked()
+	c.m.Unlock()
+
+	if exitErr := p.kill(); exitErr != nil && !timedOut {
+		err = fmt.Errorf("%s: %s", err, exitErr)
+	}
+	return err
+}
+
+// backoffLocked records a failure and computes when the process may be restarted.
+// c.m must be held.
+func (c *Check) backoffLocked() {
+	c.failures++
+	backoff := maxRestartBackoff
+	if c.failures < 10 {
+		backoff = minRestartBackoff << uint(c.failures-1)
+		if backoff > maxRestartBackoff {
+			backoff = maxRestartBackoff
+		}
+	}
+	c.retryAt = time.Now().Add(backoff)
+}
+
+func (c *Check) succeed() {
+	c.m.Lock()
+	c.failures = 0
+	c.m.Unlock()
+}
+
+// Stop kills the process, the check can not run anymore.
+func (c *Check) Stop() {
+	c.m.Lock()
+	p := c.proc
+	c.proc = nil
+	c.stopped = true
+	c.m.Unlock()
+
+	if p != nil {
+		p.kill()
+	}
+}
+
+// Cancel implements check.Canceler, it kills the process kept between runs when
+// the check is unscheduled while it is not running.
+func (c *Check) Cancel() {
+	c.Stop()
+}
+
+// ExtraStats implements check.ExtraStatsProvider.
+func (c *Check) ExtraStats() map[string]int64 {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return map[string]int64{
+		"Process Restarts": c.restarts,
+		"Process Timeouts": c.timeouts,
+	}
+}
@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package external
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/collector"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// TestHelperProcess is not a real test, it is the check process started by the
+// other tests. Its behavior depends on the argument following "--".
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	mode := os.Args[len(os.Args)-1]
+	var value float64
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			Type     string
+			Instance map[string]interface{}
+			Run      uint64
+		}
+		json.Unmarshal(scanner.Bytes(), &req)
+		switch req.Type {
+		case requestConfigure:
+			value, _ = req.Instance["value"].(float64)
+		case requestRun:
+			switch mode {
+			case "hang":
+				time.Sleep(time.Hour)
+			case "crash":
+				os.Exit(1)
+			case "verbose":
+				// longer than maxLineSize, stderr must be drained anyway
+				fmt.Fprintln(os.Stderr, strings.Repeat("x", 2*maxLineSize))
+			}
+			fmt.Fprintln(os.Stderr, "running")
+			fmt.Printf(`{"type":"metric","name":"external.value","value":%g,"tags":["foo:bar"]}`+"\n", value)
+			fmt.Println(`{"type":"metric","name":"external.count","metric_type":"count","value":1}`)
+			fmt.Println(`{"type":"service_check","name":"external.up","status":0,"message":"ok"}`)
+			fmt.Println(`not json`)
+			fmt.Println(`{"type":"warning","message":"careful"}`)
+			fmt.Println(`{"type":"done"}`)
+			if mode == "late" {
+				// written after the end of the run, must not be counted in the next one
+				fmt.Printf(`{"type":"metric","name":"external.late","value":1,"run":%d}`+"\n", req.Run)
+				fmt.Println(`{"type":"warning","message":"too late"}`)
+			}
+		}
+	}
+}
+
+func newTestCheck(t *testing.T, mode string, options string) *Check {
+	os.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	c := NewCheck("external_test", os.Args[0], time.Second)
+	instance := fmt.Sprintf("value: 3\nargs: [\"-test.run=TestHelperProcess\", \"--\", %q]\n%s", mode, options)
+	require.NoError(t, c.Configure([]byte(instance), []byte("foo: bar"), "test"))
+	return c
+}
+
+func TestRun(t *testing.T) {
+	c := newTestCheck(t, "ok", "")
+	defer c.Stop()
+
+	sender := mocksender.NewMockSender(c.ID())
+	sender.On("Gauge", "external.value", 3.0, "", []string{"foo:bar"}).Return().Times(2)
+	sender.On("Count", "external.count", 1.0, "", []string(nil)).Return().Times(2)
+	sender.On("ServiceCheck", "external.up", metrics.ServiceCheckOK, "", []string(nil), "ok").Return().Times(2)
+	sender.On("Commit").Return().Times(2)
+
+	// the process keeps running between runs
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, c.Run())
+		assert.Len(t, c.GetWarnings(), 2)
+	}
+	sender.AssertExpectations(t)
+	assert.Equal(t, map[string]int64{"Process Restarts": 0, "Process Timeouts": 0}, c.ExtraStats())
+}
+
+func TestRunLateOutput(t *testing.T) {
+	c := newTestCheck(t, "late", "")
+	defer c.Stop()
+
+	// the mock sender fails on the unexpected external.late metric
+	sender := mocksender.NewMockSender(c.ID())
+	sender.On("Gauge", "external.value", 3.0, "", []string{"foo:bar"}).Return().Times(3)
+	sender.On("Count", "external.count", 1.0, "", []string(nil)).Return().Times(3)
+	sender.On("ServiceCheck", "external.up", metrics.ServiceCheckOK, "", []string(nil), "ok").Return().Times(3)
+	sender.On("Commit").Return().Times(3)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, c.Run())
+		time.Sleep(50 * time.Millisecond)
+	}
+	sender.AssertExpectations(t)
+}
+
+func TestRunLongStderrLine(t *testing.T) {
+	c := newTestCheck(t, "verbose", "")
+	defer c.Stop()
+
+	sender := mocksender.NewMockSender(c.ID())
+	sender.SetupAcceptAll()
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, c.Run())
+	}
+	sender.AssertNumberOfCalls(t, "Commit", 2)
+}
+
+func TestRunTimeout(t *testing.T) {
+	c := newTestCheck(t, "hang", "timeout: 1")
+	defer c.Stop()
+	mocksender.NewMockSender(c.ID())
+
+	assert.Error(t, c.Run())
+	assert.EqualValues(t, 1, c.ExtraStats()["Process Timeouts"])
+
+	// the process is restarted after a backoff
+	err := c.Run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "restarting in")
+	assert.EqualValues(t, 0, c.ExtraStats()["Process Restarts"])
+}
+
+func TestRestartPolicy(t *testing.T) {
+	c := newTestCheck(t, "crash", "restart_policy: always")
+	defer c.Stop()
+	mocksender.NewMockSender(c.ID())
+
+	assert.Error(t, c.Run())
+	c.m.Lock()
+	c.retryAt = time.Time{}
+	c.m.Unlock()
+	assert.Error(t, c.Run())
+	assert.EqualValues(t, 1, c.ExtraStats()["Process Restarts"])
+
+	c = newTestCheck(t, "crash", "restart_policy: never")
+	defer c.Stop()
+	mocksender.NewMockSender(c.ID())
+
+	assert.Error(t, c.Run())
+	err := c.Run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "restart_policy")
+	assert.EqualValues(t, 0, c.ExtraStats()["Process Restarts"])
+
+	c = NewCheck("external_test", os.Args[0], time.Second)
+	assert.Error(t, c.Configure([]byte("restart_policy: sometimes"), nil, "test"))
+}
+
+func TestStopCheckIdle(t *testing.T) {
+	c := newTestCheck(t, "ok", "")
+	defer c.Stop()
+
+	sender := mocksender.NewMockSender(c.ID())
+	sender.On("Gauge", "external.value", 3.0, "", []string{"foo:bar"}).Return()
+	sender.On("Count", "external.count", 1.0, "", []string(nil)).Return()
+	sender.On("ServiceCheck", "external.up", metrics.ServiceCheckOK, "", []string(nil), "ok").Return()
+	sender.On("Commit").Return()
+
+	coll := collector.NewCollector()
+	defer coll.Stop()
+	_, err := coll.RunCheck(c)
+	require.NoError(t, err)
+
+	// wait for the first run to start the process and complete
+	var p *process
+	for i := 0; i < 500 && p == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		c.m.Lock()
+		p = c.proc
+		c.m.Unlock()
+	}
+	require.NotNil(t, p)
+
+	// the runner doesn't stop idle checks, the collector cancels them
+	require.NoError(t, coll.StopCheck(c.ID()))
+	c.m.Lock()
+	assert.Nil(t, c.proc)
+	c.m.Unlock()
+	assert.NotNil(t, p.cmd.ProcessState, "the process must have exited")
+}
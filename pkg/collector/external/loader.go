@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// Package external implements a check loader running checks as external
+// executables, which can thus be written in any language. The executables
+// exchange JSON messages with the agent on their standard input and output.
+package external
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/loaders"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// CheckLoader loads the checks whose executable, named after the check, is
+// found in the external checks directory.
+type CheckLoader struct {
+	dir     string
+	timeout time.Duration
+}
+
+// NewCheckLoader creates a loader for the checks found in dir, whose runs time out
+// after timeout unless their instance sets another one.
+func NewCheckLoader(dir string, timeout time.Duration) (*CheckLoader, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("external_checks_dir is not set")
+	}
+	return &CheckLoader{dir: dir, timeout: timeout}, nil
+}
+
+// executable returns the path to the executable of the check with the given name.
+func (cl *CheckLoader) executable(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid check name %q", name)
+	}
+	path := filepath.Join(cl.dir, name)
+	if runtime.GOOS == "windows" {
+		path += ".exe"
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() || (runtime.GOOS != "windows" && fi.Mode()&0111 == 0) {
+		return "", fmt.Errorf("%s is not an executable file", path)
+	}
+	return path, nil
+}
+
+// Load returns a list of checks, one for every configuration instance found in `config`
+func (cl *CheckLoader) Load(config integration.Config) ([]check.Check, error) {
+	checks := []check.Check{}
+
+	if check.IsJMXConfig(config.Name, config.InitConfig) {
+		return checks, fmt.Errorf("check %s appears to be a JMX check - skipping", config.Name)
+	}
+
+	path, err := cl.executable(config.Name)
+	if err != nil {
+		return checks, fmt.Errorf("check %s not found in %s: %s", config.Name, cl.dir, err)
+	}
+
+	errors := []string{}
+	for _, instance := range config.Instances {
+		c := NewCheck(config.Name, path, cl.timeout)
+		if err := c.Configure(instance, config.InitConfig, config.Source); err != nil {
+			errors = append(errors, fmt.Sprintf("Could not configure check %s: %s", c, err))
+			log.Errorf("external.loader: could not configure check %s: %s", c, err)
+			continue
+		}
+		checks = append(checks, c)
+	}
+
+	if len(errors) != 0 {
+		return checks, fmt.Errorf(strings.Join(errors, "\n"))
+	}
+
+	return checks, nil
+}
+
+func (cl *CheckLoader) String() string {
+	return "External Check Loader"
+}
+
+func init() {
+	factory := func() (check.Loader, error) {
+		return NewCheckLoader(
+			config.Datadog.GetString("external_checks_dir"),
+			config.Datadog.GetDuration("external_checks_timeout")*time.Second,
+		)
+	}
+
+	loaders.RegisterLoader(40, factory)
+}
@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build !windows
+
+package external
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external-checks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "mycheck"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "notexec"), []byte("#!/bin/sh\n"), 0644))
+
+	_, err = NewCheckLoader("", time.Second)
+	assert.Error(t, err)
+	loader, err := NewCheckLoader(dir, time.Second)
+	require.NoError(t, err)
+
+	checks, err := loader.Load(integration.Config{
+		Name:       "mycheck",
+		Instances:  []integration.Data{integration.Data("value: 1"), integration.Data("value: 2")},
+		InitConfig: integration.Data("{}"),
+	})
+	assert.NoError(t, err)
+	require.Len(t, checks, 2)
+	assert.NotEqual(t, checks[0].ID(), checks[1].ID())
+	assert.Equal(t, "mycheck", checks[0].String())
+
+	for _, name := range []string{"notexec", "missing", "../mycheck"} {
+		checks, err = loader.Load(integration.Config{Name: name, Instances: []integration.Data{integration.Data("{}")}})
+		assert.Error(t, err, name)
+		assert.Empty(t, checks, name)
+	}
+
+	checks, err = loader.Load(integration.Config{
+		Name:       "mycheck",
+		Instances:  []integration.Data{integration.Data("{}")},
+		InitConfig: integration.Data("is_jmx: true"),
+	})
+	assert.Error(t, err)
+	assert.Empty(t, checks)
+}
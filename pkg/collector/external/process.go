@@ -0,0 +1,161 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package external
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// maxLineSize is the maximum size of a message written by a check process.
+const maxLineSize = 1024 * 1024
+
+// outputLine is a line written by a check process on its stdout.
+type outputLine struct {
+	// run is the sequence number of the last run request sent to the process
+	// when the line was read.
+	run  uint64
+	data []byte
+}
+
+// process is a running check executable.
+type process struct {
+	// run is the sequence number of the last run request, accessed atomically.
+	// It is kept first for its 64-bit alignment on 32-bit platforms.
+	run uint64
+
+	name  string
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	// lines receives the lines written by the process on its stdout. It is
+	// closed when stdout is closed, usually because the process exited.
+	lines chan outputLine
+	// stderrDone is closed once stderr is closed.
+	stderrDone chan struct{}
+
+	killOnce sync.Once
+	exitErr  error
+}
+
+// startProcess starts the executable at path with the given arguments. name is
+// used to prefix the logs written by the process on stderr.
+func startProcess(name, path string, args []string) (*process, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &process{
+		name:       name,
+		cmd:        cmd,
+		stdin:      stdin,
+		lines:      make(chan outputLine),
+		stderrDone: make(chan struct{}),
+	}
+	go p.readLines(stdout)
+	go p.logStderr(stderr)
+	return p, nil
+}
+
+func (p *process) readLines(r io.Reader) {
+	defer close(p.lines)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	for scanner.Scan() {
+		line := outputLine{
+			run:  atomic.LoadUint64(&p.run),
+			data: make([]byte, len(scanner.Bytes())),
+		}
+		copy(line.data, scanner.Bytes())
+		p.lines <- line
+	}
+	if err := scanner.Err(); err != nil {
+		log.Warnf("Error reading the output of check %s: %s", p.name, err)
+	}
+}
+
+func (p *process) logStderr(r io.Reader) {
+	defer close(p.stderrDone)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	for scanner.Scan() {
+		log.Infof("check %s: %s", p.name, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		log.Warnf("Error reading the logs of check %s, not logging them anymore: %s", p.name, err)
+		// keep reading so that the process does not block writing on stderr
+		io.Copy(ioutil.Discard, r)
+	}
+}
+
+// startRun sends the run request with the sequence number run. The lines read
+// afterwards are tagged with it.
+func (p *process) startRun(run uint64) error {
+	atomic.StoreUint64(&p.run, run)
+	return p.send(request{Type: requestRun, Run: run})
+}
+
+// send writes the request to the process stdin.
+func (p *process) send(req request) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	_, err = p.stdin.Write(append(b, '\n'))
+	return err
+}
+
+// discard drops the lines the process has written but which were not read, e.g.
+// those written after the end of the previous run. It returns false if the
+// process closed its stdout. A line still being read when it returns is tagged
+// with the previous run and dropped by the next one.
+func (p *process) discard() bool {
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return false
+			}
+			log.Debugf("Discarding output of check %s written between runs: %s", p.name, line.data)
+		default:
+			return true
+		}
+	}
+}
+
+// kill kills the process, drains its output and waits for it to exit. It returns
+// the exit error of the process, if any. It is safe to call it several times.
+func (p *process) kill() error {
+	p.killOnce.Do(func() {
+		p.stdin.Close()
+		p.cmd.Process.Kill()
+		for range p.lines {
+		}
+		<-p.stderrDone
+		p.exitErr = p.cmd.Wait()
+	})
+	return p.exitErr
+}
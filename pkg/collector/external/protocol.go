@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// Messages are exchanged with the check process as JSON objects, one per line.
+//
+// The agent writes a "configure" request on the process stdin when starting it,
+// then a "run" request every time the check runs. The process answers every "run"
+// request by writing any number of "metric", "service_check", "event" and "warning"
+// messages on its stdout, followed by a single "done" message. Anything written on
+// stderr is logged by the agent.
+//
+// Every "run" request holds a "run" sequence number. Messages written after the
+// "done" message of a run are discarded, as are the messages holding a "run" number
+// other than the one of the current run.
+const (
+	requestConfigure = "configure"
+	requestRun       = "run"
+
+	messageMetric       = "metric"
+	messageServiceCheck = "service_check"
+	messageEvent        = "event"
+	messageWarning      = "warning"
+	messageDone         = "done"
+)
+
+// request is a message sent to the check process.
+type request struct {
+	Type string `json:"type"`
+
+	// Set on configure requests only.
+	Name       string      `json:"name,omitempty"`
+	ID         string      `json:"id,omitempty"`
+	Instance   interface{} `json:"instance,omitempty"`
+	InitConfig interface{} `json:"init_config,omitempty"`
+
+	// Set on run requests only.
+	Run uint64 `json:"run,omitempty"`
+}
+
+// message is a message received from the check process. Fields are set depending
+// on its type.
+type message struct {
+	Type string `json:"type"`
+	// Run optionally echoes the sequence number of the run request
+	Run uint64 `json:"run"`
+
+	// metric and service_check
+	Name     string   `json:"name"`
+	Hostname string   `json:"hostname"`
+	Tags     []string `json:"tags"`
+
+	// metric
+	MetricType string  `json:"metric_type"`
+	Value      float64 `json:"value"`
+
+	// service_check
+	Status int `json:"status"`
+
+	// service_check and warning
+	Message string `json:"message"`
+
+	// event
+	Event *metrics.Event `json:"event"`
+
+	// done
+	Error string `json:"error"`
+}
+
+// parseMessage decodes a line written by the check process.
+func parseMessage(line []byte) (*message, error) {
+	var m message
+	if err := json.Unmarshal(line, &m); err != nil {
+		return nil, err
+	}
+	switch m.Type {
+	case messageMetric, messageServiceCheck:
+		if m.Name == "" {
+			return nil, fmt.Errorf("%s message without a name", m.Type)
+		}
+	case messageEvent:
+		if m.Event == nil {
+			return nil, fmt.Errorf("event message without an event")
+		}
+	case messageWarning, messageDone:
+	default:
+		return nil, fmt.Errorf("unknown message type %q", m.Type)
+	}
+	return &m, nil
+}
+
+// submit forwards a metric, service check or event message to the sender.
+func (m *message) submit(sender aggregator.Sender) error {
+	switch m.Type {
+	case messageMetric:
+		switch m.MetricType {
+		case "gauge", "":
+			sender.Gauge(m.Name, m.Value, m.Hostname, m.Tags)
+		case "rate":
+			sender.Rate(m.Name, m.Value, m.Hostname, m.Tags)
+		case "count":
+			sender.Count(m.Name, m.Value, m.Hostname, m.Tags)
+		case "monotonic_count":
+			sender.MonotonicCount(m.Name, m.Value, m.Hostname, m.Tags)
+		case "counter":
+			sender.Counter(m.Name, m.Value, m.Hostname, m.Tags)
+		case "histogram":
+			sender.Histogram(m.Name, m.Value, m.Hostname, m.Tags)
+		case "historate":
+			sender.Historate(m.Name, m.Value, m.Hostname, m.Tags)
+		default:
+			return fmt.Errorf("unknown metric type %q for %s", m.MetricType, m.Name)
+		}
+	case messageServiceCheck:
+		sender.ServiceCheck(m.Name, metrics.ServiceCheckStatus(m.Status), m.Hostname, m.Tags, m.Message)
+	case messageEvent:
+		sender.Event(*m.Event)
+	}
+	return nil
+}
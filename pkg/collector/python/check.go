@@ -43,6 +43,7 @@ type PythonCheck struct {
 	class        *C.rtloader_pyobject_t
 	ModuleName   string
 	interval     time.Duration
+	scheduling   integration.SchedulingConfig
 	lastWarnings []error
 	source       string
 	telemetry    bool // whether or not the telemetry is enabled for this check
@@ -183,6 +184,7 @@ func (c *PythonCheck) Configure(data integration.Data, initConfig integration.Da
 	if commonOptions.MinCollectionInterval > 0 {
 		c.interval = time.Duration(commonOptions.MinCollectionInterval) * time.Second
 	}
+	c.scheduling = commonOptions.Scheduling
 
 	// Disable default hostname if specified
 	if commonOptions.EmptyDefaultHostname {
@@ -250,6 +252,11 @@ func (c *PythonCheck) Interval() time.Duration {
 	return c.interval
 }
 
+// SchedulingConfig returns the scheduling options of the check instance
+func (c *PythonCheck) SchedulingConfig() integration.SchedulingConfig {
+	return c.scheduling
+}
+
 // ID returns the ID of the check
 func (c *PythonCheck) ID() check.ID {
 	return c.id
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package runner
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Health states of a check
+const (
+	// healthy checks ran successfully without warnings on their last run
+	healthHealthy = "healthy"
+	// degraded checks had warnings, or fewer consecutive errors than the failing threshold
+	healthDegraded = "degraded"
+	// failing checks had too many consecutive errors, their runs are skipped
+	// with an exponential backoff
+	healthFailing = "failing"
+	// quarantined checks failed for so long that they only run once in a
+	// while to probe whether they recovered
+	healthQuarantined = "quarantined"
+)
+
+// checkHealth is the health of a check, updated after each of its runs.
+type checkHealth struct {
+	state             string
+	consecutiveErrors int
+	retryAt           time.Time // runs are skipped until then
+}
+
+// serviceCheckStatus returns the status of the check health service check.
+func (h checkHealth) serviceCheckStatus() metrics.ServiceCheckStatus {
+	switch h.state {
+	case healthHealthy:
+		return metrics.ServiceCheckOK
+	case healthDegraded:
+		return metrics.ServiceCheckWarning
+	default:
+		return metrics.ServiceCheckCritical
+	}
+}
+
+// healthTracker keeps the health of the checks the runner runs. Checks failing
+// consecutively get their runs skipped with an exponential backoff, then are
+// quarantined. They get healthy again as soon as one of their runs succeeds.
+type healthTracker struct {
+	enabled             bool
+	failingThreshold    int
+	quarantineThreshold int
+	maxBackoff          time.Duration
+	probeInterval       time.Duration
+	checks              map[check.ID]*checkHealth
+	m                   sync.Mutex
+}
+
+func newHealthTracker() *healthTracker {
+	return &healthTracker{
+		enabled:             config.Datadog.GetBool("check_health.enabled"),
+		failingThreshold:    config.Datadog.GetInt("check_health.failing_threshold"),
+		quarantineThreshold: config.Datadog.GetInt("check_health.quarantine_threshold"),
+		maxBackoff:          config.Datadog.GetDuration("check_health.max_backoff") * time.Second,
+		probeInterval:       config.Datadog.GetDuration("check_health.probe_interval") * time.Second,
+		checks:              make(map[check.ID]*checkHealth),
+	}
+}
+
+// shouldRun returns whether a check should run now, or be skipped as it is
+// backing off.
+func (t *healthTracker) shouldRun(c check.Check, now time.Time) bool {
+	if !t.enabled || c.Interval() == 0 {
+		return true
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	h, found := t.checks[c.ID()]
+	return !found || !now.Before(h.retryAt)
+}
+
+// update updates the health of a check after a run, and returns it.
+func (t *healthTracker) update(c check.Check, err error, warnings []error, now time.Time) checkHealth {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	h, found := t.checks[c.ID()]
+	if !found {
+		h = &checkHealth{state: healthHealthy}
+		t.checks[c.ID()] = h
+	}
+	previous := h.state
+
+	if err == nil {
+		h.consecutiveErrors = 0
+		h.retryAt = time.Time{}
+		h.state = healthHealthy
+		if len(warnings) > 0 {
+			h.state = healthDegraded
+		}
+		if previous == healthFailing || previous == healthQuarantined {
+			log.Infof("Check %s recovered, it is scheduled normally again", c)
+		}
+		return *h
+	}
+
+	h.consecutiveErrors++
+	switch {
+	case !t.enabled || c.Interval() == 0 || h.consecutiveErrors < t.failingThreshold:
+		h.state = healthDegraded
+	case t.quarantineThreshold > 0 && h.consecutiveErrors >= t.quarantineThreshold:
+		h.state = healthQuarantined
+		h.retryAt = now.Add(t.probeInterval)
+	default:
+		h.state = healthFailing
+		h.retryAt = now.Add(t.backoff(c.Interval(), h.consecutiveErrors))
+	}
+
+	if h.state != previous {
+		switch h.state {
+		case healthFailing:
+			log.Warnf("Check %s failed %d times in a row, backing off until %s", c, h.consecutiveErrors, h.retryAt.Format(time.RFC3339))
+		case healthQuarantined:
+			log.Warnf("Check %s failed %d times in a row, quarantining it: it will only run every %s until it succeeds", c, h.consecutiveErrors, t.probeInterval)
+		}
+	}
+	return *h
+}
+
+// backoff returns how long to skip the runs of a check after an error: it
+// starts at the check interval and doubles with every consecutive error, up
+// to maxBackoff.
+func (t *healthTracker) backoff(interval time.Duration, consecutiveErrors int) time.Duration {
+	backoff := interval
+	for i := t.failingThreshold; i < consecutiveErrors && backoff < t.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > t.maxBackoff {
+		backoff = t.maxBackoff
+	}
+	return backoff
+}
+
+// remove forgets the health of a check
+func (t *healthTracker) remove(id check.ID) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	delete(t.checks, id)
+}
+
+// healthServiceCheckMessage returns the message of the health service check of a check
+func healthServiceCheckMessage(h checkHealth) string {
+	switch h.state {
+	case healthFailing, healthQuarantined:
+		return fmt.Sprintf("check is %s after %d consecutive errors, next run at %s", h.state, h.consecutiveErrors, h.retryAt.Format(time.RFC3339))
+	case healthDegraded:
+		if h.consecutiveErrors > 0 {
+			return fmt.Sprintf("check is degraded after %d consecutive errors", h.consecutiveErrors)
+		}
+		return "check is degraded, its last run had warnings"
+	}
+	return ""
+}
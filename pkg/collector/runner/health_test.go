@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+type intervalCheck struct {
+	TestCheck
+	interval time.Duration
+}
+
+func (c *intervalCheck) Interval() time.Duration { return c.interval }
+
+func newTestHealthTracker() *healthTracker {
+	return &healthTracker{
+		enabled:             true,
+		failingThreshold:    3,
+		quarantineThreshold: 6,
+		maxBackoff:          time.Minute,
+		probeInterval:       time.Hour,
+		checks:              make(map[check.ID]*checkHealth),
+	}
+}
+
+func TestHealthTracker(t *testing.T) {
+	tracker := newTestHealthTracker()
+	c := &intervalCheck{TestCheck: TestCheck{id: "1"}, interval: 15 * time.Second}
+	now := time.Now()
+	failure := errors.New("failure")
+
+	assert.True(t, tracker.shouldRun(c, now))
+	h := tracker.update(c, nil, nil, now)
+	assert.Equal(t, healthHealthy, h.state)
+	assert.Equal(t, metrics.ServiceCheckOK, h.serviceCheckStatus())
+
+	h = tracker.update(c, nil, []error{errors.New("warning")}, now)
+	assert.Equal(t, healthDegraded, h.state)
+	assert.Equal(t, metrics.ServiceCheckWarning, h.serviceCheckStatus())
+
+	// errors below the failing threshold don't change the schedule
+	for i := 1; i < 3; i++ {
+		h = tracker.update(c, failure, nil, now)
+		assert.Equal(t, healthDegraded, h.state)
+		assert.Equal(t, i, h.consecutiveErrors)
+		assert.True(t, tracker.shouldRun(c, now))
+	}
+
+	// then runs are skipped with an exponential backoff
+	for _, backoff := range []time.Duration{15 * time.Second, 30 * time.Second, time.Minute} {
+		h = tracker.update(c, failure, nil, now)
+		assert.Equal(t, healthFailing, h.state)
+		assert.Equal(t, metrics.ServiceCheckCritical, h.serviceCheckStatus())
+		assert.Equal(t, now.Add(backoff), h.retryAt)
+		assert.False(t, tracker.shouldRun(c, now.Add(backoff-time.Second)))
+		assert.True(t, tracker.shouldRun(c, now.Add(backoff)))
+	}
+
+	// until the check gets quarantined
+	h = tracker.update(c, failure, nil, now)
+	assert.Equal(t, healthQuarantined, h.state)
+	assert.Equal(t, now.Add(time.Hour), h.retryAt)
+	assert.Contains(t, healthServiceCheckMessage(h), "quarantined after 6 consecutive errors")
+
+	// a successful probe makes it healthy again
+	h = tracker.update(c, nil, nil, now.Add(time.Hour))
+	assert.Equal(t, healthHealthy, h.state)
+	assert.Equal(t, 0, h.consecutiveErrors)
+	assert.True(t, tracker.shouldRun(c, now.Add(time.Hour)))
+
+	tracker.remove(c.ID())
+	assert.Len(t, tracker.checks, 0)
+}
+
+func TestHealthTrackerDisabled(t *testing.T) {
+	tracker := newTestHealthTracker()
+	tracker.enabled = false
+	c := &intervalCheck{TestCheck: TestCheck{id: "1"}, interval: 15 * time.Second}
+	longRunning := &intervalCheck{TestCheck: TestCheck{id: "2"}}
+	now := time.Now()
+
+	for i := 0; i < 10; i++ {
+		h := tracker.update(c, errors.New("failure"), nil, now)
+		assert.Equal(t, healthDegraded, h.state)
+		assert.True(t, tracker.shouldRun(c, now))
+
+		tracker.enabled = true
+		h = tracker.update(longRunning, errors.New("failure"), nil, now)
+		assert.Equal(t, healthDegraded, h.state)
+		assert.True(t, tracker.shouldRun(longRunning, now))
+		tracker.enabled = false
+	}
+}
+
+func TestWorkSkipsBackingOffChecks(t *testing.T) {
+	r := NewRunner()
+	defer r.Stop()
+	r.health = newTestHealthTracker()
+
+	c := newTestCheck(false, "backoff")
+	r.health.checks[c.ID()] = &checkHealth{state: healthFailing, consecutiveErrors: 3, retryAt: time.Now().Add(time.Hour)}
+	r.pending <- c
+	// wait to be sure the worker tried to run the check
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, c.HasRun())
+}
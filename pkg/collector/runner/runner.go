@@ -64,6 +64,7 @@ type Runner struct {
 	pending          chan check.Check         // The channel where checks come from
 	runningChecks    map[check.ID]check.Check // The list of checks running
 	scheduler        *scheduler.Scheduler     // Scheduler runner operates on
+	health           *healthTracker           // Health of the checks, to back off failing ones
 	m                sync.Mutex               // To control races on runningChecks
 
 }
@@ -76,6 +77,7 @@ func NewRunner() *Runner {
 		// initialize the channel
 		pending:          make(chan check.Check),
 		runningChecks:    make(map[check.ID]check.Check),
+		health:           newHealthTracker(),
 		running:          1,
 		staticNumWorkers: numWorkers != 0,
 	}
@@ -213,6 +215,9 @@ func (r *Runner) SetScheduler(s *scheduler.Scheduler) {
 func (r *Runner) StopCheck(id check.ID) error {
 	done := make(chan bool)
 
+	// the check starts healthy if it is scheduled again
+	r.health.remove(id)
+
 	r.m.Lock()
 	defer r.m.Unlock()
 
@@ -243,6 +248,13 @@ func (r *Runner) work() {
 	defer runnerStats.Add("Workers", -1)
 
 	for check := range r.pending {
+		// skip the runs of failing checks backing off
+		if !r.health.shouldRun(check, time.Now()) {
+			log.Tracef("Check %s is backing off, skip execution...", check)
+			runnerStats.Add("SkippedRuns", 1)
+			continue
+		}
+
 		// see if the check is already running
 		r.m.Lock()
 		if _, isRunning := r.runningChecks[check.ID()]; isRunning {
@@ -294,8 +306,14 @@ func (r *Runner) work() {
 			serviceCheckStatus = metrics.ServiceCheckCritical
 		}
 
+		health := r.health.update(check, err, warnings, time.Now())
+
 		if sender != nil && !longRunning {
 			sender.ServiceCheck("datadog.agent.check_status", serviceCheckStatus, hostname, serviceCheckTags, "")
+			if r.health.enabled {
+				healthTags := []string{fmt.Sprintf("check:%s", check.String()), fmt.Sprintf("check_id:%s", check.ID())}
+				sender.ServiceCheck("datadog.agent.check_health", health.serviceCheckStatus(), hostname, healthTags, healthServiceCheckMessage(health))
+			}
 			sender.Commit()
 		}
 
@@ -314,7 +332,7 @@ func (r *Runner) work() {
 			// otherwise only do so if the check is in the scheduler
 			if r.scheduler == nil || r.scheduler.IsCheckScheduled(check.ID()) {
 				mStats, _ := check.GetMetricStats()
-				addWorkStats(check, time.Since(t0), err, warnings, mStats)
+				addWorkStats(check, time.Since(t0), err, warnings, mStats, health)
 			}
 		}
 		r.m.Unlock()
@@ -366,7 +384,7 @@ func shouldLog(id check.ID) (doLog bool, lastLog bool) {
 	return
 }
 
-func addWorkStats(c check.Check, execTime time.Duration, err error, warnings []error, mStats map[string]int64) {
+func addWorkStats(c check.Check, execTime time.Duration, err error, warnings []error, mStats map[string]int64, health checkHealth) {
 	var s *check.Stats
 	var found bool
 
@@ -385,6 +403,10 @@ func addWorkStats(c check.Check, execTime time.Duration, err error, warnings []e
 	checkStats.M.Unlock()
 
 	s.Add(execTime, err, warnings, mStats)
+	s.SetHealth(health.state, uint64(health.consecutiveErrors), health.retryAt)
+	if p, ok := c.(check.ExtraStatsProvider); ok {
+		s.SetExtraStats(p.ExtraStats())
+	}
 }
 
 func expCheckStats() interface{} {
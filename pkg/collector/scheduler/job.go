@@ -34,6 +34,18 @@ func (jb *jobBucket) addJob(c check.Check) {
 	jb.jobs = append(jb.jobs, c)
 }
 
+func (jb *jobBucket) hasJob(match func(check.Check) bool) bool {
+	jb.mu.RLock()
+	defer jb.mu.RUnlock()
+
+	for _, c := range jb.jobs {
+		if match(c) {
+			return true
+		}
+	}
+	return false
+}
+
 // jobQueue contains a list of checks (called jobs) that need to be
 // scheduled at a certain interval.
 type jobQueue struct {
@@ -93,11 +105,22 @@ func newJobQueue(interval time.Duration) *jobQueue {
 	return jq
 }
 
-// addJob is a convenience method to add a check to a queue
-func (jq *jobQueue) addJob(c check.Check) {
+// addJob is a convenience method to add a check to a queue. The check is added
+// to the bucket of the first check of the queue it is related to, if any, so
+// that they are due at the same time.
+func (jq *jobQueue) addJob(c check.Check, related func(check.Check) bool) {
 	jq.mu.Lock()
 	defer jq.mu.Unlock()
 
+	if related != nil {
+		for _, bucket := range jq.buckets {
+			if bucket.hasJob(related) {
+				bucket.addJob(c)
+				return
+			}
+		}
+	}
+
 	// Checks scheduled to buckets scheduled with sparse round-robin
 	jq.buckets[jq.schedulingBucketIdx].addJob(c)
 	jq.schedulingBucketIdx = (jq.schedulingBucketIdx + jq.sparseStep) % uint(len(jq.buckets))
@@ -178,6 +201,7 @@ func (jq *jobQueue) process(s *Scheduler) bool {
 		jobs := []check.Check{}
 		jobs = append(jobs, bucket.jobs...)
 		bucket.mu.RUnlock()
+		jobs = s.orderByDependencies(jobs)
 
 		log.Tracef("Jobs in bucket: %v", jobs)
 
@@ -186,6 +210,15 @@ func (jq *jobQueue) process(s *Scheduler) bool {
 				continue
 			}
 
+			delay, run := s.schedule(check.ID(), t)
+			if !run {
+				continue
+			}
+			if delay > 0 {
+				s.enqueueAfter(check, delay)
+				continue
+			}
+
 			select {
 			// blocking, we'll be here as long as it takes
 			case s.checksPipe <- check:
@@ -48,6 +48,9 @@ type Scheduler struct {
 	checkToQueue map[check.ID]*jobQueue      // Keep track of what is the queue for any Check
 	mu           sync.Mutex                  // To protect critical sections in struct's fields
 
+	checkScheduling map[check.ID]*checkScheduling // Scheduling options of the checks having some
+	hostFreeAt      map[string]time.Time          // When the next check of a host can run, to space them
+
 	cancelOneTime chan bool      // Used to internally communicate a cancel signal to one-time schedule goroutines
 	wgOneTime     sync.WaitGroup // WaitGroup to track the exit of one-time schedule goroutines
 }
@@ -64,6 +67,9 @@ func NewScheduler(checksPipe chan<- check.Check) *Scheduler {
 		running:       0,
 		cancelOneTime: make(chan bool),
 		wgOneTime:     sync.WaitGroup{},
+
+		checkScheduling: make(map[check.ID]*checkScheduling),
+		hostFreeAt:      make(map[string]time.Time),
 	}
 }
 
@@ -80,7 +86,15 @@ func (s *Scheduler) Enter(check check.Check) error {
 		return fmt.Errorf("Schedule interval must be greater than %v or 0", minAllowedInterval)
 	}
 
+	scheduling, err := newCheckScheduling(check)
+	if err != nil {
+		return fmt.Errorf("invalid scheduling options: %s", err)
+	}
+
 	log.Infof("Scheduling check %v with an interval of %v", check, check.Interval())
+	if scheduling != nil {
+		log.Infof("Check %v is scheduled with %s", check, scheduling.summary())
+	}
 
 	// sync when accessing `jobQueues` and `check2queue`
 	s.mu.Lock()
@@ -94,15 +108,19 @@ func (s *Scheduler) Enter(check check.Check) error {
 		}
 		schedulerQueuesCount.Add(1)
 	}
-	s.jobQueues[check.Interval()].addJob(check)
+	s.jobQueues[check.Interval()].addJob(check, s.relatedChecksLocked(check, scheduling))
 	// map each check to the Job Queue it was assigned to
 	s.checkToQueue[check.ID()] = s.jobQueues[check.Interval()]
+	if scheduling != nil {
+		s.checkScheduling[check.ID()] = scheduling
+	}
 
 	schedulerChecksEntered.Add(1)
 	if check.IsTelemetryEnabled() {
 		tlmChecksEntered.Inc(check.String())
 	}
 	schedulerExpvars.Set("Queues", expvar.Func(expQueues(s)))
+	schedulerExpvars.Set("Checks", expvar.Func(expChecks(s)))
 	return nil
 }
 
@@ -124,9 +142,14 @@ func (s *Scheduler) Cancel(id check.ID) error {
 		return fmt.Errorf("unable to remove the Job from the queue: %s", err)
 	}
 	delete(s.checkToQueue, id)
+	if cs, found := s.checkScheduling[id]; found {
+		delete(s.checkScheduling, id)
+		s.pruneHostLocked(cs.host)
+	}
 
 	schedulerChecksEntered.Add(-1)
 	schedulerExpvars.Set("Queues", expvar.Func(expQueues(s)))
+	schedulerExpvars.Set("Checks", expvar.Func(expChecks(s)))
 	return nil
 }
 
@@ -0,0 +1,278 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// checkScheduling holds the scheduling options of a check instance, set in the
+// `scheduling` section of its instance, along with the decisions taken for its runs.
+type checkScheduling struct {
+	interval   time.Duration
+	jitter     time.Duration
+	windows    []*window
+	host       string
+	minSpacing time.Duration
+	dependsOn  []string
+
+	lastDecision string
+	delayedRuns  int64
+	skippedRuns  int64
+}
+
+// newCheckScheduling returns the scheduling options of a check, or nil if it has none.
+func newCheckScheduling(c check.Check) (*checkScheduling, error) {
+	provider, ok := c.(check.SchedulingConfigProvider)
+	if !ok {
+		return nil, nil
+	}
+	conf := provider.SchedulingConfig()
+	if conf.Jitter < 0 || conf.MinSpacing < 0 {
+		return nil, fmt.Errorf("scheduling jitter and min_spacing must be positive")
+	}
+	if conf.Jitter == 0 && len(conf.Windows) == 0 && (conf.Host == "" || conf.MinSpacing == 0) && len(conf.DependsOn) == 0 {
+		return nil, nil
+	}
+
+	cs := &checkScheduling{
+		interval:     c.Interval(),
+		jitter:       time.Duration(conf.Jitter) * time.Second,
+		host:         conf.Host,
+		minSpacing:   time.Duration(conf.MinSpacing) * time.Second,
+		dependsOn:    conf.DependsOn,
+		lastDecision: "not run yet",
+	}
+	if cs.jitter >= cs.interval {
+		return nil, fmt.Errorf("scheduling jitter must be lower than the check interval")
+	}
+	for _, expr := range conf.Windows {
+		w, err := parseWindow(expr)
+		if err != nil {
+			return nil, err
+		}
+		cs.windows = append(cs.windows, w)
+	}
+	return cs, nil
+}
+
+// inWindows returns whether runs are allowed at the given time.
+func (cs *checkScheduling) inWindows(t time.Time) bool {
+	if len(cs.windows) == 0 {
+		return true
+	}
+	for _, w := range cs.windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// dependsOnCheck returns whether the check must be enqueued after c when they are due
+// at the same time.
+func (cs *checkScheduling) dependsOnCheck(c check.Check) bool {
+	if cs == nil {
+		return false
+	}
+	for _, name := range cs.dependsOn {
+		if name == c.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// summary describes the scheduling options
+func (cs *checkScheduling) summary() string {
+	var options []string
+	if cs.jitter > 0 {
+		options = append(options, fmt.Sprintf("jitter %s", cs.jitter))
+	}
+	if len(cs.windows) > 0 {
+		exprs := make([]string, 0, len(cs.windows))
+		for _, w := range cs.windows {
+			exprs = append(exprs, w.String())
+		}
+		options = append(options, fmt.Sprintf("windows [%s]", strings.Join(exprs, ", ")))
+	}
+	if cs.host != "" && cs.minSpacing > 0 {
+		options = append(options, fmt.Sprintf("%s apart from other checks of host %s", cs.minSpacing, cs.host))
+	}
+	if len(cs.dependsOn) > 0 {
+		options = append(options, fmt.Sprintf("after checks [%s]", strings.Join(cs.dependsOn, ", ")))
+	}
+	return strings.Join(options, ", ")
+}
+
+// schedule decides when a check due at the given time runs. It returns whether
+// the check runs, and how long after now.
+// Runs outside of the check windows are skipped. Others are delayed by a random
+// jitter, then until the check host is free if needed. Runs that would be delayed
+// by more than the check interval are skipped.
+func (s *Scheduler) schedule(id check.ID, now time.Time) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cs, found := s.checkScheduling[id]
+	if !found {
+		return 0, true
+	}
+
+	if !cs.inWindows(now) {
+		cs.skippedRuns++
+		cs.lastDecision = fmt.Sprintf("skipped at %s, outside of the run windows", now.Format(time.RFC3339))
+		log.Debugf("Skipping run of check %s, outside of its run windows", id)
+		return 0, false
+	}
+
+	var delay time.Duration
+	if cs.jitter > 0 {
+		delay = time.Duration(rand.Int63n(int64(cs.jitter)))
+	}
+	if cs.host != "" && cs.minSpacing > 0 {
+		at := now.Add(delay)
+		if free := s.hostFreeAt[cs.host]; at.Before(free) {
+			at = free
+		}
+		if at.Sub(now) >= cs.interval {
+			cs.skippedRuns++
+			cs.lastDecision = fmt.Sprintf("skipped at %s, host %s busy", now.Format(time.RFC3339), cs.host)
+			log.Debugf("Skipping run of check %s, too many checks of host %s are waiting", id, cs.host)
+			return 0, false
+		}
+		s.hostFreeAt[cs.host] = at.Add(cs.minSpacing)
+		delay = at.Sub(now)
+	}
+
+	if delay > 0 {
+		cs.delayedRuns++
+	}
+	cs.lastDecision = fmt.Sprintf("run at %s, delayed by %s", now.Add(delay).Format(time.RFC3339), delay.Round(time.Millisecond))
+	return delay, true
+}
+
+// relatedChecksLocked returns a function telling whether a check depends on c or
+// c depends on it, given the scheduling options cs of c. It returns nil if no
+// scheduled check has dependencies. s.mu must be held.
+func (s *Scheduler) relatedChecksLocked(c check.Check, cs *checkScheduling) func(check.Check) bool {
+	if (cs == nil || len(cs.dependsOn) == 0) && !s.hasDependenciesLocked() {
+		return nil
+	}
+	return func(other check.Check) bool {
+		return cs.dependsOnCheck(other) || s.checkScheduling[other.ID()].dependsOnCheck(c)
+	}
+}
+
+// hasDependenciesLocked returns whether a scheduled check depends on others.
+// s.mu must be held.
+func (s *Scheduler) hasDependenciesLocked() bool {
+	for _, cs := range s.checkScheduling {
+		if len(cs.dependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// orderByDependencies returns the checks due at the same time ordered so that
+// every check comes after the checks it depends on. Dependency cycles are broken
+// arbitrarily.
+func (s *Scheduler) orderByDependencies(jobs []check.Check) []check.Check {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(jobs) < 2 || !s.hasDependenciesLocked() {
+		return jobs
+	}
+
+	ordered := make([]check.Check, 0, len(jobs))
+	visited := make(map[check.ID]bool, len(jobs))
+	var visit func(c check.Check)
+	visit = func(c check.Check) {
+		if visited[c.ID()] {
+			return
+		}
+		visited[c.ID()] = true
+		cs := s.checkScheduling[c.ID()]
+		for _, dep := range jobs {
+			if dep.ID() != c.ID() && cs.dependsOnCheck(dep) {
+				visit(dep)
+			}
+		}
+		ordered = append(ordered, c)
+	}
+	for _, c := range jobs {
+		visit(c)
+	}
+	return ordered
+}
+
+// pruneHostLocked forgets when the given host is free once no scheduled check
+// targets it anymore. s.mu must be held.
+func (s *Scheduler) pruneHostLocked(host string) {
+	if host == "" {
+		return
+	}
+	for _, cs := range s.checkScheduling {
+		if cs.host == host {
+			return
+		}
+	}
+	delete(s.hostFreeAt, host)
+}
+
+// enqueueAfter enqueues a check to the checksPipe after a delay, unless it is
+// unscheduled in the meantime. The queuing can be cancelled by closing the
+// `cancelOneTime` channel.
+func (s *Scheduler) enqueueAfter(check check.Check, delay time.Duration) {
+	s.wgOneTime.Add(1)
+
+	go func(cancelOneTime <-chan bool) {
+		defer s.wgOneTime.Done()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-cancelOneTime:
+			return
+		}
+		if !s.IsCheckScheduled(check.ID()) {
+			return
+		}
+		select {
+		case s.checksPipe <- check:
+		case <-cancelOneTime:
+		}
+	}(s.cancelOneTime)
+}
+
+// expChecks return a function to get the scheduling decisions of the checks
+// having scheduling options
+func expChecks(s *Scheduler) func() interface{} {
+	return func() interface{} {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		checks := make(map[string]interface{}, len(s.checkScheduling))
+		for id, cs := range s.checkScheduling {
+			checks[string(id)] = map[string]interface{}{
+				"Options":      cs.summary(),
+				"LastDecision": cs.lastDecision,
+				"DelayedRuns":  cs.delayedRuns,
+				"SkippedRuns":  cs.skippedRuns,
+			}
+		}
+		return checks
+	}
+}
@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+type scheduledCheck struct {
+	TestCheck
+	id   string
+	conf integration.SchedulingConfig
+}
+
+func (c *scheduledCheck) ID() check.ID                                   { return check.ID(c.id) }
+func (c *scheduledCheck) String() string                                 { return c.id }
+func (c *scheduledCheck) SchedulingConfig() integration.SchedulingConfig { return c.conf }
+
+func newScheduledCheck(id string, conf integration.SchedulingConfig) *scheduledCheck {
+	return &scheduledCheck{TestCheck: TestCheck{intl: 15 * time.Second}, id: id, conf: conf}
+}
+
+func TestEnterSchedulingOptions(t *testing.T) {
+	s := getScheduler()
+
+	assert.NoError(t, s.Enter(newScheduledCheck("none", integration.SchedulingConfig{})))
+	assert.NoError(t, s.Enter(newScheduledCheck("jitter", integration.SchedulingConfig{Jitter: 5})))
+	assert.Len(t, s.checkScheduling, 1)
+	assert.Contains(t, s.checkScheduling, check.ID("jitter"))
+
+	for _, conf := range []integration.SchedulingConfig{
+		{Jitter: -1},
+		{Jitter: 15},
+		{Windows: []string{"* * * *"}},
+	} {
+		assert.Error(t, s.Enter(newScheduledCheck("invalid", conf)), "%+v", conf)
+	}
+	assert.False(t, s.IsCheckScheduled("invalid"))
+
+	s.Cancel("jitter")
+	assert.Len(t, s.checkScheduling, 0)
+}
+
+func TestSchedule(t *testing.T) {
+	s := getScheduler()
+	now := time.Date(2020, 1, 15, 12, 0, 0, 0, time.Local)
+
+	// checks without options run right away
+	delay, run := s.schedule("unknown", now)
+	assert.True(t, run)
+	assert.Zero(t, delay)
+
+	require.NoError(t, s.Enter(newScheduledCheck("jitter", integration.SchedulingConfig{Jitter: 5})))
+	for i := 0; i < 10; i++ {
+		delay, run = s.schedule("jitter", now)
+		assert.True(t, run)
+		assert.True(t, delay >= 0 && delay < 5*time.Second, delay)
+	}
+
+	require.NoError(t, s.Enter(newScheduledCheck("night", integration.SchedulingConfig{Windows: []string{"* 0-6 * * *"}})))
+	_, run = s.schedule("night", now)
+	assert.False(t, run)
+	_, run = s.schedule("night", now.Add(-8*time.Hour))
+	assert.True(t, run)
+	assert.EqualValues(t, 1, s.checkScheduling["night"].skippedRuns)
+
+	// checks of the same host run 6 seconds apart, and are skipped once delayed by
+	// more than their 15 seconds interval
+	for _, id := range []string{"db-1", "db-2", "db-3", "db-4"} {
+		require.NoError(t, s.Enter(newScheduledCheck(id, integration.SchedulingConfig{Host: "db", MinSpacing: 6})))
+	}
+	for i, id := range []string{"db-1", "db-2", "db-3"} {
+		delay, run = s.schedule(check.ID(id), now)
+		assert.True(t, run)
+		assert.Equal(t, time.Duration(i)*6*time.Second, delay)
+	}
+	_, run = s.schedule("db-4", now)
+	assert.False(t, run)
+	assert.Contains(t, s.checkScheduling["db-4"].lastDecision, "host db busy")
+	assert.Contains(t, s.checkScheduling["db-3"].lastDecision, "delayed by 12s")
+
+	stats := expChecks(s)().(map[string]interface{})
+	assert.Len(t, stats, 6)
+	assert.Equal(t, "6s apart from other checks of host db", stats["db-1"].(map[string]interface{})["Options"])
+
+	// hosts are forgotten once none of their checks is scheduled
+	for _, id := range []string{"db-1", "db-2", "db-3"} {
+		require.NoError(t, s.Cancel(check.ID(id)))
+		assert.Contains(t, s.hostFreeAt, "db")
+	}
+	require.NoError(t, s.Cancel("db-4"))
+	assert.NotContains(t, s.hostFreeAt, "db")
+}
+
+func TestDependencies(t *testing.T) {
+	s := getScheduler()
+	load := newScheduledCheck("load", integration.SchedulingConfig{DependsOn: []string{"db"}})
+	other := newScheduledCheck("other", integration.SchedulingConfig{})
+	db := newScheduledCheck("db", integration.SchedulingConfig{})
+	for _, c := range []*scheduledCheck{load, other, db} {
+		require.NoError(t, s.Enter(c))
+	}
+	assert.Equal(t, "after checks [db]", s.checkScheduling["load"].summary())
+
+	// dependencies are due at the same time as their dependents
+	for _, bucket := range s.jobQueues[15*time.Second].buckets {
+		if bucket.hasJob(func(c check.Check) bool { return c.ID() == "load" }) {
+			assert.Equal(t, []check.Check{load, db}, bucket.jobs)
+		}
+	}
+
+	// and enqueued before them
+	ordered := s.orderByDependencies([]check.Check{load, other, db})
+	assert.Equal(t, []check.Check{db, load, other}, ordered)
+
+	// cycles don't prevent the checks from running
+	require.NoError(t, s.Enter(newScheduledCheck("a", integration.SchedulingConfig{DependsOn: []string{"b"}})))
+	require.NoError(t, s.Enter(newScheduledCheck("b", integration.SchedulingConfig{DependsOn: []string{"a"}})))
+	ordered = s.orderByDependencies([]check.Check{newScheduledCheck("a", integration.SchedulingConfig{}), newScheduledCheck("b", integration.SchedulingConfig{})})
+	assert.Len(t, ordered, 2)
+}
+
+func TestEnqueueAfter(t *testing.T) {
+	ch := make(chan check.Check, 1)
+	s := NewScheduler(ch)
+	c := newScheduledCheck("jitter", integration.SchedulingConfig{Jitter: 5})
+	require.NoError(t, s.Enter(c))
+
+	s.enqueueAfter(c, time.Millisecond)
+	select {
+	case enqueued := <-ch:
+		assert.Equal(t, c.ID(), enqueued.ID())
+	case <-time.After(time.Second):
+		t.Fatal("the check was not enqueued")
+	}
+
+	// checks unscheduled in the meantime are not enqueued
+	s.enqueueAfter(c, 10*time.Millisecond)
+	s.Cancel(c.ID())
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, ch, 0)
+}
@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// window is a time window written like a cron expression, made of 5 fields:
+// minute, hour, day of month, month and day of week. Each field is either `*`,
+// a value, a range `a-b`, or a comma-separated list of those, optionally followed
+// by a step `/n`. For instance "* 0-6 * * 1-5" matches week days from midnight to
+// 06:59. Times are matched in the local time zone of the host.
+type window struct {
+	expr    string
+	minutes uint64
+	hours   uint64
+	days    uint64
+	months  uint64
+	weekDay uint64
+	anyDay  bool // the day of month field is `*`
+	anyWeek bool // the day of week field is `*`
+}
+
+type windowField struct {
+	min, max int
+}
+
+var windowFields = []windowField{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week, 0 and 7 being Sunday
+}
+
+// parseWindow parses a cron-style time window.
+func parseWindow(expr string) (*window, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(windowFields) {
+		return nil, fmt.Errorf("invalid window %q: expected %d fields, got %d", expr, len(windowFields), len(fields))
+	}
+	bits := make([]uint64, len(fields))
+	for i, f := range fields {
+		b, err := parseWindowField(f, windowFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %s", expr, err)
+		}
+		bits[i] = b
+	}
+	// Sunday is both 0 and 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &window{
+		expr:    expr,
+		minutes: bits[0],
+		hours:   bits[1],
+		days:    bits[2],
+		months:  bits[3],
+		weekDay: bits[4],
+		anyDay:  fields[2] == "*",
+		anyWeek: fields[4] == "*",
+	}, nil
+}
+
+// parseWindowField returns the bitset of the values matched by a field.
+func parseWindowField(field string, bounds windowField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step, hasStep := 1, false
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step, hasStep = s, true
+			part = part[:i]
+		}
+
+		start, end := bounds.min, bounds.max
+		if part != "*" {
+			values := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(values[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			end = start
+			if hasStep {
+				// "a/n" stands for "a-max/n"
+				end = bounds.max
+			}
+			if len(values) == 2 {
+				if end, err = strconv.Atoi(values[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			}
+		}
+		if start < bounds.min || end > bounds.max || start > end {
+			return 0, fmt.Errorf("%q is out of the %d-%d range", part, bounds.min, bounds.max)
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// contains returns whether the window contains the given time. Like cron, when
+// both the day of month and the day of week are restricted, matching either is enough.
+func (w *window) contains(t time.Time) bool {
+	if w.minutes&(1<<uint(t.Minute())) == 0 || w.hours&(1<<uint(t.Hour())) == 0 || w.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	dayMatch := w.days&(1<<uint(t.Day())) != 0
+	weekDayMatch := w.weekDay&(1<<uint(t.Weekday())) != 0
+	if w.anyDay || w.anyWeek {
+		return dayMatch && weekDayMatch
+	}
+	return dayMatch || weekDayMatch
+}
+
+// String returns the expression of the window
+func (w *window) String() string {
+	return w.expr
+}
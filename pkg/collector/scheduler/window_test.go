@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWindow(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "1-a * * * *"} {
+		_, err := parseWindow(expr)
+		assert.Error(t, err, expr)
+	}
+
+	// Wednesday 2020-01-15
+	day := time.Date(2020, 1, 15, 0, 0, 0, 0, time.Local)
+	for _, tc := range []struct {
+		expr     string
+		time     time.Time
+		contains bool
+	}{
+		{"* * * * *", day, true},
+		{"* 0-6 * * *", day.Add(6*time.Hour + 59*time.Minute), true},
+		{"* 0-6 * * *", day.Add(7 * time.Hour), false},
+		{"0-9,30-39 * * * *", day.Add(35 * time.Minute), true},
+		{"0-9,30-39 * * * *", day.Add(25 * time.Minute), false},
+		{"*/15 * * * *", day.Add(45 * time.Minute), true},
+		{"*/15 * * * *", day.Add(46 * time.Minute), false},
+		{"5/20 * * * *", day.Add(45 * time.Minute), true},
+		{"5/20 * * * *", day.Add(40 * time.Minute), false},
+		{"* * * * 1-5", day, true},
+		{"* * * * 0,6", day, false},
+		{"* * * * 7", day.AddDate(0, 0, 4), true},
+		{"* * 1 * *", day, false},
+		{"* * 1 * 3", day, true}, // the day of month or the day of week must match
+		{"* * 15 2 *", day, false},
+	} {
+		w, err := parseWindow(tc.expr)
+		require.NoError(t, err, tc.expr)
+		assert.Equal(t, tc.contains, w.contains(tc.time), "%s at %s", tc.expr, tc.time)
+	}
+}
@@ -133,9 +133,13 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("hostname", "")
 	config.BindEnvAndSetDefault("tags", []string{})
 	config.BindEnvAndSetDefault("tag_value_split_separator", map[string]string{})
+	config.SetKnown("tag_extraction_rules")
+	config.BindEnvAndSetDefault("tag_deny_list", []string{})
 	config.BindEnvAndSetDefault("conf_path", ".")
 	config.BindEnvAndSetDefault("confd_path", defaultConfdPath)
 	config.BindEnvAndSetDefault("additional_checksd", defaultAdditionalChecksPath)
+	config.BindEnvAndSetDefault("external_checks_dir", "")
+	config.BindEnvAndSetDefault("external_checks_timeout", 30)
 	config.BindEnvAndSetDefault("log_payloads", false)
 	config.BindEnvAndSetDefault("log_file", "")
 	config.BindEnvAndSetDefault("log_file_max_size", "10Mb")
@@ -157,6 +161,11 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("enable_metadata_collection", true)
 	config.BindEnvAndSetDefault("enable_gohai", true)
 	config.BindEnvAndSetDefault("check_runners", int64(4))
+	config.BindEnvAndSetDefault("check_health.enabled", false)
+	config.BindEnvAndSetDefault("check_health.failing_threshold", 3)     // consecutive errors before backing off
+	config.BindEnvAndSetDefault("check_health.max_backoff", 600)         // in seconds
+	config.BindEnvAndSetDefault("check_health.quarantine_threshold", 20) // consecutive errors before quarantining
+	config.BindEnvAndSetDefault("check_health.probe_interval", 1800)     // in seconds
 	config.BindEnvAndSetDefault("auth_token_file_path", "")
 	config.BindEnvAndSetDefault("bind_host", "localhost")
 	config.BindEnvAndSetDefault("ipc_address", "localhost")
@@ -302,6 +311,7 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("ac_exclude", []string{})
 	config.BindEnvAndSetDefault("ad_config_poll_interval", int64(10)) // in seconds
 	config.BindEnvAndSetDefault("extra_listeners", []string{})
+	config.BindEnvAndSetDefault("process_listener_polling_interval", 10) // in seconds
 	config.BindEnvAndSetDefault("extra_config_providers", []string{})
 
 	// Docker
@@ -311,8 +321,14 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("kubernetes_pod_labels_as_tags", map[string]string{})
 	config.BindEnvAndSetDefault("kubernetes_pod_annotations_as_tags", map[string]string{})
 	config.BindEnvAndSetDefault("kubernetes_node_labels_as_tags", map[string]string{})
+	config.BindEnvAndSetDefault("kubernetes_namespace_labels_as_tags", map[string]string{}) // only available from the metadata stream of the cluster agent
 	config.BindEnvAndSetDefault("container_cgroup_prefix", "")
 
+	// Host processes
+	config.BindEnvAndSetDefault("process_tagging_enabled", false)
+	config.BindEnvAndSetDefault("process_env_as_tags", map[string]string{})
+	config.BindEnvAndSetDefault("systemd_unit_labels_as_tags", map[string]string{})
+
 	// CRI
 	config.BindEnvAndSetDefault("cri_socket_path", "")              // empty is disabled
 	config.BindEnvAndSetDefault("cri_connection_timeout", int64(1)) // in seconds
@@ -352,6 +368,7 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("leader_lease_duration", "60")
 	config.BindEnvAndSetDefault("leader_election", false)
 	config.BindEnvAndSetDefault("kube_resources_namespace", "")
+	config.BindEnvAndSetDefault("kubernetes_use_endpoint_slices", false) // Read the EndpointSlices for the endpoints checks, if served by the API server
 
 	// Datadog cluster agent
 	config.BindEnvAndSetDefault("cluster_agent.enabled", false)
@@ -359,10 +376,13 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("cluster_agent.url", "")
 	config.BindEnvAndSetDefault("cluster_agent.kubernetes_service_name", "datadog-cluster-agent")
 	config.BindEnvAndSetDefault("cluster_agent.tagging_fallback", false)
+	config.BindEnvAndSetDefault("cluster_agent.metadata_stream.enabled", false)         // Subscribe to the metadata stream of the DCA instead of polling it
+	config.BindEnvAndSetDefault("cluster_agent.external_metrics_series.enabled", false) // Send the series requested by the node_agent external metrics backend of the DCA after each flush
 	config.BindEnvAndSetDefault("metrics_port", "5000")
 
 	// Metadata endpoints
-
+	config.BindEnvAndSetDefault("metadata_stream.enabled", false)  // Push the cluster metadata to the subscribed node agents
+	config.BindEnvAndSetDefault("metadata_stream.log_size", 10000) // Number of events kept for the node agents to resume the stream
 	// Defines the maximum size of hostame gathered from EC2, GCE, Azure and Alibabacloud metadata endpoints.
 	// Used internally to protect against configurations where metadata endpoints return incorrect values with 200 status codes.
 	config.BindEnvAndSetDefault("metadata_endpoints_max_hostname_size", 255)
@@ -466,6 +486,8 @@ func initConfig(config Config) {
 	// Changing this setting may impact your custom metrics billing.
 	config.BindEnvAndSetDefault("checks_tag_cardinality", "low")
 	config.BindEnvAndSetDefault("dogstatsd_tag_cardinality", "low")
+	// Serve the tags of the process-agent and trace-agent from the core agent tagger stream
+	config.BindEnvAndSetDefault("remote_tagger.enabled", false)
 
 	config.BindEnvAndSetDefault("histogram_copy_to_distribution", false)
 	config.BindEnvAndSetDefault("histogram_copy_to_distribution_prefix", "")
@@ -480,10 +502,13 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("external_metrics_provider.refresh_period", 30)          // value in seconds. Frequency of batch calls to the ConfigMap persistent store (GlobalStore) by the Leader.
 	config.BindEnvAndSetDefault("external_metrics_provider.batch_window", 10)            // value in seconds. Batch the events from the Autoscalers informer to push updates to the ConfigMap (GlobalStore)
 	config.BindEnvAndSetDefault("external_metrics_provider.max_age", 120)                // value in seconds. 4 cycles from the Autoscaler controller (up to Kubernetes 1.11) is enough to consider a metric stale
+	config.BindEnvAndSetDefault("external_metrics_provider.fallback_max_age", 60*10)     // value in seconds. Age after which the last value of a metric with the last_value fallback is not served anymore
 	config.BindEnvAndSetDefault("external_metrics.aggregator", "avg")                    // aggregator used for the external metrics. Choose from [avg,sum,max,min]
 	config.BindEnvAndSetDefault("external_metrics_provider.bucket_size", 60*5)           // Window to query to get the metric from Datadog.
 	config.BindEnvAndSetDefault("external_metrics_provider.rollup", 30)                  // Bucket size to circumvent time aggregation side effects.
 	config.BindEnvAndSetDefault("external_metrics_provider.wpa_controller", false)       // Activates the controller for Watermark Pod Autoscalers.
+	config.BindEnvAndSetDefault("external_metrics_provider.backend", "datadog")          // Source of the external metrics values. Choose from [datadog,prometheus,node_agent]
+	config.BindEnvAndSetDefault("external_metrics_provider.prometheus_url", "")          // Base URL of the Prometheus-compatible query API used by the prometheus backend.
 	config.BindEnvAndSetDefault("kubernetes_event_collection_timeout", 100)              // timeout between two successful event collections in milliseconds.
 	config.BindEnvAndSetDefault("kubernetes_informers_resync_period", 60*5)              // value in seconds. Default to 5 minutes
 	config.BindEnvAndSetDefault("external_metrics_provider.local_copy_refresh_rate", 30) // value in seconds
@@ -495,12 +520,31 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("cluster_checks.extra_tags", []string{})
 	config.BindEnvAndSetDefault("cluster_checks.advanced_dispatching_enabled", false)
 	config.BindEnvAndSetDefault("cluster_checks.clc_runners_port", 5005)
+	config.BindEnvAndSetDefault("cluster_checks.checkpoint_enabled", false)
+	config.BindEnvAndSetDefault("cluster_checks.checkpoint_interval", 10) // value in seconds
+	config.BindEnvAndSetDefault("cluster_checks.checkpoint_max_age", 300) // value in seconds
+	config.BindEnvAndSetDefault("cluster_checks.checkpoint_configmap_name", "datadog-cluster-checks-state")
 	// Cluster check runner
 	config.BindEnvAndSetDefault("clc_runner_enabled", false)
 	config.BindEnvAndSetDefault("clc_runner_host", "") // must be set using the Kubernetes downward API
 	config.BindEnvAndSetDefault("clc_runner_port", 5005)
 	config.BindEnvAndSetDefault("clc_runner_server_write_timeout", 15)
 	config.BindEnvAndSetDefault("clc_runner_server_readheader_timeout", 10)
+	config.BindEnvAndSetDefault("clc_runner_pool", "") // cluster checks can be pinned to a pool of runners
+
+	// Admission controller
+	config.BindEnvAndSetDefault("admission_controller.enabled", false)
+	config.BindEnvAndSetDefault("admission_controller.mutate_unlabelled", false) // mutate the pods without the admission.datadoghq.com/enabled label
+	config.BindEnvAndSetDefault("admission_controller.port", 8000)
+	config.BindEnvAndSetDefault("admission_controller.service_name", "datadog-admission-controller")
+	config.BindEnvAndSetDefault("admission_controller.webhook_name", "datadog-webhook")
+	config.BindEnvAndSetDefault("admission_controller.certificate.secret_name", "webhook-certificate")
+	config.BindEnvAndSetDefault("admission_controller.certificate.validity_bound", 365*24)      // value in hours
+	config.BindEnvAndSetDefault("admission_controller.certificate.expiration_threshold", 30*24) // value in hours
+	config.BindEnvAndSetDefault("admission_controller.inject_config.enabled", true)
+	config.BindEnvAndSetDefault("admission_controller.inject_config.mode", "hostip") // Choose from [hostip,socket]
+	config.BindEnvAndSetDefault("admission_controller.inject_config.socket_path", "/var/run/datadog/dsd.socket")
+	config.BindEnvAndSetDefault("admission_controller.inject_tags.enabled", true)
 
 	// Telemetry
 	// Enable telemetry metrics on the internals of the Agent.
@@ -575,6 +619,7 @@ func initConfig(config Config) {
 	config.SetKnown("apm_config.additional_endpoints.*")
 	config.SetKnown("apm_config.apm_non_local_traffic")
 	config.SetKnown("apm_config.max_traces_per_second")
+	config.SetKnown("apm_config.target_traces_per_second")
 	config.SetKnown("apm_config.max_memory")
 	config.SetKnown("apm_config.log_file")
 	config.SetKnown("apm_config.apm_dd_url")
@@ -583,7 +628,15 @@ func initConfig(config Config) {
 	config.SetKnown("apm_config.receiver_socket")
 	config.SetKnown("apm_config.connection_limit")
 	config.SetKnown("apm_config.ignore_resources")
+	config.SetKnown("apm_config.stats_tags")
+	config.SetKnown("apm_config.inspect.enabled")
+	config.SetKnown("apm_config.inspect.max_traces")
+	config.SetKnown("apm_config.spool.enabled")
+	config.SetKnown("apm_config.spool.dir")
+	config.SetKnown("apm_config.spool.max_size_mb")
+	config.SetKnown("apm_config.spool.max_age_seconds")
 	config.SetKnown("apm_config.replace_tags")
+	config.SetKnown("apm_config.span_rules")
 	config.SetKnown("apm_config.obfuscation.elasticsearch.enabled")
 	config.SetKnown("apm_config.obfuscation.elasticsearch.keep_values")
 	config.SetKnown("apm_config.obfuscation.mongodb.enabled")
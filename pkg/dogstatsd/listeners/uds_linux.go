@@ -14,6 +14,8 @@ import (
 
 	"golang.org/x/sys/unix"
 
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/util/cache"
 	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
@@ -101,14 +103,18 @@ func getEntityForPID(pid int32) (string, error) {
 	}
 }
 
-// entityForPID returns the entity ID for a given PID. It can return
-// errNoContainerMatch if no match is found for the PID.
+// entityForPID returns the entity ID for a given PID. Processes running outside
+// of containers get a process entity when process tagging is enabled, otherwise it
+// returns errNoContainerMatch.
 func entityForPID(pid int32) (string, error) {
 	cID, err := metrics.ContainerIDForPID(int(pid))
 	if err != nil {
 		return "", err
 	}
 	if cID == "" {
+		if config.Datadog.GetBool("process_tagging_enabled") {
+			return collectors.GetProcessEntity(int(pid))
+		}
 		return "", errNoContainerMatch
 	}
 
@@ -17,6 +17,10 @@ const (
 	DockerType       = "docker"
 	JournaldType     = "journald"
 	WindowsEventType = "windows_event"
+
+	// StringChannelType is a special log type, used for logs sent by other
+	// components of the agent through a Go channel
+	StringChannelType = "string_channel"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -39,6 +43,8 @@ type LogsConfig struct {
 	ChannelPath string `mapstructure:"channel_path" json:"channel_path"` // Windows Event
 	Query       string // Windows Event
 
+	Channel chan string `mapstructure:"-" json:"-"` // String Channel
+
 	Service         string
 	Source          string
 	SourceCategory  string
@@ -60,6 +66,8 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == StringChannelType && c.Channel == nil:
+		return fmt.Errorf("string channel source must have a channel")
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package channel
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/restart"
+)
+
+// Launcher is in charge of starting and stopping the tailers of the logs
+// sent by other components of the agent through a Go channel.
+type Launcher struct {
+	addedSources     chan *config.LogSource
+	removedSources   chan *config.LogSource
+	pipelineProvider pipeline.Provider
+	tailers          map[*config.LogSource]*Tailer
+	stop             chan struct{}
+}
+
+// NewLauncher returns a new Launcher.
+func NewLauncher(sources *config.LogSources, pipelineProvider pipeline.Provider) *Launcher {
+	return &Launcher{
+		addedSources:     sources.GetAddedForType(config.StringChannelType),
+		removedSources:   sources.GetRemovedForType(config.StringChannelType),
+		pipelineProvider: pipelineProvider,
+		tailers:          make(map[*config.LogSource]*Tailer),
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start starts the launcher.
+func (l *Launcher) Start() {
+	go l.run()
+}
+
+// run starts and stops the tailers of the sources.
+func (l *Launcher) run() {
+	for {
+		select {
+		case source := <-l.addedSources:
+			if _, exists := l.tailers[source]; exists {
+				// tailer already setup
+				continue
+			}
+			tailer := NewTailer(source, source.Config.Channel, l.pipelineProvider.NextPipelineChan())
+			tailer.Start()
+			l.tailers[source] = tailer
+		case source := <-l.removedSources:
+			if tailer, exists := l.tailers[source]; exists {
+				tailer.Stop()
+				delete(l.tailers, source)
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Stop stops all active tailers
+func (l *Launcher) Stop() {
+	l.stop <- struct{}{}
+	stopper := restart.NewParallelStopper()
+	for source, tailer := range l.tailers {
+		stopper.Add(tailer)
+		delete(l.tailers, source)
+	}
+	stopper.Stop()
+}
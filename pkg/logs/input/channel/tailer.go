@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package channel
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// Tailer consumes the logs of a Go channel and forwards them to the pipeline.
+type Tailer struct {
+	source     *config.LogSource
+	inputChan  chan string
+	outputChan chan *message.Message
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewTailer returns a new Tailer
+func NewTailer(source *config.LogSource, inputChan chan string, outputChan chan *message.Message) *Tailer {
+	return &Tailer{
+		source:     source,
+		inputChan:  inputChan,
+		outputChan: outputChan,
+		stop:       make(chan struct{}, 1),
+		done:       make(chan struct{}, 1),
+	}
+}
+
+// Start starts the tailer.
+func (t *Tailer) Start() {
+	t.source.Status.Success()
+	go t.run()
+}
+
+// Stop stops the tailer, the channel is left open as it is owned by the sender.
+func (t *Tailer) Stop() {
+	t.stop <- struct{}{}
+	<-t.done
+}
+
+func (t *Tailer) run() {
+	defer func() {
+		t.done <- struct{}{}
+	}()
+	for {
+		select {
+		case content, ok := <-t.inputChan:
+			if !ok {
+				// The sender is done, wait to be stopped
+				<-t.stop
+				return
+			}
+			origin := message.NewOrigin(t.source)
+			select {
+			case t.outputChan <- message.NewMessage([]byte(content), origin, message.StatusInfo):
+			case <-t.stop:
+				return
+			}
+		case <-t.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package channel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestTailerForwardsMessages(t *testing.T) {
+	inputChan := make(chan string, 2)
+	outputChan := make(chan *message.Message, 2)
+	source := config.NewLogSource("test", &config.LogsConfig{Type: config.StringChannelType, Channel: inputChan})
+
+	tailer := NewTailer(source, inputChan, outputChan)
+	tailer.Start()
+	assert.True(t, source.Status.IsSuccess())
+
+	inputChan <- "foo"
+	inputChan <- "bar"
+	msg := <-outputChan
+	assert.Equal(t, "foo", string(msg.Content))
+	assert.Equal(t, source, msg.Origin.LogSource)
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	msg = <-outputChan
+	assert.Equal(t, "bar", string(msg.Content))
+
+	tailer.Stop()
+}
+
+func TestTailerStopsWithClosedChannel(t *testing.T) {
+	inputChan := make(chan string)
+	source := config.NewLogSource("test", &config.LogsConfig{Type: config.StringChannelType, Channel: inputChan})
+
+	tailer := NewTailer(source, inputChan, make(chan *message.Message))
+	tailer.Start()
+	close(inputChan)
+	tailer.Stop()
+}
+
+func TestTailerStopsWithBlockedPipeline(t *testing.T) {
+	inputChan := make(chan string, 1)
+	source := config.NewLogSource("test", &config.LogsConfig{Type: config.StringChannelType, Channel: inputChan})
+
+	tailer := NewTailer(source, inputChan, make(chan *message.Message))
+	tailer.Start()
+	inputChan <- "foo"
+	tailer.Stop()
+}
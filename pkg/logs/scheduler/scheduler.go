@@ -128,6 +128,17 @@ func (s *Scheduler) Unschedule(configs []integration.Config) {
 	}
 }
 
+// AddSource adds a source created by another component of the agent,
+// for instance a check forwarding its data as logs.
+func (s *Scheduler) AddSource(source *logsConfig.LogSource) {
+	s.sources.AddSource(source)
+}
+
+// RemoveSource removes a source previously added with AddSource.
+func (s *Scheduler) RemoveSource(source *logsConfig.LogSource) {
+	s.sources.RemoveSource(source)
+}
+
 // newSources returns true if the config can be mapped to sources.
 func (s *Scheduler) newSources(config integration.Config) bool {
 	return config.Provider != ""
@@ -0,0 +1,114 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build orchestrator
+
+// Package orchestrator holds the helpers shared by the orchestrator explorer
+// collectors of the process agent and the cluster agent.
+package orchestrator
+
+import (
+	model "github.com/DataDog/agent-payload/process"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/process/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	redactedValue = "********"
+	// lastAppliedConfigurationAnnotation holds the whole object as applied by
+	// kubectl, including the unscrubbed commands and env vars of its containers
+	lastAppliedConfigurationAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// ScrubContainer scrubs sensitive information in the command line & env vars
+func ScrubContainer(c *v1.Container, scrubber *config.DataScrubber) {
+	// scrub command line
+	scrubbedCmd, _ := scrubber.ScrubCommand(c.Command)
+	c.Command = scrubbedCmd
+	// scrub env vars
+	for e := 0; e < len(c.Env); e++ {
+		// use the "key: value" format to work with the regular credential cleaner
+		combination := c.Env[e].Name + ": " + c.Env[e].Value
+		scrubbedVal, err := log.CredentialsCleanerBytes([]byte(combination))
+		if err == nil && combination != string(scrubbedVal) {
+			c.Env[e].Value = redactedValue
+		}
+	}
+}
+
+// ScrubPodTemplateSpec scrubs the containers and init containers of a pod template
+func ScrubPodTemplateSpec(template *v1.PodTemplateSpec, scrubber *config.DataScrubber) {
+	for c := 0; c < len(template.Spec.Containers); c++ {
+		ScrubContainer(&template.Spec.Containers[c], scrubber)
+	}
+	for c := 0; c < len(template.Spec.InitContainers); c++ {
+		ScrubContainer(&template.Spec.InitContainers[c], scrubber)
+	}
+}
+
+// RemoveLastAppliedConfigurationAnnotation removes the copy of the object kubectl
+// apply stores in its annotations, which cannot be scrubbed
+func RemoveLastAppliedConfigurationAnnotation(m *metav1.ObjectMeta) {
+	delete(m.Annotations, lastAppliedConfigurationAnnotation)
+}
+
+// GroupSize returns the number of messages needed to send elements with at
+// most perMessage elements per message.
+func GroupSize(elements, perMessage int) int {
+	groupSize := elements / perMessage
+	if elements%perMessage != 0 {
+		groupSize++
+	}
+	return groupSize
+}
+
+// ChunkRange returns the bounds of the chunk of index counter, for elements
+// split in chunks of chunkSize elements.
+func ChunkRange(elements, chunkSize, counter int) (int, int) {
+	start := counter * chunkSize
+	end := start + chunkSize
+	if end > elements {
+		end = elements
+	}
+	return start, end
+}
+
+// ExtractMetadata extracts the object metadata into the proto model
+func ExtractMetadata(m *metav1.ObjectMeta) *model.Metadata {
+	meta := model.Metadata{
+		Name:      m.Name,
+		Namespace: m.Namespace,
+		Uid:       string(m.UID),
+	}
+	if !m.CreationTimestamp.IsZero() {
+		meta.CreationTimestamp = m.CreationTimestamp.Unix()
+	}
+	if m.DeletionTimestamp != nil {
+		meta.DeletionTimestamp = m.DeletionTimestamp.Unix()
+	}
+	if len(m.Annotations) > 0 {
+		meta.Annotations = make([]string, 0, len(m.Annotations))
+		for k, v := range m.Annotations {
+			meta.Annotations = append(meta.Annotations, k+":"+v)
+		}
+	}
+	if len(m.Labels) > 0 {
+		meta.Labels = make([]string, 0, len(m.Labels))
+		for k, v := range m.Labels {
+			meta.Labels = append(meta.Labels, k+":"+v)
+		}
+	}
+	for _, o := range m.OwnerReferences {
+		meta.OwnerReferences = append(meta.OwnerReferences, &model.OwnerReference{
+			Name: o.Name,
+			Uid:  string(o.UID),
+			Kind: o.Kind,
+		})
+	}
+	return &meta
+}
@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build orchestrator
+
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/process/config"
+)
+
+func TestScrubContainer(t *testing.T) {
+	scrubber := config.NewDefaultDataScrubber()
+	tests := map[string]struct {
+		input    v1.Container
+		expected v1.Container
+	}{
+		"sensitive CLI": {
+			input: v1.Container{
+				Command: []string{"mysql", "--password", "afztyerbzio1234"},
+			},
+			expected: v1.Container{
+				Command: []string{"mysql", "--password", "********"},
+			},
+		},
+		"sensitive env var": {
+			input: v1.Container{
+				Env: []v1.EnvVar{{Name: "password", Value: "kqhkiG9w0BAQEFAASCAl8wggJbAgEAAoGBAOLJ"}},
+			},
+			expected: v1.Container{
+				Env: []v1.EnvVar{{Name: "password", Value: "********"}},
+			},
+		},
+		"sensitive container": {
+			input: v1.Container{
+				Name:    "test container",
+				Image:   "random",
+				Command: []string{"decrypt", "--password", "afztyerbzio1234", "--access_token", "yolo123"},
+				Env: []v1.EnvVar{
+					{Name: "hostname", Value: "password"},
+					{Name: "pwd", Value: "yolo"},
+				},
+			},
+			expected: v1.Container{
+				Name:    "test container",
+				Image:   "random",
+				Command: []string{"decrypt", "--password", "********", "--access_token", "********"},
+				Env: []v1.EnvVar{
+					{Name: "hostname", Value: "password"},
+					{Name: "pwd", Value: "********"},
+				},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ScrubContainer(&tc.input, scrubber)
+			assert.Equal(t, tc.expected, tc.input)
+		})
+	}
+}
+
+func TestChunkRange(t *testing.T) {
+	assert.Equal(t, 3, GroupSize(25, 10))
+	assert.Equal(t, 2, GroupSize(20, 10))
+
+	var chunks [][2]int
+	for i := 0; i < GroupSize(25, 10); i++ {
+		start, end := ChunkRange(25, 10, i)
+		chunks = append(chunks, [2]int{start, end})
+	}
+	assert.Equal(t, [][2]int{{0, 10}, {10, 20}, {20, 25}}, chunks)
+}
+
+func TestRemoveLastAppliedConfigurationAnnotation(t *testing.T) {
+	meta := metav1.ObjectMeta{Annotations: map[string]string{
+		"kubectl.kubernetes.io/last-applied-configuration": `{"spec":{"containers":[{"command":["mysql","--password","afztyerbzio1234"]}]}}`,
+		"team": "payments",
+	}}
+	RemoveLastAppliedConfigurationAnnotation(&meta)
+	assert.Equal(t, map[string]string{"team": "payments"}, meta.Annotations)
+
+	// objects without annotations are left as is
+	meta = metav1.ObjectMeta{}
+	RemoveLastAppliedConfigurationAnnotation(&meta)
+	assert.Nil(t, meta.Annotations)
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	model "github.com/DataDog/agent-payload/process"
+	"github.com/DataDog/datadog-agent/pkg/orchestrator"
 	"github.com/DataDog/datadog-agent/pkg/process/config"
 	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"github.com/DataDog/datadog-agent/pkg/tagger"
@@ -26,8 +27,6 @@ import (
 // Pod is a singleton PodCheck.
 var Pod = &PodCheck{}
 
-const redactedValue = "********"
-
 // PodCheck is a check that returns container metadata and stats.
 type PodCheck struct {
 	sysInfo                 *model.SystemInfo
@@ -80,10 +79,10 @@ func (c *PodCheck) Run(cfg *config.AgentConfig, groupID int32) ([]model.MessageB
 
 		// scrub & generate YAML
 		for c := 0; c < len(podList[p].Spec.Containers); c++ {
-			scrubContainer(&podList[p].Spec.Containers[c], cfg)
+			orchestrator.ScrubContainer(&podList[p].Spec.Containers[c], cfg.Scrubber)
 		}
 		for c := 0; c < len(podList[p].Spec.InitContainers); c++ {
-			scrubContainer(&podList[p].Spec.InitContainers[c], cfg)
+			orchestrator.ScrubContainer(&podList[p].Spec.InitContainers[c], cfg.Scrubber)
 		}
 		yamlPod, _ := yaml.Marshal(podList[p])
 		podModel.Yaml = yamlPod
@@ -91,10 +90,7 @@ func (c *PodCheck) Run(cfg *config.AgentConfig, groupID int32) ([]model.MessageB
 		podMsgs = append(podMsgs, podModel)
 	}
 
-	groupSize := len(podMsgs) / cfg.MaxPerMessage
-	if len(podMsgs)%cfg.MaxPerMessage != 0 {
-		groupSize++
-	}
+	groupSize := orchestrator.GroupSize(len(podMsgs), cfg.MaxPerMessage)
 	chunked := chunkPods(podMsgs, groupSize, cfg.MaxPerMessage)
 	messages := make([]model.MessageBody, 0, groupSize)
 	for i := 0; i < groupSize; i++ {
@@ -111,22 +107,6 @@ func (c *PodCheck) Run(cfg *config.AgentConfig, groupID int32) ([]model.MessageB
 	return messages, nil
 }
 
-// scrubContainer scrubs sensitive information in the command line & env vars
-func scrubContainer(c *v1.Container, cfg *config.AgentConfig) {
-	// scrub command line
-	scrubbedCmd, _ := cfg.Scrubber.ScrubCommand(c.Command)
-	c.Command = scrubbedCmd
-	// scrub env vars
-	for e := 0; e < len(c.Env); e++ {
-		// use the "key: value" format to work with the regular credential cleaner
-		combination := c.Env[e].Name + ": " + c.Env[e].Value
-		scrubbedVal, err := log.CredentialsCleanerBytes([]byte(combination))
-		if err == nil && combination != string(scrubbedVal) {
-			c.Env[e].Value = redactedValue
-		}
-	}
-}
-
 // chunkPods formats and chunks the pods into a slice of chunks using a specific number of chunks.
 func chunkPods(pods []*model.Pod, chunks, perChunk int) [][]*model.Pod {
 	chunked := make([][]*model.Pod, 0, chunks)
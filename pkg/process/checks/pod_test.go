@@ -12,7 +12,6 @@ import (
 	"time"
 
 	model "github.com/DataDog/agent-payload/process"
-	"github.com/DataDog/datadog-agent/pkg/process/config"
 
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
@@ -219,54 +218,3 @@ func TestExtractPodMessage(t *testing.T) {
 		})
 	}
 }
-
-func TestScrubContainer(t *testing.T) {
-	cfg := config.NewDefaultAgentConfig(true)
-	tests := map[string]struct {
-		input    v1.Container
-		expected v1.Container
-	}{
-		"sensitive CLI": {
-			input: v1.Container{
-				Command: []string{"mysql", "--password", "afztyerbzio1234"},
-			},
-			expected: v1.Container{
-				Command: []string{"mysql", "--password", "********"},
-			},
-		},
-		"sensitive env var": {
-			input: v1.Container{
-				Env: []v1.EnvVar{{Name: "password", Value: "kqhkiG9w0BAQEFAASCAl8wggJbAgEAAoGBAOLJ"}},
-			},
-			expected: v1.Container{
-				Env: []v1.EnvVar{{Name: "password", Value: "********"}},
-			},
-		},
-		"sensitive container": {
-			input: v1.Container{
-				Name:    "test container",
-				Image:   "random",
-				Command: []string{"decrypt", "--password", "afztyerbzio1234", "--access_token", "yolo123"},
-				Env: []v1.EnvVar{
-					{Name: "hostname", Value: "password"},
-					{Name: "pwd", Value: "yolo"},
-				},
-			},
-			expected: v1.Container{
-				Name:    "test container",
-				Image:   "random",
-				Command: []string{"decrypt", "--password", "********", "--access_token", "********"},
-				Env: []v1.EnvVar{
-					{Name: "hostname", Value: "password"},
-					{Name: "pwd", Value: "********"},
-				},
-			},
-		},
-	}
-	for name, tc := range tests {
-		t.Run(name, func(t *testing.T) {
-			scrubContainer(&tc.input, cfg)
-			assert.Equal(t, tc.expected, tc.input)
-		})
-	}
-}
@@ -147,15 +147,12 @@ func (a *AgentConfig) loadProcessYamlConfig(path string) error {
 		return fmt.Errorf("error parsing process_dd_url: %s", err)
 	}
 	a.APIEndpoints[0].Endpoint = URL
-	URL, err = url.Parse(config.GetMainEndpoint("https://orchestrator.", key(ns, "orchestrator_dd_url")))
-	if err != nil {
-		return fmt.Errorf("error parsing orchestrator_dd_url: %s", err)
+	if a.OrchestratorEndpoints, err = GetOrchestratorEndpoints(); err != nil {
+		return err
 	}
-	a.OrchestratorEndpoints[0].Endpoint = URL
 
 	if key := "api_key"; config.Datadog.IsSet(key) {
 		a.APIEndpoints[0].APIKey = config.Datadog.GetString(key)
-		a.OrchestratorEndpoints[0].APIKey = config.Datadog.GetString(key)
 	}
 
 	if config.Datadog.IsSet("hostname") {
@@ -294,21 +291,6 @@ func (a *AgentConfig) loadProcessYamlConfig(path string) error {
 		}
 	}
 
-	if k := key(ns, "orchestrator_additional_endpoints"); config.Datadog.IsSet(k) {
-		for endpointURL, apiKeys := range config.Datadog.GetStringMapStringSlice(k) {
-			u, err := URL.Parse(endpointURL)
-			if err != nil {
-				return fmt.Errorf("invalid additional endpoint url '%s': %s", endpointURL, err)
-			}
-			for _, k := range apiKeys {
-				a.OrchestratorEndpoints = append(a.OrchestratorEndpoints, APIEndpoint{
-					APIKey:   k,
-					Endpoint: u,
-				})
-			}
-		}
-	}
-
 	// Used to override container source auto-detection.
 	// "docker", "ecs_fargate", "kubelet", etc
 	if containerSource := config.Datadog.GetString(key(ns, "container_source")); containerSource != "" {
@@ -355,3 +337,29 @@ func (a *AgentConfig) setCheckInterval(ns, check, checkKey string) {
 		a.CheckIntervals[checkKey] = time.Duration(interval) * time.Second
 	}
 }
+
+// GetOrchestratorEndpoints returns the orchestrator explorer endpoints: the main one,
+// and the ones set in process_config.orchestrator_additional_endpoints.
+func GetOrchestratorEndpoints() ([]APIEndpoint, error) {
+	URL, err := url.Parse(config.GetMainEndpoint("https://orchestrator.", key(ns, "orchestrator_dd_url")))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing orchestrator_dd_url: %s", err)
+	}
+	endpoints := []APIEndpoint{{Endpoint: URL, APIKey: config.Datadog.GetString("api_key")}}
+
+	if k := key(ns, "orchestrator_additional_endpoints"); config.Datadog.IsSet(k) {
+		for endpointURL, apiKeys := range config.Datadog.GetStringMapStringSlice(k) {
+			u, err := URL.Parse(endpointURL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid additional endpoint url '%s': %s", endpointURL, err)
+			}
+			for _, k := range apiKeys {
+				endpoints = append(endpoints, APIEndpoint{
+					APIKey:   k,
+					Endpoint: u,
+				})
+			}
+		}
+	}
+	return endpoints, nil
+}
@@ -24,6 +24,11 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 	return data, nil
 }
 
+// DecryptHandle encrypted secrets are not available on windows
+func DecryptHandle(handle string, origin string) (string, error) {
+	return "", fmt.Errorf("secrets are not available in this version of the agent, cannot decrypt secret '%s'", handle)
+}
+
 // GetDebugInfo exposes debug informations about secrets to be included in a flare
 func GetDebugInfo() (*SecretInfo, error) {
 	return nil, fmt.Errorf("Secret feature is not available in this version of the agent")
@@ -192,6 +192,27 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 	return finalConfig, nil
 }
 
+// DecryptHandle returns the secret of a single handle, from the cache or by
+// executing "secret_backend_command"
+func DecryptHandle(handle string, origin string) (string, error) {
+	if secretBackendCommand == "" {
+		return "", fmt.Errorf("secret_backend_command is not set, cannot decrypt secret '%s'", handle)
+	}
+
+	if secret, ok := secretCache[handle]; ok {
+		log.Debugf("Secret '%s' was retrieved from cache", handle)
+		secretOrigin[handle].Add(origin)
+		return secret, nil
+	}
+
+	secrets, err := secretFetcher([]string{handle}, origin)
+	if err != nil {
+		return "", err
+	}
+	log.Debugf("Secret '%s' was retrieved from executable", handle)
+	return secrets[handle], nil
+}
+
 // GetDebugInfo exposes debug informations about secrets to be included in a flare
 func GetDebugInfo() (*SecretInfo, error) {
 	if secretBackendCommand == "" {
@@ -300,3 +300,32 @@ func TestDebugInfo(t *testing.T) {
 		"pass3": {"test2"},
 	}, handles)
 }
+
+func TestDecryptHandle(t *testing.T) {
+	_, err := DecryptHandle("pass1", "test")
+	require.NotNil(t, err)
+
+	secretBackendCommand = "some_command"
+	secretCache["pass1"] = "password1"
+	secretOrigin["pass1"] = common.NewStringSet("previous_test")
+	defer func() {
+		secretBackendCommand = ""
+		secretCache = map[string]string{}
+		secretOrigin = map[string]common.StringSet{}
+		secretFetcher = fetchSecret
+	}()
+
+	secretFetcher = func(secrets []string, origin string) (map[string]string, error) {
+		assert.Equal(t, []string{"pass2"}, secrets)
+		return map[string]string{"pass2": "password2"}, nil
+	}
+
+	secret, err := DecryptHandle("pass1", "test")
+	require.Nil(t, err)
+	assert.Equal(t, "password1", secret)
+	assert.ElementsMatch(t, []string{"previous_test", "test"}, secretOrigin["pass1"].GetAll())
+
+	secret, err = DecryptHandle("pass2", "test")
+	require.Nil(t, err)
+	assert.Equal(t, "password2", secret)
+}
@@ -268,6 +268,11 @@ func expvarStats(stats map[string]interface{}) (map[string]interface{}, error) {
 	json.Unmarshal(checkSchedulerStatsJSON, &checkSchedulerStats)
 	stats["checkSchedulerStats"] = checkSchedulerStats
 
+	schedulerStatsJSON := []byte(expvar.Get("scheduler").String())
+	schedulerStats := make(map[string]interface{})
+	json.Unmarshal(schedulerStatsJSON, &schedulerStats)
+	stats["schedulerStats"] = schedulerStats
+
 	aggregatorStatsJSON := []byte(expvar.Get("aggregator").String())
 	aggregatorStats := make(map[string]interface{})
 	json.Unmarshal(aggregatorStatsJSON, &aggregatorStats)
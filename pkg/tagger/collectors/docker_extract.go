@@ -37,6 +37,24 @@ func (c *DockerCollector) extractFromInspect(co types.ContainerJSON) ([]string,
 	return low, orchestrator, high, nil
 }
 
+// dockerExtractMetadata returns the labels and environment variables of a container
+func dockerExtractMetadata(co types.ContainerJSON) Metadata {
+	if co.Config == nil {
+		return nil
+	}
+	env := make(map[string]string, len(co.Config.Env))
+	for _, envEntry := range co.Config.Env {
+		envSplit := strings.SplitN(envEntry, "=", 2)
+		if len(envSplit) == 2 {
+			env[envSplit[0]] = envSplit[1]
+		}
+	}
+	return Metadata{
+		MetadataLabel: co.Config.Labels,
+		MetadataEnv:   env,
+	}
+}
+
 func dockerExtractImage(tags *utils.TagList, co types.ContainerJSON, resolve resolveHook) {
 	// Swarm / Compose will store the full image with tag and sha in co.Config.Image
 	// while co.Image will miss the tag. Handle this case first before using the sha
@@ -13,6 +13,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/containers"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/errors"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/util/docker"
@@ -26,11 +27,12 @@ const (
 // and feed a stram of TagInfo. It requires access to the docker socket.
 // It will also embed DockerExtractor collectors for container tagging.
 type DockerCollector struct {
-	dockerUtil   *docker.DockerUtil
-	stop         chan bool
-	infoOut      chan<- []*TagInfo
-	labelsAsTags map[string]string
-	envAsTags    map[string]string
+	dockerUtil      *docker.DockerUtil
+	stop            chan bool
+	infoOut         chan<- []*TagInfo
+	labelsAsTags    map[string]string
+	envAsTags       map[string]string
+	collectMetadata bool // whether to send container labels and environment variables for the tag extraction rules
 }
 
 // Detect tries to connect to the docker socket and returns success
@@ -47,6 +49,7 @@ func (c *DockerCollector) Detect(out chan<- []*TagInfo) (CollectionMode, error)
 	// We lower-case the values collected by viper as well as the ones from inspecting the labels of containers.
 	c.labelsAsTags = retrieveMappingFromConfig("docker_labels_as_tags")
 	c.envAsTags = retrieveMappingFromConfig("docker_env_as_tags")
+	c.collectMetadata = config.Datadog.IsSet("tag_extraction_rules")
 
 	// TODO: list and inspect existing containers once docker utils are merged
 
@@ -93,6 +96,16 @@ func (c *DockerCollector) Fetch(entity string) ([]string, []string, []string, er
 	if entityType != containers.ContainerEntityName || len(cID) == 0 {
 		return nil, nil, nil, nil
 	}
+	low, orchestrator, high, _, err := c.fetchForDockerID(cID)
+	return low, orchestrator, high, err
+}
+
+// FetchWithMetadata inspects a given container to get its tags and metadata on-demand (cache miss)
+func (c *DockerCollector) FetchWithMetadata(entity string) ([]string, []string, []string, Metadata, error) {
+	entityType, cID := containers.SplitEntityName(entity)
+	if entityType != containers.ContainerEntityName || len(cID) == 0 {
+		return nil, nil, nil, nil, nil
+	}
 	return c.fetchForDockerID(cID)
 }
 
@@ -103,13 +116,14 @@ func (c *DockerCollector) processEvent(e *docker.ContainerEvent) {
 	case "die":
 		info = &TagInfo{Entity: e.ContainerEntityName(), Source: dockerCollectorName, DeleteEntity: true}
 	case "start":
-		low, orchestrator, high, _ := c.fetchForDockerID(e.ContainerID)
+		low, orchestrator, high, metadata, _ := c.fetchForDockerID(e.ContainerID)
 		info = &TagInfo{
 			Entity:               e.ContainerEntityName(),
 			Source:               dockerCollectorName,
 			LowCardTags:          low,
 			OrchestratorCardTags: orchestrator,
 			HighCardTags:         high,
+			Metadata:             metadata,
 		}
 	default:
 		return // Nothing to see here
@@ -117,15 +131,19 @@ func (c *DockerCollector) processEvent(e *docker.ContainerEvent) {
 	c.infoOut <- []*TagInfo{info}
 }
 
-func (c *DockerCollector) fetchForDockerID(cID string) ([]string, []string, []string, error) {
+func (c *DockerCollector) fetchForDockerID(cID string) ([]string, []string, []string, Metadata, error) {
 	co, err := c.dockerUtil.Inspect(cID, false)
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			log.Debugf("Failed to inspect container %s - %s", cID, err)
 		}
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
+	}
+	low, orchestrator, high, err := c.extractFromInspect(co)
+	if !c.collectMetadata {
+		return low, orchestrator, high, nil, err
 	}
-	return c.extractFromInspect(co)
+	return low, orchestrator, high, dockerExtractMetadata(co), err
 }
 
 func dockerFactory() Collector {
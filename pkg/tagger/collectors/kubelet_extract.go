@@ -117,6 +117,14 @@ func (c *KubeletCollector) parsePods(pods []*kubelet.Pod) ([]*TagInfo, error) {
 			}
 		}
 
+		var metadata Metadata
+		if c.collectMetadata {
+			metadata = Metadata{
+				MetadataLabel:      pod.Metadata.Labels,
+				MetadataAnnotation: pod.Metadata.Annotations,
+			}
+		}
+
 		low, orch, high := tags.Compute()
 		if pod.Metadata.UID != "" {
 			podInfo := &TagInfo{
@@ -125,6 +133,7 @@ func (c *KubeletCollector) parsePods(pods []*kubelet.Pod) ([]*TagInfo, error) {
 				HighCardTags:         high,
 				OrchestratorCardTags: orch,
 				LowCardTags:          low,
+				Metadata:             metadata,
 			}
 			output = append(output, podInfo)
 		}
@@ -180,6 +189,7 @@ func (c *KubeletCollector) parsePods(pods []*kubelet.Pod) ([]*TagInfo, error) {
 				HighCardTags:         cHigh,
 				OrchestratorCardTags: cOrch,
 				LowCardTags:          cLow,
+				Metadata:             metadata,
 			}
 			output = append(output, info)
 		}
@@ -31,6 +31,7 @@ type KubeletCollector struct {
 	expireFreq        time.Duration
 	labelsAsTags      map[string]string
 	annotationsAsTags map[string]string
+	collectMetadata   bool // whether to send pod labels and annotations for the tag extraction rules
 }
 
 // Detect tries to connect to the kubelet
@@ -58,6 +59,7 @@ func (c *KubeletCollector) Detect(out chan<- []*TagInfo) (CollectionMode, error)
 		annotationsList[strings.ToLower(annotation)] = value
 	}
 	c.annotationsAsTags = annotationsList
+	c.collectMetadata = config.Datadog.IsSet("tag_extraction_rules")
 	return PullCollection, nil
 }
 
@@ -98,25 +100,31 @@ func (c *KubeletCollector) Pull() error {
 // Fetch fetches tags for a given entity by iterating on the whole podlist
 // TODO: optimize if called too often on production
 func (c *KubeletCollector) Fetch(entity string) ([]string, []string, []string, error) {
+	low, orchestrator, high, _, err := c.FetchWithMetadata(entity)
+	return low, orchestrator, high, err
+}
+
+// FetchWithMetadata fetches tags and metadata for a given entity by iterating on the whole podlist
+func (c *KubeletCollector) FetchWithMetadata(entity string) ([]string, []string, []string, Metadata, error) {
 	pod, err := c.watcher.GetPodForEntityID(entity)
 	if err != nil {
-		return []string{}, []string{}, []string{}, err
+		return []string{}, []string{}, []string{}, nil, err
 	}
 
 	pods := []*kubelet.Pod{pod}
 	updates, err := c.parsePods(pods)
 	if err != nil {
-		return []string{}, []string{}, []string{}, err
+		return []string{}, []string{}, []string{}, nil, err
 	}
 	c.infoOut <- updates
 
 	for _, info := range updates {
 		if info.Entity == entity {
-			return info.LowCardTags, info.OrchestratorCardTags, info.HighCardTags, nil
+			return info.LowCardTags, info.OrchestratorCardTags, info.HighCardTags, info.Metadata, nil
 		}
 	}
 	// entity not found in updates
-	return []string{}, []string{}, []string{}, errors.NewNotFound(entity)
+	return []string{}, []string{}, []string{}, nil, errors.NewNotFound(entity)
 }
 
 // parseExpires transforms event from the PodWatcher to TagInfo objects
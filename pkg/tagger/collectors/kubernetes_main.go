@@ -9,6 +9,7 @@ package collectors
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -30,6 +31,10 @@ type KubeMetadataCollector struct {
 	apiClient *apiserver.APIClient
 	infoOut   chan<- []*TagInfo
 	dcaClient clusteragent.DCAClientInterface
+	// metadataCache is fed by the metadata stream of the DCA, when enabled
+	metadataCache *clusteragent.MetadataCache
+	// namespaceLabelsAsTags maps the namespace labels received from the metadata stream to tags
+	namespaceLabelsAsTags map[string]string
 	// used to set a custom delay
 	lastUpdate time.Time
 	updateFreq time.Duration
@@ -66,6 +71,14 @@ func (c *KubeMetadataCollector) Detect(out chan<- []*TagInfo) (CollectionMode, e
 			log.Errorf("Permanent failure in communication with the cluster agent, will fallback to local service mapper")
 		} else {
 			c.clusterAgentEnabled = true
+			if config.Datadog.GetBool("cluster_agent.metadata_stream.enabled") {
+				c.startMetadataStream()
+			}
+			// viper lower-cases map keys from yaml, but not from envvars
+			c.namespaceLabelsAsTags = make(map[string]string)
+			for label, tag := range config.Datadog.GetStringMapString("kubernetes_namespace_labels_as_tags") {
+				c.namespaceLabelsAsTags[strings.ToLower(label)] = tag
+			}
 		}
 	}
 	// Fallback to local metamapper if DCA not enabled, or in permafail state with fallback enabled.
@@ -84,7 +97,7 @@ func (c *KubeMetadataCollector) Detect(out chan<- []*TagInfo) (CollectionMode, e
 func (c *KubeMetadataCollector) Pull() error {
 	// Time constraints, get the delta in seconds to display it in the logs:
 	timeDelta := c.lastUpdate.Add(c.updateFreq).Unix() - time.Now().Unix()
-	if timeDelta > 0 {
+	if timeDelta > 0 && !c.metadataChangedSinceLastUpdate() {
 		log.Tracef("skipping, next effective Pull will be in %d seconds", timeDelta)
 		return nil
 	}
@@ -138,6 +151,26 @@ func (c *KubeMetadataCollector) Fetch(entity string) ([]string, []string, []stri
 	return lowCards, orchestratorCards, highCards, errors.NewNotFound(entity)
 }
 
+// startMetadataStream subscribes to the metadata stream of the DCA, the
+// metadata is then pushed by the DCA instead of being polled.
+func (c *KubeMetadataCollector) startMetadataStream() {
+	if c.metadataCache != nil {
+		return
+	}
+	nodeName, err := c.kubeUtil.GetNodename()
+	if err != nil {
+		log.Errorf("Could not retrieve the Nodename, not subscribing to the metadata stream of the cluster agent: %v", err)
+		return
+	}
+	c.metadataCache = clusteragent.StartMetadataCache(c.dcaClient, nodeName)
+}
+
+// metadataChangedSinceLastUpdate returns whether the metadata stream of the DCA
+// received changes since the last update, to pull them without waiting.
+func (c *KubeMetadataCollector) metadataChangedSinceLastUpdate() bool {
+	return c.metadataCache != nil && c.metadataCache.IsSynced() && c.metadataCache.LastChange().After(c.lastUpdate)
+}
+
 func (c *KubeMetadataCollector) isClusterAgentEnabled() bool {
 	if c.clusterAgentEnabled && c.dcaClient != nil {
 		v := c.dcaClient.Version()
@@ -21,7 +21,12 @@ import (
 func (c *KubeMetadataCollector) getTagInfos(pods []*kubelet.Pod) []*TagInfo {
 	var err error
 	var metadataByNsPods apiv1.NamespacesPodsStringsSet
-	if c.isClusterAgentEnabled() && c.dcaClient.Version().Major >= 1 && c.dcaClient.Version().Minor >= 3 {
+	// The namespace labels are only available from the metadata stream
+	fromStream := c.isClusterAgentEnabled() && c.metadataCache != nil && c.metadataCache.IsSynced()
+	if fromStream {
+		// The metadata is pushed by the DCA, no need to query it
+		metadataByNsPods = c.metadataCache.GetPodsMetadata()
+	} else if c.isClusterAgentEnabled() && c.dcaClient.Version().Major >= 1 && c.dcaClient.Version().Minor >= 3 {
 		var nodeName string
 		nodeName, err = c.kubeUtil.GetNodename()
 		if err != nil {
@@ -82,6 +87,13 @@ func (c *KubeMetadataCollector) getTagInfos(pods []*kubelet.Pod) []*TagInfo {
 				continue
 			}
 		}
+		if fromStream {
+			for name, value := range c.metadataCache.GetNamespaceLabels(po.Metadata.Namespace) {
+				if tagName, found := c.namespaceLabelsAsTags[strings.ToLower(name)]; found {
+					tagList.AddAuto(tagName, value)
+				}
+			}
+		}
 
 		low, orchestrator, high := tagList.Compute()
 		// Register the tags for the pod itself
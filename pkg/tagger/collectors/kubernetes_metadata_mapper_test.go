@@ -8,6 +8,7 @@
 package collectors
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
@@ -20,6 +21,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/kubelet"
 	"github.com/DataDog/datadog-agent/pkg/version"
+	"github.com/stretchr/testify/require"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -38,6 +40,9 @@ type FakeDCAClient struct {
 	KubernetesMetadataNames    []string
 	KubernetesMetadataNamesErr error
 
+	MetadataEvents    []apiv1.MetadataEvent
+	MetadataStreamErr error
+
 	ClusterCheckStatus    types.StatusResponse
 	ClusterCheckStatusErr error
 
@@ -46,6 +51,9 @@ type FakeDCAClient struct {
 
 	EndpointsCheckConfigs    types.ConfigResponse
 	EndpointsCheckConfigsErr error
+
+	ExternalMetricsSeries    apiv1.SeriesResponse
+	ExternalMetricsSeriesErr error
 }
 
 func (f *FakeDCAClient) Version() version.Version {
@@ -67,6 +75,13 @@ func (f *FakeDCAClient) GetKubernetesMetadataNames(nodeName, ns, podName string)
 	return f.KubernetesMetadataNames, f.KubernetesMetadataNamesErr
 }
 
+func (f *FakeDCAClient) StreamMetadata(ctx context.Context, nodeName, token string, handle func(apiv1.MetadataEvent)) error {
+	for _, event := range f.MetadataEvents {
+		handle(event)
+	}
+	return f.MetadataStreamErr
+}
+
 func (f *FakeDCAClient) PostClusterCheckStatus(nodeName string, status types.NodeStatus) (types.StatusResponse, error) {
 	return f.ClusterCheckStatus, f.ClusterCheckStatusErr
 }
@@ -79,6 +94,10 @@ func (f *FakeDCAClient) GetEndpointsCheckConfigs(nodeName string) (types.ConfigR
 	return f.EndpointsCheckConfigs, f.EndpointsCheckConfigsErr
 }
 
+func (f *FakeDCAClient) PostExternalMetricsSeries(nodeName string, payload apiv1.SeriesPayload) (apiv1.SeriesResponse, error) {
+	return f.ExternalMetricsSeries, f.ExternalMetricsSeriesErr
+}
+
 func TestKubeMetadataCollector_getMetadaNames(t *testing.T) {
 	type fields struct {
 		dcaClient           clusteragent.DCAClientInterface
@@ -358,3 +377,61 @@ func TestKubeMetadataCollector_getTagInfos(t *testing.T) {
 		})
 	}
 }
+
+func TestKubeMetadataCollector_getTagInfosFromMetadataStream(t *testing.T) {
+	pods := []*kubelet.Pod{{
+		Metadata: kubelet.PodMetadata{
+			Name:      "foo",
+			Namespace: "default",
+			UID:       "foouid",
+		},
+		Spec: kubelet.Spec{
+			NodeName: "nodename",
+		},
+		Status: kubelet.Status{
+			Phase: "Running",
+			Conditions: []kubelet.Conditions{
+				{
+					Type:   "Ready",
+					Status: "True",
+				},
+			},
+		},
+	}}
+	dcaClient := &FakeDCAClient{
+		LocalVersion: version.Version{Major: 1, Minor: 6},
+		MetadataEvents: []apiv1.MetadataEvent{
+			{Type: apiv1.MetadataEventReset},
+			{Type: apiv1.MetadataEventSet, Kind: apiv1.MetadataKindNamespace, Name: "default", Labels: map[string]string{"Team": "sre", "tier": "1", "other": "x"}},
+			{Type: apiv1.MetadataEventSet, Kind: apiv1.MetadataKindPod, Namespace: "default", Name: "foo", Services: []string{"svc1"}},
+			{Type: apiv1.MetadataEventSynced},
+		},
+		MetadataStreamErr: fmt.Errorf("stream closed"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	metadataCache := clusteragent.NewMetadataCache(dcaClient, "nodename")
+	go metadataCache.Run(ctx)
+	for i := 0; i < 100 && !metadataCache.IsSynced(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.True(t, metadataCache.IsSynced())
+
+	c := &KubeMetadataCollector{
+		dcaClient:             dcaClient,
+		clusterAgentEnabled:   true,
+		metadataCache:         metadataCache,
+		namespaceLabelsAsTags: map[string]string{"team": "team", "tier": "+tier"},
+	}
+	expected := []*TagInfo{
+		{
+			Source:               kubeMetadataCollectorName,
+			Entity:               kubelet.PodUIDToTaggerEntityName("foouid"),
+			HighCardTags:         []string{"tier:1"},
+			OrchestratorCardTags: []string{},
+			LowCardTags:          []string{"kube_service:svc1", "team:sre"},
+		},
+	}
+	assertTagInfoListEqual(t, expected, c.getTagInfos(pods))
+}
@@ -0,0 +1,235 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build linux
+
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger/utils"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// systemdUnitDirs are the directories systemd loads unit files from, by priority
+var systemdUnitDirs = []string{
+	"/etc/systemd/system",
+	"/run/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+}
+
+// extractProcessTags extracts the tags of a host process from procfs
+func (c *ProcessCollector) extractProcessTags(pid int) ([]string, []string, []string, error) {
+	procDir := filepath.Join(c.procRoot, strconv.Itoa(pid))
+
+	cgroups, err := os.Open(filepath.Join(procDir, "cgroup"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cgroupPath, unit := parseProcessCgroup(cgroups)
+	cgroups.Close()
+
+	tags := utils.NewTagList()
+	tags.AddHigh("pid", strconv.Itoa(pid))
+	if cgroupPath != "" {
+		tags.AddHigh("cgroup", cgroupPath)
+	}
+	if unit != "" {
+		tags.AddLow("systemd_unit", unit)
+		if len(c.unitLabelsAsTags) > 0 {
+			processExtractUnitLabels(tags, c.readUnitFile(unit), c.unitLabelsAsTags)
+		}
+	}
+
+	if status, err := os.Open(filepath.Join(procDir, "status")); err == nil {
+		if uid := parseProcessUID(status); uid != "" {
+			tags.AddLow("user", c.lookupUser(uid))
+		}
+		status.Close()
+	}
+
+	if len(c.envAsTags) > 0 {
+		// Reading the environment of processes owned by other users needs privileges
+		if environ, err := ioutil.ReadFile(filepath.Join(procDir, "environ")); err == nil {
+			processExtractEnvironmentVariables(tags, bytes.Split(environ, []byte{0}), c.envAsTags)
+		} else {
+			log.Debugf("Cannot read the environment of process %d: %s", pid, err)
+		}
+	}
+
+	low, orchestrator, high := tags.Compute()
+	return low, orchestrator, high, nil
+}
+
+// readProcessStartTime returns the start time of a process, in clock ticks
+// since boot, from /proc/<pid>/stat
+func readProcessStartTime(procRoot string, pid int) (uint64, error) {
+	stat, err := ioutil.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	// the command name, between parentheses, may hold spaces
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("invalid stat file for process %d", pid)
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	// fields start at the 3rd one, the state
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("invalid stat file for process %d", pid)
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// parseProcessCgroup returns the cgroup of a process and the systemd unit it
+// belongs to, from the content of /proc/<pid>/cgroup. It prefers the systemd
+// hierarchy of cgroup v1, or the unified hierarchy of cgroup v2.
+func parseProcessCgroup(r io.Reader) (string, string) {
+	var cgroupPath string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] == "name=systemd" || (parts[0] == "0" && parts[1] == "") {
+			cgroupPath = parts[2]
+			if parts[1] == "name=systemd" {
+				break
+			}
+		}
+	}
+	return cgroupPath, systemdUnitFromCgroup(cgroupPath)
+}
+
+// systemdUnitFromCgroup returns the innermost service unit of a cgroup path,
+// e.g. nginx.service for /system.slice/nginx.service.
+func systemdUnitFromCgroup(cgroupPath string) string {
+	elements := strings.Split(cgroupPath, "/")
+	for i := len(elements) - 1; i >= 0; i-- {
+		if strings.HasSuffix(elements[i], ".service") {
+			return elements[i]
+		}
+	}
+	return ""
+}
+
+// parseProcessUID returns the real user ID of a process from the content of /proc/<pid>/status
+func parseProcessUID(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Uid:") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "Uid:"))
+		if len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+// lookupUser returns the name of a user, or its ID if it cannot be resolved
+func (c *ProcessCollector) lookupUser(uid string) string {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if name, found := c.users[uid]; found {
+		return name
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	c.users[uid] = name
+	return name
+}
+
+// readUnitFile returns the entries of the file of a systemd unit, or nil if it isn't found
+func (c *ProcessCollector) readUnitFile(unit string) map[string]string {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if entries, found := c.units[unit]; found {
+		return entries
+	}
+	var entries map[string]string
+	for _, dir := range systemdUnitDirs {
+		f, err := os.Open(filepath.Join(dir, unit))
+		if err != nil {
+			continue
+		}
+		entries = parseUnitFile(f)
+		f.Close()
+		break
+	}
+	c.units[unit] = entries
+	return entries
+}
+
+// parseUnitFile returns the entries of a systemd unit file, keyed by lowercase
+// name. Variables set by Environment= entries are returned as well, so that
+// both custom X- entries and environment variables can be used as labels.
+func parseUnitFile(r io.Reader) map[string]string {
+	entries := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' || line[0] == '[' {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if key != "Environment" {
+			entries[strings.ToLower(key)] = value
+			continue
+		}
+		for _, assignment := range strings.Fields(value) {
+			assignment = strings.Trim(assignment, `"`)
+			if kv := strings.SplitN(assignment, "=", 2); len(kv) == 2 {
+				entries[strings.ToLower(kv[0])] = kv[1]
+			}
+		}
+	}
+	return entries
+}
+
+// processExtractUnitLabels adds the tags configured in systemd_unit_labels_as_tags
+func processExtractUnitLabels(tags *utils.TagList, entries map[string]string, labelsAsTags map[string]string) {
+	for name, value := range entries {
+		if tagName, found := labelsAsTags[name]; found {
+			tags.AddAuto(tagName, value)
+		}
+	}
+}
+
+// processExtractEnvironmentVariables adds the tags configured in process_env_as_tags
+func processExtractEnvironmentVariables(tags *utils.TagList, environ [][]byte, envAsTags map[string]string) {
+	for _, entry := range environ {
+		parts := strings.SplitN(string(entry), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if tagName, found := envAsTags[strings.ToLower(parts[0])]; found {
+			tags.AddAuto(tagName, parts[1])
+		}
+	}
+}
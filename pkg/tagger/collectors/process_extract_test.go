@@ -0,0 +1,176 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build linux
+
+package collectors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/errors"
+)
+
+func TestParseProcessCgroup(t *testing.T) {
+	for name, tc := range map[string]struct {
+		content string
+		path    string
+		unit    string
+	}{
+		"cgroup v1": {
+			content: "12:pids:/system.slice/nginx.service\n1:name=systemd:/system.slice/nginx.service\n0::/system.slice/nginx.service\n",
+			path:    "/system.slice/nginx.service",
+			unit:    "nginx.service",
+		},
+		"cgroup v2": {
+			content: "0::/system.slice/redis-server.service\n",
+			path:    "/system.slice/redis-server.service",
+			unit:    "redis-server.service",
+		},
+		"user session": {
+			content: "0::/user.slice/user-1000.slice/session-2.scope\n",
+			path:    "/user.slice/user-1000.slice/session-2.scope",
+			unit:    "",
+		},
+		"user service": {
+			content: "0::/user.slice/user-1000.slice/user@1000.service/app.slice/gpg-agent.service\n",
+			path:    "/user.slice/user-1000.slice/user@1000.service/app.slice/gpg-agent.service",
+			unit:    "gpg-agent.service",
+		},
+		"empty": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path, unit := parseProcessCgroup(strings.NewReader(tc.content))
+			assert.Equal(t, tc.path, path)
+			assert.Equal(t, tc.unit, unit)
+		})
+	}
+}
+
+func TestParseProcessUID(t *testing.T) {
+	status := "Name:\tnginx\nUmask:\t0022\nState:\tS (sleeping)\nUid:\t33\t33\t33\t33\nGid:\t33\t33\t33\t33\n"
+	assert.Equal(t, "33", parseProcessUID(strings.NewReader(status)))
+	assert.Equal(t, "", parseProcessUID(strings.NewReader("Name:\tnginx\n")))
+}
+
+func TestParseUnitFile(t *testing.T) {
+	unit := `[Unit]
+Description=Payments API
+X-Team=payments
+
+[Service]
+# comment
+Environment="DD_SERVICE=payments-api" DD_ENV=prod
+ExecStart=/usr/bin/payments
+`
+	assert.Equal(t, map[string]string{
+		"description": "Payments API",
+		"x-team":      "payments",
+		"dd_service":  "payments-api",
+		"dd_env":      "prod",
+		"execstart":   "/usr/bin/payments",
+	}, parseUnitFile(strings.NewReader(unit)))
+}
+
+func TestParseProcessEntity(t *testing.T) {
+	pid, startTime, ok := parseProcessEntity("process://42:1042")
+	assert.True(t, ok)
+	assert.Equal(t, 42, pid)
+	assert.Equal(t, uint64(1042), startTime)
+
+	for _, entity := range []string{"container_id://42", "process://", "process://42", "process://abc:1", "process://-1:1", "process://42:abc"} {
+		_, _, ok = parseProcessEntity(entity)
+		assert.False(t, ok, entity)
+	}
+}
+
+func writeProcessStat(t *testing.T, pidDir string, pid int, startTime uint64) {
+	stat := fmt.Sprintf("%d (payments server) S 1 %s%d 0 0\n", pid, strings.Repeat("0 ", 17), startTime)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(pidDir, "stat"), []byte(stat), 0644))
+}
+
+func TestProcessCollectorFetch(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+	unitDir, err := ioutil.TempDir("", "systemd")
+	require.NoError(t, err)
+	defer os.RemoveAll(unitDir)
+
+	defer func(dirs []string) { systemdUnitDirs = dirs }(systemdUnitDirs)
+	systemdUnitDirs = []string{unitDir}
+
+	pidDir := filepath.Join(procRoot, "42")
+	require.NoError(t, os.Mkdir(pidDir, 0755))
+	writeProcessStat(t, pidDir, 42, 1042)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(pidDir, "cgroup"), []byte("0::/system.slice/payments.service\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(pidDir, "status"), []byte("Uid:\t0\t0\t0\t0\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(pidDir, "environ"), []byte("HOME=/\x00APP_VERSION=1.2\x00"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(unitDir, "payments.service"), []byte("[Unit]\nX-Team=payments\n"), 0644))
+
+	out := make(chan []*TagInfo, 1)
+	c := &ProcessCollector{
+		infoOut:          out,
+		procRoot:         procRoot,
+		envAsTags:        map[string]string{"app_version": "+version"},
+		unitLabelsAsTags: map[string]string{"x-team": "team"},
+		pids:             make(map[int]uint64),
+		users:            make(map[string]string),
+		units:            make(map[string]map[string]string),
+	}
+
+	low, orchestrator, high, err := c.Fetch("process://42:1042")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"systemd_unit:payments.service", "team:payments", "user:root"}, low)
+	assert.Empty(t, orchestrator)
+	assert.ElementsMatch(t, []string{"pid:42", "cgroup:/system.slice/payments.service", "version:1.2"}, high)
+
+	_, _, _, err = c.Fetch("process://43:1043")
+	assert.True(t, errors.IsNotFound(err))
+	_, _, _, err = c.Fetch("process://42:1000")
+	assert.True(t, errors.IsNotFound(err))
+	_, _, _, err = c.Fetch("container_id://42")
+	assert.True(t, errors.IsNotFound(err))
+
+	// Live processes get their tags extracted again
+	require.NoError(t, ioutil.WriteFile(filepath.Join(unitDir, "payments.service"), []byte("[Unit]\nX-Team=billing\n"), 0644))
+	require.NoError(t, c.Pull())
+	require.Len(t, out, 1)
+	updates := <-out
+	require.Len(t, updates, 1)
+	assert.Equal(t, "process://42:1042", updates[0].Entity)
+	assert.False(t, updates[0].DeleteEntity)
+	assert.Contains(t, updates[0].LowCardTags, "team:billing")
+
+	// Processes whose PID was reused are deleted
+	writeProcessStat(t, pidDir, 42, 2042)
+	require.NoError(t, c.Pull())
+	require.Len(t, out, 1)
+	deletions := <-out
+	require.Len(t, deletions, 1)
+	assert.Equal(t, "process://42:1042", deletions[0].Entity)
+	assert.True(t, deletions[0].DeleteEntity)
+
+	// Exited processes are deleted
+	_, _, _, err = c.Fetch("process://42:2042")
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(pidDir))
+	require.NoError(t, c.Pull())
+	require.Len(t, out, 1)
+	deletions = <-out
+	require.Len(t, deletions, 1)
+	assert.Equal(t, "process://42:2042", deletions[0].Entity)
+	assert.True(t, deletions[0].DeleteEntity)
+	require.NoError(t, c.Pull())
+	assert.Len(t, out, 0)
+}
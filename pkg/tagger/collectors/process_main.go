@@ -0,0 +1,176 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build linux
+
+package collectors
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/errors"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	processCollectorName = "process"
+)
+
+// ProcessCollector tags the processes running on the host, outside of
+// containers, from procfs: their systemd unit, cgroup and user, along with
+// labels from their systemd unit file and environment variables.
+type ProcessCollector struct {
+	infoOut          chan<- []*TagInfo
+	procRoot         string
+	envAsTags        map[string]string
+	unitLabelsAsTags map[string]string
+	pids             map[int]uint64 // start time of the processes tagged, to delete once they exit
+	users            map[string]string
+	units            map[string]map[string]string
+	m                sync.Mutex
+}
+
+// Detect enables the collector when process tagging is enabled
+func (c *ProcessCollector) Detect(out chan<- []*TagInfo) (CollectionMode, error) {
+	if !config.Datadog.GetBool("process_tagging_enabled") {
+		return NoCollection, fmt.Errorf("process tagging is disabled")
+	}
+
+	c.infoOut = out
+	c.procRoot = config.Datadog.GetString("container_proc_root")
+	c.envAsTags = retrieveMappingFromConfig("process_env_as_tags")
+	c.unitLabelsAsTags = retrieveMappingFromConfig("systemd_unit_labels_as_tags")
+	c.pids = make(map[int]uint64)
+	c.users = make(map[string]string)
+	c.units = make(map[string]map[string]string)
+
+	return PullCollection, nil
+}
+
+// Pull deletes the processes that exited, or whose PID was reused, and
+// extracts again the tags of the live ones
+func (c *ProcessCollector) Pull() error {
+	c.m.Lock()
+	// Unit files are read again, in case they changed
+	c.units = make(map[string]map[string]string)
+	pids := make(map[int]uint64, len(c.pids))
+	for pid, startTime := range c.pids {
+		pids[pid] = startTime
+	}
+	c.m.Unlock()
+
+	var updates []*TagInfo
+	for pid, startTime := range pids {
+		entity := BuildProcessEntityName(pid, startTime)
+		current, err := readProcessStartTime(c.procRoot, pid)
+		if err == nil && current == startTime {
+			low, orchestrator, high, err := c.extractProcessTags(pid)
+			if err == nil {
+				updates = append(updates, &TagInfo{
+					Source:               processCollectorName,
+					Entity:               entity,
+					LowCardTags:          low,
+					OrchestratorCardTags: orchestrator,
+					HighCardTags:         high,
+				})
+				continue
+			} else if !os.IsNotExist(err) {
+				log.Debugf("Cannot extract the tags of process %d: %s", pid, err)
+				continue
+			}
+		} else if err != nil && !os.IsNotExist(err) {
+			log.Debugf("Cannot read the start time of process %d: %s", pid, err)
+			continue
+		}
+
+		c.m.Lock()
+		if c.pids[pid] == startTime {
+			delete(c.pids, pid)
+		}
+		c.m.Unlock()
+		updates = append(updates, &TagInfo{
+			Source:       processCollectorName,
+			Entity:       entity,
+			DeleteEntity: true,
+		})
+	}
+
+	if len(updates) > 0 {
+		c.infoOut <- updates
+	}
+	return nil
+}
+
+// Fetch extracts the tags of a process on cache miss
+func (c *ProcessCollector) Fetch(entity string) ([]string, []string, []string, error) {
+	pid, startTime, ok := parseProcessEntity(entity)
+	if !ok {
+		return nil, nil, nil, errors.NewNotFound(entity)
+	}
+
+	// The PID may have been reused by another process since the entity was built
+	current, err := readProcessStartTime(c.procRoot, pid)
+	if os.IsNotExist(err) || (err == nil && current != startTime) {
+		return nil, nil, nil, errors.NewNotFound(entity)
+	} else if err != nil {
+		return nil, nil, nil, err
+	}
+
+	low, orchestrator, high, err := c.extractProcessTags(pid)
+	if os.IsNotExist(err) {
+		return nil, nil, nil, errors.NewNotFound(entity)
+	} else if err != nil {
+		return nil, nil, nil, err
+	}
+
+	c.m.Lock()
+	c.pids[pid] = startTime
+	c.m.Unlock()
+
+	return low, orchestrator, high, nil
+}
+
+// GetProcessEntity returns the tagger entity name of a host process, read
+// from the procfs set in container_proc_root
+func GetProcessEntity(pid int) (string, error) {
+	startTime, err := readProcessStartTime(config.Datadog.GetString("container_proc_root"), pid)
+	if err != nil {
+		return "", err
+	}
+	return BuildProcessEntityName(pid, startTime), nil
+}
+
+// parseProcessEntity returns the PID and start time of a process entity
+func parseProcessEntity(entity string) (int, uint64, bool) {
+	if !strings.HasPrefix(entity, ProcessEntityPrefix) {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(entity, ProcessEntityPrefix), ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	pid, err := strconv.Atoi(parts[0])
+	if err != nil || pid <= 0 {
+		return 0, 0, false
+	}
+	startTime, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return pid, startTime, true
+}
+
+func processFactory() Collector {
+	return &ProcessCollector{}
+}
+
+func init() {
+	registerCollector(processCollectorName, processFactory, NodeRuntime)
+}
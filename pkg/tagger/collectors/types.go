@@ -5,6 +5,10 @@
 
 package collectors
 
+import (
+	"fmt"
+)
+
 // TagInfo holds the tag information for a given entity and source. It's meant
 // to be created from collectors and read by the store.
 type TagInfo struct {
@@ -15,8 +19,20 @@ type TagInfo struct {
 	LowCardTags          []string // low cardinality tags safe for every pipeline
 	DeleteEntity         bool     // true if the entity is to be deleted from the store
 	CacheMiss            bool     // true if the TagInfo is generated by a tag miss
+	Metadata             Metadata // raw metadata of the entity, matched by the tag extraction rules
 }
 
+// Metadata holds the labels, annotations and environment variables of an
+// entity, by kind, for the tag extraction rules of the store to match.
+type Metadata map[string]map[string]string
+
+// Kinds of entity metadata
+const (
+	MetadataLabel      = "label"
+	MetadataAnnotation = "annotation"
+	MetadataEnv        = "env"
+)
+
 // CollectionMode informs the Tagger of how to schedule a Collector
 type CollectionMode int
 
@@ -62,6 +78,13 @@ type Fetcher interface {
 	Fetch(string) ([]string, []string, []string, error)
 }
 
+// MetadataFetcher is implemented by the fetchers that can return the metadata
+// of an entity along with its tags on cache miss, so that the tag extraction
+// rules are applied to the fetched entities as well
+type MetadataFetcher interface {
+	FetchWithMetadata(string) ([]string, []string, []string, Metadata, error)
+}
+
 // Streamer feeds back TagInfo when detecting changes
 type Streamer interface {
 	Fetcher
@@ -74,3 +97,12 @@ type Puller interface {
 	Fetcher
 	Pull() error
 }
+
+// ProcessEntityPrefix is the prefix of the entity name of host processes
+const ProcessEntityPrefix = "process://"
+
+// BuildProcessEntityName builds the tagger entity name of a host process. Its
+// start time, in clock ticks since boot, tells apart processes reusing a PID.
+func BuildProcessEntityName(pid int, startTime uint64) string {
+	return fmt.Sprintf("%s%d:%d", ProcessEntityPrefix, pid, startTime)
+}
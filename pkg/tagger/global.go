@@ -13,6 +13,7 @@ import (
 	"github.com/DataDog/datadog-agent/cmd/agent/api/response"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/tagger/remote"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -20,6 +21,9 @@ import (
 var defaultTagger *Tagger
 var initOnce sync.Once
 
+// remoteTagger, if set by InitRemote, serves the global Tag function instead of defaultTagger
+var remoteTagger *remote.Tagger
+
 // ChecksCardinality defines the cardinality of tags we should send for check metrics
 // this can still be overridden when calling get_tags in python checks.
 var ChecksCardinality collectors.TagCardinality
@@ -35,12 +39,12 @@ func Init() {
 		checkCard := config.Datadog.GetString("checks_tag_cardinality")
 		dsdCard := config.Datadog.GetString("dogstatsd_tag_cardinality")
 
-		ChecksCardinality, err = stringToTagCardinality(checkCard)
+		ChecksCardinality, err = StringToTagCardinality(checkCard)
 		if err != nil {
 			log.Warnf("failed to parse check tag cardinality, defaulting to low. Error: %s", err)
 			ChecksCardinality = collectors.LowCardinality
 		}
-		DogstatsdCardinality, err = stringToTagCardinality(dsdCard)
+		DogstatsdCardinality, err = StringToTagCardinality(dsdCard)
 		if err != nil {
 			log.Warnf("failed to parse dogstatsd tag cardinality, defaulting to low. Error: %s", err)
 			DogstatsdCardinality = collectors.LowCardinality
@@ -50,31 +54,94 @@ func Init() {
 	})
 }
 
+// InitRemote is called instead of Init by the processes running alongside the core
+// agent, such as the process-agent and the trace-agent. If `remote_tagger.enabled`
+// is set, their tags are the high cardinality tags of the core agent tagger, read
+// from its tagger stream, instead of being collected again. Otherwise, or if the
+// stream can't be set up, it calls Init.
+func InitRemote() {
+	if !config.Datadog.GetBool("remote_tagger.enabled") {
+		Init()
+		return
+	}
+	t, err := remote.NewTagger("high")
+	if err == nil {
+		err = t.Start()
+	}
+	if err != nil {
+		log.Errorf("Could not start the remote tagger, collecting tags locally: %s", err)
+		Init()
+		return
+	}
+	remoteTagger = t
+}
+
 // Tag queries the defaultTagger to get entity tags from cache or sources.
 // It can return tags at high cardinality (with tags about individual containers),
 // or at orchestrator cardinality (pod/task level)
 func Tag(entity string, cardinality collectors.TagCardinality) ([]string, error) {
+	if remoteTagger != nil {
+		if cardinality != collectors.HighCardinality {
+			return nil, fmt.Errorf("the remote tagger only serves high cardinality tags")
+		}
+		return remoteTagger.Tag(entity)
+	}
 	return defaultTagger.Tag(entity, cardinality)
 }
 
-// Stop queues a stop signal to the defaultTagger
+// Stop queues a stop signal to the defaultTagger, or stops the remote tagger
 func Stop() error {
+	if remoteTagger != nil {
+		remoteTagger.Stop()
+		return nil
+	}
 	return defaultTagger.Stop()
 }
 
-// List the content of the defaulTagger
+// List the content of the defaulTagger, or of the remote tagger which only
+// holds high cardinality tags
 func List(cardinality collectors.TagCardinality) response.TaggerListResponse {
+	if remoteTagger != nil {
+		if cardinality != collectors.HighCardinality {
+			return response.TaggerListResponse{Entities: make(map[string]response.TaggerListEntity)}
+		}
+		return remoteTagger.List()
+	}
 	return defaultTagger.List(cardinality)
 }
 
+// Subscribe returns a channel receiving the changes of the entity tags of the
+// defaultTagger, see Tagger.Subscribe. The remote tagger can't be subscribed to.
+func Subscribe(cardinality collectors.TagCardinality) (chan []EntityEvent, error) {
+	if remoteTagger != nil {
+		return nil, fmt.Errorf("the remote tagger does not support subscriptions")
+	}
+	return defaultTagger.Subscribe(cardinality), nil
+}
+
+// Unsubscribe ends a subscription to the defaultTagger
+func Unsubscribe(ch chan []EntityEvent) {
+	if remoteTagger != nil {
+		return
+	}
+	defaultTagger.Unsubscribe(ch)
+}
+
 // GetEntityHash returns the hash for the tags associated with the given entity
 func GetEntityHash(entity string) string {
+	if remoteTagger != nil {
+		tags, err := remoteTagger.Tag(entity)
+		if err != nil {
+			return ""
+		}
+		return computeTagsHash(tags)
+	}
 	return defaultTagger.GetEntityHash(entity)
 }
 
-// stringToTagCardinality extracts a TagCardinality from a string.
+// StringToTagCardinality extracts a TagCardinality from a string.
 // In case of failure to parse, returns an error and defaults to Low.
-func stringToTagCardinality(c string) (collectors.TagCardinality, error) {
+func StringToTagCardinality(c string) (collectors.TagCardinality, error) {
 	switch strings.ToLower(c) {
 	case "high":
 		return collectors.HighCardinality, nil
@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// Package remote implements a tagger for the processes running alongside the
+// core agent, such as the process-agent or the trace-agent. Instead of querying
+// the container runtimes and orchestrators themselves, they keep a copy of the
+// tags of the core agent tagger, updated through its tagger stream.
+package remote
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/api/response"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+	maxLineSize       = 16 * 1024 * 1024
+)
+
+// Tagger keeps a copy of the tags of the core agent tagger
+type Tagger struct {
+	url    string
+	client *http.Client
+	tags   map[string][]string
+	synced bool
+	m      sync.RWMutex
+
+	stop    chan struct{}
+	stopped chan struct{}
+	cancel  func() // closes the current stream
+}
+
+// NewTagger returns a Tagger getting the tags of the core agent at the given
+// cardinality: "low", "orchestrator" or "high".
+func NewTagger(cardinality string) (*Tagger, error) {
+	ipcAddress, err := config.GetIPCAddress()
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://%v:%v/agent/tagger-stream?cardinality=%s", ipcAddress, config.Datadog.GetInt("cmd_port"), cardinality)
+	return newTagger(url, util.GetClient(false)), nil
+}
+
+func newTagger(url string, client *http.Client) *Tagger {
+	return &Tagger{
+		url:     url,
+		client:  client,
+		tags:    make(map[string][]string),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start connects to the core agent tagger stream, and reconnects whenever the
+// stream breaks. It doesn't block.
+func (t *Tagger) Start() error {
+	if err := util.SetAuthToken(); err != nil {
+		return err
+	}
+	go t.run()
+	return nil
+}
+
+// Stop closes the stream
+func (t *Tagger) Stop() {
+	close(t.stop)
+	t.m.Lock()
+	if t.cancel != nil {
+		t.cancel()
+	}
+	t.m.Unlock()
+	<-t.stopped
+}
+
+// Tag returns the tags of an entity. It returns an error until the first
+// state of the core agent tagger is received.
+func (t *Tagger) Tag(entity string) ([]string, error) {
+	t.m.RLock()
+	defer t.m.RUnlock()
+
+	if !t.synced {
+		return nil, fmt.Errorf("not synced with the core agent tagger yet")
+	}
+	tags := t.tags[entity]
+	copied := make([]string, len(tags))
+	copy(copied, tags)
+	return copied, nil
+}
+
+// List returns the tags of every entity
+func (t *Tagger) List() response.TaggerListResponse {
+	t.m.RLock()
+	defer t.m.RUnlock()
+
+	r := response.TaggerListResponse{
+		Entities: make(map[string]response.TaggerListEntity, len(t.tags)),
+	}
+	for entity, tags := range t.tags {
+		copied := make([]string, len(tags))
+		copy(copied, tags)
+		r.Entities[entity] = response.TaggerListEntity{Tags: copied}
+	}
+	return r
+}
+
+func (t *Tagger) run() {
+	defer close(t.stopped)
+
+	delay := minReconnectDelay
+	for {
+		start := time.Now()
+		err := t.stream()
+		select {
+		case <-t.stop:
+			return
+		default:
+		}
+
+		if time.Since(start) > maxReconnectDelay {
+			// the stream was up for a while, reconnect right away
+			delay = minReconnectDelay
+		}
+		log.Warnf("Tagger stream interrupted, reconnecting in %s: %v", delay, err)
+		select {
+		case <-t.stop:
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// stream reads the tagger stream until it breaks
+func (t *Tagger) stream() error {
+	req, err := http.NewRequest("GET", t.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+util.GetAuthToken())
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	t.m.Lock()
+	t.cancel = func() { resp.Body.Close() }
+	t.m.Unlock()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, maxLineSize)
+	first := true
+	for scanner.Scan() {
+		var batch response.TaggerStreamResponse
+		if err := json.Unmarshal(scanner.Bytes(), &batch); err != nil {
+			return fmt.Errorf("invalid batch of events: %s", err)
+		}
+		t.apply(batch.Events, first)
+		first = false
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("stream closed by the core agent")
+}
+
+// apply applies a batch of events. The first batch of a stream holds the whole
+// state of the core agent tagger, and replaces the current one.
+func (t *Tagger) apply(events []response.TaggerStreamEvent, reset bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if reset {
+		t.tags = make(map[string][]string, len(events))
+		t.synced = true
+	}
+	for _, e := range events {
+		switch e.Type {
+		case "added", "modified":
+			t.tags[e.Entity] = e.Tags
+		case "deleted":
+			delete(t.tags, e.Entity)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package remote
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/api/response"
+)
+
+// waitFor polls the condition until it is true, for up to 5 seconds
+func waitFor(t *testing.T, condition func() bool) {
+	for i := 0; i < 500; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.FailNow(t, "condition not met after 5s")
+}
+
+func TestTaggerStream(t *testing.T) {
+	batches := make(chan response.TaggerStreamResponse, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoder := json.NewEncoder(w)
+		for {
+			select {
+			case batch := <-batches:
+				encoder.Encode(batch)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	tagger := newTagger(server.URL, server.Client())
+	_, err := tagger.Tag("entity1")
+	assert.Error(t, err)
+
+	go tagger.run()
+	defer tagger.Stop()
+
+	batches <- response.TaggerStreamResponse{Events: []response.TaggerStreamEvent{
+		{Type: "added", Entity: "entity1", Tags: []string{"a"}},
+		{Type: "added", Entity: "entity2", Tags: []string{"b"}},
+	}}
+	waitFor(t, func() bool {
+		tags, err := tagger.Tag("entity2")
+		return err == nil && len(tags) == 1
+	})
+
+	batches <- response.TaggerStreamResponse{Events: []response.TaggerStreamEvent{
+		{Type: "modified", Entity: "entity1", Tags: []string{"a", "c"}},
+		{Type: "deleted", Entity: "entity2"},
+	}}
+	waitFor(t, func() bool {
+		tags, _ := tagger.Tag("entity2")
+		return len(tags) == 0
+	})
+
+	tags, err := tagger.Tag("entity1")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "c"}, tags)
+}
+
+func TestTaggerApplySnapshot(t *testing.T) {
+	tagger := newTagger("", nil)
+	tagger.apply([]response.TaggerStreamEvent{
+		{Type: "added", Entity: "entity1", Tags: []string{"a"}},
+	}, true)
+	tagger.apply([]response.TaggerStreamEvent{
+		{Type: "added", Entity: "entity2", Tags: []string{"b"}},
+	}, false)
+
+	// A new stream replaces the state, entities deleted while disconnected are dropped
+	tagger.apply([]response.TaggerStreamEvent{
+		{Type: "added", Entity: "entity2", Tags: []string{"b"}},
+	}, true)
+
+	tags, err := tagger.Tag("entity1")
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+	tags, err = tagger.Tag("entity2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, tags)
+	list := tagger.List()
+	assert.Len(t, list.Entities, 1)
+	assert.Equal(t, []string{"b"}, list.Entities["entity2"].Tags)
+}
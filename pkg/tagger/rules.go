@@ -0,0 +1,241 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package tagger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// tagExtractionRuleConfig is a tag extraction rule, as configured in tag_extraction_rules
+type tagExtractionRuleConfig struct {
+	// Kinds restricts the rule to some kinds of metadata: label, annotation or env.
+	// An empty list matches every kind.
+	Kinds []string `mapstructure:"kinds"`
+	// Name is a regular expression which must match the whole name of the metadata.
+	Name string `mapstructure:"name"`
+	// Tag is the name of the extracted tag. It can refer to the capture groups
+	// of Name, e.g. "$1" or "${team}".
+	Tag string `mapstructure:"tag"`
+	// ValuePattern, if set, is a regular expression which must match the whole
+	// value of the metadata.
+	ValuePattern string `mapstructure:"value_pattern"`
+	// Value is the value of the extracted tag. It can refer to the capture groups
+	// of ValuePattern, and defaults to the whole value of the metadata.
+	Value string `mapstructure:"value"`
+	// Transforms are applied to the value in order: lowercase, uppercase or trim_space.
+	Transforms []string `mapstructure:"transforms"`
+	// Cardinality of the extracted tag: low (default), orchestrator or high.
+	Cardinality string `mapstructure:"cardinality"`
+}
+
+type tagExtractionRule struct {
+	kinds        map[string]struct{}
+	name         *regexp.Regexp
+	tag          string
+	valuePattern *regexp.Regexp
+	value        string
+	transforms   []func(string) string
+	cardinality  collectors.TagCardinality
+}
+
+var valueTransforms = map[string]func(string) string{
+	"lowercase":  strings.ToLower,
+	"uppercase":  strings.ToUpper,
+	"trim_space": strings.TrimSpace,
+}
+
+// tagRules extracts tags from the metadata of entities with the tag extraction
+// rules, and drops the tags matching the deny list.
+type tagRules struct {
+	extraction []*tagExtractionRule
+	deny       []*regexp.Regexp
+}
+
+// newTagRules compiles the tag extraction rules and deny list. It returns nil if
+// there are none.
+func newTagRules(ruleConfigs []tagExtractionRuleConfig, denyList []string) (*tagRules, error) {
+	if len(ruleConfigs) == 0 && len(denyList) == 0 {
+		return nil, nil
+	}
+
+	rules := &tagRules{}
+	for i, rc := range ruleConfigs {
+		rule, err := compileTagExtractionRule(rc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag extraction rule #%d: %s", i+1, err)
+		}
+		rules.extraction = append(rules.extraction, rule)
+	}
+	for _, pattern := range denyList {
+		re, err := compileWhole(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag deny list pattern %q: %s", pattern, err)
+		}
+		rules.deny = append(rules.deny, re)
+	}
+	return rules, nil
+}
+
+// loadTagRules loads the tag extraction rules and deny list from the configuration.
+// Invalid configurations are logged and ignored.
+func loadTagRules() *tagRules {
+	var ruleConfigs []tagExtractionRuleConfig
+	if err := config.Datadog.UnmarshalKey("tag_extraction_rules", &ruleConfigs); err != nil {
+		log.Errorf("Invalid tag_extraction_rules, ignoring them: %s", err)
+		ruleConfigs = nil
+	}
+	rules, err := newTagRules(ruleConfigs, config.Datadog.GetStringSlice("tag_deny_list"))
+	if err != nil {
+		log.Errorf("Tag extraction rules are disabled: %s", err)
+		return nil
+	}
+	return rules
+}
+
+func compileTagExtractionRule(rc tagExtractionRuleConfig) (*tagExtractionRule, error) {
+	if rc.Name == "" || rc.Tag == "" {
+		return nil, fmt.Errorf("name and tag are required")
+	}
+	rule := &tagExtractionRule{
+		tag:   rc.Tag,
+		value: rc.Value,
+	}
+
+	var err error
+	if rule.name, err = compileWhole(rc.Name); err != nil {
+		return nil, err
+	}
+	valuePattern := rc.ValuePattern
+	if valuePattern == "" {
+		valuePattern = "(?s).*"
+	}
+	if rule.valuePattern, err = compileWhole(valuePattern); err != nil {
+		return nil, err
+	}
+	if rule.value == "" {
+		rule.value = "$0"
+	}
+
+	if len(rc.Kinds) > 0 {
+		rule.kinds = make(map[string]struct{}, len(rc.Kinds))
+		for _, kind := range rc.Kinds {
+			switch kind {
+			case collectors.MetadataLabel, collectors.MetadataAnnotation, collectors.MetadataEnv:
+				rule.kinds[kind] = struct{}{}
+			default:
+				return nil, fmt.Errorf("unknown kind %q", kind)
+			}
+		}
+	}
+	for _, name := range rc.Transforms {
+		transform, found := valueTransforms[name]
+		if !found {
+			return nil, fmt.Errorf("unknown transform %q", name)
+		}
+		rule.transforms = append(rule.transforms, transform)
+	}
+	if rc.Cardinality != "" {
+		if rule.cardinality, err = StringToTagCardinality(rc.Cardinality); err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
+
+// compileWhole compiles a regular expression which must match whole strings
+func compileWhole(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// extract returns the tag the rule extracts from a metadata, if it matches
+func (r *tagExtractionRule) extract(kind, name, value string) (string, bool) {
+	if r.kinds != nil {
+		if _, found := r.kinds[kind]; !found {
+			return "", false
+		}
+	}
+	nameMatch := r.name.FindStringSubmatchIndex(name)
+	if nameMatch == nil {
+		return "", false
+	}
+	tagName := string(r.name.ExpandString(nil, r.tag, name, nameMatch))
+
+	valueMatch := r.valuePattern.FindStringSubmatchIndex(value)
+	if valueMatch == nil {
+		return "", false
+	}
+	value = string(r.valuePattern.ExpandString(nil, r.value, value, valueMatch))
+	for _, transform := range r.transforms {
+		value = transform(value)
+	}
+
+	if tagName == "" || value == "" {
+		return "", false
+	}
+	return tagName + ":" + value, true
+}
+
+// apply adds the tags extracted from the metadata of an entity to its tags,
+// then drops the denied ones. It doesn't modify the tag slices in place, as
+// collectors may hold on to them.
+func (r *tagRules) apply(info *collectors.TagInfo) {
+	low, orchestrator, high := info.LowCardTags, info.OrchestratorCardTags, info.HighCardTags
+
+	if len(r.extraction) > 0 && len(info.Metadata) > 0 {
+		low, orchestrator, high = copyArray(low), copyArray(orchestrator), copyArray(high)
+		for kind, entries := range info.Metadata {
+			for name, value := range entries {
+				for _, rule := range r.extraction {
+					tag, ok := rule.extract(kind, name, value)
+					if !ok {
+						continue
+					}
+					switch rule.cardinality {
+					case collectors.HighCardinality:
+						high = append(high, tag)
+					case collectors.OrchestratorCardinality:
+						orchestrator = append(orchestrator, tag)
+					default:
+						low = append(low, tag)
+					}
+				}
+			}
+		}
+	}
+
+	info.LowCardTags = r.filter(low)
+	info.OrchestratorCardTags = r.filter(orchestrator)
+	info.HighCardTags = r.filter(high)
+}
+
+// filter returns the tags not matching the deny list
+func (r *tagRules) filter(tags []string) []string {
+	if len(r.deny) == 0 || len(tags) == 0 {
+		return tags
+	}
+	filtered := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !r.denied(tag) {
+			filtered = append(filtered, tag)
+		}
+	}
+	return filtered
+}
+
+func (r *tagRules) denied(tag string) bool {
+	for _, re := range r.deny {
+		if re.MatchString(tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package tagger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+)
+
+func TestTagRulesExtraction(t *testing.T) {
+	for name, tc := range map[string]struct {
+		rules        []tagExtractionRuleConfig
+		metadata     collectors.Metadata
+		low          []string
+		orchestrator []string
+		high         []string
+	}{
+		"capture group in tag name": {
+			rules: []tagExtractionRuleConfig{{Name: `app\.company\.com/(.+)`, Tag: "$1"}},
+			metadata: collectors.Metadata{
+				collectors.MetadataLabel:      {"app.company.com/team": "payments", "other": "value"},
+				collectors.MetadataAnnotation: {"app.company.com/tier": "backend"},
+			},
+			low: []string{"team:payments", "tier:backend"},
+		},
+		"named capture groups": {
+			rules: []tagExtractionRuleConfig{{
+				Name:         `(?P<prefix>[a-z]+)\.version`,
+				Tag:          "${prefix}_major",
+				ValuePattern: `v?(?P<major>\d+)\..*`,
+				Value:        "${major}",
+			}},
+			metadata: collectors.Metadata{
+				collectors.MetadataLabel: {"api.version": "v2.3.1", "db.version": "latest"},
+			},
+			low: []string{"api_major:2"},
+		},
+		"kinds": {
+			rules: []tagExtractionRuleConfig{{Name: "TEAM", Tag: "team", Kinds: []string{"env"}}},
+			metadata: collectors.Metadata{
+				collectors.MetadataLabel: {"TEAM": "label"},
+				collectors.MetadataEnv:   {"TEAM": "env"},
+			},
+			low: []string{"team:env"},
+		},
+		"transforms and cardinality": {
+			rules: []tagExtractionRuleConfig{
+				{Name: "OWNER", Tag: "owner", Transforms: []string{"trim_space", "lowercase"}, Cardinality: "orchestrator"},
+				{Name: "REQUEST_ID", Tag: "request_id", Cardinality: "high"},
+			},
+			metadata: collectors.Metadata{
+				collectors.MetadataEnv: {"OWNER": " Alice ", "REQUEST_ID": "42"},
+			},
+			orchestrator: []string{"owner:alice"},
+			high:         []string{"request_id:42"},
+		},
+		"empty values are skipped": {
+			rules: []tagExtractionRuleConfig{{Name: "team", Tag: "team", Transforms: []string{"trim_space"}}},
+			metadata: collectors.Metadata{
+				collectors.MetadataLabel: {"team": "  "},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rules, err := newTagRules(tc.rules, nil)
+			require.NoError(t, err)
+
+			info := &collectors.TagInfo{Source: "source", Entity: "entity", Metadata: tc.metadata}
+			rules.apply(info)
+			assert.ElementsMatch(t, tc.low, info.LowCardTags)
+			assert.ElementsMatch(t, tc.orchestrator, info.OrchestratorCardTags)
+			assert.ElementsMatch(t, tc.high, info.HighCardTags)
+		})
+	}
+}
+
+func TestTagRulesDenyList(t *testing.T) {
+	rules, err := newTagRules(
+		[]tagExtractionRuleConfig{{Name: "secret", Tag: "secret"}},
+		[]string{"pod_name:.*", "secret:.*"},
+	)
+	require.NoError(t, err)
+
+	low := []string{"kube_namespace:default", "kube_pod_name:foo"}
+	info := &collectors.TagInfo{
+		Source:               "source",
+		Entity:               "entity",
+		LowCardTags:          low,
+		OrchestratorCardTags: []string{"pod_name:foo"},
+		Metadata:             collectors.Metadata{collectors.MetadataLabel: {"secret": "value"}},
+	}
+	rules.apply(info)
+	assert.ElementsMatch(t, []string{"kube_namespace:default", "kube_pod_name:foo"}, info.LowCardTags)
+	assert.Empty(t, info.OrchestratorCardTags)
+	// The tags of the collector are not modified in place
+	assert.Equal(t, []string{"kube_namespace:default", "kube_pod_name:foo"}, low)
+}
+
+func TestNewTagRulesErrors(t *testing.T) {
+	rules, err := newTagRules(nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, rules)
+
+	for name, rule := range map[string]tagExtractionRuleConfig{
+		"missing tag":         {Name: "team"},
+		"invalid name":        {Name: "team(", Tag: "team"},
+		"invalid value":       {Name: "team", Tag: "team", ValuePattern: "[a-"},
+		"unknown kind":        {Name: "team", Tag: "team", Kinds: []string{"attribute"}},
+		"unknown transform":   {Name: "team", Tag: "team", Transforms: []string{"reverse"}},
+		"unknown cardinality": {Name: "team", Tag: "team", Cardinality: "medium"},
+	} {
+		_, err := newTagRules([]tagExtractionRuleConfig{rule}, nil)
+		assert.Error(t, err, name)
+	}
+
+	_, err = newTagRules(nil, []string{"team:("})
+	assert.Error(t, err)
+}
+
+func TestStoreAppliesTagRules(t *testing.T) {
+	store := newTagStore()
+	rules, err := newTagRules([]tagExtractionRuleConfig{{Name: `app\.company\.com/(.+)`, Tag: "$1"}}, []string{"pod_phase:.*"})
+	require.NoError(t, err)
+	store.rules = rules
+
+	store.processTagInfo(&collectors.TagInfo{
+		Source:      "source",
+		Entity:      "entity",
+		LowCardTags: []string{"pod_phase:running", "kube_namespace:default"},
+		Metadata:    collectors.Metadata{collectors.MetadataLabel: {"app.company.com/team": "payments"}},
+	})
+
+	tags, _, _ := store.lookup("entity", collectors.LowCardinality)
+	assert.ElementsMatch(t, []string{"kube_namespace:default", "team:payments"}, tags)
+}
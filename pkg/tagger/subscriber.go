@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package tagger
+
+import (
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// subscriptionBufferSize is the number of event batches a subscriber can lag
+// behind before being unsubscribed
+const subscriptionBufferSize = 100
+
+// EventType is the type of an entity event
+type EventType int
+
+// List of entity event types
+const (
+	EventTypeAdded EventType = iota
+	EventTypeModified
+	EventTypeDeleted
+)
+
+// String returns the name of the event type
+func (t EventType) String() string {
+	switch t {
+	case EventTypeAdded:
+		return "added"
+	case EventTypeModified:
+		return "modified"
+	case EventTypeDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// EntityEvent is sent to subscribers when the tags of an entity change
+type EntityEvent struct {
+	EventType EventType
+	Entity    string
+	Tags      []string // at the cardinality of the subscription, nil for deleted entities
+}
+
+// subscription holds the channel of a subscriber, along with the hash of the
+// tags last sent for every entity, so that only actual changes are sent.
+type subscription struct {
+	cardinality collectors.TagCardinality
+	ch          chan []EntityEvent
+	sent        map[string]string
+}
+
+// subscriber dispatches the changes of the tag store to subscribers.
+type subscriber struct {
+	sync.Mutex
+	subscriptions map[chan []EntityEvent]*subscription
+}
+
+func newSubscriber() *subscriber {
+	return &subscriber{
+		subscriptions: make(map[chan []EntityEvent]*subscription),
+	}
+}
+
+// subscribe registers a subscription, and sends it an added event for every
+// entity of the store.
+func (s *subscriber) subscribe(store *tagStore, cardinality collectors.TagCardinality) chan []EntityEvent {
+	s.Lock()
+	defer s.Unlock()
+
+	sub := &subscription{
+		cardinality: cardinality,
+		ch:          make(chan []EntityEvent, subscriptionBufferSize),
+		sent:        make(map[string]string),
+	}
+
+	store.storeMutex.RLock()
+	events := make([]EntityEvent, 0, len(store.store))
+	for entity, et := range store.store {
+		tags, _, _ := et.get(cardinality)
+		sub.sent[entity] = computeTagsHash(tags)
+		events = append(events, EntityEvent{EventType: EventTypeAdded, Entity: entity, Tags: copyArray(tags)})
+	}
+	store.storeMutex.RUnlock()
+
+	sub.ch <- events
+	s.subscriptions[sub.ch] = sub
+	return sub.ch
+}
+
+// unsubscribe removes a subscription and closes its channel
+func (s *subscriber) unsubscribe(ch chan []EntityEvent) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, found := s.subscriptions[ch]; found {
+		delete(s.subscriptions, ch)
+		close(ch)
+	}
+}
+
+// publishTags sends the tags of an entity to the subscriptions for which they changed.
+// Nothing is sent if the entity was pruned or replaced since its tags were stored:
+// its deletion was published already, or the newer tags are published instead.
+func (s *subscriber) publishTags(store *tagStore, entity string, et *entityTags) {
+	s.Lock()
+	defer s.Unlock()
+
+	store.storeMutex.RLock()
+	current, found := store.store[entity]
+	store.storeMutex.RUnlock()
+	if !found || current != et {
+		return
+	}
+
+	for _, sub := range s.subscriptions {
+		tags, _, _ := et.get(sub.cardinality)
+		hash := computeTagsHash(tags)
+		previous, found := sub.sent[entity]
+		if found && previous == hash {
+			continue
+		}
+		eventType := EventTypeModified
+		if !found {
+			eventType = EventTypeAdded
+		}
+		sub.sent[entity] = hash
+		s.send(sub, []EntityEvent{{EventType: eventType, Entity: entity, Tags: copyArray(tags)}})
+	}
+}
+
+// publishDeletes sends deleted events to the subscriptions knowing the entities.
+func (s *subscriber) publishDeletes(entities []string) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, sub := range s.subscriptions {
+		var events []EntityEvent
+		for _, entity := range entities {
+			if _, found := sub.sent[entity]; !found {
+				continue
+			}
+			delete(sub.sent, entity)
+			events = append(events, EntityEvent{EventType: EventTypeDeleted, Entity: entity})
+		}
+		if len(events) > 0 {
+			s.send(sub, events)
+		}
+	}
+}
+
+// send sends events to a subscription without blocking. Subscribers lagging too
+// far behind are unsubscribed, they have to subscribe again to get a fresh state.
+// Must be called with the lock held.
+func (s *subscriber) send(sub *subscription, events []EntityEvent) {
+	select {
+	case sub.ch <- events:
+	default:
+		log.Warnf("Tagger subscriber is lagging behind, unsubscribing it")
+		delete(s.subscriptions, sub.ch)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,164 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package tagger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+)
+
+func receive(t *testing.T, ch chan []EntityEvent) []EntityEvent {
+	select {
+	case events, ok := <-ch:
+		require.True(t, ok, "subscription channel closed")
+		return events
+	default:
+		require.FailNow(t, "no events received")
+	}
+	return nil
+}
+
+func assertNoEvents(t *testing.T, ch chan []EntityEvent) {
+	select {
+	case events := <-ch:
+		assert.FailNow(t, "unexpected events", "%v", events)
+	default:
+	}
+}
+
+func TestSubscribeSnapshot(t *testing.T) {
+	store := newTagStore()
+	store.processTagInfo(&collectors.TagInfo{
+		Source:       "source",
+		Entity:       "entity",
+		LowCardTags:  []string{"low"},
+		HighCardTags: []string{"high"},
+	})
+
+	low := store.subscriber.subscribe(store, collectors.LowCardinality)
+	high := store.subscriber.subscribe(store, collectors.HighCardinality)
+
+	events := receive(t, low)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventTypeAdded, events[0].EventType)
+	assert.Equal(t, "entity", events[0].Entity)
+	assert.ElementsMatch(t, []string{"low"}, events[0].Tags)
+
+	events = receive(t, high)
+	require.Len(t, events, 1)
+	assert.ElementsMatch(t, []string{"low", "high"}, events[0].Tags)
+}
+
+func TestSubscribeChanges(t *testing.T) {
+	store := newTagStore()
+	ch := store.subscriber.subscribe(store, collectors.LowCardinality)
+	assert.Len(t, receive(t, ch), 0)
+
+	store.processTagInfo(&collectors.TagInfo{
+		Source:      "source",
+		Entity:      "entity",
+		LowCardTags: []string{"low"},
+	})
+	events := receive(t, ch)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventTypeAdded, events[0].EventType)
+	assert.ElementsMatch(t, []string{"low"}, events[0].Tags)
+
+	// High cardinality changes are not sent to low cardinality subscribers
+	store.processTagInfo(&collectors.TagInfo{
+		Source:       "source",
+		Entity:       "entity",
+		LowCardTags:  []string{"low"},
+		HighCardTags: []string{"high"},
+	})
+	assertNoEvents(t, ch)
+
+	store.processTagInfo(&collectors.TagInfo{
+		Source:      "source",
+		Entity:      "entity",
+		LowCardTags: []string{"low", "other"},
+	})
+	events = receive(t, ch)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventTypeModified, events[0].EventType)
+	assert.ElementsMatch(t, []string{"low", "other"}, events[0].Tags)
+
+	store.processTagInfo(&collectors.TagInfo{
+		Source:       "source",
+		Entity:       "entity",
+		DeleteEntity: true,
+	})
+	assertNoEvents(t, ch)
+	store.prune()
+	events = receive(t, ch)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventTypeDeleted, events[0].EventType)
+	assert.Equal(t, "entity", events[0].Entity)
+	assert.Nil(t, events[0].Tags)
+}
+
+func TestPublishPrunedEntity(t *testing.T) {
+	store := newTagStore()
+	store.processTagInfo(&collectors.TagInfo{
+		Source:      "source",
+		Entity:      "entity",
+		LowCardTags: []string{"low"},
+	})
+	ch := store.subscriber.subscribe(store, collectors.LowCardinality)
+	assert.Len(t, receive(t, ch), 1)
+
+	// tags stored right before the entity is pruned are published late
+	et := store.store["entity"]
+	store.processTagInfo(&collectors.TagInfo{
+		Source:       "source",
+		Entity:       "entity",
+		DeleteEntity: true,
+	})
+	store.prune()
+	assert.Equal(t, EventTypeDeleted, receive(t, ch)[0].EventType)
+
+	et.lowCardTags["source"] = []string{"low", "other"}
+	et.cacheValid = false
+	store.subscriber.publishTags(store, "entity", et)
+	assertNoEvents(t, ch)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	store := newTagStore()
+	ch := store.subscriber.subscribe(store, collectors.LowCardinality)
+	store.subscriber.unsubscribe(ch)
+
+	<-ch
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	// Unsubscribing twice is a no-op
+	store.subscriber.unsubscribe(ch)
+}
+
+func TestLaggingSubscriber(t *testing.T) {
+	store := newTagStore()
+	ch := store.subscriber.subscribe(store, collectors.LowCardinality)
+
+	for i := 0; i < subscriptionBufferSize; i++ {
+		store.processTagInfo(&collectors.TagInfo{
+			Source:      "source",
+			Entity:      "entity",
+			LowCardTags: []string{string(rune('a' + i%26)), string(rune('a' + i/26))},
+		})
+	}
+
+	received := 0
+	for range ch {
+		received++
+	}
+	assert.Equal(t, subscriptionBufferSize, received)
+	assert.Len(t, store.subscriber.subscriptions, 0)
+}
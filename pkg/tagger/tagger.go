@@ -71,6 +71,7 @@ func (t *Tagger) Init(catalog collectors.Catalog) {
 
 	// Only register the health check when the tagger is started
 	t.health = health.Register("tagger")
+	t.tagStore.rules = loadTagRules()
 
 	// Populate collector candidate list from catalog
 	// as we'll remove entries we need to copy the map
@@ -244,7 +245,14 @@ IterCollectors:
 			}
 		}
 		log.Debugf("cache miss for %s, collecting tags for %s", name, entity)
-		low, orch, high, err := collector.Fetch(entity)
+		var low, orch, high []string
+		var metadata collectors.Metadata
+		var err error
+		if mf, ok := collector.(collectors.MetadataFetcher); ok && t.tagStore.rules != nil {
+			low, orch, high, metadata, err = mf.FetchWithMetadata(entity)
+		} else {
+			low, orch, high, err = collector.Fetch(entity)
+		}
 		cacheMiss := false
 		switch {
 		case errors.IsNotFound(err):
@@ -254,22 +262,25 @@ IterCollectors:
 			log.Warnf("error collecting from %s: %s", name, err)
 			continue // don't store empty tags, retry next time
 		}
-		tagArrays = append(tagArrays, low)
-		if cardinality == collectors.OrchestratorCardinality {
-			tagArrays = append(tagArrays, orch)
-		} else if cardinality == collectors.HighCardinality {
-			tagArrays = append(tagArrays, orch)
-			tagArrays = append(tagArrays, high)
-		}
-		// Submit to cache for next lookup
-		t.tagStore.processTagInfo(&collectors.TagInfo{
+		// Submit to cache for next lookup, the store applies the tag rules to the info
+		info := &collectors.TagInfo{
 			Entity:               entity,
 			Source:               name,
 			LowCardTags:          low,
 			OrchestratorCardTags: orch,
 			HighCardTags:         high,
 			CacheMiss:            cacheMiss,
-		})
+			Metadata:             metadata,
+		}
+		t.tagStore.processTagInfo(info)
+
+		tagArrays = append(tagArrays, info.LowCardTags)
+		if cardinality == collectors.OrchestratorCardinality {
+			tagArrays = append(tagArrays, info.OrchestratorCardTags)
+		} else if cardinality == collectors.HighCardinality {
+			tagArrays = append(tagArrays, info.OrchestratorCardTags)
+			tagArrays = append(tagArrays, info.HighCardTags)
+		}
 	}
 	t.RUnlock()
 
@@ -297,6 +308,19 @@ func (t *Tagger) List(cardinality collectors.TagCardinality) response.TaggerList
 	return r
 }
 
+// Subscribe returns a channel receiving the changes of the entity tags at the
+// given cardinality. The first batch of events holds an added event for every
+// entity already known. Subscribers must read events promptly: the channel of
+// those lagging too far behind is closed.
+func (t *Tagger) Subscribe(cardinality collectors.TagCardinality) chan []EntityEvent {
+	return t.tagStore.subscriber.subscribe(t.tagStore, cardinality)
+}
+
+// Unsubscribe ends a subscription and closes its channel
+func (t *Tagger) Unsubscribe(ch chan []EntityEvent) {
+	t.tagStore.subscriber.unsubscribe(ch)
+}
+
 // copyArray makes sure the tagger does not return internal slices
 // that could be modified by others, by explicitly copying the slice
 // contents to a new slice. As strings are references, the size of
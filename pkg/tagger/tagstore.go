@@ -34,12 +34,15 @@ type tagStore struct {
 	store         map[string]*entityTags
 	toDeleteMutex sync.RWMutex
 	toDelete      map[string]struct{} // set emulation
+	subscriber    *subscriber
+	rules         *tagRules // nil when no tag extraction rules nor deny list are configured
 }
 
 func newTagStore() *tagStore {
 	return &tagStore{
-		store:    make(map[string]*entityTags),
-		toDelete: make(map[string]struct{}),
+		store:      make(map[string]*entityTags),
+		toDelete:   make(map[string]struct{}),
+		subscriber: newSubscriber(),
 	}
 }
 
@@ -59,10 +62,12 @@ func (s *tagStore) processTagInfo(info *collectors.TagInfo) error {
 		s.toDeleteMutex.Unlock()
 		return nil
 	}
+	if s.rules != nil {
+		s.rules.apply(info)
+	}
 
 	// TODO: check if real change
 	s.storeMutex.Lock()
-	defer s.storeMutex.Unlock()
 	storedTags, exist := s.store[info.Entity]
 	if !exist {
 		storedTags = &entityTags{
@@ -74,9 +79,10 @@ func (s *tagStore) processTagInfo(info *collectors.TagInfo) error {
 	}
 
 	storedTags.Lock()
-	defer storedTags.Unlock()
 	_, found := storedTags.lowCardTags[info.Source]
 	if found && info.CacheMiss {
+		storedTags.Unlock()
+		s.storeMutex.Unlock()
 		// check if the source tags is already present for this entry
 		// Only check once since we always write all cardinality tag levels.
 		err := fmt.Errorf("try to overwrite an existing entry with and empty cache-miss entry, info.Source: %s, info.Entity: %s", info.Source, info.Entity)
@@ -87,7 +93,10 @@ func (s *tagStore) processTagInfo(info *collectors.TagInfo) error {
 	storedTags.orchestratorCardTags[info.Source] = info.OrchestratorCardTags
 	storedTags.highCardTags[info.Source] = info.HighCardTags
 	storedTags.cacheValid = false
+	storedTags.Unlock()
+	s.storeMutex.Unlock()
 
+	s.subscriber.publishTags(s, info.Entity, storedTags)
 	return nil
 }
 
@@ -117,16 +126,19 @@ func (s *tagStore) prune() error {
 	}
 
 	s.storeMutex.Lock()
-	defer s.storeMutex.Unlock()
+	deleted := make([]string, 0, len(s.toDelete))
 	for entity := range s.toDelete {
 		delete(s.store, entity)
+		deleted = append(deleted, entity)
 	}
 
 	log.Debugf("pruned %d removed entities, %d remaining", len(s.toDelete), len(s.store))
 
 	// Start fresh
 	s.toDelete = make(map[string]struct{})
+	s.storeMutex.Unlock()
 
+	s.subscriber.publishDeletes(deleted)
 	return nil
 }
 
@@ -16,6 +16,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/event"
 	"github.com/DataDog/datadog-agent/pkg/trace/filters"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/inspect"
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
 	"github.com/DataDog/datadog-agent/pkg/trace/obfuscate"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
@@ -36,6 +37,7 @@ type Agent struct {
 	Concentrator       *stats.Concentrator
 	Blacklister        *filters.Blacklister
 	Replacer           *filters.Replacer
+	SpanRules          *filters.SpanRules
 	ScoreSampler       *Sampler
 	ErrorsScoreSampler *Sampler
 	PrioritySampler    *Sampler
@@ -67,9 +69,10 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 
 	return &Agent{
 		Receiver:           api.NewHTTPReceiver(conf, dynConf, in),
-		Concentrator:       stats.NewConcentrator(conf.ExtraAggregators, conf.BucketInterval.Nanoseconds(), statsChan),
+		Concentrator:       stats.NewConcentrator(conf.ExtraAggregators, conf.AggregatorCardinality, conf.BucketInterval.Nanoseconds(), statsChan),
 		Blacklister:        filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:           filters.NewReplacer(conf.ReplaceTags),
+		SpanRules:          filters.NewSpanRules(conf.SpanRules),
 		ScoreSampler:       NewScoreSampler(conf),
 		ErrorsScoreSampler: NewErrorsSampler(conf),
 		PrioritySampler:    NewPrioritySampler(conf, dynConf),
@@ -150,6 +153,11 @@ func (a *Agent) Process(t *api.Trace) {
 
 	defer timing.Since("datadog.trace_agent.internal.process_trace_ms", time.Now())
 
+	var entry *inspect.Entry
+	if a.Receiver.Inspector.Enabled() {
+		entry = inspect.NewEntry(t.Source, t.ContainerTags, t.Spans)
+	}
+
 	// Root span is used to carry some trace-level metadata, such as sampling rate and priority.
 	root := traceutil.GetRoot(t.Spans)
 
@@ -178,6 +186,7 @@ func (a *Agent) Process(t *api.Trace) {
 		log.Debugf("Trace rejected by blacklister. root: %v", root)
 		atomic.AddInt64(&ts.TracesFiltered, 1)
 		atomic.AddInt64(&ts.SpansFiltered, int64(len(t.Spans)))
+		a.Receiver.Inspector.Record(entry, inspect.Decision{Reason: inspect.ReasonFiltered}, nil)
 		return
 	}
 
@@ -188,6 +197,18 @@ func (a *Agent) Process(t *api.Trace) {
 	}
 	a.Replacer.Replace(t.Spans)
 
+	n := len(t.Spans)
+	spans, keep := a.SpanRules.Apply(t.Spans)
+	if !keep {
+		log.Debugf("Trace rejected by span rules. root: %v", root)
+		atomic.AddInt64(&ts.TracesFiltered, 1)
+		atomic.AddInt64(&ts.SpansFiltered, int64(n))
+		a.Receiver.Inspector.Record(entry, inspect.Decision{Reason: inspect.ReasonSpanRule}, nil)
+		return
+	}
+	atomic.AddInt64(&ts.SpansFiltered, int64(n-len(spans)))
+	t.Spans = spans
+
 	{
 		// this section sets up any necessary tags on the root:
 		clientSampleRate := sampler.GetGlobalRate(root)
@@ -227,9 +248,11 @@ func (a *Agent) Process(t *api.Trace) {
 		pt.Env = tenv
 	}
 
+	decision := inspect.Decision{Reason: inspect.ReasonPriorityReject}
 	if priority >= 0 {
-		a.sample(ts, pt)
+		decision = a.sample(ts, pt)
 	}
+	a.Receiver.Inspector.Record(entry, decision, pt.Trace)
 
 	a.Concentrator.In <- &stats.Input{
 		Trace:     pt.WeightedTrace,
@@ -239,11 +262,11 @@ func (a *Agent) Process(t *api.Trace) {
 }
 
 // sample decides whether the trace will be kept and extracts any APM events
-// from it.
-func (a *Agent) sample(ts *info.TagStats, pt ProcessedTrace) {
+// from it. It returns the sampling decision.
+func (a *Agent) sample(ts *info.TagStats, pt ProcessedTrace) inspect.Decision {
 	var ss writer.SampledSpans
 
-	sampled, rate := a.runSamplers(pt)
+	sampled, rate, reason := a.runSamplers(pt)
 	if sampled {
 		sampler.AddGlobalRate(pt.Root, rate)
 		ss.Trace = pt.Trace
@@ -258,11 +281,12 @@ func (a *Agent) sample(ts *info.TagStats, pt ProcessedTrace) {
 	if !ss.Empty() {
 		a.Out <- &ss
 	}
+	return inspect.Decision{Kept: sampled, Reason: reason, Rate: rate}
 }
 
 // runSamplers runs all the agent's samplers on pt and returns the sampling decision
-// along with the sampling rate.
-func (a *Agent) runSamplers(pt ProcessedTrace) (sampled bool, rate float64) {
+// along with the sampling rate and the reason for the decision.
+func (a *Agent) runSamplers(pt ProcessedTrace) (sampled bool, rate float64, reason string) {
 	var sampledPriority, sampledScore bool
 	var ratePriority, rateScore float64
 
@@ -270,13 +294,23 @@ func (a *Agent) runSamplers(pt ProcessedTrace) (sampled bool, rate float64) {
 		sampledPriority, ratePriority = a.PrioritySampler.Add(pt)
 	}
 
+	scoreReason := inspect.ReasonScore
 	if traceContainsError(pt.Trace) {
 		sampledScore, rateScore = a.ErrorsScoreSampler.Add(pt)
+		scoreReason = inspect.ReasonErrors
 	} else {
 		sampledScore, rateScore = a.ScoreSampler.Add(pt)
 	}
 
-	return sampledScore || sampledPriority, sampler.CombineRates(ratePriority, rateScore)
+	switch {
+	case sampledPriority:
+		reason = inspect.ReasonPriority
+	case sampledScore:
+		reason = scoreReason
+	default:
+		reason = inspect.ReasonNotSampled
+	}
+	return sampledScore || sampledPriority, sampler.CombineRates(ratePriority, rateScore), reason
 }
 
 func traceContainsError(trace pb.Trace) bool {
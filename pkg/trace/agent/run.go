@@ -7,6 +7,7 @@ package agent
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"math/rand"
 	"os"
@@ -20,6 +21,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/flags"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/inspect"
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
 	"github.com/DataDog/datadog-agent/pkg/trace/osutil"
@@ -54,6 +56,13 @@ func Run(ctx context.Context) {
 		return
 	}
 
+	if flag.Arg(0) == "inspect" {
+		if err := inspect.Print(os.Stdout, cfg, flag.Args()[1:]); err != nil {
+			osutil.Exitf("failed to inspect traces: %s", err)
+		}
+		return
+	}
+
 	if err := coreconfig.SetupLogger(
 		coreconfig.LoggerName("TRACE"),
 		cfg.LogLevel,
@@ -111,7 +120,7 @@ func Run(ctx context.Context) {
 
 	rand.Seed(time.Now().UTC().UnixNano())
 
-	tagger.Init()
+	tagger.InitRemote()
 	defer tagger.Stop()
 
 	agnt := NewAgent(ctx, cfg)
@@ -53,11 +53,25 @@ func NewErrorsSampler(conf *config.AgentConfig) *Sampler {
 // NewPrioritySampler creates a new empty distributed sampler ready to be started
 func NewPrioritySampler(conf *config.AgentConfig, dynConf *sampler.DynamicConfig) *Sampler {
 	return &Sampler{
-		engine: sampler.NewPriorityEngine(conf.ExtraSampleRate, conf.MaxTPS, &dynConf.RateByService),
+		engine: sampler.NewPriorityEngine(conf.ExtraSampleRate, conf.MaxTPS, tpsRules(conf.TargetTPS), &dynConf.RateByService),
 		exit:   make(chan struct{}),
 	}
 }
 
+// tpsRules converts the configured target TPS into priority sampler rules.
+func tpsRules(targets []*config.TargetTPS) []sampler.TPSRule {
+	rules := make([]sampler.TPSRule, 0, len(targets))
+	for _, t := range targets {
+		rules = append(rules, sampler.TPSRule{
+			Service:   t.Service,
+			Env:       t.Env,
+			Resource:  t.Resource,
+			TargetTPS: t.TPS,
+		})
+	}
+	return rules
+}
+
 // Start starts sampling traces
 func (s *Sampler) Start() {
 	go func() {
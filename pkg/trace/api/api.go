@@ -33,6 +33,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/inspect"
 	"github.com/DataDog/datadog-agent/pkg/trace/logutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
@@ -75,6 +76,8 @@ const (
 type HTTPReceiver struct {
 	Stats       *info.ReceiverStats
 	RateLimiter *rateLimiter
+	// Inspector records recent traces for debugging. It is nil when inspection is disabled.
+	Inspector *inspect.Recorder
 
 	out     chan *Trace
 	conf    *config.AgentConfig
@@ -98,6 +101,7 @@ func NewHTTPReceiver(conf *config.AgentConfig, dynConf *sampler.DynamicConfig, o
 	return &HTTPReceiver{
 		Stats:       info.NewReceiverStats(),
 		RateLimiter: newRateLimiter(),
+		Inspector:   inspect.NewRecorder(conf.InspectMaxTraces),
 		out:         out,
 
 		conf:    conf,
@@ -202,6 +206,7 @@ func (r *HTTPReceiver) attachDebugHandlers(mux *http.ServeMux) {
 	})
 
 	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle(inspect.Path, r.Inspector)
 }
 
 // listenUnix returns a net.Listener listening on the given "unix" socket path.
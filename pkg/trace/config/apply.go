@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -22,6 +23,9 @@ import (
 // apiEndpointPrefix is the URL prefix prepended to the default site value from YamlAgentConfig.
 const apiEndpointPrefix = "https://trace.agent."
 
+// defaultInspectMaxTraces is the number of traces kept for inspection when it is enabled.
+const defaultInspectMaxTraces = 100
+
 // ObfuscationConfig holds the configuration for obfuscating sensitive data
 // for various span types.
 type ObfuscationConfig struct {
@@ -90,6 +94,81 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// StatsTag specifies a span tag to be used as an extra aggregation dimension
+// when computing trace stats.
+type StatsTag struct {
+	// Name specifies the span tag to aggregate on, such as "peer.service". The
+	// special name "http.status_class" groups "http.status_code" values by class.
+	Name string `mapstructure:"name"`
+
+	// MaxCardinality specifies the maximum number of distinct values kept for this
+	// tag within a stats bucket. Zero means no limit.
+	MaxCardinality int `mapstructure:"max_cardinality"`
+}
+
+// TargetTPS sets the number of traces per second which the priority sampler keeps for
+// a service, optionally restricted to an env and a resource.
+type TargetTPS struct {
+	// Service and Env select the traces whose root span has this service and env.
+	// An empty env matches any env.
+	Service string `mapstructure:"service"`
+	Env     string `mapstructure:"env"`
+
+	// Resource, if not empty, further restricts the rule to traces whose root span has
+	// this resource. Such rules are enforced by the agent instead of the tracers.
+	Resource string `mapstructure:"resource"`
+
+	// TPS is the target number of traces per second.
+	TPS float64 `mapstructure:"target_tps"`
+}
+
+// Span rule actions.
+const (
+	// SpanRuleDropSpan removes the matching spans from their trace. Their children are
+	// attached to their parent. Matching a root span drops the whole trace.
+	SpanRuleDropSpan = "drop_span"
+	// SpanRuleDropTrace drops the whole trace when any of its spans matches.
+	SpanRuleDropTrace = "drop_trace"
+	// SpanRuleModify sets or removes tags, or renames the service of matching spans.
+	SpanRuleModify = "modify"
+)
+
+// SpanRule specifies a rule matching spans on any combination of their service, name,
+// resource, tags and duration, along with the action to take on the matching spans.
+// All specified conditions must match.
+type SpanRule struct {
+	// Service, Name and Resource are regular expressions which, if not empty, must
+	// match the span's service, name and resource respectively.
+	Service  string `mapstructure:"service"`
+	Name     string `mapstructure:"name"`
+	Resource string `mapstructure:"resource"`
+
+	// Tags maps tag names to regular expressions which their values must match.
+	// An empty expression matches any span having the tag.
+	Tags map[string]string `mapstructure:"tags"`
+
+	// MinDuration and MaxDuration, if set, bound the span's duration. They are
+	// expressed as Go durations, e.g. "150ms".
+	MinDuration string `mapstructure:"min_duration"`
+	MaxDuration string `mapstructure:"max_duration"`
+
+	// Action is one of SpanRuleDropSpan, SpanRuleDropTrace or SpanRuleModify.
+	Action string `mapstructure:"action"`
+
+	// SetTags, RemoveTags and RenameService are used by the SpanRuleModify action.
+	SetTags       map[string]string `mapstructure:"set_tags"`
+	RemoveTags    []string          `mapstructure:"remove_tags"`
+	RenameService string            `mapstructure:"rename_service"`
+
+	// The compiled conditions, only used internally.
+	ServiceRe  *regexp.Regexp            `mapstructure:"-"`
+	NameRe     *regexp.Regexp            `mapstructure:"-"`
+	ResourceRe *regexp.Regexp            `mapstructure:"-"`
+	TagsRe     map[string]*regexp.Regexp `mapstructure:"-"`
+	MinDur     time.Duration             `mapstructure:"-"`
+	MaxDur     time.Duration             `mapstructure:"-"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -105,6 +184,24 @@ type WriterConfig struct {
 	FlushPeriodSeconds float64 `mapstructure:"flush_period_seconds"`
 }
 
+// SpoolConfig specifies the configuration of the on-disk spool used by the writers to
+// persist payloads which can not be sent while the intake is unreachable.
+type SpoolConfig struct {
+	// Enabled specifies whether payloads are spooled to disk instead of being dropped.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Dir specifies the directory where payloads are spooled. It defaults to the
+	// "trace-spool" directory inside the agent's run path.
+	Dir string `mapstructure:"dir"`
+
+	// MaxSizeMB specifies the maximum disk space used by each writer (traces and
+	// stats) and endpoint, in megabytes. The oldest payloads are discarded first.
+	MaxSizeMB int `mapstructure:"max_size_mb"`
+
+	// MaxAgeSeconds specifies the age after which spooled payloads are discarded.
+	MaxAgeSeconds int `mapstructure:"max_age_seconds"`
+}
+
 func (c *AgentConfig) applyDatadogConfig() error {
 	if len(c.Endpoints) == 0 {
 		c.Endpoints = []*Endpoint{{}}
@@ -210,6 +307,48 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		}
 	}
 
+	if config.Datadog.IsSet("apm_config.stats_tags") {
+		var tags []*StatsTag
+		if err := config.Datadog.UnmarshalKey("apm_config.stats_tags", &tags); err != nil {
+			return err
+		}
+		for _, t := range tags {
+			if t.Name == "" {
+				log.Errorf("'stats_tags' entries must have a name")
+				continue
+			}
+			c.addAggregator(t.Name)
+			if t.MaxCardinality > 0 {
+				c.AggregatorCardinality[t.Name] = t.MaxCardinality
+			}
+		}
+	}
+
+	if config.Datadog.IsSet("apm_config.target_traces_per_second") {
+		var targets []*TargetTPS
+		if err := config.Datadog.UnmarshalKey("apm_config.target_traces_per_second", &targets); err != nil {
+			return err
+		}
+		for _, t := range targets {
+			if t.Service == "" || t.TPS <= 0 {
+				log.Errorf("'target_traces_per_second' entries must have a service and a positive target_tps")
+				continue
+			}
+			c.TargetTPS = append(c.TargetTPS, t)
+		}
+	}
+
+	if config.Datadog.IsSet("apm_config.span_rules") {
+		var rules []*SpanRule
+		if err := config.Datadog.UnmarshalKey("apm_config.span_rules", &rules); err != nil {
+			return err
+		}
+		if err := compileSpanRules(rules); err != nil {
+			osutil.Exitf("span_rules: %s", err)
+		}
+		c.SpanRules = rules
+	}
+
 	if config.Datadog.IsSet("bind_host") {
 		host := config.Datadog.GetString("bind_host")
 		c.StatsdHost = host
@@ -250,6 +389,15 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		}
 	}
 
+	if config.Datadog.IsSet("apm_config.spool") {
+		if err := config.Datadog.UnmarshalKey("apm_config.spool", c.Spool); err != nil {
+			log.Errorf("Error reading spool config: %v", err)
+		}
+	}
+	if c.Spool.Dir == "" {
+		c.Spool.Dir = filepath.Join(config.Datadog.GetString("run_path"), "trace-spool")
+	}
+
 	// undocumented deprecated
 	if config.Datadog.IsSet("apm_config.analyzed_rate_by_service") {
 		rateByService := make(map[string]float64)
@@ -281,6 +429,13 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		}
 	}
 
+	if config.Datadog.GetBool("apm_config.inspect.enabled") {
+		c.InspectMaxTraces = defaultInspectMaxTraces
+		if config.Datadog.IsSet("apm_config.inspect.max_traces") {
+			c.InspectMaxTraces = config.Datadog.GetInt("apm_config.inspect.max_traces")
+		}
+	}
+
 	// undocumented
 	if config.Datadog.IsSet("apm_config.dd_agent_bin") {
 		c.DDAgentBin = config.Datadog.GetString("apm_config.dd_agent_bin")
@@ -315,7 +470,9 @@ func (c *AgentConfig) loadDeprecatedValues() error {
 		if err != nil {
 			return err
 		}
-		c.ExtraAggregators = append(c.ExtraAggregators, aggs...)
+		for _, agg := range aggs {
+			c.addAggregator(agg)
+		}
 	}
 	if cfg.IsSet("apm_config.log_throttling") {
 		c.LogThrottling = cfg.GetBool("apm_config.log_throttling")
@@ -334,6 +491,16 @@ func (c *AgentConfig) loadDeprecatedValues() error {
 	return nil
 }
 
+// addAggregator adds the given tag to the list of extra aggregators, unless it is already present.
+func (c *AgentConfig) addAggregator(tag string) {
+	for _, agg := range c.ExtraAggregators {
+		if agg == tag {
+			return
+		}
+	}
+	c.ExtraAggregators = append(c.ExtraAggregators, tag)
+}
+
 // addReplaceRule adds the specified replace rule to the agent configuration. If the pattern fails
 // to compile as valid regexp, it exits the application with status code 1.
 func (c *AgentConfig) addReplaceRule(tag, pattern, repl string) {
@@ -368,6 +535,60 @@ func compileReplaceRules(rules []*ReplaceRule) error {
 	return nil
 }
 
+// compileSpanRules validates the span rules and compiles their conditions.
+// If it fails it returns the first error.
+func compileSpanRules(rules []*SpanRule) error {
+	compile := func(expr string) (*regexp.Regexp, error) {
+		if expr == "" {
+			return nil, nil
+		}
+		return regexp.Compile(expr)
+	}
+	var err error
+	for i, r := range rules {
+		if r.Service == "" && r.Name == "" && r.Resource == "" && len(r.Tags) == 0 &&
+			r.MinDuration == "" && r.MaxDuration == "" {
+			// a rule without conditions would match every span
+			return fmt.Errorf("rule %d: needs at least one of service, name, resource, tags, min_duration or max_duration", i)
+		}
+		switch r.Action {
+		case SpanRuleDropSpan, SpanRuleDropTrace:
+		case SpanRuleModify:
+			if len(r.SetTags) == 0 && len(r.RemoveTags) == 0 && r.RenameService == "" {
+				return fmt.Errorf("rule %d: %q needs one of set_tags, remove_tags or rename_service", i, r.Action)
+			}
+		default:
+			return fmt.Errorf("rule %d: unknown action %q", i, r.Action)
+		}
+		if r.ServiceRe, err = compile(r.Service); err != nil {
+			return fmt.Errorf("rule %d: service: %s", i, err)
+		}
+		if r.NameRe, err = compile(r.Name); err != nil {
+			return fmt.Errorf("rule %d: name: %s", i, err)
+		}
+		if r.ResourceRe, err = compile(r.Resource); err != nil {
+			return fmt.Errorf("rule %d: resource: %s", i, err)
+		}
+		r.TagsRe = make(map[string]*regexp.Regexp, len(r.Tags))
+		for k, expr := range r.Tags {
+			if r.TagsRe[k], err = compile(expr); err != nil {
+				return fmt.Errorf("rule %d: tag %q: %s", i, k, err)
+			}
+		}
+		if r.MinDuration != "" {
+			if r.MinDur, err = time.ParseDuration(r.MinDuration); err != nil {
+				return fmt.Errorf("rule %d: min_duration: %s", i, err)
+			}
+		}
+		if r.MaxDuration != "" {
+			if r.MaxDur, err = time.ParseDuration(r.MaxDuration); err != nil {
+				return fmt.Errorf("rule %d: max_duration: %s", i, err)
+			}
+		}
+	}
+	return nil
+}
+
 // getDuration returns the duration of the provided value in seconds
 func getDuration(seconds int) time.Duration {
 	return time.Duration(seconds) * time.Second
@@ -7,6 +7,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -27,3 +28,25 @@ func TestParseRepaceRules(t *testing.T) {
 		assert.Equal(r.Pattern, r.Re.String())
 	}
 }
+
+// TestParseSpanRules tests the compileSpanRules helper function.
+func TestParseSpanRules(t *testing.T) {
+	assert := assert.New(t)
+	rules := []*SpanRule{
+		{Name: "^cache\\.", Tags: map[string]string{"cache.hit": ""}, MaxDuration: "1ms", Action: SpanRuleDropSpan},
+		{Service: "^legacy-db$", Action: SpanRuleModify, RenameService: "postgres"},
+	}
+	assert.NoError(compileSpanRules(rules))
+	assert.Equal("^cache\\.", rules[0].NameRe.String())
+	assert.Equal(time.Millisecond, rules[0].MaxDur)
+
+	for name, rule := range map[string]*SpanRule{
+		"no conditions":  {Action: SpanRuleDropTrace},
+		"unknown action": {Name: "a", Action: "drop"},
+		"no change":      {Name: "a", Action: SpanRuleModify},
+		"bad regexp":     {Name: "(", Action: SpanRuleDropSpan},
+		"bad duration":   {Name: "a", MinDuration: "1", Action: SpanRuleDropSpan},
+	} {
+		assert.Error(compileSpanRules([]*SpanRule{rule}), name)
+	}
+}
@@ -64,11 +64,17 @@ type AgentConfig struct {
 	// Concentrator
 	BucketInterval   time.Duration // the size of our pre-aggregation per bucket
 	ExtraAggregators []string
+	// AggregatorCardinality maps extra aggregators to the maximum number of distinct
+	// values kept for them within a stats bucket.
+	AggregatorCardinality map[string]int
 
 	// Sampler configuration
 	ExtraSampleRate float64
 	MaxTPS          float64
 	MaxEPS          float64
+	// TargetTPS overrides MaxTPS for the services and resources it lists, so that
+	// high-throughput services do not starve the others.
+	TargetTPS []*TargetTPS
 
 	// Receiver
 	ReceiverHost    string
@@ -80,6 +86,7 @@ type AgentConfig struct {
 	// Writers
 	StatsWriter *WriterConfig
 	TraceWriter *WriterConfig
+	Spool       *SpoolConfig
 
 	// internal telemetry
 	StatsdHost string
@@ -106,6 +113,9 @@ type AgentConfig struct {
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
 
+	// SpanRules is a list of rules dropping or modifying spans before sampling.
+	SpanRules []*SpanRule
+
 	// transaction analytics
 	AnalyzedRateByServiceLegacy map[string]float64
 	AnalyzedSpansByService      map[string]map[string]float64
@@ -113,6 +123,10 @@ type AgentConfig struct {
 	// infrastructure agent binary
 	DDAgentBin string
 
+	// InspectMaxTraces is the number of recently received traces kept in memory for
+	// inspection through the receiver's /debug/traces endpoint. Zero disables it.
+	InspectMaxTraces int
+
 	// Obfuscation holds sensitive data obufscator's configuration.
 	Obfuscation *ObfuscationConfig
 }
@@ -124,8 +138,9 @@ func New() *AgentConfig {
 		DefaultEnv: "none",
 		Endpoints:  []*Endpoint{{Host: "https://trace.agent.datadoghq.com"}},
 
-		BucketInterval:   time.Duration(10) * time.Second,
-		ExtraAggregators: []string{"http.status_code"},
+		BucketInterval:        time.Duration(10) * time.Second,
+		ExtraAggregators:      []string{"http.status_code"},
+		AggregatorCardinality: make(map[string]int),
 
 		ExtraSampleRate: 1.0,
 		MaxTPS:          10,
@@ -137,6 +152,10 @@ func New() *AgentConfig {
 
 		StatsWriter: new(WriterConfig),
 		TraceWriter: new(WriterConfig),
+		Spool: &SpoolConfig{
+			MaxSizeMB:     100,
+			MaxAgeSeconds: 3600,
+		},
 
 		StatsdHost: "localhost",
 		StatsdPort: 8125,
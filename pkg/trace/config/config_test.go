@@ -10,6 +10,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/stretchr/testify/assert"
@@ -157,11 +158,16 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.Equal(18126, c.ReceiverPort)
 	assert.Equal(0.5, c.ExtraSampleRate)
 	assert.Equal(5.0, c.MaxTPS)
+	assert.Equal([]*TargetTPS{
+		{Service: "chatty", Env: "prod", TPS: 2},
+		{Service: "web", Resource: "GET /health", TPS: 0.5},
+	}, c.TargetTPS)
 	assert.Equal(50.0, c.MaxEPS)
 	assert.Equal(0.5, c.MaxCPU)
 	assert.EqualValues(123.4, c.MaxMemory)
 	assert.Equal("0.0.0.0", c.ReceiverHost)
 	assert.True(c.LogThrottling)
+	assert.Equal(10, c.InspectMaxTraces)
 
 	noProxy := true
 	if _, ok := os.LookupEnv("NO_PROXY"); ok {
@@ -199,7 +205,23 @@ func TestFullYamlConfig(t *testing.T) {
 		},
 	}, c.ReplaceTags)
 
+	assert.Len(c.SpanRules, 2)
+	dropCache := c.SpanRules[0]
+	assert.Equal(SpanRuleDropSpan, dropCache.Action)
+	assert.Equal(regexp.MustCompile(`^cache\.`), dropCache.NameRe)
+	assert.Equal(map[string]*regexp.Regexp{"cache.hit": nil}, dropCache.TagsRe)
+	assert.Equal(time.Millisecond, dropCache.MaxDur)
+	assert.Nil(dropCache.ServiceRe)
+	rename := c.SpanRules[1]
+	assert.Equal(SpanRuleModify, rename.Action)
+	assert.Equal(regexp.MustCompile("^legacy-db$"), rename.ServiceRe)
+	assert.Equal(map[string]string{"team": "storage"}, rename.SetTags)
+	assert.Equal([]string{"db.user"}, rename.RemoveTags)
+	assert.Equal("postgres", rename.RenameService)
+
 	assert.EqualValues([]string{"/health", "/500"}, c.Ignore["resource"])
+	assert.EqualValues([]string{"http.status_code", "peer.service", "http.status_class"}, c.ExtraAggregators)
+	assert.EqualValues(map[string]int{"peer.service": 50, "http.status_code": 20}, c.AggregatorCardinality)
 
 	o := c.Obfuscation
 	assert.NotNil(o)
@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package filters
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// SpanRules is a filter which drops or modifies spans matching its rules. Rules
+// are applied in order, so a rule sees the changes made by the previous ones.
+type SpanRules struct {
+	rules []*config.SpanRule
+}
+
+// NewSpanRules returns a new SpanRules filter using the given compiled rules.
+func NewSpanRules(rules []*config.SpanRule) *SpanRules {
+	return &SpanRules{rules: rules}
+}
+
+// Apply applies the rules to the given trace. It returns the resulting trace, which
+// may hold fewer spans than the original one, and false if the whole trace should
+// be dropped. Spans dropped by a rule have their children attached to their parent.
+func (f *SpanRules) Apply(trace pb.Trace) (pb.Trace, bool) {
+	if len(f.rules) == 0 {
+		return trace, true
+	}
+	root := traceutil.GetRoot(trace)
+	for _, rule := range f.rules {
+		// dropped maps the IDs of the spans dropped by this rule to their parent's ID.
+		var dropped map[uint64]uint64
+		for _, s := range trace {
+			if !matchSpanRule(rule, s) {
+				continue
+			}
+			switch rule.Action {
+			case config.SpanRuleDropTrace:
+				return trace, false
+			case config.SpanRuleDropSpan:
+				if s == root {
+					return trace, false
+				}
+				if dropped == nil {
+					dropped = make(map[uint64]uint64)
+				}
+				dropped[s.SpanID] = s.ParentID
+			case config.SpanRuleModify:
+				modifySpan(rule, s)
+			}
+		}
+		if dropped != nil {
+			trace = dropSpans(trace, dropped)
+		}
+	}
+	return trace, true
+}
+
+// dropSpans removes from trace the spans whose IDs are keys of dropped, attaching
+// their children to the closest parent which is kept.
+func dropSpans(trace pb.Trace, dropped map[uint64]uint64) pb.Trace {
+	kept := trace[:0]
+	for _, s := range trace {
+		if _, ok := dropped[s.SpanID]; ok {
+			continue
+		}
+		for i := 0; i < len(dropped); i++ {
+			// bounded by len(dropped) to guard against malformed traces with cycles
+			parent, ok := dropped[s.ParentID]
+			if !ok {
+				break
+			}
+			s.ParentID = parent
+		}
+		kept = append(kept, s)
+	}
+	return kept
+}
+
+// matchSpanRule reports whether the span s matches all the conditions of rule r.
+func matchSpanRule(r *config.SpanRule, s *pb.Span) bool {
+	if r.ServiceRe != nil && !r.ServiceRe.MatchString(s.Service) {
+		return false
+	}
+	if r.NameRe != nil && !r.NameRe.MatchString(s.Name) {
+		return false
+	}
+	if r.ResourceRe != nil && !r.ResourceRe.MatchString(s.Resource) {
+		return false
+	}
+	for k, re := range r.TagsRe {
+		v, ok := s.Meta[k]
+		if !ok {
+			return false
+		}
+		if re != nil && !re.MatchString(v) {
+			return false
+		}
+	}
+	d := time.Duration(s.Duration)
+	if r.MinDur > 0 && d < r.MinDur {
+		return false
+	}
+	if r.MaxDur > 0 && d > r.MaxDur {
+		return false
+	}
+	return true
+}
+
+// modifySpan applies the modifications of rule r to the span s.
+func modifySpan(r *config.SpanRule, s *pb.Span) {
+	for _, k := range r.RemoveTags {
+		delete(s.Meta, k)
+	}
+	for k, v := range r.SetTags {
+		traceutil.SetMeta(s, k, v)
+	}
+	if r.RenameService != "" {
+		s.Service = r.RenameService
+	}
+}
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package filters
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func testSpanRulesTrace() pb.Trace {
+	return pb.Trace{
+		{SpanID: 1, Service: "web", Name: "http.request", Resource: "GET /users", Duration: int64(50 * time.Millisecond)},
+		{SpanID: 2, ParentID: 1, Service: "web", Name: "cache.get", Resource: "GET", Duration: int64(time.Millisecond), Meta: map[string]string{"cache.hit": "true"}},
+		{SpanID: 3, ParentID: 2, Service: "redis", Name: "redis.command", Resource: "GET", Duration: int64(time.Millisecond)},
+		{SpanID: 4, ParentID: 1, Service: "pg", Name: "postgres.query", Resource: "SELECT 1", Duration: int64(30 * time.Millisecond), Meta: map[string]string{"db.user": "admin"}},
+	}
+}
+
+func TestSpanRules(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		trace, keep := NewSpanRules(nil).Apply(testSpanRulesTrace())
+		assert.True(t, keep)
+		assert.Len(t, trace, 4)
+	})
+
+	t.Run("drop-trace", func(t *testing.T) {
+		f := NewSpanRules([]*config.SpanRule{{
+			Action:     config.SpanRuleDropTrace,
+			ResourceRe: regexp.MustCompile("^SELECT"),
+			MinDur:     10 * time.Millisecond,
+		}})
+		_, keep := f.Apply(testSpanRulesTrace())
+		assert.False(t, keep)
+	})
+
+	t.Run("drop-span", func(t *testing.T) {
+		assert := assert.New(t)
+		f := NewSpanRules([]*config.SpanRule{{
+			Action: config.SpanRuleDropSpan,
+			NameRe: regexp.MustCompile(`^cache\.`),
+			TagsRe: map[string]*regexp.Regexp{"cache.hit": nil},
+		}})
+		trace, keep := f.Apply(testSpanRulesTrace())
+		assert.True(keep)
+		assert.Len(trace, 3)
+		for _, s := range trace {
+			assert.NotEqual("cache.get", s.Name)
+			if s.SpanID == 3 {
+				// re-attached to the parent of the dropped span
+				assert.EqualValues(1, s.ParentID)
+			}
+		}
+	})
+
+	t.Run("drop-root", func(t *testing.T) {
+		f := NewSpanRules([]*config.SpanRule{{
+			Action:    config.SpanRuleDropSpan,
+			ServiceRe: regexp.MustCompile("^web$"),
+		}})
+		_, keep := f.Apply(testSpanRulesTrace())
+		assert.False(t, keep)
+	})
+
+	t.Run("modify", func(t *testing.T) {
+		assert := assert.New(t)
+		f := NewSpanRules([]*config.SpanRule{
+			{
+				Action:        config.SpanRuleModify,
+				ServiceRe:     regexp.MustCompile("^pg$"),
+				SetTags:       map[string]string{"team": "storage"},
+				RemoveTags:    []string{"db.user"},
+				RenameService: "postgres",
+			},
+			{
+				// sees the changes made by the previous rule
+				Action:     config.SpanRuleDropSpan,
+				ServiceRe:  regexp.MustCompile("^postgres$"),
+				MaxDur:     time.Millisecond,
+				ResourceRe: regexp.MustCompile(".*"),
+			},
+		})
+		trace, keep := f.Apply(testSpanRulesTrace())
+		assert.True(keep)
+		assert.Len(trace, 4)
+		pg := trace[3]
+		assert.Equal("postgres", pg.Service)
+		assert.Equal(map[string]string{"team": "storage"}, pg.Meta)
+	})
+}
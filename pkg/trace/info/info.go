@@ -85,8 +85,10 @@ const (
 
   Traces: {{.Status.TraceWriter.Payloads}} payloads, {{.Status.TraceWriter.Traces}} traces, {{if gt .Status.TraceWriter.Events 0}}{{.Status.TraceWriter.Events}} events, {{end}}{{.Status.TraceWriter.Bytes}} bytes
   {{if gt .Status.TraceWriter.Errors 0}}WARNING: Traces API errors (1 min): {{.Status.TraceWriter.Errors}}{{end}}
+  {{if gt .Status.TraceWriter.SpooledBytes 0}}WARNING: Traces spooled to disk: {{.Status.TraceWriter.SpooledBytes}} bytes{{end}}
   Stats: {{.Status.StatsWriter.Payloads}} payloads, {{.Status.StatsWriter.StatsBuckets}} stats buckets, {{.Status.StatsWriter.Bytes}} bytes
   {{if gt .Status.StatsWriter.Errors 0}}WARNING: Stats API errors (1 min): {{.Status.StatsWriter.Errors}}{{end}}
+  {{if gt .Status.StatsWriter.SpooledBytes 0}}WARNING: Stats spooled to disk: {{.Status.StatsWriter.SpooledBytes}} bytes{{end}}
 `
 
 	notRunningTmplSrc = `{{.Banner}}
@@ -17,6 +17,7 @@ type TraceWriterInfo struct {
 	BytesUncompressed int64
 	BytesEstimated    int64
 	SingleMaxSize     int64
+	SpooledBytes      int64
 }
 
 // StatsWriterInfo represents statistics from the stats writer.
@@ -27,6 +28,7 @@ type StatsWriterInfo struct {
 	Retries      int64
 	Splits       int64
 	Bytes        int64
+	SpooledBytes int64
 }
 
 // UpdateTraceWriterInfo updates internal trace writer stats
@@ -36,6 +38,13 @@ func UpdateTraceWriterInfo(tws TraceWriterInfo) {
 	traceWriterInfo = tws
 }
 
+// UpdateTraceWriterSpooledBytes updates the number of bytes spooled to disk by the trace writer.
+func UpdateTraceWriterSpooledBytes(n int64) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	traceWriterInfo.SpooledBytes = n
+}
+
 func publishTraceWriterInfo() interface{} {
 	infoMu.RLock()
 	defer infoMu.RUnlock()
@@ -49,6 +58,13 @@ func UpdateStatsWriterInfo(sws StatsWriterInfo) {
 	statsWriterInfo = sws
 }
 
+// UpdateStatsWriterSpooledBytes updates the number of bytes spooled to disk by the stats writer.
+func UpdateStatsWriterSpooledBytes(n int64) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	statsWriterInfo.SpooledBytes = n
+}
+
 func publishStatsWriterInfo() interface{} {
 	infoMu.RLock()
 	defer infoMu.RUnlock()
@@ -0,0 +1,230 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// Package inspect keeps a bounded, in-memory record of the most recent traces
+// received by the trace-agent along with the sampling decision taken for each
+// of them. It is a debugging aid for misbehaving tracers and is disabled by default.
+package inspect
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// Reasons explaining why a trace was kept or dropped.
+const (
+	// ReasonFiltered is used for traces rejected by the resource blacklister.
+	ReasonFiltered = "filtered"
+	// ReasonSpanRule is used for traces dropped by a span rule.
+	ReasonSpanRule = "span_rule"
+	// ReasonPriorityReject is used for traces which the tracer marked as rejected
+	// by means of a negative sampling priority.
+	ReasonPriorityReject = "priority_reject"
+	// ReasonPriority is used for traces kept by the priority sampler.
+	ReasonPriority = "priority_sampler"
+	// ReasonScore is used for traces kept by the score sampler.
+	ReasonScore = "score_sampler"
+	// ReasonErrors is used for traces kept by the errors sampler.
+	ReasonErrors = "errors_sampler"
+	// ReasonNotSampled is used for traces dropped by the samplers. APM events may
+	// still have been extracted from them.
+	ReasonNotSampled = "not_sampled"
+)
+
+// Decision holds the outcome of processing a trace.
+type Decision struct {
+	// Kept is true if the trace was sent to the intake.
+	Kept bool `json:"kept"`
+	// Reason explains the decision. It is one of the Reason* constants.
+	Reason string `json:"reason"`
+	// Rate is the sampling rate which was applied to the trace, if any.
+	Rate float64 `json:"rate,omitempty"`
+}
+
+// Entry is a recorded trace.
+type Entry struct {
+	// Time is the time at which the trace started being processed.
+	Time time.Time `json:"time"`
+	// Source describes the tracer which sent the trace.
+	Source info.Tags `json:"source"`
+	// ContainerTags holds the orchestrator tags of the trace's origin, if any.
+	ContainerTags string `json:"container_tags,omitempty"`
+	// Decision holds the outcome of processing the trace.
+	Decision Decision `json:"decision"`
+	// Received is the trace, as sent by the tracer.
+	Received pb.Trace `json:"received"`
+	// Processed is the trace after normalization, obfuscation and tagging by the samplers.
+	// It is not set for traces dropped before sampling.
+	Processed pb.Trace `json:"processed,omitempty"`
+}
+
+// NewEntry returns a new entry for a trace about to be processed. It takes a copy of
+// the spans, so that later modifications are not reflected in the entry.
+func NewEntry(source *info.Tags, containerTags string, spans pb.Trace) *Entry {
+	e := &Entry{
+		Time:          time.Now(),
+		ContainerTags: containerTags,
+		Received:      copyTrace(spans),
+	}
+	if source != nil {
+		e.Source = *source
+	}
+	return e
+}
+
+// Recorder keeps the last recorded entries in a fixed size ring buffer. A nil
+// recorder is valid and records nothing, so it can be used when inspection is disabled.
+type Recorder struct {
+	mu      sync.RWMutex
+	entries []*Entry
+	next    int  // index in entries where the next entry will be written
+	full    bool // true once entries has wrapped around
+}
+
+// NewRecorder returns a recorder keeping at most size entries. It returns nil if
+// size is not positive, which disables recording.
+func NewRecorder(size int) *Recorder {
+	if size <= 0 {
+		return nil
+	}
+	return &Recorder{entries: make([]*Entry, size)}
+}
+
+// Enabled reports whether the recorder records anything.
+func (r *Recorder) Enabled() bool { return r != nil }
+
+// Record sets the decision d on the entry e and stores it, evicting the oldest
+// entry if the recorder is full. The processed trace is copied if not nil.
+func (r *Recorder) Record(e *Entry, d Decision, processed pb.Trace) {
+	if r == nil || e == nil {
+		return
+	}
+	e.Decision = d
+	if processed != nil {
+		e.Processed = copyTrace(processed)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Filter selects entries when listing them.
+type Filter struct {
+	// Service, if not empty, selects only traces having a span with this service.
+	Service string
+	// Kept, if not nil, selects only traces having this sampling decision.
+	Kept *bool
+	// Limit, if positive, is the maximum number of entries returned.
+	Limit int
+}
+
+func (f Filter) match(e *Entry) bool {
+	if f.Kept != nil && *f.Kept != e.Decision.Kept {
+		return false
+	}
+	if f.Service == "" {
+		return true
+	}
+	for _, s := range e.Received {
+		if s.Service == f.Service {
+			return true
+		}
+	}
+	return false
+}
+
+// Entries returns the recorded entries matching f, most recent first.
+func (r *Recorder) Entries(f Filter) []*Entry {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n := r.next
+	if r.full {
+		n = len(r.entries)
+	}
+	out := make([]*Entry, 0, n)
+	for i := 0; i < n; i++ {
+		// walk backwards from the last written entry
+		e := r.entries[(r.next-1-i+len(r.entries))%len(r.entries)]
+		if !f.match(e) {
+			continue
+		}
+		out = append(out, e)
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// ServeHTTP implements http.Handler. It writes the recorded entries as JSON. The
+// "service", "kept" and "limit" query string parameters map to the fields of Filter.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r == nil {
+		http.Error(w, "trace inspection is disabled; set apm_config.inspect.enabled to true", http.StatusNotFound)
+		return
+	}
+	var f Filter
+	q := req.URL.Query()
+	f.Service = q.Get("service")
+	if v := q.Get("kept"); v != "" {
+		kept, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "kept must be a boolean", http.StatusBadRequest)
+			return
+		}
+		f.Kept = &kept
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "limit must be an integer", http.StatusBadRequest)
+			return
+		}
+		f.Limit = n
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r.Entries(f))
+}
+
+// copyTrace returns a deep copy of t.
+func copyTrace(t pb.Trace) pb.Trace {
+	out := make(pb.Trace, len(t))
+	for i, s := range t {
+		if s == nil {
+			continue
+		}
+		cp := *s
+		if s.Meta != nil {
+			cp.Meta = make(map[string]string, len(s.Meta))
+			for k, v := range s.Meta {
+				cp.Meta[k] = v
+			}
+		}
+		if s.Metrics != nil {
+			cp.Metrics = make(map[string]float64, len(s.Metrics))
+			for k, v := range s.Metrics {
+				cp.Metrics[k] = v
+			}
+		}
+		out[i] = &cp
+	}
+	return out
+}
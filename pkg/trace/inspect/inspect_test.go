@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package inspect
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testEntry(service string) *Entry {
+	return NewEntry(&info.Tags{Lang: "go"}, "", pb.Trace{{Service: service, Meta: map[string]string{"k": "v"}}})
+}
+
+func TestNilRecorder(t *testing.T) {
+	assert := assert.New(t)
+
+	r := NewRecorder(0)
+	assert.Nil(r)
+	assert.False(r.Enabled())
+	r.Record(testEntry("a"), Decision{Kept: true}, nil)
+	assert.Empty(r.Entries(Filter{}))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", Path, nil))
+	assert.Equal(http.StatusNotFound, rec.Code)
+}
+
+func TestRecorder(t *testing.T) {
+	assert := assert.New(t)
+
+	r := NewRecorder(3)
+	assert.True(r.Enabled())
+	for _, service := range []string{"a", "b", "c", "d"} {
+		r.Record(testEntry(service), Decision{Kept: service != "c", Reason: ReasonScore}, nil)
+	}
+
+	services := func(entries []*Entry) []string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.Received[0].Service)
+		}
+		return out
+	}
+	assert.Equal([]string{"d", "c", "b"}, services(r.Entries(Filter{})))
+	assert.Equal([]string{"d"}, services(r.Entries(Filter{Limit: 1})))
+	assert.Equal([]string{"c"}, services(r.Entries(Filter{Service: "c"})))
+	kept := false
+	assert.Equal([]string{"c"}, services(r.Entries(Filter{Kept: &kept})))
+}
+
+func TestEntryCopiesSpans(t *testing.T) {
+	assert := assert.New(t)
+
+	trace := pb.Trace{{Service: "a", Meta: map[string]string{"k": "v"}, Metrics: map[string]float64{"m": 1}}}
+	e := NewEntry(nil, "", trace)
+	trace[0].Service = "b"
+	trace[0].Meta["k"] = "w"
+	trace[0].Metrics["m"] = 2
+
+	assert.Equal("a", e.Received[0].Service)
+	assert.Equal("v", e.Received[0].Meta["k"])
+	assert.Equal(1., e.Received[0].Metrics["m"])
+}
+
+func TestServeHTTP(t *testing.T) {
+	assert := assert.New(t)
+
+	r := NewRecorder(10)
+	r.Record(testEntry("a"), Decision{Kept: true, Reason: ReasonPriority, Rate: 0.5}, pb.Trace{{Service: "a"}})
+	r.Record(testEntry("b"), Decision{Reason: ReasonFiltered}, nil)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", Path+"?kept=true", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+	var entries []*Entry
+	assert.NoError(json.NewDecoder(rec.Body).Decode(&entries))
+	assert.Len(entries, 1)
+	assert.Equal(Decision{Kept: true, Reason: ReasonPriority, Rate: 0.5}, entries[0].Decision)
+	assert.Equal("go", entries[0].Source.Lang)
+	assert.Len(entries[0].Processed, 1)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", Path+"?limit=x", nil))
+	assert.Equal(http.StatusBadRequest, rec.Code)
+}
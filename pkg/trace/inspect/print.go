@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package inspect
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// Path is the path of the inspection endpoint on the receiver.
+const Path = "/debug/traces"
+
+// Print queries the inspection endpoint of the trace-agent running with the given
+// configuration and writes the recorded traces to w. The args are the command line
+// arguments following the "inspect" command.
+func Print(w io.Writer, conf *config.AgentConfig, args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	fs.SetOutput(w)
+	service := fs.String("service", "", "only show traces having a span with this service")
+	limit := fs.Int("n", 20, "maximum number of traces to show")
+	kept := fs.String("kept", "", "only show kept (true) or dropped (false) traces")
+	raw := fs.Bool("json", false, "write the recorded entries as JSON, including all spans")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	q.Set("limit", strconv.Itoa(*limit))
+	if *service != "" {
+		q.Set("service", *service)
+	}
+	if *kept != "" {
+		q.Set("kept", *kept)
+	}
+	u := fmt.Sprintf("http://%s:%d%s?%s", conf.ReceiverHost, conf.ReceiverPort, Path, q.Encode())
+	client := http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(u)
+	if err != nil {
+		return fmt.Errorf("could not reach the trace-agent on port %d, is it running? %v", conf.ReceiverPort, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	if *raw {
+		_, err := io.Copy(w, resp.Body)
+		return err
+	}
+
+	var entries []*Entry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tKEPT\tREASON\tRATE\tSPANS\tSERVICE\tRESOURCE\tLANG")
+	for _, e := range entries {
+		var service, resource string
+		if root := traceutil.GetRoot(e.Received); root != nil {
+			service, resource = root.Service, root.Resource
+		}
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%g\t%d\t%s\t%s\t%s\n",
+			e.Time.Format(time.RFC3339), e.Decision.Kept, e.Decision.Reason, e.Decision.Rate,
+			len(e.Received), service, resource, e.Source.Lang)
+	}
+	return tw.Flush()
+}
@@ -39,15 +39,18 @@ type PriorityEngine struct {
 
 	rateByService *RateByService
 	catalog       *serviceKeyCatalog
+	rules         *tpsRules
 	exit          chan struct{}
 }
 
-// NewPriorityEngine returns an initialized Sampler
-func NewPriorityEngine(extraRate float64, maxTPS float64, rateByService *RateByService) *PriorityEngine {
+// NewPriorityEngine returns an initialized Sampler. The given rules override the
+// rates computed from maxTPS for the services and resources they match.
+func NewPriorityEngine(extraRate float64, maxTPS float64, rules []TPSRule, rateByService *RateByService) *PriorityEngine {
 	s := &PriorityEngine{
 		Sampler:       newSampler(extraRate, maxTPS),
 		rateByService: rateByService,
 		catalog:       newServiceLookup(),
+		rules:         newTPSRules(rules),
 		exit:          make(chan struct{}),
 	}
 
@@ -57,13 +60,18 @@ func NewPriorityEngine(extraRate float64, maxTPS float64, rateByService *RateByS
 // Run runs and block on the Sampler main loop
 func (s *PriorityEngine) Run() {
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(3)
 
 	go func() {
 		s.Sampler.Run()
 		wg.Done()
 	}()
 
+	go func() {
+		s.rules.run()
+		wg.Done()
+	}()
+
 	go func() {
 		t := time.NewTicker(syncPeriod)
 		defer t.Stop()
@@ -85,6 +93,7 @@ func (s *PriorityEngine) Run() {
 // Stop stops the main Run loop
 func (s *PriorityEngine) Stop() {
 	s.Sampler.Stop()
+	s.rules.stop()
 	close(s.exit)
 }
 
@@ -112,7 +121,8 @@ func (s *PriorityEngine) Sample(trace pb.Trace, root *pb.Span, env string) (samp
 		return sampled, 1
 	}
 
-	signature := s.catalog.register(ServiceSignature{root.Service, env})
+	svcSig := ServiceSignature{root.Service, env}
+	signature := s.catalog.register(svcSig)
 
 	// Update sampler state by counting this trace
 	s.Sampler.Backend.CountSignature(signature)
@@ -121,10 +131,21 @@ func (s *PriorityEngine) Sample(trace pb.Trace, root *pb.Span, env string) (samp
 	var ok bool
 	rate, ok = root.Metrics[SamplingPriorityRateKey]
 	if !ok {
-		rate = s.Sampler.GetSignatureSampleRate(signature)
+		rate = s.serviceRate(svcSig, signature)
 		root.Metrics[SamplingPriorityRateKey] = rate
 	}
 
+	if sampled {
+		// Tracers only know about rates by service, so rules targeting resources
+		// are enforced here, on the traces they decided to keep.
+		if r := s.rules.resourceRate(root.Resource, root.Service, env); r < 1 {
+			rate *= r
+			// the applied rate is used to upscale the stats of the trace
+			root.Metrics[SamplingPriorityRateKey] = rate
+			sampled = SampleByRate(root.TraceID, r)
+		}
+	}
+
 	if sampled {
 		// Count the trace to allow us to check for the maxTPS limit.
 		// It has to happen before the maxTPS sampling.
@@ -142,7 +163,26 @@ func (s *PriorityEngine) GetState() interface{} {
 // ratesByService returns all rates by service, this information is useful for
 // agents to pick the right service rate.
 func (s *PriorityEngine) ratesByService() map[ServiceSignature]float64 {
-	return s.catalog.ratesByService(s.Sampler.GetAllSignatureSampleRates(), s.Sampler.GetDefaultSampleRate())
+	rates := s.catalog.ratesByService(s.Sampler.GetAllSignatureSampleRates(), s.Sampler.GetDefaultSampleRate())
+	if s.rules == nil {
+		return rates
+	}
+	for svcSig := range rates {
+		if svcSig.Name == "" {
+			continue
+		}
+		rates[svcSig] = s.serviceRate(svcSig, svcSig.Hash())
+	}
+	return rates
+}
+
+// serviceRate returns the rate to apply to the traces of the given service signature,
+// using the first rule matching it or, if none, the rate computed from its throughput.
+func (s *PriorityEngine) serviceRate(svcSig ServiceSignature, signature Signature) float64 {
+	if r := s.rules.serviceRule(svcSig.Name, svcSig.Env); r != nil {
+		return r.rate(s.Sampler.Backend.GetSignatureScore(signature))
+	}
+	return s.Sampler.GetSignatureSampleRate(signature)
 }
 
 // GetType return the type of the sampler engine
@@ -30,7 +30,7 @@ func getTestPriorityEngine() *PriorityEngine {
 	maxTPS := 0.0
 
 	rateByService := RateByService{}
-	return NewPriorityEngine(extraRate, maxTPS, &rateByService)
+	return NewPriorityEngine(extraRate, maxTPS, nil, &rateByService)
 }
 
 func getTestTraceWithService(t *testing.T, service string, s *PriorityEngine) (pb.Trace, *pb.Span) {
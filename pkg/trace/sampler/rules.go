@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sampler
+
+// TPSRule sets the number of traces per second which the priority sampler targets
+// for the traces whose root span matches it. An empty Service or Env matches any
+// value. Rules without a Resource are enforced by the tracers, through the rates
+// they receive by service. Rules with a Resource are enforced by the agent, by
+// dropping traces kept by the tracers above the target.
+type TPSRule struct {
+	Service   string
+	Env       string
+	Resource  string
+	TargetTPS float64
+}
+
+func (r *TPSRule) matchService(service, env string) bool {
+	return (r.Service == "" || r.Service == service) && (r.Env == "" || r.Env == env)
+}
+
+// rate returns the sampling rate needed to bring tps down to the rule's target.
+func (r *TPSRule) rate(tps float64) float64 {
+	if tps <= r.TargetTPS {
+		return 1
+	}
+	return r.TargetTPS / tps
+}
+
+// tpsRules holds TPS rules, split by how they are enforced. The first matching
+// rule applies.
+type tpsRules struct {
+	service  []*TPSRule
+	resource []*TPSRule
+
+	// backend counts the traces kept by the tracers matching each resource rule,
+	// under the signature Signature(i) for the i-th rule.
+	backend *MemoryBackend
+}
+
+// newTPSRules returns the rules to use in a priority sampler. It returns nil if
+// there are none.
+func newTPSRules(rules []TPSRule) *tpsRules {
+	if len(rules) == 0 {
+		return nil
+	}
+	rs := &tpsRules{backend: NewMemoryBackend(defaultDecayPeriod, defaultDecayFactor)}
+	for i := range rules {
+		r := &rules[i]
+		if r.Resource == "" {
+			rs.service = append(rs.service, r)
+		} else {
+			rs.resource = append(rs.resource, r)
+		}
+	}
+	return rs
+}
+
+// serviceRule returns the first service rule matching the given service and env, if any.
+func (rs *tpsRules) serviceRule(service, env string) *TPSRule {
+	if rs == nil {
+		return nil
+	}
+	for _, r := range rs.service {
+		if r.matchService(service, env) {
+			return r
+		}
+	}
+	return nil
+}
+
+// resourceRate counts a trace kept by the tracer whose root span has the given resource,
+// service and env, and returns the extra sampling rate to apply to it.
+func (rs *tpsRules) resourceRate(resource, service, env string) float64 {
+	if rs == nil {
+		return 1
+	}
+	for i, r := range rs.resource {
+		if r.Resource != resource || !r.matchService(service, env) {
+			continue
+		}
+		sig := Signature(i)
+		rs.backend.CountSignature(sig)
+		return r.rate(rs.backend.GetSignatureScore(sig))
+	}
+	return 1
+}
+
+func (rs *tpsRules) run() {
+	if rs != nil {
+		rs.backend.Run()
+	}
+}
+
+func (rs *tpsRules) stop() {
+	if rs != nil {
+		rs.backend.Stop()
+	}
+}
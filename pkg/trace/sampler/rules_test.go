@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestRulesTrace(service, resource string, priority SamplingPriority) (pb.Trace, *pb.Span) {
+	root := &pb.Span{TraceID: randomTraceID(), SpanID: 1, Service: service, Resource: resource, Metrics: map[string]float64{}}
+	SetSamplingPriority(root, priority)
+	return pb.Trace{root}, root
+}
+
+func TestTPSRulesByService(t *testing.T) {
+	assert := assert.New(t)
+	s := NewPriorityEngine(1, 0, []TPSRule{
+		{Service: "chatty", Env: "prod", TargetTPS: 1},
+		{Service: "chatty", TargetTPS: 5},
+	}, &RateByService{})
+	backend := s.Sampler.Backend.(*MemoryBackend)
+
+	// 100 traces per second for chatty in both envs
+	for i := 0; i < int(100*backend.countScaleFactor); i++ {
+		for _, env := range []string{"prod", "staging"} {
+			trace, root := getTestRulesTrace("chatty", "GET /", PriorityAutoDrop)
+			s.Sample(trace, root, env)
+		}
+	}
+	trace, root := getTestRulesTrace("quiet", "GET /", PriorityAutoKeep)
+	s.Sample(trace, root, "prod")
+
+	rates := s.ratesByService()
+	assert.InDelta(0.01, rates[ServiceSignature{"chatty", "prod"}], 0.001)
+	assert.InDelta(0.05, rates[ServiceSignature{"chatty", "staging"}], 0.001)
+	assert.Equal(s.Sampler.GetSignatureSampleRate(ServiceSignature{"quiet", "prod"}.Hash()), rates[ServiceSignature{"quiet", "prod"}])
+
+	// the rate applied to traces without a client rate comes from the rule too
+	trace, root = getTestRulesTrace("chatty", "GET /", PriorityAutoKeep)
+	_, rate := s.Sample(trace, root, "prod")
+	assert.InDelta(0.01, rate, 0.001)
+}
+
+func TestTPSRulesByResource(t *testing.T) {
+	assert := assert.New(t)
+	s := NewPriorityEngine(1, 0, []TPSRule{
+		{Service: "web", Resource: "GET /health", TargetTPS: 2},
+	}, &RateByService{})
+
+	n := int(100 * s.rules.backend.countScaleFactor)
+	var health, other int
+	for i := 0; i < n; i++ {
+		trace, root := getTestRulesTrace("web", "GET /health", PriorityAutoKeep)
+		sampled, rate := s.Sample(trace, root, "prod")
+		if sampled {
+			health++
+		}
+		assert.Equal(rate, root.Metrics[SamplingPriorityRateKey])
+		trace, root = getTestRulesTrace("web", "GET /users", PriorityAutoKeep)
+		if sampled, _ := s.Sample(trace, root, "prod"); sampled {
+			other++
+		}
+	}
+	assert.Equal(n, other)
+	assert.True(health > 0)
+	assert.True(health < n/4, "%d traces out of %d kept", health, n)
+
+	// user decisions are always respected
+	trace, root := getTestRulesTrace("web", "GET /health", PriorityUserKeep)
+	sampled, rate := s.Sample(trace, root, "prod")
+	assert.True(sampled)
+	assert.Equal(1.0, rate)
+}
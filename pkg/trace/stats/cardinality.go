@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package stats
+
+// OverflowTagValue is the value given to an extra aggregator once the number of
+// distinct values seen for it within a bucket has reached its cardinality limit.
+// Hits, errors and durations are still counted, but folded into this value.
+const OverflowTagValue = "_other"
+
+const (
+	tagStatusCode = "http.status_code"
+	// tagStatusClass is a synthetic aggregator derived from tagStatusCode, grouping
+	// status codes by class (e.g. "2xx", "5xx").
+	tagStatusClass = "http.status_class"
+)
+
+// cardinalityLimiter caps the number of distinct values tracked for each aggregator
+// within a single stats bucket. It is not thread-safe and a nil limiter allows everything.
+type cardinalityLimiter struct {
+	limits map[string]int
+	seen   map[string]map[string]struct{}
+}
+
+// newCardinalityLimiter returns a limiter enforcing the given per-aggregator limits.
+// It returns nil if there are no limits to enforce.
+func newCardinalityLimiter(limits map[string]int) *cardinalityLimiter {
+	if len(limits) == 0 {
+		return nil
+	}
+	return &cardinalityLimiter{
+		limits: limits,
+		seen:   make(map[string]map[string]struct{}, len(limits)),
+	}
+}
+
+// limit returns v if it is already known for the given aggregator or if there is room
+// left for a new value. Otherwise, it returns OverflowTagValue.
+func (l *cardinalityLimiter) limit(aggr, v string) string {
+	if l == nil {
+		return v
+	}
+	max, ok := l.limits[aggr]
+	if !ok || max <= 0 {
+		return v
+	}
+	values, ok := l.seen[aggr]
+	if !ok {
+		values = make(map[string]struct{})
+		l.seen[aggr] = values
+	}
+	if _, ok := values[v]; ok {
+		return v
+	}
+	if len(values) >= max {
+		return OverflowTagValue
+	}
+	values[v] = struct{}{}
+	return v
+}
+
+// aggregatorValue returns the value of the aggregator aggr for the span s and whether
+// the span has it at all.
+func aggregatorValue(s *WeightedSpan, aggr string) (string, bool) {
+	if v, ok := s.Meta[aggr]; ok {
+		return v, true
+	}
+	if aggr == tagStatusClass {
+		if code, ok := s.Meta[tagStatusCode]; ok && len(code) == 3 {
+			return code[:1] + "xx", true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardinalityLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	var l *cardinalityLimiter
+	assert.Nil(newCardinalityLimiter(nil))
+	assert.Equal("a", l.limit("peer.service", "a"))
+
+	l = newCardinalityLimiter(map[string]int{"peer.service": 2})
+	assert.Equal("a", l.limit("peer.service", "a"))
+	assert.Equal("b", l.limit("peer.service", "b"))
+	assert.Equal(OverflowTagValue, l.limit("peer.service", "c"))
+	assert.Equal("a", l.limit("peer.service", "a"))
+	assert.Equal("c", l.limit("version", "c"), "aggregators without limit are not capped")
+}
+
+func TestAggregatorValue(t *testing.T) {
+	assert := assert.New(t)
+
+	s := &WeightedSpan{Span: &pb.Span{Meta: map[string]string{"http.status_code": "404", "peer.service": "db"}}}
+	v, ok := aggregatorValue(s, "peer.service")
+	assert.True(ok)
+	assert.Equal("db", v)
+	v, ok = aggregatorValue(s, "http.status_class")
+	assert.True(ok)
+	assert.Equal("4xx", v)
+	_, ok = aggregatorValue(s, "version")
+	assert.False(ok)
+
+	s.Meta["http.status_code"] = "bad"
+	_, ok = aggregatorValue(s, "http.status_class")
+	assert.False(ok)
+}
+
+func TestConcentratorCardinality(t *testing.T) {
+	assert := assert.New(t)
+	statsChan := make(chan []Bucket)
+	c := NewConcentrator([]string{"peer.service"}, map[string]int{"peer.service": 1}, testBucketInterval, statsChan)
+
+	now := time.Now().UnixNano()
+	alignedNow := now - now%testBucketInterval
+	trace := pb.Trace{
+		testSpan(1, 0, 50, 5, "A1", "resource1", 0),
+		testSpan(2, 0, 40, 4, "A1", "resource1", 0),
+		testSpan(3, 0, 30, 3, "A1", "resource1", 0),
+	}
+	trace[0].Meta = map[string]string{"peer.service": "db"}
+	trace[1].Meta = map[string]string{"peer.service": "cache"}
+	trace[2].Meta = map[string]string{"peer.service": "queue"}
+	for _, s := range trace {
+		s.Start = alignedNow - s.Duration
+	}
+	traceutil.ComputeTopLevel(trace)
+	wt := NewWeightedTrace(trace, traceutil.GetRoot(trace))
+	c.addNow(&Input{Trace: wt, Env: "none"}, now)
+
+	buckets := c.flushNow(alignedNow + int64(c.bufferLen)*testBucketInterval)
+	assert.Len(buckets, 1)
+	hits := make(map[string]float64)
+	for _, count := range buckets[0].Counts {
+		if count.Measure != HITS {
+			continue
+		}
+		for _, tag := range count.TagSet {
+			if tag.Name == "peer.service" {
+				hits[tag.Value] += count.Value
+			}
+		}
+	}
+	assert.Equal(map[string]float64{"db": 1, OverflowTagValue: 2}, hits)
+}
@@ -27,6 +27,8 @@ const defaultBufferLen = 2
 type Concentrator struct {
 	// list of attributes to use for extra aggregation
 	aggregators []string
+	// maximum number of distinct values per bucket for some of the aggregators
+	cardinality map[string]int
 	// bucket duration in nanoseconds
 	bsize int64
 	// Timestamp of the oldest time bucket for which we allow data.
@@ -48,10 +50,13 @@ type Concentrator struct {
 	mu      sync.Mutex
 }
 
-// NewConcentrator initializes a new concentrator ready to be started
-func NewConcentrator(aggregators []string, bsize int64, out chan []Bucket) *Concentrator {
+// NewConcentrator initializes a new concentrator ready to be started. The cardinality
+// map optionally limits the number of distinct values kept per bucket for some of the
+// aggregators; values above the limit are counted under OverflowTagValue.
+func NewConcentrator(aggregators []string, cardinality map[string]int, bsize int64, out chan []Bucket) *Concentrator {
 	c := Concentrator{
 		aggregators: aggregators,
+		cardinality: cardinality,
 		bsize:       bsize,
 		buckets:     make(map[int64]*RawBucket),
 		// At start, only allow stats for the current time bucket. Ensure we don't
@@ -147,6 +152,7 @@ func (c *Concentrator) addNow(i *Input, now int64) {
 		b, ok := c.buckets[btime]
 		if !ok {
 			b = NewRawBucket(btime, c.bsize)
+			b.limiter = newCardinalityLimiter(c.cardinality)
 			c.buckets[btime] = b
 		}
 
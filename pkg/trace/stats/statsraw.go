@@ -73,6 +73,9 @@ type RawBucket struct {
 	data         map[statsKey]groupedStats
 	sublayerData map[statsSubKey]sublayerStats
 
+	// limiter caps the number of distinct values per extra aggregator, nil means no limit
+	limiter *cardinalityLimiter
+
 	// internal buffer for aggregate strings - not threadsafe
 	keyBuf bytes.Buffer
 }
@@ -198,8 +201,8 @@ func (sb *RawBucket) HandleSpan(s *WeightedSpan, env string, aggregators []strin
 
 	for _, agg := range aggregators {
 		if agg != "env" && agg != "resource" && agg != "service" {
-			if v, ok := s.Meta[agg]; ok {
-				m[agg] = v
+			if v, ok := aggregatorValue(s, agg); ok {
+				m[agg] = sb.limiter.limit(agg, v)
 			}
 		}
 	}
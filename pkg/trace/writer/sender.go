@@ -7,12 +7,15 @@ package writer
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math"
 	"math/rand"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -25,8 +28,9 @@ import (
 )
 
 // newSenders returns a list of senders based on the given agent configuration, using climit
-// as the maximum number of concurrent outgoing connections, writing to path.
-func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, qsize int) []*sender {
+// as the maximum number of concurrent outgoing connections, writing to path. If spooling
+// is enabled, each sender spools to its own directory, under the spoolName sub-directory.
+func newSenders(cfg *config.AgentConfig, r eventRecorder, path, spoolName string, climit, qsize int) []*sender {
 	if e := cfg.Endpoints; len(e) == 0 || e[0].Host == "" || e[0].APIKey == "" {
 		panic(errors.New("config was not properly validated"))
 	}
@@ -46,11 +50,35 @@ func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, q
 			url:       url,
 			apiKey:    endpoint.APIKey,
 			recorder:  r,
+			spool:     newEndpointSpool(cfg.Spool, spoolName, endpoint),
 		})
 	}
 	return senders
 }
 
+// newEndpointSpool returns the spool for the endpoint e of the writer named name,
+// or nil if spooling is disabled or the spool can not be created.
+func newEndpointSpool(cfg *config.SpoolConfig, name string, e *config.Endpoint) *spool {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	dir := filepath.Join(cfg.Dir, name, endpointSpoolKey(e))
+	sp, err := newSpool(dir, int64(cfg.MaxSizeMB)*1024*1024, time.Duration(cfg.MaxAgeSeconds)*time.Second)
+	if err != nil {
+		log.Errorf("Could not create spool in %s, payloads will not be spooled to disk: %v", dir, err)
+		return nil
+	}
+	return sp
+}
+
+// endpointSpoolKey returns the name of the spool directory of the endpoint e. It
+// identifies the host and API key, to never replay payloads to another endpoint
+// when the configured endpoints change.
+func endpointSpoolKey(e *config.Endpoint) string {
+	h := sha256.Sum256([]byte(e.Host + "\n" + e.APIKey))
+	return hex.EncodeToString(h[:8])
+}
+
 // eventRecorder implementations are able to take note of events happening in
 // the sender.
 type eventRecorder interface {
@@ -72,6 +100,9 @@ const (
 	// eventTypeDropped specifies that a payload had to be dropped to make room
 	// in the queue.
 	eventTypeDropped
+	// eventTypeSpooled specifies that a payload was written to disk to make room
+	// in the queue. It will be retried once the intake is reachable again.
+	eventTypeSpooled
 )
 
 var eventTypeStrings = map[eventType]string{
@@ -79,6 +110,7 @@ var eventTypeStrings = map[eventType]string{
 	eventTypeSent:     "eventTypeSent",
 	eventTypeRejected: "eventTypeRejected",
 	eventTypeDropped:  "eventTypeDropped",
+	eventTypeSpooled:  "eventTypeSpooled",
 }
 
 // String implements fmt.Stringer.
@@ -123,6 +155,9 @@ type senderConfig struct {
 	// recorder specifies the eventRecorder to use when reporting events occurring
 	// in the sender.
 	recorder eventRecorder
+	// spool, if not nil, is used to persist payloads instead of dropping them
+	// when the queue is full.
+	spool *spool
 }
 
 // sender is responsible for sending payloads to a given URL. It uses a size-limited
@@ -130,13 +165,16 @@ type senderConfig struct {
 type sender struct {
 	cfg *senderConfig
 
-	queue    chan *payload // payload queue
-	climit   chan struct{} // semaphore for limiting concurrent connections
-	inflight int32         // inflight payloads
-	attempt  int32         // active retry attempt
+	queue     chan *payload // payload queue
+	climit    chan struct{} // semaphore for limiting concurrent connections
+	inflight  int32         // inflight payloads
+	attempt   int32         // active retry attempt
+	replaying int32         // 1 while spooled payloads are being replayed
 
-	mu     sync.RWMutex // guards closed
-	closed bool         // closed reports if the loop is stopped
+	mu     sync.RWMutex   // guards closed
+	closed bool           // closed reports if the loop is stopped
+	stop   chan struct{}  // closed when the sender is stopping
+	wg     sync.WaitGroup // waits for the replay of spooled payloads
 }
 
 // newSender returns a new sender based on the given config cfg.
@@ -145,8 +183,11 @@ func newSender(cfg *senderConfig) *sender {
 		cfg:    cfg,
 		queue:  make(chan *payload, cfg.maxQueued),
 		climit: make(chan struct{}, cfg.maxConns),
+		stop:   make(chan struct{}),
 	}
 	go s.loop()
+	// payloads may have been left in the spool by a previous run
+	s.replay()
 	return &s
 }
 
@@ -188,9 +229,24 @@ outer:
 			time.Sleep(10 * time.Millisecond)
 		}
 	}
+	// unblock a running replay before waiting for it
+	close(s.stop)
 	s.mu.Lock()
 	s.closed = true
 	s.mu.Unlock()
+	s.wg.Wait()
+	if s.cfg.spool != nil {
+		// persist whatever could not be sent, it will be replayed on the next run
+	drain:
+		for {
+			select {
+			case p := <-s.queue:
+				s.spoolPayload(p, &eventData{bytes: p.body.Len(), count: 1})
+			default:
+				break drain
+			}
+		}
+	}
 	close(s.queue)
 }
 
@@ -203,10 +259,10 @@ func (s *sender) Push(p *payload) {
 			atomic.AddInt32(&s.inflight, 1)
 			return
 		default:
-			// drop the oldest item in the queue to make room
+			// drop (or spool) the oldest item in the queue to make room
 			select {
 			case p := <-s.queue:
-				s.releasePayload(p, eventTypeDropped, &eventData{
+				s.spoolPayload(p, &eventData{
 					bytes: p.body.Len(),
 					count: 1,
 				})
@@ -241,6 +297,9 @@ func (s *sender) sendPayload(p *payload) {
 		defer s.mu.RUnlock()
 		if s.closed {
 			// sender is stopped
+			if s.cfg.spool != nil {
+				s.spoolPayload(p, stats)
+			}
 			return
 		}
 		atomic.AddInt32(&s.attempt, 1)
@@ -249,8 +308,8 @@ func (s *sender) sendPayload(p *payload) {
 			s.recordEvent(eventTypeRetry, stats)
 			return
 		default:
-			// queue is full; since this is the oldest payload, we drop it
-			s.releasePayload(p, eventTypeDropped, stats)
+			// queue is full; since this is the oldest payload, we drop (or spool) it
+			s.spoolPayload(p, stats)
 		}
 	case nil:
 		// request was successful; the retry queue may have grown large - we should
@@ -263,12 +322,75 @@ func (s *sender) sendPayload(p *payload) {
 			}
 		}
 		s.releasePayload(p, eventTypeSent, stats)
+		// the intake is reachable, catch up on spooled payloads
+		s.replay()
 	default:
 		// this is a fatal error, we have to drop this payload
 		s.releasePayload(p, eventTypeRejected, stats)
 	}
 }
 
+// spoolPayload writes the payload p to the spool and releases it. If there is no spool
+// or writing fails, the payload is dropped.
+func (s *sender) spoolPayload(p *payload, data *eventData) {
+	if sp := s.cfg.spool; sp != nil {
+		if err := sp.put(p); err != nil {
+			log.Errorf("Error spooling payload to disk: %v", err)
+		} else {
+			s.releasePayload(p, eventTypeSpooled, data)
+			return
+		}
+	}
+	s.releasePayload(p, eventTypeDropped, data)
+}
+
+// replay starts moving spooled payloads back into the queue, oldest first, for as
+// long as the queue is less than half full. It does nothing if a replay is already
+// running or if there is nothing to replay.
+func (s *sender) replay() {
+	sp := s.cfg.spool
+	if sp == nil || sp.len() == 0 || !atomic.CompareAndSwapInt32(&s.replaying, 0, 1) {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		atomic.StoreInt32(&s.replaying, 0)
+		return
+	}
+	// Stop waits for the replay before closing the queue
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer atomic.StoreInt32(&s.replaying, 0)
+		for len(s.queue) < cap(s.queue)/2+1 {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+			p, err := sp.pop()
+			if err != nil {
+				log.Errorf("Error reading spooled payload, skipping: %v", err)
+				continue
+			}
+			if p == nil {
+				return
+			}
+			atomic.AddInt32(&s.inflight, 1)
+			select {
+			case s.queue <- p:
+			case <-s.stop:
+				// keep the payload for the next run
+				sp.put(p)
+				ppool.Put(p)
+				atomic.AddInt32(&s.inflight, -1)
+				return
+			}
+		}
+	}()
+}
+
 // releasePayload releases the payload p and records the specified event. The payload
 // should not be used again after a release.
 func (s *sender) releasePayload(p *payload, t eventType, data *eventData) {
@@ -365,6 +487,17 @@ func (p *payload) httpRequest(url *url.URL) (*http.Request, error) {
 	return req, nil
 }
 
+// spooledBytes returns the total number of bytes spooled to disk by the given senders.
+func spooledBytes(senders []*sender) int64 {
+	var n int64
+	for _, s := range senders {
+		if s.cfg.spool != nil {
+			n += s.cfg.spool.bytes()
+		}
+	}
+	return n
+}
+
 // stopSenders attempts to simultaneously stop a group of senders.
 func stopSenders(senders []*sender) {
 	var wg sync.WaitGroup
@@ -387,7 +520,9 @@ const (
 
 // backoffDuration returns the backoff duration necessary for the given attempt.
 // The formula is "Full Jitter":
-//   random_between(0, min(cap, base * 2 ** attempt))
+//
+//	random_between(0, min(cap, base * 2 ** attempt))
+//
 // https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
 var backoffDuration = func(attempt int) time.Duration {
 	if attempt == 0 {
@@ -285,8 +285,8 @@ func useBackoffDuration(d time.Duration) func() {
 
 // mockRecorder is a mock eventRecorder which records all calls to recordEvent.
 type mockRecorder struct {
-	mu                             sync.RWMutex
-	retry, sent, dropped, rejected []*eventData
+	mu                                      sync.RWMutex
+	retry, sent, dropped, rejected, spooled []*eventData
 }
 
 // data returns all call data for the given eventType.
@@ -302,6 +302,8 @@ func (r *mockRecorder) data(t eventType) []*eventData {
 		return r.dropped
 	case eventTypeRejected:
 		return r.rejected
+	case eventTypeSpooled:
+		return r.spooled
 	default:
 		panic("unknown event")
 	}
@@ -320,5 +322,7 @@ func (r *mockRecorder) recordEvent(t eventType, data *eventData) {
 		r.dropped = append(r.dropped, data)
 	case eventTypeRejected:
 		r.rejected = append(r.rejected, data)
+	case eventTypeSpooled:
+		r.spooled = append(r.spooled, data)
 	}
 }
@@ -0,0 +1,212 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package writer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// spoolFileExt is the extension of the files holding spooled payloads.
+const spoolFileExt = ".payload"
+
+// spool is a bounded, on-disk FIFO queue of payloads. Senders use it to persist the
+// payloads they would otherwise drop while the intake is unreachable, and replay them
+// in order once it is reachable again. Payloads older than maxAge are discarded and
+// the oldest payloads are evicted to keep the spool under maxBytes.
+//
+// Each payload is stored in its own file, named after its creation time, which holds
+// the JSON encoded headers on the first line, followed by the body.
+type spool struct {
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+
+	mu    sync.Mutex  // guards below
+	files []spoolFile // spooled files, oldest first
+	size  int64       // total size of files
+	seq   uint64      // sequence number, ensuring unique file names
+}
+
+// spoolFile describes a spooled payload.
+type spoolFile struct {
+	name    string
+	size    int64
+	created time.Time
+}
+
+// newSpool returns a new spool writing to dir, which is created if needed. Payloads
+// left in dir by a previous run are picked up, so that they can be replayed.
+func newSpool(dir string, maxBytes int64, maxAge time.Duration) (*spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &spool{
+		dir:      dir,
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range infos {
+		created, ok := parseSpoolFileName(fi.Name())
+		if !ok || fi.IsDir() {
+			continue
+		}
+		s.files = append(s.files, spoolFile{name: fi.Name(), size: fi.Size(), created: created})
+		s.size += fi.Size()
+	}
+	sort.Slice(s.files, func(i, j int) bool { return s.files[i].name < s.files[j].name })
+	s.mu.Lock()
+	s.expireLocked(time.Now())
+	s.mu.Unlock()
+	return s, nil
+}
+
+// spoolFileName returns the name of a file created at time t with sequence number seq.
+// Names sort in creation order.
+func spoolFileName(t time.Time, seq uint64) string {
+	return fmt.Sprintf("%020d-%010d%s", t.UnixNano(), seq, spoolFileExt)
+}
+
+// parseSpoolFileName returns the creation time of the spooled file with the given name.
+func parseSpoolFileName(name string) (time.Time, bool) {
+	if !strings.HasSuffix(name, spoolFileExt) {
+		return time.Time{}, false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(name, spoolFileExt), "-", 2)
+	ns, err := strconv.ParseInt(parts[0], 10, 64)
+	if len(parts) != 2 || err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ns), true
+}
+
+// put writes the payload p to the spool, evicting the oldest payloads if it grows
+// above its maximum size.
+func (s *spool) put(p *payload) error {
+	headers, err := json.Marshal(p.headers)
+	if err != nil {
+		return err
+	}
+	size := int64(len(headers) + 1 + p.body.Len())
+	if size > s.maxBytes {
+		return fmt.Errorf("payload of %d bytes exceeds the spool size", size)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expireLocked(now)
+	for s.size+size > s.maxBytes && len(s.files) > 0 {
+		log.Debugf("Spool %s full, evicting %s", s.dir, s.files[0].name)
+		s.removeLocked()
+	}
+
+	s.seq++
+	f := spoolFile{name: spoolFileName(now, s.seq), size: size, created: now}
+	path := filepath.Join(s.dir, f.name)
+	fd, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fd)
+	w.Write(headers)
+	w.WriteByte('\n')
+	w.Write(p.body.Bytes())
+	if err := w.Flush(); err != nil {
+		fd.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+	s.files = append(s.files, f)
+	s.size += size
+	return nil
+}
+
+// pop removes the oldest payload from the spool and returns it. It returns nil if
+// the spool is empty.
+func (s *spool) pop() (*payload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireLocked(time.Now())
+	if len(s.files) == 0 {
+		return nil, nil
+	}
+	path := filepath.Join(s.dir, s.files[0].name)
+	data, err := ioutil.ReadFile(path)
+	// the file is removed even if it can not be read, a corrupted file would
+	// otherwise block the spool forever.
+	s.removeLocked()
+	if err != nil {
+		return nil, err
+	}
+	i := strings.IndexByte(string(data), '\n')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid spool file %s", path)
+	}
+	var headers map[string]string
+	if err := json.Unmarshal(data[:i], &headers); err != nil {
+		return nil, fmt.Errorf("invalid spool file %s: %v", path, err)
+	}
+	p := newPayload(headers)
+	p.body.Write(data[i+1:])
+	return p, nil
+}
+
+// bytes returns the number of bytes currently spooled.
+func (s *spool) bytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// len returns the number of payloads currently spooled.
+func (s *spool) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.files)
+}
+
+// expireLocked removes all payloads older than maxAge. s.mu must be held.
+func (s *spool) expireLocked(now time.Time) {
+	if s.maxAge <= 0 {
+		return
+	}
+	for len(s.files) > 0 && now.Sub(s.files[0].created) > s.maxAge {
+		log.Debugf("Spooled payload %s expired", s.files[0].name)
+		s.removeLocked()
+	}
+}
+
+// removeLocked removes the oldest payload. s.mu must be held.
+func (s *spool) removeLocked() {
+	f := s.files[0]
+	if err := os.Remove(filepath.Join(s.dir, f.name)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Error removing spooled payload: %v", err)
+	}
+	s.files = s.files[1:]
+	s.size -= f.size
+}
@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package writer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func testSpoolPayload(body string) *payload {
+	p := newPayload(map[string]string{"Content-Type": "application/json"})
+	p.body.WriteString(body)
+	return p
+}
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("fifo", func(t *testing.T) {
+		assert := assert.New(t)
+		s, err := newSpool(filepath.Join(dir, "fifo"), 1024, time.Hour)
+		assert.NoError(err)
+
+		for _, body := range []string{"a", "b", "c"} {
+			assert.NoError(s.put(testSpoolPayload(body)))
+		}
+		assert.Equal(3, s.len())
+		assert.True(s.bytes() > 0)
+
+		for _, body := range []string{"a", "b", "c"} {
+			p, err := s.pop()
+			assert.NoError(err)
+			assert.Equal(body, p.body.String())
+			assert.Equal("application/json", p.headers["Content-Type"])
+		}
+		p, err := s.pop()
+		assert.NoError(err)
+		assert.Nil(p)
+		assert.EqualValues(0, s.bytes())
+	})
+
+	t.Run("max-size", func(t *testing.T) {
+		assert := assert.New(t)
+		size := int64(len(`{"Content-Type":"application/json"}`) + 1 + 10)
+		s, err := newSpool(filepath.Join(dir, "max-size"), 2*size, time.Hour)
+		assert.NoError(err)
+
+		for _, body := range []string{"0000000001", "0000000002", "0000000003"} {
+			assert.NoError(s.put(testSpoolPayload(body)))
+		}
+		assert.Equal(2, s.len())
+		assert.Equal(2*size, s.bytes())
+		p, err := s.pop()
+		assert.NoError(err)
+		assert.Equal("0000000002", p.body.String())
+
+		assert.Error(s.put(testSpoolPayload(strings.Repeat("0", int(2*size)))))
+	})
+
+	t.Run("max-age", func(t *testing.T) {
+		assert := assert.New(t)
+		s, err := newSpool(filepath.Join(dir, "max-age"), 1024, time.Millisecond)
+		assert.NoError(err)
+
+		assert.NoError(s.put(testSpoolPayload("a")))
+		time.Sleep(5 * time.Millisecond)
+		p, err := s.pop()
+		assert.NoError(err)
+		assert.Nil(p)
+		assert.Equal(0, s.len())
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		assert := assert.New(t)
+		path := filepath.Join(dir, "reload")
+		s, err := newSpool(path, 1024, time.Hour)
+		assert.NoError(err)
+		assert.NoError(s.put(testSpoolPayload("a")))
+		assert.NoError(s.put(testSpoolPayload("b")))
+
+		s, err = newSpool(path, 1024, time.Hour)
+		assert.NoError(err)
+		assert.Equal(2, s.len())
+		p, err := s.pop()
+		assert.NoError(err)
+		assert.Equal("a", p.body.String())
+	})
+}
+
+func TestSenderSpool(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "trace-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer useBackoffDuration(time.Millisecond)()
+
+	server := newTestServer()
+	defer server.Close()
+	u, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp, err := newSpool(dir, 1024*1024, time.Hour)
+	assert.NoError(err)
+	var recorder mockRecorder
+	s := newSender(&senderConfig{
+		client:    &http.Client{},
+		url:       u,
+		maxConns:  1,
+		maxQueued: 1,
+		apiKey:    testAPIKey,
+		recorder:  &recorder,
+		spool:     sp,
+	})
+	// the first payload fails long enough for the following ones to overflow the queue
+	s.Push(expectResponses(503, 503, 503, 200))
+	for i := 0; i < 5; i++ {
+		s.Push(expectResponses(200))
+	}
+	assert.NotEmpty(recorder.data(eventTypeSpooled))
+	assert.Empty(recorder.data(eventTypeDropped))
+
+	timeout := time.After(5 * time.Second)
+	for sp.len() > 0 || server.Accepted() < 6 {
+		select {
+		case <-timeout:
+			t.Fatalf("spool was not replayed: %d spooled, %d accepted", sp.len(), server.Accepted())
+		default:
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	s.Stop()
+	assert.Equal(6, server.Accepted())
+}
+
+func TestEndpointSpoolKey(t *testing.T) {
+	assert := assert.New(t)
+	e := &config.Endpoint{Host: "https://trace.agent.datadoghq.com", APIKey: "123"}
+	key := endpointSpoolKey(e)
+	assert.Equal(key, endpointSpoolKey(&config.Endpoint{Host: e.Host, APIKey: e.APIKey}))
+	assert.NotContains(key, e.APIKey)
+	// payloads are never replayed to another host, or with another API key
+	assert.NotEqual(key, endpointSpoolKey(&config.Endpoint{Host: "https://trace.agent.datadoghq.eu", APIKey: e.APIKey}))
+	assert.NotEqual(key, endpointSpoolKey(&config.Endpoint{Host: e.Host, APIKey: "456"}))
+}
@@ -68,7 +68,7 @@ func NewStatsWriter(cfg *config.AgentConfig, in <-chan []stats.Bucket) *StatsWri
 		qsize = int(math.Max(1, cfg.MaxMemory/4/payloadSize))
 	}
 	log.Debugf("Stats writer initialized (climit=%d qsize=%d)", climit, qsize)
-	sw.senders = newSenders(cfg, sw, pathStats, climit, qsize)
+	sw.senders = newSenders(cfg, sw, pathStats, "stats", climit, qsize)
 	return sw
 }
 
@@ -236,6 +236,10 @@ func (w *StatsWriter) report() {
 	metrics.Count("datadog.trace_agent.stats_writer.retries", atomic.SwapInt64(&w.stats.Retries, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.stats_writer.splits", atomic.SwapInt64(&w.stats.Splits, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.stats_writer.errors", atomic.SwapInt64(&w.stats.Errors, 0), nil, 1)
+
+	spooled := spooledBytes(w.senders)
+	metrics.Gauge("datadog.trace_agent.stats_writer.spooled_bytes", float64(spooled), nil, 1)
+	info.UpdateStatsWriterSpooledBytes(spooled)
 }
 
 // recordEvent implements eventRecorder.
@@ -263,5 +267,9 @@ func (w *StatsWriter) recordEvent(t eventType, data *eventData) {
 		w.easylog.Warn("Stats writer queue full. Payload dropped (%.2fKB).", float64(data.bytes)/1024)
 		metrics.Count("datadog.trace_agent.stats_writer.dropped", 1, nil, 1)
 		metrics.Count("datadog.trace_agent.stats_writer.dropped_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeSpooled:
+		w.easylog.Warn("Stats writer queue full. Payload spooled to disk (%.2fKB).", float64(data.bytes)/1024)
+		metrics.Count("datadog.trace_agent.stats_writer.spooled", 1, nil, 1)
 	}
 }
@@ -97,7 +97,7 @@ func NewTraceWriter(cfg *config.AgentConfig, in <-chan *SampledSpans) *TraceWrit
 		tw.tick = time.Duration(s*1000) * time.Millisecond
 	}
 	log.Debugf("Trace writer initialized (climit=%d qsize=%d)", climit, qsize)
-	tw.senders = newSenders(cfg, tw, pathTraces, climit, qsize)
+	tw.senders = newSenders(cfg, tw, pathTraces, "traces", climit, qsize)
 	return tw
 }
 
@@ -232,6 +232,10 @@ func (w *TraceWriter) report() {
 	metrics.Count("datadog.trace_agent.trace_writer.traces", atomic.SwapInt64(&w.stats.Traces, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.trace_writer.events", atomic.SwapInt64(&w.stats.Events, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.trace_writer.spans", atomic.SwapInt64(&w.stats.Spans, 0), nil, 1)
+
+	spooled := spooledBytes(w.senders)
+	metrics.Gauge("datadog.trace_agent.trace_writer.spooled_bytes", float64(spooled), nil, 1)
+	info.UpdateTraceWriterSpooledBytes(spooled)
 }
 
 var _ eventRecorder = (*TraceWriter)(nil)
@@ -261,5 +265,9 @@ func (w *TraceWriter) recordEvent(t eventType, data *eventData) {
 		w.easylog.Warn("Trace writer queue full. Payload dropped (%.2fKB).", float64(data.bytes)/1024)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped", 1, nil, 1)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeSpooled:
+		w.easylog.Warn("Trace writer queue full. Payload spooled to disk (%.2fKB).", float64(data.bytes)/1024)
+		metrics.Count("datadog.trace_agent.trace_writer.spooled", 1, nil, 1)
 	}
 }
@@ -6,6 +6,7 @@
 package clusteragent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -50,10 +51,13 @@ type DCAClientInterface interface {
 	GetNodeLabels(nodeName string) (map[string]string, error)
 	GetPodsMetadataForNode(nodeName string) (apiv1.NamespacesPodsStringsSet, error)
 	GetKubernetesMetadataNames(nodeName, ns, podName string) ([]string, error)
+	StreamMetadata(ctx context.Context, nodeName, token string, handle func(apiv1.MetadataEvent)) error
 
 	PostClusterCheckStatus(nodeName string, status types.NodeStatus) (types.StatusResponse, error)
 	GetClusterCheckConfigs(nodeName string) (types.ConfigResponse, error)
 	GetEndpointsCheckConfigs(nodeName string) (types.ConfigResponse, error)
+
+	PostExternalMetricsSeries(nodeName string, payload apiv1.SeriesPayload) (apiv1.SeriesResponse, error)
 }
 
 // DCAClient is required to query the API of Datadog cluster agent
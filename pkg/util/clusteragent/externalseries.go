@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package clusteragent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const dcaExternalSeriesPath = "api/v1/autoscalers/series"
+
+// PostExternalMetricsSeries sends the series requested by the node_agent external
+// metrics backend, the response lists the metrics to send on the next flush.
+func (c *DCAClient) PostExternalMetricsSeries(nodeName string, payload apiv1.SeriesPayload) (apiv1.SeriesResponse, error) {
+	// Retry on the main URL if the leader fails
+	willRetry := c.leaderClient.hasLeader()
+
+	result, err := c.doPostExternalMetricsSeries(nodeName, payload)
+	if err != nil && willRetry {
+		log.Debugf("Got error on leader, retrying via the service: %s", err)
+		c.leaderClient.resetURL()
+		return c.doPostExternalMetricsSeries(nodeName, payload)
+	}
+	return result, err
+}
+
+func (c *DCAClient) doPostExternalMetricsSeries(nodeName string, payload apiv1.SeriesPayload) (apiv1.SeriesResponse, error) {
+	var response apiv1.SeriesResponse
+
+	queryBody, err := json.Marshal(payload)
+	if err != nil {
+		return response, err
+	}
+
+	// https://host:port/api/v1/autoscalers/series/{nodeName}
+	rawURL := c.leaderClient.buildURL(dcaExternalSeriesPath, nodeName)
+	req, err := http.NewRequest("POST", rawURL, bytes.NewBuffer(queryBody))
+	if err != nil {
+		return response, err
+	}
+	req.Header = c.clusterAgentAPIRequestHeaders
+
+	resp, err := c.leaderClient.Do(req)
+	if err != nil {
+		return response, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return response, fmt.Errorf("unexpected response: %d - %s", resp.StatusCode, resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return response, err
+	}
+	err = json.Unmarshal(b, &response)
+	return response, err
+}
+
+// ExternalSeriesForwarder sends the latest point of the series requested by the
+// node_agent external metrics backend of the cluster agent after each flush.
+type ExternalSeriesForwarder struct {
+	getClient func() (DCAClientInterface, error)
+	nodeName  string
+
+	m       sync.Mutex
+	wanted  map[string]struct{}
+	pending bool
+}
+
+// NewExternalSeriesForwarder returns a new ExternalSeriesForwarder, the
+// client of the cluster agent is initialized on the first post.
+func NewExternalSeriesForwarder(nodeName string) *ExternalSeriesForwarder {
+	return &ExternalSeriesForwarder{
+		getClient: GetClusterAgentClient,
+		nodeName:  nodeName,
+		wanted:    make(map[string]struct{}),
+	}
+}
+
+// ForwardSeries posts the requested series asynchronously, the series are
+// dropped if the previous post is still pending. The cluster agent is called
+// even without requested series to learn which metrics to send.
+func (f *ExternalSeriesForwarder) ForwardSeries(series metrics.Series) {
+	f.m.Lock()
+	if f.pending {
+		f.m.Unlock()
+		log.Debugf("The previous series are still being sent to the cluster agent, skipping this flush")
+		return
+	}
+	var points []apiv1.SeriesPoint
+	for _, serie := range series {
+		if _, found := f.wanted[serie.Name]; !found || len(serie.Points) == 0 {
+			continue
+		}
+		last := serie.Points[0]
+		for _, p := range serie.Points[1:] {
+			if p.Ts > last.Ts {
+				last = p
+			}
+		}
+		tags := make([]string, len(serie.Tags))
+		copy(tags, serie.Tags)
+		points = append(points, apiv1.SeriesPoint{
+			Name:      serie.Name,
+			Tags:      tags,
+			Timestamp: int64(last.Ts),
+			Value:     last.Value,
+		})
+	}
+	f.pending = true
+	f.m.Unlock()
+
+	go f.post(points)
+}
+
+func (f *ExternalSeriesForwarder) post(points []apiv1.SeriesPoint) {
+	var response apiv1.SeriesResponse
+	client, err := f.getClient()
+	if err == nil {
+		response, err = client.PostExternalMetricsSeries(f.nodeName, apiv1.SeriesPayload{Series: points})
+	}
+
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.pending = false
+	if err != nil {
+		log.Debugf("Could not send the series to the cluster agent: %v", err)
+		return
+	}
+	f.wanted = make(map[string]struct{}, len(response.Metrics))
+	for _, name := range response.Metrics {
+		f.wanted[name] = struct{}{}
+	}
+}
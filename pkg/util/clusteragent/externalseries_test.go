@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package clusteragent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// fakeSeriesClient records the series posted, the other methods are not implemented
+type fakeSeriesClient struct {
+	DCAClientInterface
+	payloads chan apiv1.SeriesPayload
+	release  chan struct{}
+	wanted   []string
+}
+
+func (f *fakeSeriesClient) PostExternalMetricsSeries(nodeName string, payload apiv1.SeriesPayload) (apiv1.SeriesResponse, error) {
+	f.payloads <- payload
+	<-f.release
+	return apiv1.SeriesResponse{Metrics: f.wanted}, nil
+}
+
+func isPending(f *ExternalSeriesForwarder) bool {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.pending
+}
+
+func TestExternalSeriesForwarder(t *testing.T) {
+	client := &fakeSeriesClient{
+		payloads: make(chan apiv1.SeriesPayload, 10),
+		release:  make(chan struct{}),
+		wanted:   []string{"requests"},
+	}
+	forwarder := NewExternalSeriesForwarder("mynode")
+	forwarder.getClient = func() (DCAClientInterface, error) { return client, nil }
+	series := metrics.Series{
+		{Name: "requests", Tags: []string{"service:web"}, Points: []metrics.Point{{Ts: 20, Value: 2}, {Ts: 10, Value: 1}}},
+		{Name: "errors", Tags: []string{"service:web"}, Points: []metrics.Point{{Ts: 20, Value: 5}}},
+	}
+
+	// Nothing is wanted before the first response
+	forwarder.ForwardSeries(series)
+	payload := <-client.payloads
+	assert.Empty(t, payload.Series)
+
+	// Flushes are skipped while a post is pending
+	forwarder.ForwardSeries(series)
+	select {
+	case <-client.payloads:
+		require.FailNow(t, "the flush should have been skipped")
+	case <-time.After(50 * time.Millisecond):
+	}
+	client.release <- struct{}{}
+
+	for i := 0; i < 100 && isPending(forwarder); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.False(t, isPending(forwarder))
+	forwarder.ForwardSeries(series)
+	payload = <-client.payloads
+	assert.Equal(t, []apiv1.SeriesPoint{{Name: "requests", Tags: []string{"service:web"}, Timestamp: 20, Value: 2}}, payload.Series)
+	client.release <- struct{}{}
+}
@@ -0,0 +1,278 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package clusteragent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// metadataStreamIdleTimeout is the time after which the stream is considered
+	// broken, the cluster agent sends heartbeats every 30 seconds
+	metadataStreamIdleTimeout = 90 * time.Second
+	metadataStreamMinBackoff  = time.Second
+	metadataStreamMaxBackoff  = time.Minute
+)
+
+// StreamMetadata subscribes to the metadata stream of the node, resuming it
+// from the token if not empty, and calls handle for every event received.
+// It returns when the context is cancelled or the stream is broken.
+func (c *DCAClient) StreamMetadata(ctx context.Context, nodeName, token string, handle func(apiv1.MetadataEvent)) error {
+	const dcaMetadataStreamPath = "api/v1/tags/stream"
+
+	if c == nil {
+		return fmt.Errorf("cluster agent's client is not properly initialized")
+	}
+
+	// https://host:port/api/v1/tags/stream/{nodeName}?token={token}
+	rawURL := fmt.Sprintf("%s/%s/%s", c.clusterAgentAPIEndpoint, dcaMetadataStreamPath, nodeName)
+	if token != "" {
+		rawURL = fmt.Sprintf("%s?token=%s", rawURL, url.QueryEscape(token))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header = c.clusterAgentAPIRequestHeaders
+
+	// The stream is long-lived, the timeout of the client can't apply
+	streamClient := *c.clusterAgentAPIClient
+	streamClient.Timeout = 0
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code from cluster agent: %d", resp.StatusCode)
+	}
+
+	// Cancel the request if the cluster agent stops sending heartbeats
+	idle := time.AfterFunc(metadataStreamIdleTimeout, cancel)
+	defer idle.Stop()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event apiv1.MetadataEvent
+		if err = decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("metadata stream closed by the cluster agent")
+			}
+			return err
+		}
+		idle.Reset(metadataStreamIdleTimeout)
+		handle(event)
+	}
+}
+
+var (
+	globalMetadataCache     *MetadataCache
+	globalMetadataCacheLock sync.Mutex
+)
+
+// MetadataCache keeps the cluster metadata of a node up to date from the
+// metadata stream of the cluster agent.
+type MetadataCache struct {
+	client   DCAClientInterface
+	nodeName string
+
+	mu              sync.RWMutex
+	token           string
+	synced          bool
+	lastChange      time.Time
+	nodeLabels      map[string]string
+	namespaceLabels map[string]map[string]string
+	pods            map[string]apiv1.MetadataEvent
+}
+
+// NewMetadataCache returns a new MetadataCache for the node
+func NewMetadataCache(client DCAClientInterface, nodeName string) *MetadataCache {
+	return &MetadataCache{
+		client:          client,
+		nodeName:        nodeName,
+		namespaceLabels: make(map[string]map[string]string),
+		pods:            make(map[string]apiv1.MetadataEvent),
+	}
+}
+
+// StartMetadataCache subscribes the node to the metadata stream on the first
+// call and returns the cache shared by the consumers of the cluster metadata.
+func StartMetadataCache(client DCAClientInterface, nodeName string) *MetadataCache {
+	globalMetadataCacheLock.Lock()
+	defer globalMetadataCacheLock.Unlock()
+
+	if globalMetadataCache == nil {
+		globalMetadataCache = NewMetadataCache(client, nodeName)
+		go globalMetadataCache.Run(context.Background())
+	}
+	return globalMetadataCache
+}
+
+// GetSyncedMetadataCache returns the cache started by StartMetadataCache if it
+// holds a full snapshot of the metadata of the node, nil otherwise.
+func GetSyncedMetadataCache() *MetadataCache {
+	globalMetadataCacheLock.Lock()
+	cache := globalMetadataCache
+	globalMetadataCacheLock.Unlock()
+
+	if cache == nil || !cache.IsSynced() {
+		return nil
+	}
+	return cache
+}
+
+// Run subscribes to the metadata stream until the context is cancelled,
+// reconnecting with the last token received when the stream breaks.
+func (m *MetadataCache) Run(ctx context.Context) {
+	backoff := metadataStreamMinBackoff
+	for {
+		received := false
+		err := m.client.StreamMetadata(ctx, m.nodeName, m.getToken(), func(event apiv1.MetadataEvent) {
+			received = true
+			m.handle(event)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if received {
+			backoff = metadataStreamMinBackoff
+		}
+		log.Debugf("Metadata stream of the cluster agent interrupted, reconnecting in %s: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > metadataStreamMaxBackoff {
+			backoff = metadataStreamMaxBackoff
+		}
+	}
+}
+
+func (m *MetadataCache) handle(event apiv1.MetadataEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if event.Token != "" {
+		m.token = event.Token
+	}
+
+	switch event.Type {
+	case apiv1.MetadataEventReset:
+		m.synced = false
+		m.nodeLabels = nil
+		m.namespaceLabels = make(map[string]map[string]string)
+		m.pods = make(map[string]apiv1.MetadataEvent)
+	case apiv1.MetadataEventSynced:
+		m.synced = true
+	case apiv1.MetadataEventSet, apiv1.MetadataEventDelete:
+		set := event.Type == apiv1.MetadataEventSet
+		switch event.Kind {
+		case apiv1.MetadataKindNode:
+			if set {
+				m.nodeLabels = event.Labels
+			} else {
+				m.nodeLabels = nil
+			}
+		case apiv1.MetadataKindNamespace:
+			if set {
+				m.namespaceLabels[event.Name] = event.Labels
+			} else {
+				delete(m.namespaceLabels, event.Name)
+			}
+		case apiv1.MetadataKindPod:
+			key := event.Namespace + "/" + event.Name
+			if set {
+				m.pods[key] = event
+			} else {
+				delete(m.pods, key)
+			}
+		default:
+			return
+		}
+	default:
+		return
+	}
+	m.lastChange = time.Now()
+}
+
+func (m *MetadataCache) getToken() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.token
+}
+
+// IsSynced returns whether the cache holds a full snapshot of the metadata of the node
+func (m *MetadataCache) IsSynced() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.synced
+}
+
+// LastChange returns the time of the last change of the metadata
+func (m *MetadataCache) LastChange() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastChange
+}
+
+// GetNodeLabels returns the labels of the node
+func (m *MetadataCache) GetNodeLabels() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.nodeLabels
+}
+
+// GetNamespaceLabels returns the labels of a namespace
+func (m *MetadataCache) GetNamespaceLabels(namespace string) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.namespaceLabels[namespace]
+}
+
+// GetPodOwners returns the owners of a pod of the node
+func (m *MetadataCache) GetPodOwners(namespace, podName string) []apiv1.OwnerReference {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pods[namespace+"/"+podName].Owners
+}
+
+// GetPodsMetadata returns the services of the pods of the node, in the format
+// returned by GetPodsMetadataForNode
+func (m *MetadataCache) GetPodsMetadata() apiv1.NamespacesPodsStringsSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	metadata := apiv1.NewNamespacesPodsStringsSet()
+	for _, pod := range m.pods {
+		if len(pod.Services) == 0 {
+			continue
+		}
+		if _, found := metadata[pod.Namespace]; !found {
+			metadata[pod.Namespace] = make(apiv1.MapStringSet)
+		}
+		metadata[pod.Namespace][pod.Name] = sets.NewString(pod.Services...)
+	}
+	return metadata
+}
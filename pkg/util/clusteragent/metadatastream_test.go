@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+package clusteragent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+)
+
+var dummyMetadataStream = `{"type":"reset"}
+{"token":"abc-2","type":"set","kind":"node","name":"mynode","labels":{"zone":"a"}}
+{"token":"abc-2","type":"set","kind":"pod","namespace":"default","name":"pod1","services":["svc1"]}
+{"token":"abc-2","type":"synced"}
+`
+
+func (suite *clusterAgentSuite) TestStreamMetadata() {
+	dca, err := newDummyClusterAgent()
+	require.NoError(suite.T(), err)
+
+	dca.rawResponses["/api/v1/tags/stream/mynode"] = dummyMetadataStream
+
+	ts, p, err := dca.StartTLS()
+	defer ts.Close()
+	require.NoError(suite.T(), err)
+	mockConfig.Set("cluster_agent.url", fmt.Sprintf("https://127.0.0.1:%d", p))
+
+	ca, err := GetClusterAgentClient()
+	require.NoError(suite.T(), err)
+	dca.PopRequest() // version
+
+	var events []apiv1.MetadataEvent
+	err = ca.StreamMetadata(context.Background(), "mynode", "abc-1", func(event apiv1.MetadataEvent) {
+		events = append(events, event)
+	})
+	// The dummy cluster agent closes the stream after the events
+	assert.Error(suite.T(), err)
+
+	require.Len(suite.T(), events, 4)
+	assert.Equal(suite.T(), apiv1.MetadataEventReset, events[0].Type)
+	assert.Equal(suite.T(), map[string]string{"zone": "a"}, events[1].Labels)
+	assert.Equal(suite.T(), []string{"svc1"}, events[2].Services)
+	assert.Equal(suite.T(), "abc-2", events[3].Token)
+
+	r := dca.PopRequest()
+	require.NotNil(suite.T(), r)
+	assert.Equal(suite.T(), "abc-1", r.URL.Query().Get("token"))
+}
+
+func TestMetadataCacheHandle(t *testing.T) {
+	cache := NewMetadataCache(nil, "mynode")
+
+	for _, event := range []apiv1.MetadataEvent{
+		{Type: apiv1.MetadataEventReset},
+		{Token: "abc-3", Type: apiv1.MetadataEventSet, Kind: apiv1.MetadataKindNode, Name: "mynode", Labels: map[string]string{"zone": "a"}},
+		{Token: "abc-3", Type: apiv1.MetadataEventSet, Kind: apiv1.MetadataKindNamespace, Name: "default", Labels: map[string]string{"team": "b"}},
+		{Token: "abc-3", Type: apiv1.MetadataEventSet, Kind: apiv1.MetadataKindPod, Namespace: "default", Name: "pod1", Services: []string{"svc1", "svc2"}},
+		{Token: "abc-3", Type: apiv1.MetadataEventSet, Kind: apiv1.MetadataKindPod, Namespace: "default", Name: "pod2", Owners: []apiv1.OwnerReference{{Kind: "Job", Name: "job"}}},
+	} {
+		cache.handle(event)
+	}
+	assert.False(t, cache.IsSynced())
+	cache.handle(apiv1.MetadataEvent{Token: "abc-3", Type: apiv1.MetadataEventSynced})
+	assert.True(t, cache.IsSynced())
+
+	assert.Equal(t, "abc-3", cache.getToken())
+	assert.Equal(t, map[string]string{"zone": "a"}, cache.GetNodeLabels())
+	assert.Equal(t, map[string]string{"team": "b"}, cache.GetNamespaceLabels("default"))
+	assert.Equal(t, []apiv1.OwnerReference{{Kind: "Job", Name: "job"}}, cache.GetPodOwners("default", "pod2"))
+	assert.Equal(t, apiv1.NamespacesPodsStringsSet{
+		"default": {"pod1": sets.NewString("svc1", "svc2")},
+	}, cache.GetPodsMetadata())
+
+	// Heartbeats only update the token
+	lastChange := cache.LastChange()
+	cache.handle(apiv1.MetadataEvent{Token: "abc-4", Type: apiv1.MetadataEventHeartbeat})
+	assert.Equal(t, "abc-4", cache.getToken())
+	assert.Equal(t, lastChange, cache.LastChange())
+
+	cache.handle(apiv1.MetadataEvent{Token: "abc-5", Type: apiv1.MetadataEventDelete, Kind: apiv1.MetadataKindPod, Namespace: "default", Name: "pod1"})
+	assert.Empty(t, cache.GetPodsMetadata())
+	assert.True(t, cache.LastChange().After(lastChange))
+
+	cache.handle(apiv1.MetadataEvent{Type: apiv1.MetadataEventReset})
+	assert.False(t, cache.IsSynced())
+	assert.Nil(t, cache.GetNodeLabels())
+	assert.Nil(t, cache.GetNamespaceLabels("default"))
+	assert.Nil(t, cache.GetPodOwners("default", "pod2"))
+}
+
+// fakeStreamClient serves the metadata stream, the other methods are not implemented
+type fakeStreamClient struct {
+	DCAClientInterface
+	tokens  []string
+	streams [][]apiv1.MetadataEvent
+	cancel  context.CancelFunc
+}
+
+func (f *fakeStreamClient) StreamMetadata(ctx context.Context, nodeName, token string, handle func(apiv1.MetadataEvent)) error {
+	f.tokens = append(f.tokens, token)
+	if len(f.streams) == 0 {
+		f.cancel()
+		return ctx.Err()
+	}
+	for _, event := range f.streams[0] {
+		handle(event)
+	}
+	f.streams = f.streams[1:]
+	return errors.New("stream closed")
+}
+
+func TestMetadataCacheRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &fakeStreamClient{
+		streams: [][]apiv1.MetadataEvent{{
+			{Type: apiv1.MetadataEventReset},
+			{Token: "abc-1", Type: apiv1.MetadataEventSet, Kind: apiv1.MetadataKindPod, Namespace: "default", Name: "pod1", Services: []string{"svc1"}},
+			{Token: "abc-1", Type: apiv1.MetadataEventSynced},
+		}},
+		cancel: cancel,
+	}
+
+	cache := NewMetadataCache(client, "mynode")
+	cache.Run(ctx)
+
+	// The stream is resumed with the last token received
+	assert.Equal(t, []string{"", "abc-1"}, client.tokens)
+	assert.True(t, cache.IsSynced())
+	assert.Equal(t, apiv1.NamespacesPodsStringsSet{
+		"default": {"pod1": sets.NewString("svc1")},
+	}, cache.GetPodsMetadata())
+}
+
+func TestGetSyncedMetadataCache(t *testing.T) {
+	defer func() { globalMetadataCache = nil }()
+	assert.Nil(t, GetSyncedMetadataCache())
+
+	globalMetadataCache = NewMetadataCache(nil, "mynode")
+	assert.Nil(t, GetSyncedMetadataCache())
+
+	globalMetadataCache.handle(apiv1.MetadataEvent{Type: apiv1.MetadataEventSynced})
+	assert.Equal(t, globalMetadataCache, GetSyncedMetadataCache())
+}
@@ -16,6 +16,8 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -59,6 +61,13 @@ type APIClient struct {
 
 	// WPAInformerFactory gives access to informers for Watermark Pod Autoscalers.
 	WPAInformerFactory wpa_informers.SharedInformerFactory
+
+	// DynamicCl gives access to custom resources
+	DynamicCl dynamic.Interface
+
+	// DynamicInformerFactory gives access to informers for custom resources.
+	DynamicInformerFactory dynamicinformer.DynamicSharedInformerFactory
+
 	// used to setup the APIClient
 	initRetry      retry.Retrier
 	Cl             kubernetes.Interface
@@ -140,6 +149,27 @@ func getWPAInformerFactory() (wpa_informers.SharedInformerFactory, error) {
 	return wpa_informers.NewSharedInformerFactory(client, resyncPeriodSeconds*time.Second), nil
 }
 
+func getDynamicClient(timeout time.Duration) (dynamic.Interface, error) {
+	clientConfig, err := getClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	// custom resources are not available in protobuf
+	clientConfig.ContentType = ""
+	clientConfig.Timeout = timeout
+	return dynamic.NewForConfig(clientConfig)
+}
+
+func getDynamicInformerFactory() (dynamicinformer.DynamicSharedInformerFactory, error) {
+	resyncPeriodSeconds := time.Duration(config.Datadog.GetInt64("kubernetes_informers_resync_period"))
+	client, err := getDynamicClient(0) // No timeout for the Informers, to allow long watch.
+	if err != nil {
+		log.Infof("Could not get apiserver client: %v", err)
+		return nil, err
+	}
+	return dynamicinformer.NewDynamicSharedInformerFactory(client, resyncPeriodSeconds*time.Second), nil
+}
+
 func getInformerFactory() (informers.SharedInformerFactory, error) {
 	resyncPeriodSeconds := time.Duration(config.Datadog.GetInt64("kubernetes_informers_resync_period"))
 	client, err := getKubeClient(0) // No timeout for the Informers, to allow long watch.
@@ -162,6 +192,13 @@ func (c *APIClient) connect() error {
 	if err != nil {
 		return err
 	}
+	// the dynamic informer factory only starts the informers of the custom resources requested
+	if c.DynamicCl, err = getDynamicClient(time.Duration(c.timeoutSeconds) * time.Second); err != nil {
+		return err
+	}
+	if c.DynamicInformerFactory, err = getDynamicInformerFactory(); err != nil {
+		return err
+	}
 	if config.Datadog.GetBool("external_metrics_provider.wpa_controller") {
 		if c.WPAInformerFactory, err = getWPAInformerFactory(); err != nil {
 			log.Errorf("Error getting WPA Informer Factory: %s", err.Error())
@@ -28,7 +28,7 @@ import (
 )
 
 // NewAutoscalersController returns a new AutoscalersController
-func NewAutoscalersController(client kubernetes.Interface, eventRecorder record.EventRecorder, le LeaderElectorInterface, dogCl autoscalers.DatadogClient) (*AutoscalersController, error) {
+func NewAutoscalersController(client kubernetes.Interface, eventRecorder record.EventRecorder, le LeaderElectorInterface, backend autoscalers.MetricsBackend) (*AutoscalersController, error) {
 	var err error
 
 	h := &AutoscalersController{
@@ -54,7 +54,7 @@ func NewAutoscalersController(client kubernetes.Interface, eventRecorder record.
 	}
 
 	// Setup the client to process the Ref and metrics
-	h.hpaProc, err = autoscalers.NewProcessor(dogCl)
+	h.hpaProc, err = autoscalers.NewProcessor(backend)
 	if err != nil {
 		log.Errorf("Could not instantiate the Ref Processor: %v", err.Error())
 		return nil, err
@@ -184,8 +184,13 @@ func (h *AutoscalersController) updateExternalMetrics() {
 		if _, ok := globalCache[i]; !ok {
 			globalCache[i] = j
 		} else {
-			if !reflect.DeepEqual(j.Labels, globalCache[i].Labels) {
+			if !reflect.DeepEqual(j.Labels, globalCache[i].Labels) || j.Query != globalCache[i].Query {
 				globalCache[i] = j
+			} else if !reflect.DeepEqual(j.Fallback, globalCache[i].Fallback) {
+				// The last value stays relevant when only the fallback changes
+				em := globalCache[i]
+				em.Fallback = j.Fallback
+				globalCache[i] = em
 			}
 		}
 	}
@@ -8,6 +8,7 @@
 package apiserver
 
 import (
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/externalseries"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/autoscalers"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -47,6 +48,7 @@ type ControllerContext struct {
 	LeaderElector      LeaderElectorInterface
 	EventRecorder      record.EventRecorder
 	StopCh             chan struct{}
+	ExternalSeries     *externalseries.Store
 }
 
 // StartControllers runs the enabled Kubernetes controllers for the Datadog Cluster Agent. This is
@@ -86,7 +88,7 @@ func startMetadataController(ctx ControllerContext) error {
 }
 
 func startAutoscalersController(ctx ControllerContext) error {
-	dogCl, err := autoscalers.NewDatadogClient()
+	backend, err := autoscalers.NewMetricsBackend(ctx.ExternalSeries)
 	if err != nil {
 		return err
 	}
@@ -94,7 +96,7 @@ func startAutoscalersController(ctx ControllerContext) error {
 		ctx.Client,
 		ctx.EventRecorder,
 		ctx.LeaderElector,
-		dogCl,
+		backend,
 	)
 	if err != nil {
 		return err
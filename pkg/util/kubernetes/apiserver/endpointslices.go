@@ -0,0 +1,211 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package apiserver
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// EndpointSliceServiceNameLabel is the label holding the name of the service of an EndpointSlice
+	EndpointSliceServiceNameLabel = "kubernetes.io/service-name"
+
+	endpointSliceHostnameTopology = "kubernetes.io/hostname"
+)
+
+// EndpointSliceGVR identifies the EndpointSlice resources. They are read with
+// the dynamic client as the Kubernetes API vendored predates them.
+var EndpointSliceGVR = schema.GroupVersionResource{
+	Group:    "discovery.k8s.io",
+	Version:  "v1beta1",
+	Resource: "endpointslices",
+}
+
+// EndpointSlice holds the fields of an EndpointSlice used by the endpoints checks
+type EndpointSlice struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	AddressType string                  `json:"addressType"`
+	Endpoints   []EndpointSliceEndpoint `json:"endpoints"`
+	Ports       []EndpointSlicePort     `json:"ports,omitempty"`
+}
+
+// EndpointSliceEndpoint is an endpoint of an EndpointSlice
+type EndpointSliceEndpoint struct {
+	Addresses  []string            `json:"addresses"`
+	Conditions EndpointConditions  `json:"conditions,omitempty"`
+	TargetRef  *v1.ObjectReference `json:"targetRef,omitempty"`
+	Topology   map[string]string   `json:"topology,omitempty"`
+	NodeName   *string             `json:"nodeName,omitempty"`
+}
+
+// EndpointConditions holds the conditions of an endpoint
+type EndpointConditions struct {
+	Ready *bool `json:"ready,omitempty"`
+}
+
+// EndpointSlicePort is a port of an EndpointSlice
+type EndpointSlicePort struct {
+	Name *string `json:"name,omitempty"`
+	Port *int32  `json:"port,omitempty"`
+}
+
+// EndpointAddress is a ready address of the endpoints of a service, read from
+// an Endpoints or EndpointSlice object
+type EndpointAddress struct {
+	IP        string
+	NodeName  string
+	TargetRef *v1.ObjectReference
+}
+
+// UseEndpointSlices returns whether the endpoints checks should read the
+// EndpointSlices instead of the Endpoints, if enabled and served by the API server.
+func UseEndpointSlices(ac *APIClient) bool {
+	if !config.Datadog.GetBool("kubernetes_use_endpoint_slices") {
+		return false
+	}
+	_, err := ac.Cl.Discovery().ServerResourcesForGroupVersion(EndpointSliceGVR.GroupVersion().String())
+	if err != nil {
+		log.Warnf("EndpointSlices are not served by the API server, using Endpoints instead: %v", err)
+		return false
+	}
+	return true
+}
+
+// ParseEndpointSlice converts an EndpointSlice read with the dynamic client
+func ParseEndpointSlice(obj interface{}) (*EndpointSlice, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("expected an unstructured EndpointSlice, got: %T", obj)
+	}
+	slice := &EndpointSlice{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), slice); err != nil {
+		return nil, fmt.Errorf("invalid EndpointSlice %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+	}
+	return slice, nil
+}
+
+// ServiceName returns the name of the service of the EndpointSlice
+func (s *EndpointSlice) ServiceName() string {
+	return s.Labels[EndpointSliceServiceNameLabel]
+}
+
+// ReadyAddresses returns the ready addresses of the EndpointSlice. Only IP
+// addresses are returned, IPv4 and IPv6 addresses are in different slices.
+func (s *EndpointSlice) ReadyAddresses() []EndpointAddress {
+	// "IP" is the address type of the v1alpha1 API for both IPv4 and IPv6
+	if s.AddressType != "IPv4" && s.AddressType != "IPv6" && s.AddressType != "IP" {
+		return nil
+	}
+	var addresses []EndpointAddress
+	for _, endpoint := range s.Endpoints {
+		// An unknown condition must be interpreted as ready
+		if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+			continue
+		}
+		nodeName := endpoint.Topology[endpointSliceHostnameTopology]
+		if endpoint.NodeName != nil {
+			nodeName = *endpoint.NodeName
+		}
+		for _, ip := range endpoint.Addresses {
+			addresses = append(addresses, EndpointAddress{
+				IP:        ip,
+				NodeName:  nodeName,
+				TargetRef: endpoint.TargetRef,
+			})
+		}
+	}
+	return addresses
+}
+
+// ReadyAddressesForSlices returns the ready addresses of the EndpointSlices of a
+// service. An address can transiently be in several slices, it is returned once.
+func ReadyAddressesForSlices(slices []*EndpointSlice) []EndpointAddress {
+	var addresses []EndpointAddress
+	seen := make(map[string]bool)
+	for _, slice := range slices {
+		for _, address := range slice.ReadyAddresses() {
+			if seen[address.IP] {
+				continue
+			}
+			seen[address.IP] = true
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// ReadyAddressesForEndpoints returns the ready addresses of an Endpoints
+func ReadyAddressesForEndpoints(kep *v1.Endpoints) []EndpointAddress {
+	var addresses []EndpointAddress
+	for _, subset := range kep.Subsets {
+		for _, address := range subset.Addresses {
+			endpointAddress := EndpointAddress{
+				IP:        address.IP,
+				TargetRef: address.TargetRef,
+			}
+			if address.NodeName != nil {
+				endpointAddress.NodeName = *address.NodeName
+			}
+			addresses = append(addresses, endpointAddress)
+		}
+	}
+	return addresses
+}
+
+// FilterReadyPods drops the addresses backed by pods which are not ready, their
+// readiness gates included. The endpoints of the services publishing the not
+// ready addresses, usually headless services, are reported ready regardless.
+func FilterReadyPods(podLister listersv1.PodLister, addresses []EndpointAddress) []EndpointAddress {
+	var ready []EndpointAddress
+	for _, address := range addresses {
+		if IsTargetPodReady(podLister, address.TargetRef) {
+			ready = append(ready, address)
+		}
+	}
+	return ready
+}
+
+// IsTargetPodReady returns false if the target of an endpoint is a pod which is not ready
+func IsTargetPodReady(podLister listersv1.PodLister, ref *v1.ObjectReference) bool {
+	if ref == nil || ref.Kind != "Pod" {
+		return true
+	}
+	pod, err := podLister.Pods(ref.Namespace).Get(ref.Name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Debugf("Unable to retrieve pod %s/%s: %v", ref.Namespace, ref.Name, err)
+		}
+		// Trust the endpoints until the pod is known
+		return true
+	}
+	return IsPodReady(pod)
+}
+
+// IsPodReady returns whether the Ready condition of a pod is true, it takes
+// the readiness gates of the pod into account.
+func IsPodReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
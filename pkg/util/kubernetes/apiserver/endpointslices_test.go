@@ -0,0 +1,162 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package apiserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestParseEndpointSlice(t *testing.T) {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "discovery.k8s.io/v1beta1",
+		"kind":       "EndpointSlice",
+		"metadata": map[string]interface{}{
+			"name":      "myservice-abcde",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"kubernetes.io/service-name": "myservice"},
+		},
+		"addressType": "IPv6",
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"addresses":  []interface{}{"fd00::1"},
+				"conditions": map[string]interface{}{"ready": true},
+				"targetRef":  map[string]interface{}{"kind": "Pod", "name": "pod1", "namespace": "default", "uid": "pod-uid-1"},
+				"topology":   map[string]interface{}{"kubernetes.io/hostname": "node1"},
+			},
+		},
+		"ports": []interface{}{
+			map[string]interface{}{"name": "http", "port": int64(80)},
+		},
+	}}
+
+	slice, err := ParseEndpointSlice(u)
+	require.NoError(t, err)
+	assert.Equal(t, "myservice", slice.ServiceName())
+	assert.Equal(t, "IPv6", slice.AddressType)
+	require.Len(t, slice.Ports, 1)
+	assert.Equal(t, int32(80), *slice.Ports[0].Port)
+	assert.Equal(t, []EndpointAddress{{
+		IP:        "fd00::1",
+		NodeName:  "node1",
+		TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "pod1", Namespace: "default", UID: "pod-uid-1"},
+	}}, slice.ReadyAddresses())
+
+	_, err = ParseEndpointSlice(&v1.Endpoints{})
+	assert.Error(t, err)
+}
+
+func TestReadyAddressesForSlices(t *testing.T) {
+	ready, notReady := true, false
+	nodeName := "node2"
+	slices := []*EndpointSlice{
+		{
+			AddressType: "IPv4",
+			Endpoints: []EndpointSliceEndpoint{
+				{Addresses: []string{"10.0.0.1"}, Conditions: EndpointConditions{Ready: &ready}, Topology: map[string]string{"kubernetes.io/hostname": "node1"}},
+				{Addresses: []string{"10.0.0.2"}, Conditions: EndpointConditions{Ready: &notReady}},
+				// Unknown readiness
+				{Addresses: []string{"10.0.0.3"}, NodeName: &nodeName},
+			},
+		},
+		{
+			AddressType: "IPv6",
+			Endpoints: []EndpointSliceEndpoint{
+				{Addresses: []string{"fd00::1"}, Conditions: EndpointConditions{Ready: &ready}},
+			},
+		},
+		{
+			AddressType: "FQDN",
+			Endpoints: []EndpointSliceEndpoint{
+				{Addresses: []string{"myservice.example.com"}},
+			},
+		},
+		{
+			// The address is moving between two slices
+			AddressType: "IPv4",
+			Endpoints: []EndpointSliceEndpoint{
+				{Addresses: []string{"10.0.0.1"}, Conditions: EndpointConditions{Ready: &ready}},
+			},
+		},
+	}
+
+	assert.Equal(t, []EndpointAddress{
+		{IP: "10.0.0.1", NodeName: "node1"},
+		{IP: "10.0.0.3", NodeName: "node2"},
+		{IP: "fd00::1"},
+	}, ReadyAddressesForSlices(slices))
+}
+
+func TestReadyAddressesForEndpoints(t *testing.T) {
+	nodeName := "node1"
+	kep := &v1.Endpoints{
+		Subsets: []v1.EndpointSubset{
+			{
+				Addresses: []v1.EndpointAddress{
+					{IP: "10.0.0.1", NodeName: &nodeName, TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "pod1"}},
+					{IP: "10.0.0.2"},
+				},
+				NotReadyAddresses: []v1.EndpointAddress{
+					{IP: "10.0.0.3"},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, []EndpointAddress{
+		{IP: "10.0.0.1", NodeName: "node1", TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "pod1"}},
+		{IP: "10.0.0.2"},
+	}, ReadyAddressesForEndpoints(kep))
+}
+
+func newFakeReadinessPod(name string, ready v1.ConditionStatus) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status: v1.PodStatus{
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: ready}},
+		},
+	}
+}
+
+func TestFilterReadyPods(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, indexer.Add(newFakeReadinessPod("ready", v1.ConditionTrue)))
+	// A pod with running containers but unmet readiness gates is not ready
+	require.NoError(t, indexer.Add(newFakeReadinessPod("notready", v1.ConditionFalse)))
+	podLister := listersv1.NewPodLister(indexer)
+
+	podRef := func(name string) *v1.ObjectReference {
+		return &v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: name}
+	}
+	addresses := []EndpointAddress{
+		{IP: "10.0.0.1", TargetRef: podRef("ready")},
+		{IP: "10.0.0.2", TargetRef: podRef("notready")},
+		{IP: "10.0.0.3", TargetRef: podRef("unknown")},
+		{IP: "10.0.0.4"},
+	}
+
+	assert.Equal(t, []EndpointAddress{
+		{IP: "10.0.0.1", TargetRef: podRef("ready")},
+		{IP: "10.0.0.3", TargetRef: podRef("unknown")},
+		{IP: "10.0.0.4"},
+	}, FilterReadyPods(podLister, addresses))
+}
+
+func TestIsPodReady(t *testing.T) {
+	assert.True(t, IsPodReady(newFakeReadinessPod("pod", v1.ConditionTrue)))
+	assert.False(t, IsPodReady(newFakeReadinessPod("pod", v1.ConditionFalse)))
+	assert.False(t, IsPodReady(newFakeReadinessPod("pod", v1.ConditionUnknown)))
+	assert.False(t, IsPodReady(&v1.Pod{}))
+}
@@ -74,7 +74,7 @@ func newFakeAutoscalerController(t *testing.T, client kubernetes.Interface, itf
 		client,
 		eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "FakeAutoscalerController"}),
 		itf,
-		dcl,
+		autoscalers.NewDatadogBackend(dcl),
 	)
 	autoscalerController.EnableHPA(informerFactory.Autoscaling().V2beta1().HorizontalPodAutoscalers())
 
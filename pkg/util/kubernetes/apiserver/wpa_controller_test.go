@@ -161,7 +161,7 @@ func newFakeWPAController(t *testing.T, kubeClient kubernetes.Interface, client
 		kubeClient,
 		eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "FakeWPAController"}),
 		itf,
-		dcl,
+		autoscalers.NewDatadogBackend(dcl),
 	)
 
 	autoscalerController.enableWPA(inf)
@@ -8,7 +8,10 @@
 package autoscalers
 
 import (
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
 	autoscalingv2 "k8s.io/api/autoscaling/v2beta1"
 
@@ -17,6 +20,44 @@ import (
 	"github.com/DataDog/watermarkpodautoscaler/pkg/apis/datadoghq/v1alpha1"
 )
 
+const (
+	// externalMetricsAnnotationPrefix prefixes the autoscaler annotations configuring an external metric,
+	// the annotation name is the metric name followed by the option, e.g. `<prefix><metric_name>.query`.
+	externalMetricsAnnotationPrefix = "external-metrics.datadoghq.com/"
+	queryAnnotationSuffix           = ".query"
+	fallbackAnnotationSuffix        = ".fallback"
+)
+
+// setQueryAndFallback sets the query expression and the fallback of an external metric
+// from the annotations of its autoscaler.
+func setQueryAndFallback(em *custommetrics.ExternalMetricValue, annotations map[string]string) {
+	if query, found := annotations[externalMetricsAnnotationPrefix+em.MetricName+queryAnnotationSuffix]; found {
+		em.Query = strings.TrimSpace(query)
+	}
+	if value, found := annotations[externalMetricsAnnotationPrefix+em.MetricName+fallbackAnnotationSuffix]; found {
+		fallback, err := parseFallback(value)
+		if err != nil {
+			log.Errorf("Invalid fallback for the external metric %s of %s/%s, using the default one: %v", em.MetricName, em.Ref.Namespace, em.Ref.Name, err)
+			return
+		}
+		em.Fallback = fallback
+	}
+}
+
+// parseFallback parses a fallback annotation: `fail`, `last_value` or a static value.
+func parseFallback(value string) (*custommetrics.MetricFallback, error) {
+	value = strings.TrimSpace(value)
+	switch fallbackType := custommetrics.FallbackType(value); fallbackType {
+	case custommetrics.FallbackFail, custommetrics.FallbackLastValue:
+		return &custommetrics.MetricFallback{Type: fallbackType}, nil
+	}
+	static, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("expected %q, %q or a number, got %q", custommetrics.FallbackFail, custommetrics.FallbackLastValue, value)
+	}
+	return &custommetrics.MetricFallback{Type: custommetrics.FallbackStatic, Value: static}, nil
+}
+
 // InspectHPA returns the list of external metrics from the hpa to use for autoscaling.
 func InspectHPA(hpa *autoscalingv2.HorizontalPodAutoscaler) (emList []custommetrics.ExternalMetricValue) {
 	for _, metricSpec := range hpa.Spec.Metrics {
@@ -39,6 +80,7 @@ func InspectHPA(hpa *autoscalingv2.HorizontalPodAutoscaler) (emList []custommetr
 			if metricSpec.External.MetricSelector != nil {
 				em.Labels = metricSpec.External.MetricSelector.MatchLabels
 			}
+			setQueryAndFallback(&em, hpa.Annotations)
 			emList = append(emList, em)
 		default:
 			log.Debugf("Unsupported metric type %s", metricSpec.Type)
@@ -69,6 +111,7 @@ func InspectWPA(wpa *v1alpha1.WatermarkPodAutoscaler) (emList []custommetrics.Ex
 			if metricSpec.External.MetricSelector != nil {
 				em.Labels = metricSpec.External.MetricSelector.MatchLabels
 			}
+			setQueryAndFallback(&em, wpa.Annotations)
 			emList = append(emList, em)
 		default:
 			log.Debugf("Unsupported metric type %s", metricSpec.Type)
@@ -101,7 +144,8 @@ func DiffExternalMetrics(informerList []*autoscalingv2.HorizontalPodAutoscaler,
 			// We have previously processed an external metric from this Ref.
 			// Check that it's still the same. If not, remove the entry from the Global Store.
 			// Use the Ref Type to get rid of the old template in the Store
-			if em.MetricName == m.MetricName && reflect.DeepEqual(em.Labels, m.Labels) && em.Ref.Type == m.Ref.Type {
+			if em.MetricName == m.MetricName && reflect.DeepEqual(em.Labels, m.Labels) && em.Ref.Type == m.Ref.Type &&
+				em.Query == m.Query && reflect.DeepEqual(em.Fallback, m.Fallback) {
 				found = true
 				break
 			}
@@ -360,6 +360,50 @@ func TestInspect(t *testing.T) {
 				},
 			},
 		},
+		"with query and fallback annotations": {
+			&autoscalingv2.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"external-metrics.datadoghq.com/requests_per_replica.query":    "sum:requests{service:web}.as_count() / sum:kubernetes_state.deployment.replicas_available{deployment:web}",
+						"external-metrics.datadoghq.com/requests_per_replica.fallback": "last_value",
+						"external-metrics.datadoghq.com/requests_per_s.fallback":       "1.5",
+					},
+				},
+				Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+					Metrics: []autoscalingv2.MetricSpec{
+						{
+							Type: autoscalingv2.ExternalMetricSourceType,
+							External: &autoscalingv2.ExternalMetricSource{
+								MetricName: "requests_per_replica",
+							},
+						},
+						{
+							Type: autoscalingv2.ExternalMetricSourceType,
+							External: &autoscalingv2.ExternalMetricSource{
+								MetricName: metricName,
+							},
+						},
+					},
+				},
+			},
+			[]custommetrics.ExternalMetricValue{
+				{
+					MetricName: "requests_per_replica",
+					Ref: custommetrics.ObjectReference{
+						Type: "horizontal",
+					},
+					Query:    "sum:requests{service:web}.as_count() / sum:kubernetes_state.deployment.replicas_available{deployment:web}",
+					Fallback: &custommetrics.MetricFallback{Type: custommetrics.FallbackLastValue},
+				},
+				{
+					MetricName: metricName,
+					Ref: custommetrics.ObjectReference{
+						Type: "horizontal",
+					},
+					Fallback: &custommetrics.MetricFallback{Type: custommetrics.FallbackStatic, Value: 1.5},
+				},
+			},
+		},
 	}
 
 	for name, testCase := range testCases {
@@ -370,6 +414,22 @@ func TestInspect(t *testing.T) {
 	}
 }
 
+func TestParseFallback(t *testing.T) {
+	for value, expected := range map[string]*custommetrics.MetricFallback{
+		"fail":         {Type: custommetrics.FallbackFail},
+		" last_value ": {Type: custommetrics.FallbackLastValue},
+		"0":            {Type: custommetrics.FallbackStatic, Value: 0},
+		"12.5":         {Type: custommetrics.FallbackStatic, Value: 12.5},
+	} {
+		fallback, err := parseFallback(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, fallback)
+	}
+
+	_, err := parseFallback("min")
+	assert.Error(t, err)
+}
+
 func makeSpec(metricName string, labels map[string]string) autoscalingv2.HorizontalPodAutoscalerSpec {
 	return autoscalingv2.HorizontalPodAutoscalerSpec{
 		Metrics: []autoscalingv2.MetricSpec{
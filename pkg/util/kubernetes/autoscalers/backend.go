@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package autoscalers
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/custommetrics"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/externalseries"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+const (
+	datadogBackendName    = "datadog"
+	prometheusBackendName = "prometheus"
+	nodeAgentBackendName  = "node_agent"
+)
+
+// MetricsBackend is a source of values for the external metrics
+type MetricsBackend interface {
+	// QueryExternalMetrics returns the most recent point of the external metrics, keyed by QueryKey.
+	// Metrics without value can be omitted or returned as invalid points.
+	QueryExternalMetrics(emList map[string]custommetrics.ExternalMetricValue) (map[string]Point, error)
+}
+
+// Point is the value of an external metric returned by a backend
+type Point struct {
+	value     float64
+	timestamp int64
+	valid     bool
+}
+
+// NewPoint returns a valid point, the timestamp is in seconds
+func NewPoint(value float64, timestamp int64) Point {
+	return Point{value: value, timestamp: timestamp, valid: true}
+}
+
+// QueryKey returns the key of an external metric in the points returned by the backends
+func QueryKey(em custommetrics.ExternalMetricValue) string {
+	if em.Query != "" {
+		return em.Query
+	}
+	return getKey(em.MetricName, em.Labels)
+}
+
+// NewMetricsBackend returns the backend configured with `external_metrics_provider.backend`,
+// the node agent backend serves the series of the store.
+func NewMetricsBackend(store *externalseries.Store) (MetricsBackend, error) {
+	switch backend := config.Datadog.GetString("external_metrics_provider.backend"); backend {
+	case datadogBackendName:
+		client, err := NewDatadogClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewDatadogBackend(client), nil
+	case prometheusBackendName:
+		return NewPrometheusBackend(config.Datadog.GetString("external_metrics_provider.prometheus_url"))
+	case nodeAgentBackendName:
+		return NewNodeAgentBackend(store)
+	default:
+		return nil, fmt.Errorf("unknown external metrics backend %q, choose from [%s,%s,%s]", backend, datadogBackendName, prometheusBackendName, nodeAgentBackendName)
+	}
+}
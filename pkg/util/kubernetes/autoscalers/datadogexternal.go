@@ -12,11 +12,13 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/zorkian/go-datadog-api.v2"
 	utilserror "k8s.io/apimachinery/pkg/util/errors"
 
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/custommetrics"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
@@ -47,26 +49,112 @@ var (
 		telemetry.Options{NoDoubleUnderscoreSep: true})
 )
 
-type Point struct {
-	value     float64
-	timestamp int64
-	valid     bool
-}
-
 const (
 	value         = 1
 	timestamp     = 0
 	queryEndpoint = "/api/v1/query"
 )
 
+// chunkSize ensures batch queries are limited in size.
+const chunkSize = 45
+
+// DatadogClient is the client used by the Datadog backend to query metrics
+type DatadogClient interface {
+	QueryMetrics(from, to int64, query string) ([]datadog.Series, error)
+	GetRateLimitStats() map[string]datadog.RateLimit
+}
+
+// datadogBackend queries the external metrics from the Datadog API
+type datadogBackend struct {
+	client DatadogClient
+}
+
+// NewDatadogBackend returns a backend querying the external metrics from the Datadog API
+func NewDatadogBackend(client DatadogClient) MetricsBackend {
+	return &datadogBackend{client: client}
+}
+
+// queryResponse ensures that we capture all the signals from the call to Datadog's backend.
+type queryResponse struct {
+	metrics map[string]Point
+	err     error
+}
+
+func makeChunks(batch []string) (chunks [][]string) {
+	for i := 0; i < len(batch); i += chunkSize {
+		if i+chunkSize > len(batch) {
+			chunks = append(chunks, batch[i:])
+			break
+		}
+		chunks = append(chunks, batch[i:i+chunkSize])
+	}
+	return chunks
+}
+
+// QueryExternalMetrics queries Datadog to validate the availability and value of one or more external metrics
+// Also updates the rate limits statistics as a result of the query.
+func (d *datadogBackend) QueryExternalMetrics(emList map[string]custommetrics.ExternalMetricValue) (processed map[string]Point, err error) {
+	batch := []string{}
+	expressions := make(map[string]struct{})
+	for _, e := range emList {
+		// query expressions cannot be batched
+		if e.Query != "" {
+			expressions[e.Query] = struct{}{}
+			continue
+		}
+		q := getKey(e.MetricName, e.Labels)
+		batch = append(batch, q)
+	}
+	chunks := makeChunks(batch)
+	log.Tracef("List of batches %v", chunks)
+
+	// we have a number of chunks with `chunkSize` metrics and a query per expression.
+	responses := make(chan queryResponse, len(chunks)+len(expressions))
+	processed = make(map[string]Point)
+
+	var waitResp sync.WaitGroup
+	waitResp.Add(len(chunks) + len(expressions))
+	for _, c := range chunks {
+		go func(chunk []string) {
+			defer waitResp.Done()
+			resp, err := d.queryDatadogExternal(chunk)
+			responses <- queryResponse{resp, err}
+		}(c)
+	}
+	for e := range expressions {
+		go func(expression string) {
+			defer waitResp.Done()
+			point, err := d.queryDatadogExpression(expression)
+			responses <- queryResponse{map[string]Point{expression: point}, err}
+		}(e)
+	}
+	waitResp.Wait()
+	close(responses)
+	var errors []error
+	for elem := range responses {
+		for k, v := range elem.metrics {
+			processed[k] = v
+		}
+		if elem.err != nil {
+			errors = append(errors, elem.err)
+		}
+	}
+	log.Debugf("Processed %d chunks and %d query expressions", len(chunks), len(expressions))
+
+	if err := d.updateRateLimitingMetrics(); err != nil {
+		errors = append(errors, err)
+	}
+	return processed, utilserror.NewAggregate(errors)
+}
+
 // queryDatadogExternal converts the metric name and labels from the Ref format into a Datadog metric.
 // It returns the last value for a bucket of 5 minutes,
-func (p *Processor) queryDatadogExternal(metricNames []string) (map[string]Point, error) {
+func (d *datadogBackend) queryDatadogExternal(metricNames []string) (map[string]Point, error) {
 	if metricNames == nil {
 		log.Tracef("No processed external metrics to query")
 		return nil, nil
 	}
-	// TODO move viper parameters to the Processor struct
+	// TODO move viper parameters to the datadogBackend struct
 	bucketSize := config.Datadog.GetInt64("external_metrics_provider.bucket_size")
 
 	aggregator := config.Datadog.GetString("external_metrics.aggregator")
@@ -78,7 +166,7 @@ func (p *Processor) queryDatadogExternal(metricNames []string) (map[string]Point
 
 	query := strings.Join(toQuery, ",")
 
-	seriesSlice, err := p.datadogClient.QueryMetrics(time.Now().Unix()-bucketSize, time.Now().Unix(), query)
+	seriesSlice, err := d.client.QueryMetrics(time.Now().Unix()-bucketSize, time.Now().Unix(), query)
 	if err != nil {
 		ddRequests.Inc("error")
 		return nil, log.Errorf("Error while executing metric query %s: %s", query, err)
@@ -105,41 +193,84 @@ func (p *Processor) queryDatadogExternal(metricNames []string) (map[string]Point
 			continue
 		}
 
-		// Use on the penultimate bucket, since the very last bucket can be subject to variations due to late points.
-		var skippedLastPoint bool
-		var point Point
-		// Find the most recent value.
-		for i := len(serie.Points) - 1; i >= 0; i-- {
-			if serie.Points[i][value] == nil {
-				// We need this as if multiple metrics are queried, their points' timestamps align this can result in empty values.
-				continue
-			}
-			// We need at least 2 points per window queried on batched metrics.
-			// If a single sparse metric is processed and only has 1 point in the window, use the value.
-			if !skippedLastPoint && len(serie.Points) > 1 {
-				// Skip last point unless the query window only contains one valid point
-				skippedLastPoint = true
-				continue
-			}
-			point.value = *serie.Points[i][value]                       // store the original value
-			point.timestamp = int64(*serie.Points[i][timestamp] / 1000) // Datadog's API returns timestamps in s
-			point.valid = true
-
-			m := fmt.Sprintf("%s{%s}", *serie.Metric, *serie.Scope)
-			processedMetrics[m] = point
-
-			// Prometheus submissions on the processed external metrics
-			metricsEval.Set(point.value, m)
-			precision := time.Now().Unix() - point.timestamp
-			metricsDelay.Set(float64(precision), m)
-
-			log.Debugf("Validated %s | Value:%v at %d after %d/%d buckets", m, point.value, point.timestamp, i+1, len(serie.Points))
-			break
+		point, bucket := lastPoint(serie)
+		if !point.valid {
+			continue
 		}
+
+		m := fmt.Sprintf("%s{%s}", *serie.Metric, *serie.Scope)
+		processedMetrics[m] = point
+
+		// Prometheus submissions on the processed external metrics
+		metricsEval.Set(point.value, m)
+		precision := time.Now().Unix() - point.timestamp
+		metricsDelay.Set(float64(precision), m)
+
+		log.Debugf("Validated %s | Value:%v at %d after %d/%d buckets", m, point.value, point.timestamp, bucket+1, len(serie.Points))
 	}
 	return processedMetrics, nil
 }
 
+// queryDatadogExpression evaluates a full query expression, which must return a single series.
+// Expressions are queried one by one as the series returned cannot be matched back to their expression.
+func (d *datadogBackend) queryDatadogExpression(query string) (Point, error) {
+	bucketSize := config.Datadog.GetInt64("external_metrics_provider.bucket_size")
+	invalid := Point{timestamp: time.Now().Unix()}
+
+	seriesSlice, err := d.client.QueryMetrics(time.Now().Unix()-bucketSize, time.Now().Unix(), query)
+	if err != nil {
+		ddRequests.Inc("error")
+		return invalid, log.Errorf("Error while executing metric query %s: %s", query, err)
+	}
+	ddRequests.Inc("success")
+
+	if len(seriesSlice) == 0 {
+		return invalid, fmt.Errorf("returned series slice empty for the query %s", query)
+	}
+	if len(seriesSlice) > 1 {
+		return invalid, fmt.Errorf("the query %s returned %d series, it must return a single series", query, len(seriesSlice))
+	}
+
+	point, bucket := lastPoint(seriesSlice[0])
+	if !point.valid {
+		return invalid, fmt.Errorf("no value returned for the query %s", query)
+	}
+
+	// Prometheus submissions on the processed external metrics
+	metricsEval.Set(point.value, query)
+	precision := time.Now().Unix() - point.timestamp
+	metricsDelay.Set(float64(precision), query)
+
+	log.Debugf("Validated %s | Value:%v at %d after %d/%d buckets", query, point.value, point.timestamp, bucket+1, len(seriesSlice[0].Points))
+	return point, nil
+}
+
+// lastPoint returns the most recent complete point of a series and its bucket, the point is invalid if none is found.
+func lastPoint(serie datadog.Series) (Point, int) {
+	// Use on the penultimate bucket, since the very last bucket can be subject to variations due to late points.
+	var skippedLastPoint bool
+	var point Point
+	// Find the most recent value.
+	for i := len(serie.Points) - 1; i >= 0; i-- {
+		if serie.Points[i][value] == nil {
+			// We need this as if multiple metrics are queried, their points' timestamps align this can result in empty values.
+			continue
+		}
+		// We need at least 2 points per window queried on batched metrics.
+		// If a single sparse metric is processed and only has 1 point in the window, use the value.
+		if !skippedLastPoint && len(serie.Points) > 1 {
+			// Skip last point unless the query window only contains one valid point
+			skippedLastPoint = true
+			continue
+		}
+		point.value = *serie.Points[i][value]                       // store the original value
+		point.timestamp = int64(*serie.Points[i][timestamp] / 1000) // Datadog's API returns timestamps in s
+		point.valid = true
+		return point, i
+	}
+	return point, -1
+}
+
 // setTelemetryMetric is a helper to submit telemetry metrics
 func setTelemetryMetric(val string, metric telemetry.Gauge) error {
 	valFloat, err := strconv.Atoi(val)
@@ -149,8 +280,8 @@ func setTelemetryMetric(val string, metric telemetry.Gauge) error {
 	return err
 }
 
-func (p *Processor) updateRateLimitingMetrics() error {
-	updateMap := p.datadogClient.GetRateLimitStats()
+func (d *datadogBackend) updateRateLimitingMetrics() error {
+	updateMap := d.client.GetRateLimitStats()
 	queryLimits := updateMap[queryEndpoint]
 
 	errors := []error{
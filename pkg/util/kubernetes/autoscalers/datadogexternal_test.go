@@ -115,8 +115,8 @@ func TestDatadogExternalQuery(t *testing.T) {
 			cl := &fakeDatadogClient{
 				queryMetricsFunc: test.queryfunc,
 			}
-			p := Processor{datadogClient: cl}
-			points, err := p.queryDatadogExternal(test.metricName)
+			d := datadogBackend{client: cl}
+			points, err := d.queryDatadogExternal(test.metricName)
 			if test.err != nil {
 				require.EqualError(t, test.err, err.Error())
 			}
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package autoscalers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	utilserror "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/custommetrics"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/externalseries"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// nodeAgentBackend aggregates the series sent by the node agents to the cluster agent
+type nodeAgentBackend struct {
+	store *externalseries.Store
+}
+
+// NewNodeAgentBackend returns a backend aggregating the latest points of the series
+// sent by the node agents across the nodes.
+func NewNodeAgentBackend(store *externalseries.Store) (MetricsBackend, error) {
+	if store == nil {
+		return nil, errors.New("the store of the node agent series is not initialized")
+	}
+	log.Infof("Initialized the node agent backend for HPA")
+	return &nodeAgentBackend{store: store}, nil
+}
+
+// QueryExternalMetrics aggregates the series of each metric having all its labels as tags,
+// the names of the metrics are requested from the node agents on their next flush.
+// Query expressions are not supported.
+func (b *nodeAgentBackend) QueryExternalMetrics(emList map[string]custommetrics.ExternalMetricValue) (map[string]Point, error) {
+	aggregator := config.Datadog.GetString("external_metrics.aggregator")
+	names := make(map[string]struct{})
+	processed := make(map[string]Point, len(emList))
+	var errs []error
+	for _, em := range emList {
+		key := QueryKey(em)
+		if em.Query != "" {
+			errs = append(errs, fmt.Errorf("query expressions are not supported by the node agent backend: %s", em.Query))
+			processed[key] = Point{timestamp: time.Now().Unix()}
+			continue
+		}
+		names[em.MetricName] = struct{}{}
+
+		tags := make([]string, 0, len(em.Labels))
+		for k, v := range em.Labels {
+			tags = append(tags, fmt.Sprintf("%s:%s", k, v))
+		}
+		value, timestamp, err := b.store.Query(em.MetricName, tags, aggregator)
+		if err != nil {
+			errs = append(errs, err)
+			processed[key] = Point{timestamp: time.Now().Unix()}
+			continue
+		}
+		log.Debugf("Validated %s | Value:%v at %d", key, value, timestamp)
+		processed[key] = NewPoint(value, timestamp)
+	}
+
+	wanted := make([]string, 0, len(names))
+	for name := range names {
+		wanted = append(wanted, name)
+	}
+	sort.Strings(wanted)
+	b.store.SetWanted(wanted)
+
+	return processed, utilserror.NewAggregate(errs)
+}
@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package autoscalers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/DataDog/datadog-agent/pkg/clusteragent/api/v1"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/custommetrics"
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/externalseries"
+)
+
+func TestNodeAgentBackend(t *testing.T) {
+	store := externalseries.NewStore(time.Minute)
+	backend, err := NewNodeAgentBackend(store)
+	require.NoError(t, err)
+
+	web := custommetrics.ExternalMetricValue{MetricName: "requests", Labels: map[string]string{"service": "web"}}
+	expression := custommetrics.ExternalMetricValue{MetricName: "requests", Query: "avg:requests{*}"}
+	emList := map[string]custommetrics.ExternalMetricValue{"web": web, "expression": expression}
+
+	// The first query registers the wanted metrics
+	points, err := backend.QueryExternalMetrics(emList)
+	assert.Error(t, err)
+	assert.False(t, points[QueryKey(web)].valid)
+	assert.Equal(t, []string{"requests"}, store.Wanted())
+
+	now := time.Now().Unix()
+	store.Push("node1", []apiv1.SeriesPoint{{Name: "requests", Tags: []string{"service:web", "env:prod"}, Timestamp: now, Value: 10}})
+	store.Push("node2", []apiv1.SeriesPoint{{Name: "requests", Tags: []string{"service:web"}, Timestamp: now, Value: 30}})
+
+	points, err = backend.QueryExternalMetrics(emList)
+	assert.Error(t, err)
+	assert.Equal(t, NewPoint(20, now), points[QueryKey(web)])
+	assert.False(t, points[QueryKey(expression)].valid)
+
+	_, err = NewNodeAgentBackend(nil)
+	assert.Error(t, err)
+}
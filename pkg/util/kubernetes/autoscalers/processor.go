@@ -12,13 +12,10 @@ import (
 	"math"
 	"sort"
 	"strings"
-	"sync"
 	"time"
 
-	"gopkg.in/zorkian/go-datadog-api.v2"
 	autoscalingv2 "k8s.io/api/autoscaling/v2beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	utilserror "k8s.io/apimachinery/pkg/util/errors"
 
 	"github.com/DataDog/datadog-agent/pkg/clusteragent/custommetrics"
 	"github.com/DataDog/datadog-agent/pkg/config"
@@ -26,16 +23,6 @@ import (
 	"github.com/DataDog/watermarkpodautoscaler/pkg/apis/datadoghq/v1alpha1"
 )
 
-const (
-	// chunkSize ensures batch queries are limited in size.
-	chunkSize = 45
-)
-
-type DatadogClient interface {
-	QueryMetrics(from, to int64, query string) ([]datadog.Series, error)
-	GetRateLimitStats() map[string]datadog.RateLimit
-}
-
 // ProcessorInterface is used to easily mock the interface for testing
 type ProcessorInterface interface {
 	UpdateExternalMetrics(emList map[string]custommetrics.ExternalMetricValue) (updated map[string]custommetrics.ExternalMetricValue)
@@ -43,24 +30,20 @@ type ProcessorInterface interface {
 	ProcessEMList(emList []custommetrics.ExternalMetricValue) map[string]custommetrics.ExternalMetricValue
 }
 
-// Processor embeds the configuration to refresh metrics from Datadog and process Ref structs to ExternalMetrics.
+// Processor embeds the configuration to refresh metrics from the backend and process Ref structs to ExternalMetrics.
 type Processor struct {
 	externalMaxAge time.Duration
-	datadogClient  DatadogClient
-}
-
-// queryResponse ensures that we capture all the signals from the call to Datadog's backend.
-type queryResponse struct {
-	metrics map[string]Point
-	err     error
+	fallbackMaxAge time.Duration
+	backend        MetricsBackend
 }
 
 // NewProcessor returns a new Processor
-func NewProcessor(datadogCl DatadogClient) (*Processor, error) {
+func NewProcessor(backend MetricsBackend) (*Processor, error) {
 	externalMaxAge := math.Max(config.Datadog.GetFloat64("external_metrics_provider.max_age"), 3*config.Datadog.GetFloat64("external_metrics_provider.rollup"))
 	return &Processor{
 		externalMaxAge: time.Duration(externalMaxAge) * time.Second,
-		datadogClient:  datadogCl,
+		fallbackMaxAge: config.Datadog.GetDuration("external_metrics_provider.fallback_max_age") * time.Second,
+		backend:        backend,
 	}, nil
 }
 
@@ -70,25 +53,22 @@ func (p *Processor) UpdateExternalMetrics(emList map[string]custommetrics.Extern
 	maxAge := int64(p.externalMaxAge.Seconds())
 	var err error
 	updated = make(map[string]custommetrics.ExternalMetricValue)
-	metrics, err := p.queryExternalMetric(emList)
+	metrics, err := p.backend.QueryExternalMetrics(emList)
 	if len(metrics) == 0 && err != nil {
-		log.Errorf("Error getting metrics from Datadog: %v", err.Error())
-		// If no metrics can be retrieved from Datadog in a given list, we need to invalidate them
-		// To avoid undesirable autoscaling behaviors
-		return invalidate(emList)
+		log.Errorf("Error getting metrics from the external metrics backend: %v", err.Error())
+		// If no metrics can be retrieved from the backend in a given list, we need to invalidate them
+		// To avoid undesirable autoscaling behaviors, unless they have a fallback
+		return p.invalidate(emList)
 	}
 
 	for id, em := range emList {
 		// use query (metricName{scope}) as a key to avoid conflict if multiple hpas are using the same metric with different scopes.
-		metricIdentifier := getKey(em.MetricName, em.Labels)
+		metricIdentifier := QueryKey(em)
 		metric := metrics[metricIdentifier]
 
 		if time.Now().Unix()-metric.timestamp > maxAge || !metric.valid {
-			// invalidating sparse metrics that are outdated
-			em.Valid = false
-			em.Value = metric.value
-			em.Timestamp = time.Now().Unix()
-			updated[id] = em
+			// invalidating sparse metrics that are outdated, unless they have a fallback
+			updated[id] = p.applyFallback(em, metric.value)
 			continue
 		}
 
@@ -145,68 +125,42 @@ func (p *Processor) ProcessWPAs(wpa *v1alpha1.WatermarkPodAutoscaler) map[string
 	return externalMetrics
 }
 
-func makeChunks(batch []string) (chunks [][]string) {
-	for i := 0; i < len(batch); i += chunkSize {
-		if i+chunkSize > len(batch) {
-			chunks = append(chunks, batch[i:])
-			break
-		}
-		chunks = append(chunks, batch[i:i+chunkSize])
+// invalidate invalidates all the external metrics, or applies their fallback if they have one.
+func (p *Processor) invalidate(emList map[string]custommetrics.ExternalMetricValue) (invList map[string]custommetrics.ExternalMetricValue) {
+	invList = make(map[string]custommetrics.ExternalMetricValue)
+	for id, e := range emList {
+		invList[id] = p.applyFallback(e, e.Value)
 	}
-	return chunks
+	return invList
 }
 
-// queryExternalMetric queries Datadog to validate the availability and value of one or more external metrics
-// Also updates the rate limits statistics as a result of the query.
-func (p *Processor) queryExternalMetric(emList map[string]custommetrics.ExternalMetricValue) (processed map[string]Point, err error) {
-	batch := []string{}
-	for _, e := range emList {
-		q := getKey(e.MetricName, e.Labels)
-		batch = append(batch, q)
-	}
-	chunks := makeChunks(batch)
-	log.Tracef("List of batches %v", chunks)
-
-	// we have a number of chunks with `chunkSize` metrics.
-	responses := make(chan queryResponse, len(batch))
-	processed = make(map[string]Point)
-
-	var waitResp sync.WaitGroup
-	waitResp.Add(len(chunks))
-	for _, c := range chunks {
-		go func(chunk []string) {
-			defer waitResp.Done()
-			resp, err := p.queryDatadogExternal(chunk)
-			responses <- queryResponse{resp, err}
-		}(c)
-	}
-	waitResp.Wait()
-	close(responses)
-	var errors []error
-	for elem := range responses {
-		for k, v := range elem.metrics {
-			processed[k] = v
-		}
-		if elem.err != nil {
-			errors = append(errors, elem.err)
+// applyFallback applies the fallback of an external metric that could not be retrieved from Datadog.
+// Without fallback, or if there is no last value to use, the metric is invalidated with the given value.
+// The last value is used until it is older than fallbackMaxAge.
+func (p *Processor) applyFallback(em custommetrics.ExternalMetricValue, value float64) custommetrics.ExternalMetricValue {
+	if em.Fallback != nil {
+		switch em.Fallback.Type {
+		case custommetrics.FallbackLastValue:
+			if em.Valid && time.Now().Unix()-em.Timestamp <= int64(p.fallbackMaxAge.Seconds()) {
+				// Keep the last value and its timestamp
+				log.Debugf("Using the last value of the external metric %s{%v} for %s %s/%s", em.MetricName, em.Labels, em.Ref.Type, em.Ref.Namespace, em.Ref.Name)
+				return em
+			}
+			if em.Valid {
+				log.Infof("The last value of the external metric %s{%v} for %s %s/%s is older than %s, invalidating it", em.MetricName, em.Labels, em.Ref.Type, em.Ref.Namespace, em.Ref.Name, p.fallbackMaxAge)
+			}
+		case custommetrics.FallbackStatic:
+			log.Debugf("Using the static fallback value of the external metric %s{%v} for %s %s/%s", em.MetricName, em.Labels, em.Ref.Type, em.Ref.Namespace, em.Ref.Name)
+			em.Valid = true
+			em.Value = em.Fallback.Value
+			em.Timestamp = metav1.Now().Unix()
+			return em
 		}
 	}
-	log.Debugf("Processed %d chunks", len(chunks))
-
-	if err := p.updateRateLimitingMetrics(); err != nil {
-		errors = append(errors, err)
-	}
-	return processed, utilserror.NewAggregate(errors)
-}
-
-func invalidate(emList map[string]custommetrics.ExternalMetricValue) (invList map[string]custommetrics.ExternalMetricValue) {
-	invList = make(map[string]custommetrics.ExternalMetricValue)
-	for id, e := range emList {
-		e.Valid = false
-		e.Timestamp = metav1.Now().Unix()
-		invList[id] = e
-	}
-	return invList
+	em.Valid = false
+	em.Value = value
+	em.Timestamp = metav1.Now().Unix()
+	return em
 }
 
 func getKey(name string, labels map[string]string) string {
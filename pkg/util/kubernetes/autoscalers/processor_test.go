@@ -139,7 +139,7 @@ func TestProcessor_UpdateExternalMetrics(t *testing.T) {
 					return tt.series, nil
 				},
 			}
-			hpaCl := &Processor{datadogClient: datadogClient, externalMaxAge: maxAge}
+			hpaCl := &Processor{backend: NewDatadogBackend(datadogClient), externalMaxAge: maxAge}
 
 			externalMetrics := hpaCl.UpdateExternalMetrics(tt.metrics)
 			fmt.Println(externalMetrics)
@@ -175,7 +175,7 @@ func TestProcessor_UpdateExternalMetrics(t *testing.T) {
 			return nil, fmt.Errorf("API error 400 Bad Request: {\"error\": [\"Rate limit of 300 requests in 3600 seconds reqchec.\"]}")
 		},
 	}
-	hpaCl := &Processor{datadogClient: datadogClient, externalMaxAge: maxAge}
+	hpaCl := &Processor{backend: NewDatadogBackend(datadogClient), externalMaxAge: maxAge}
 	invList := hpaCl.UpdateExternalMetrics(emList)
 	require.Len(t, invList, len(emList))
 	for _, i := range invList {
@@ -184,6 +184,117 @@ func TestProcessor_UpdateExternalMetrics(t *testing.T) {
 
 }
 
+func TestProcessor_UpdateExternalMetricsWithQuery(t *testing.T) {
+	penTime := (int(time.Now().Unix()) - int(maxAge.Seconds()/2)) * 1000
+	expression := "sum:requests{service:web}.as_count() / sum:kubernetes_state.deployment.replicas_available{deployment:web}"
+	emList := map[string]custommetrics.ExternalMetricValue{
+		"id1": {
+			MetricName: "requests_per_replica",
+			Query:      expression,
+		},
+		"id2": {
+			MetricName: "requests_per_s",
+			Labels:     map[string]string{"foo": "bar"},
+		},
+	}
+
+	var queries []string
+	var m sync.Mutex
+	datadogClient := &fakeDatadogClient{
+		queryMetricsFunc: func(from, to int64, query string) ([]datadog.Series, error) {
+			m.Lock()
+			queries = append(queries, query)
+			m.Unlock()
+			if query != expression {
+				return nil, nil
+			}
+			return []datadog.Series{
+				{
+					Points: []datadog.DataPoint{
+						makePoints(penTime, 42),
+						makePoints(0, 43),
+					},
+					Expression: makePtr(expression),
+				},
+			}, nil
+		},
+	}
+	hpaCl := &Processor{backend: NewDatadogBackend(datadogClient), externalMaxAge: maxAge}
+
+	updated := hpaCl.UpdateExternalMetrics(emList)
+	assert.ElementsMatch(t, []string{expression, "avg:requests_per_s{foo:bar}.rollup(30)"}, queries)
+	require.True(t, updated["id1"].Valid)
+	assert.Equal(t, float64(42), updated["id1"].Value)
+	assert.False(t, updated["id2"].Valid)
+}
+
+func TestProcessor_UpdateExternalMetricsFallback(t *testing.T) {
+	lastValueTs := time.Now().Unix() - 60
+	emList := map[string]custommetrics.ExternalMetricValue{
+		"fail": {
+			MetricName: "fail",
+			Value:      12,
+			Valid:      true,
+			Fallback:   &custommetrics.MetricFallback{Type: custommetrics.FallbackFail},
+		},
+		"last_value": {
+			MetricName: "last_value",
+			Value:      12,
+			Timestamp:  lastValueTs,
+			Valid:      true,
+			Fallback:   &custommetrics.MetricFallback{Type: custommetrics.FallbackLastValue},
+		},
+		"last_value_stale": {
+			MetricName: "last_value_stale",
+			Value:      12,
+			Timestamp:  1300,
+			Valid:      true,
+			Fallback:   &custommetrics.MetricFallback{Type: custommetrics.FallbackLastValue},
+		},
+		"last_value_invalid": {
+			MetricName: "last_value_invalid",
+			Value:      12,
+			Valid:      false,
+			Fallback:   &custommetrics.MetricFallback{Type: custommetrics.FallbackLastValue},
+		},
+		"static": {
+			MetricName: "static",
+			Value:      12,
+			Valid:      false,
+			Fallback:   &custommetrics.MetricFallback{Type: custommetrics.FallbackStatic, Value: 5},
+		},
+	}
+
+	for desc, queryMetrics := range map[string]func(int64, int64, string) ([]datadog.Series, error){
+		"no data": func(int64, int64, string) ([]datadog.Series, error) {
+			return nil, nil
+		},
+		"query error": func(int64, int64, string) ([]datadog.Series, error) {
+			return nil, fmt.Errorf("API error 500 Internal Server Error")
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			hpaCl := &Processor{backend: NewDatadogBackend(&fakeDatadogClient{queryMetricsFunc: queryMetrics}), externalMaxAge: maxAge, fallbackMaxAge: 10 * time.Minute}
+			updated := hpaCl.UpdateExternalMetrics(emList)
+			require.Len(t, updated, len(emList))
+
+			assert.False(t, updated["fail"].Valid)
+
+			assert.True(t, updated["last_value"].Valid)
+			assert.Equal(t, float64(12), updated["last_value"].Value)
+			assert.Equal(t, lastValueTs, updated["last_value"].Timestamp)
+
+			// the last value is dropped once older than the max age
+			assert.False(t, updated["last_value_stale"].Valid)
+
+			assert.False(t, updated["last_value_invalid"].Valid)
+
+			assert.True(t, updated["static"].Valid)
+			assert.Equal(t, float64(5), updated["static"].Value)
+		})
+	}
+}
+
 func TestValidateExternalMetricsBatching(t *testing.T) {
 	metricName := "foo"
 	penTime := (int(time.Now().Unix()) - int(maxAge.Seconds()/2)) * 1000
@@ -290,9 +401,9 @@ func TestValidateExternalMetricsBatching(t *testing.T) {
 					return tt.out, nil
 				},
 			}
-			p := &Processor{datadogClient: datadogClient}
+			d := &datadogBackend{client: datadogClient}
 
-			_, err := p.queryExternalMetric(tt.in)
+			_, err := d.QueryExternalMetrics(tt.in)
 			if err != nil || tt.err != nil {
 				assert.Contains(t, err.Error(), tt.err.Error())
 			}
@@ -422,7 +533,7 @@ func TestProcessor_ProcessHPAs(t *testing.T) {
 	for i, tt := range tests {
 		t.Run(fmt.Sprintf("#%d %s", i, tt.desc), func(t *testing.T) {
 			datadogClient := &fakeDatadogClient{}
-			hpaCl := &Processor{datadogClient: datadogClient, externalMaxAge: maxAge}
+			hpaCl := &Processor{backend: NewDatadogBackend(datadogClient), externalMaxAge: maxAge}
 
 			externalMetrics := hpaCl.ProcessHPAs(&tt.metrics)
 			for id, m := range externalMetrics {
@@ -487,7 +598,8 @@ func TestInvalidate(t *testing.T) {
 		},
 	}
 
-	invalid := invalidate(eml)
+	p := &Processor{}
+	invalid := p.invalidate(eml)
 	for _, e := range invalid {
 		require.False(t, e.Valid)
 		require.WithinDuration(t, time.Now(), time.Unix(e.Timestamp, 0), 5*time.Second)
@@ -571,9 +683,9 @@ func TestUpdateRateLimiting(t *testing.T) {
 					return tt.rateLimits
 				},
 			}
-			hpaCl := &Processor{datadogClient: datadogClient, externalMaxAge: maxAge}
+			d := &datadogBackend{client: datadogClient}
 
-			err := hpaCl.updateRateLimitingMetrics()
+			err := d.updateRateLimitingMetrics()
 			if err != nil {
 				assert.EqualError(t, tt.error, err.Error())
 			}
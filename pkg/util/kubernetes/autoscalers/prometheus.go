@@ -0,0 +1,175 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package autoscalers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	utilserror "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/custommetrics"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	prometheusQueryPath   = "/api/v1/query"
+	prometheusHTTPTimeout = 10 * time.Second
+)
+
+// invalidPrometheusNameChars matches the characters not allowed in Prometheus metric and label names
+var invalidPrometheusNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// prometheusBackend queries the external metrics from a Prometheus-compatible query API
+type prometheusBackend struct {
+	queryURL   string
+	httpClient http.Client
+}
+
+// prometheusResponse is the response of the instant query API
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// prometheusSample is an element of a vector result
+type prometheusSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+// NewPrometheusBackend returns a backend querying the external metrics from the Prometheus-compatible API at baseURL
+func NewPrometheusBackend(baseURL string) (MetricsBackend, error) {
+	if baseURL == "" {
+		return nil, errors.New("missing the URL of the Prometheus query API")
+	}
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("invalid URL for the Prometheus query API: %v", err)
+	}
+	log.Infof("Initialized the Prometheus backend for HPA with %s", baseURL)
+	return &prometheusBackend{
+		queryURL:   strings.TrimSuffix(baseURL, "/") + prometheusQueryPath,
+		httpClient: http.Client{Timeout: prometheusHTTPTimeout, Transport: httputils.CreateHTTPTransport()},
+	}, nil
+}
+
+// QueryExternalMetrics evaluates an instant query per external metric, each query must return a single value
+func (b *prometheusBackend) QueryExternalMetrics(emList map[string]custommetrics.ExternalMetricValue) (map[string]Point, error) {
+	aggregator := config.Datadog.GetString("external_metrics.aggregator")
+	queries := make(map[string]string)
+	for _, em := range emList {
+		queries[QueryKey(em)] = prometheusQuery(em, aggregator)
+	}
+
+	processed := make(map[string]Point, len(queries))
+	var errs []error
+	for key, query := range queries {
+		point, err := b.query(query)
+		if err != nil {
+			errs = append(errs, err)
+			processed[key] = Point{timestamp: time.Now().Unix()}
+			continue
+		}
+		log.Debugf("Validated %s | Value:%v at %d", query, point.value, point.timestamp)
+		processed[key] = point
+	}
+	return processed, utilserror.NewAggregate(errs)
+}
+
+func (b *prometheusBackend) query(query string) (Point, error) {
+	resp, err := b.httpClient.Get(b.queryURL + "?query=" + url.QueryEscape(query))
+	if err != nil {
+		return Point{}, fmt.Errorf("error while executing query %s: %v", query, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Point{}, fmt.Errorf("error while reading the response of the query %s: %v", query, err)
+	}
+	var res prometheusResponse
+	if err = json.Unmarshal(body, &res); err != nil {
+		return Point{}, fmt.Errorf("unexpected response for the query %s, status %s: %v", query, resp.Status, err)
+	}
+	if res.Status != "success" {
+		return Point{}, fmt.Errorf("error while executing query %s: %s", query, res.Error)
+	}
+
+	switch res.Data.ResultType {
+	case "scalar":
+		var sample []interface{}
+		if err = json.Unmarshal(res.Data.Result, &sample); err != nil {
+			return Point{}, err
+		}
+		return parsePrometheusValue(sample)
+	case "vector":
+		var samples []prometheusSample
+		if err = json.Unmarshal(res.Data.Result, &samples); err != nil {
+			return Point{}, err
+		}
+		if len(samples) != 1 {
+			return Point{}, fmt.Errorf("the query %s returned %d series, it must return a single series", query, len(samples))
+		}
+		return parsePrometheusValue(samples[0].Value)
+	default:
+		return Point{}, fmt.Errorf("unsupported result type %q for the query %s", res.Data.ResultType, query)
+	}
+}
+
+// parsePrometheusValue parses a [<timestamp>, "<value>"] pair
+func parsePrometheusValue(sample []interface{}) (Point, error) {
+	if len(sample) != 2 {
+		return Point{}, fmt.Errorf("unexpected value %v", sample)
+	}
+	ts, ok := sample[0].(float64)
+	if !ok {
+		return Point{}, fmt.Errorf("unexpected timestamp %v", sample[0])
+	}
+	str, ok := sample[1].(string)
+	if !ok {
+		return Point{}, fmt.Errorf("unexpected value %v", sample[1])
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return Point{}, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return Point{}, fmt.Errorf("invalid value %s", str)
+	}
+	return NewPoint(value, int64(ts)), nil
+}
+
+// prometheusQuery returns the query expression of the external metric, or aggregates
+// the metric matching its labels. Characters not allowed by Prometheus are replaced by `_`.
+func prometheusQuery(em custommetrics.ExternalMetricValue, aggregator string) string {
+	if em.Query != "" {
+		return em.Query
+	}
+	matchers := make([]string, 0, len(em.Labels))
+	for key, val := range em.Labels {
+		matchers = append(matchers, fmt.Sprintf("%s=%s", invalidPrometheusNameChars.ReplaceAllString(key, "_"), strconv.Quote(val)))
+	}
+	sort.Strings(matchers)
+	return fmt.Sprintf("%s(%s{%s})", aggregator, invalidPrometheusNameChars.ReplaceAllString(em.MetricName, "_"), strings.Join(matchers, ","))
+}
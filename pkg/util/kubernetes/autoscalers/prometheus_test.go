@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2017-2020 Datadog, Inc.
+
+// +build kubeapiserver
+
+package autoscalers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/clusteragent/custommetrics"
+)
+
+func TestPrometheusQuery(t *testing.T) {
+	em := custommetrics.ExternalMetricValue{
+		MetricName: "nginx.net.request_per_s",
+		Labels:     map[string]string{"kube_container_name": "nginx", "app.kubernetes.io/name": "web"},
+	}
+	assert.Equal(t, `avg(nginx_net_request_per_s{app_kubernetes_io_name="web",kube_container_name="nginx"})`, prometheusQuery(em, "avg"))
+
+	em.Query = `sum(rate(http_requests_total{service="web"}[1m]))`
+	assert.Equal(t, em.Query, prometheusQuery(em, "avg"))
+}
+
+func TestPrometheusBackend(t *testing.T) {
+	responses := map[string]string{
+		"vector":   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1582000000.123,"42.5"]}]}}`,
+		"scalar":   `{"status":"success","data":{"resultType":"scalar","result":[1582000000,"3"]}}`,
+		"multiple": `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"a":"1"},"value":[1582000000,"1"]},{"metric":{"a":"2"},"value":[1582000000,"2"]}]}}`,
+		"empty":    `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		"error":    `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+		"nan":      `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1582000000,"NaN"]}]}}`,
+		"inf":      `{"status":"success","data":{"resultType":"scalar","result":[1582000000,"+Inf"]}}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, prometheusQueryPath, r.URL.Path)
+		fmt.Fprint(w, responses[r.URL.Query().Get("query")])
+	}))
+	defer ts.Close()
+
+	backend, err := NewPrometheusBackend(ts.URL + "/")
+	require.NoError(t, err)
+
+	emList := make(map[string]custommetrics.ExternalMetricValue)
+	for query := range responses {
+		emList[query] = custommetrics.ExternalMetricValue{MetricName: query, Query: query}
+	}
+	points, err := backend.QueryExternalMetrics(emList)
+	assert.Error(t, err)
+	require.Len(t, points, len(responses))
+
+	assert.Equal(t, NewPoint(42.5, 1582000000), points["vector"])
+	assert.Equal(t, NewPoint(3, 1582000000), points["scalar"])
+	assert.False(t, points["multiple"].valid)
+	assert.False(t, points["empty"].valid)
+	assert.False(t, points["error"].valid)
+	assert.False(t, points["nan"].valid)
+	assert.False(t, points["inf"].valid)
+
+	_, err = NewPrometheusBackend("")
+	assert.Error(t, err)
+}
@@ -26,6 +26,10 @@ func GetNodeLabels() (map[string]string, error) {
 	}
 
 	if config.Datadog.GetBool("cluster_agent.enabled") {
+		// The labels are pushed by the cluster agent when subscribed to its metadata stream
+		if cache := clusteragent.GetSyncedMetadataCache(); cache != nil {
+			return cache.GetNodeLabels(), nil
+		}
 		cl, err := clusteragent.GetClusterAgentClient()
 		if err != nil {
 			return nil, err
@@ -16,6 +16,15 @@ var tmplVarRegex = regexp.MustCompile(`%%.+?%%`)
 // TemplateVar is the info for a parsed template variable.
 type TemplateVar struct {
 	Raw, Name, Key []byte
+	// Filters are the filter functions applied to the value of the variable,
+	// separated from its name by pipes, e.g. %%kube_namespace|uppercase%%
+	Filters []Filter
+}
+
+// Filter is a filter function of a template variable, with its optional
+// argument, e.g. default:value
+type Filter struct {
+	Name, Arg []byte
 }
 
 // ParseString returns parsed template variables found in the input string.
@@ -28,8 +37,9 @@ func Parse(b []byte) []TemplateVar {
 	var parsed []TemplateVar
 	vars := tmplVarRegex.FindAll(b, -1)
 	for _, v := range vars {
-		name, key := parseTemplateVar(v)
-		parsed = append(parsed, TemplateVar{v, name, key})
+		parts := bytes.Split(bytes.Trim(v, "%"), []byte("|"))
+		name, key := parseTemplateVar(parts[0])
+		parsed = append(parsed, TemplateVar{v, name, key, parseFilters(parts[1:])})
 	}
 	return parsed
 }
@@ -52,3 +62,21 @@ func parseTemplateVar(v []byte) (name, key []byte) {
 	}
 	return name, key
 }
+
+// parseFilters extracts the filter functions of a template variable. Unlike
+// variable names, the arguments of filters keep their inner spaces.
+func parseFilters(parts [][]byte) []Filter {
+	if len(parts) == 0 {
+		return nil
+	}
+	filters := make([]Filter, 0, len(parts))
+	for _, part := range parts {
+		split := bytes.SplitN(part, []byte(":"), 2)
+		filter := Filter{Name: bytes.TrimSpace(split[0])}
+		if len(split) == 2 {
+			filter.Arg = bytes.TrimSpace(split[1])
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
@@ -51,3 +51,22 @@ func TestParseTemplateVar(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFilters(t *testing.T) {
+	vars := ParseString(`url: http://%%host%%:%%port_http|default:80%%/%%kube_label_app.kubernetes.io/name | lowercase | default: my app %%`)
+	assert.Len(t, vars, 3)
+
+	assert.Equal(t, "host", string(vars[0].Name))
+	assert.Empty(t, vars[0].Filters)
+
+	assert.Equal(t, "port", string(vars[1].Name))
+	assert.Equal(t, "http", string(vars[1].Key))
+	assert.Equal(t, []Filter{{Name: []byte("default"), Arg: []byte("80")}}, vars[1].Filters)
+
+	assert.Equal(t, "kube", string(vars[2].Name))
+	assert.Equal(t, "label_app.kubernetes.io/name", string(vars[2].Key))
+	assert.Equal(t, []Filter{
+		{Name: []byte("lowercase")},
+		{Name: []byte("default"), Arg: []byte("my app")},
+	}, vars[2].Filters)
+}